@@ -1,8 +1,9 @@
 module github.com/honerlaw/mentordoc
 
-go 1.12
+go 1.13
 
 require (
+	github.com/blevesearch/bleve v1.0.14
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-chi/chi v4.0.2+incompatible
 	github.com/go-chi/cors v1.0.0
@@ -10,15 +11,18 @@ require (
 	github.com/go-playground/universal-translator v0.16.0 // indirect
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/golang-migrate/migrate v3.5.4+incompatible
-	github.com/google/go-cmp v0.3.1 // indirect
 	github.com/joho/godotenv v1.3.0
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/leodido/go-urn v1.1.0 // indirect
-	github.com/pkg/errors v0.8.1
+	github.com/microcosm-cc/bluemonday v1.0.5
+	github.com/pkg/errors v0.9.1
 	github.com/rubenv/sql-migrate v0.0.0-20190902133344-8926f37f0bc1
 	github.com/satori/go.uuid v1.2.0
 	github.com/stretchr/testify v1.4.0
+	github.com/yuin/goldmark v1.2.1
 	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4
 	gopkg.in/go-playground/validator.v9 v9.29.1
 	gopkg.in/gorp.v1 v1.7.2 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 	gotest.tools v2.2.0+incompatible
 )
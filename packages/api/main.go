@@ -1,18 +1,37 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/honerlaw/mentordoc/server/http"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/util"
 	"github.com/joho/godotenv"
 	"log"
 	"sync"
 )
 
 func main() {
+	aclDryRun := flag.Bool("acl-dry-run", false, "print the changes the acl policy would make to the roles without applying them")
+	reindexSearch := flag.Bool("reindex-search", false, "rebuild the search index from the database")
+	flag.Parse()
+
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if *aclDryRun {
+		printAclPolicyChanges()
+		return
+	}
+
+	if *reindexSearch {
+		rebuildSearchIndex()
+		return
+	}
+
 	waitGroup := &sync.WaitGroup{}
 	waitGroup.Add(1)
 
@@ -20,3 +39,43 @@ func main() {
 
 	waitGroup.Wait()
 }
+
+func printAclPolicyChanges() {
+	policy, err := acl.NewPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db := util.NewDb()
+	aclService := acl.NewAclService(util.NewTransactionManager(db, nil), policy, db, nil)
+
+	changes, err := aclService.Reconcile(true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("the roles are up to date with the acl policy")
+		return
+	}
+
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+}
+
+func rebuildSearchIndex() {
+	db := util.NewDb()
+	searchIndex, err := document.NewSearchIndexFromEnv(document.NewDocumentRepository(db, nil), document.NewDocumentDraftRepository(db, nil))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer searchIndex.Close()
+
+	err = searchIndex.Rebuild()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("the search index has been rebuilt")
+}
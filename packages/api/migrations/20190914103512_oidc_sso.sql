@@ -0,0 +1,23 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- users that only sign in through single sign on do not have a password
+ALTER TABLE `user` MODIFY `password` varchar(255) NULL DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS `oidc_auth_request` (
+  `id` CHAR(36) NOT NULL,
+  `state` varchar(255) NOT NULL,
+  `code_verifier` varchar(255) NOT NULL,
+  `nonce` varchar(255) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_oidc_auth_request_state` (`state`),
+  KEY `idx_oidc_auth_request_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `oidc_auth_request`;
+ALTER TABLE `user` MODIFY `password` varchar(255) NOT NULL;
@@ -0,0 +1,23 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE IF NOT EXISTS `personal_access_token` (
+  `id` CHAR(36) NOT NULL,
+  `user_id` CHAR(36) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `token_hash` CHAR(64) NOT NULL,
+  `scopes` varchar(1024) NOT NULL,
+  `expires_at` BIGINT NOT NULL,
+  `last_used_at` BIGINT NULL DEFAULT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`user_id`) REFERENCES user(`id`),
+  UNIQUE KEY `uk_personal_access_token_token_hash` (`token_hash`),
+  KEY `idx_personal_access_token_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `personal_access_token`;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- drafts and history of deleted users are handed over to this user, it is marked as deleted so it can never sign in
+INSERT INTO `user` (`id`, `email`, `password`, `created_at`, `updated_at`, `deleted_at`)
+VALUES ('00000000-0000-0000-0000-000000000000', 'deleted-user@mentordoc.invalid', NULL, 0, 0, 0);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DELETE FROM `user` WHERE `id` = '00000000-0000-0000-0000-000000000000';
@@ -0,0 +1,11 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- roles without an organization are the built in roles, the rest are custom roles of that organization
+ALTER TABLE `role` ADD `organization_id` CHAR(36) NULL DEFAULT NULL;
+ALTER TABLE `role` ADD CONSTRAINT `fk_role_organization_id` FOREIGN KEY (`organization_id`) REFERENCES organization(`id`);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE `role` DROP FOREIGN KEY `fk_role_organization_id`;
+ALTER TABLE `role` DROP `organization_id`;
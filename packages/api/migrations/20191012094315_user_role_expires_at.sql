@@ -0,0 +1,11 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- grants without an expiration never expire
+ALTER TABLE `user_role` ADD `expires_at` BIGINT NULL DEFAULT NULL;
+ALTER TABLE `user_role` ADD KEY `idx_user_role_expires_at` (`expires_at`);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE `user_role` DROP KEY `idx_user_role_expires_at`;
+ALTER TABLE `user_role` DROP `expires_at`;
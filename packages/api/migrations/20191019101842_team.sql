@@ -0,0 +1,38 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS `team` (
+  `id` CHAR(36) NOT NULL,
+  `organization_id` CHAR(36) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`organization_id`) REFERENCES organization(`id`),
+  KEY `idx_team_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `team_member` (
+  `team_id` CHAR(36) NOT NULL,
+  `user_id` CHAR(36) NOT NULL,
+  PRIMARY KEY (`team_id`, `user_id`),
+  FOREIGN KEY (`team_id`) REFERENCES team(`id`),
+  FOREIGN KEY (`user_id`) REFERENCES user(`id`),
+  KEY `idx_team_member_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- the same as user_role, every member of the team is granted the role on the resource
+CREATE TABLE IF NOT EXISTS `team_role` (
+  `team_id` CHAR(36) NOT NULL,
+  `role_id` CHAR(36) NOT NULL,
+  `resource_id` CHAR(36) NOT NULL,
+  PRIMARY KEY (`team_id`, `role_id`, `resource_id`),
+  FOREIGN KEY (`team_id`) REFERENCES team(`id`),
+  FOREIGN KEY (`role_id`) REFERENCES role(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `team_role`;
+DROP TABLE `team_member`;
+DROP TABLE `team`;
@@ -0,0 +1,25 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- a deny belongs to either a user or a role, and takes precedence over every role that would grant the action
+CREATE TABLE IF NOT EXISTS `acl_deny` (
+  `id` CHAR(36) NOT NULL,
+  `organization_id` CHAR(36) NOT NULL,
+  `user_id` CHAR(36) NULL DEFAULT NULL,
+  `role_id` CHAR(36) NULL DEFAULT NULL,
+  `resource_path` varchar(255) NOT NULL,
+  `resource_id` CHAR(36) NOT NULL,
+  `action` varchar(255) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`organization_id`) REFERENCES organization(`id`),
+  FOREIGN KEY (`user_id`) REFERENCES user(`id`),
+  FOREIGN KEY (`role_id`) REFERENCES role(`id`),
+  KEY `idx_acl_deny_user_id` (`user_id`),
+  KEY `idx_acl_deny_role_id` (`role_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `acl_deny`;
@@ -0,0 +1,24 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- the files themselves are kept in the blob store under storage_key
+CREATE TABLE IF NOT EXISTS `attachment` (
+  `id` CHAR(36) NOT NULL,
+  `document_id` CHAR(36) NOT NULL,
+  `creator_id` CHAR(36) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `content_type` varchar(255) NOT NULL,
+  `size` BIGINT NOT NULL,
+  `storage_key` varchar(255) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`document_id`) REFERENCES document(`id`),
+  FOREIGN KEY (`creator_id`) REFERENCES user(`id`),
+  KEY `idx_attachment_document_id` (`document_id`),
+  KEY `idx_attachment_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `attachment`;
@@ -0,0 +1,45 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- a thread is on a single draft, and can be anchored to a range of its content or to one of its lines
+CREATE TABLE IF NOT EXISTS `comment_thread` (
+  `id` CHAR(36) NOT NULL,
+  `document_id` CHAR(36) NOT NULL,
+  `document_draft_id` CHAR(36) NOT NULL,
+  `creator_id` CHAR(36) NOT NULL,
+  `anchor_start` INT NULL DEFAULT NULL,
+  `anchor_end` INT NULL DEFAULT NULL,
+  `anchor_line` INT NULL DEFAULT NULL,
+  `anchor_text` TEXT NULL,
+  `resolved_at` BIGINT NULL DEFAULT NULL,
+  `resolved_by` CHAR(36) NULL DEFAULT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`document_id`) REFERENCES document(`id`),
+  FOREIGN KEY (`document_draft_id`) REFERENCES document_draft(`id`),
+  FOREIGN KEY (`creator_id`) REFERENCES user(`id`),
+  KEY `idx_comment_thread_document_id` (`document_id`),
+  KEY `idx_comment_thread_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `comment` (
+  `id` CHAR(36) NOT NULL,
+  `comment_thread_id` CHAR(36) NOT NULL,
+  `creator_id` CHAR(36) NOT NULL,
+  `content` TEXT NOT NULL,
+  `edited_at` BIGINT NULL DEFAULT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`comment_thread_id`) REFERENCES comment_thread(`id`),
+  FOREIGN KEY (`creator_id`) REFERENCES user(`id`),
+  KEY `idx_comment_comment_thread_id` (`comment_thread_id`),
+  KEY `idx_comment_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `comment`;
+DROP TABLE `comment_thread`;
@@ -0,0 +1,55 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- the approvals a draft needs before it can be published, set on an organization (folder_id is null) or on a folder,
+-- the policy of the nearest folder applies before the policy of the organization
+CREATE TABLE IF NOT EXISTS `review_policy` (
+  `id` CHAR(36) NOT NULL,
+  `organization_id` CHAR(36) NOT NULL,
+  `folder_id` CHAR(36) NULL DEFAULT NULL,
+  `required_approvals` INT NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`organization_id`) REFERENCES organization(`id`),
+  FOREIGN KEY (`folder_id`) REFERENCES folder(`id`),
+  KEY `idx_review_policy_organization_id` (`organization_id`),
+  KEY `idx_review_policy_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- a draft is in review from when its creator requests a review until it is published or the request is withdrawn
+CREATE TABLE IF NOT EXISTS `document_review_request` (
+  `id` CHAR(36) NOT NULL,
+  `document_draft_id` CHAR(36) NOT NULL,
+  `requester_id` CHAR(36) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`document_draft_id`) REFERENCES document_draft(`id`),
+  FOREIGN KEY (`requester_id`) REFERENCES user(`id`),
+  KEY `idx_document_review_request_document_draft_id` (`document_draft_id`),
+  KEY `idx_document_review_request_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `document_review` (
+  `id` CHAR(36) NOT NULL,
+  `document_draft_id` CHAR(36) NOT NULL,
+  `reviewer_id` CHAR(36) NOT NULL,
+  `decision` varchar(32) NOT NULL,
+  `comment` TEXT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`document_draft_id`) REFERENCES document_draft(`id`),
+  FOREIGN KEY (`reviewer_id`) REFERENCES user(`id`),
+  KEY `idx_document_review_document_draft_id` (`document_draft_id`),
+  KEY `idx_document_review_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `document_review`;
+DROP TABLE `document_review_request`;
+DROP TABLE `review_policy`;
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- when the scheduler publishes or retracts the draft, cleared once it does
+ALTER TABLE `document_draft` ADD `publish_at` BIGINT NULL DEFAULT NULL;
+ALTER TABLE `document_draft` ADD `retract_at` BIGINT NULL DEFAULT NULL;
+ALTER TABLE `document_draft` ADD KEY `idx_document_draft_publish_at` (`publish_at`);
+ALTER TABLE `document_draft` ADD KEY `idx_document_draft_retract_at` (`retract_at`);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE `document_draft` DROP KEY `idx_document_draft_retract_at`;
+ALTER TABLE `document_draft` DROP KEY `idx_document_draft_publish_at`;
+ALTER TABLE `document_draft` DROP `retract_at`;
+ALTER TABLE `document_draft` DROP `publish_at`;
@@ -0,0 +1,36 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- tags are scoped to an organization, the names are unique within it among the tags that are not deleted
+CREATE TABLE IF NOT EXISTS `tag` (
+  `id` CHAR(36) NOT NULL,
+  `organization_id` CHAR(36) NOT NULL,
+  `name` varchar(64) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`organization_id`) REFERENCES organization(`id`),
+  KEY `idx_tag_organization_id_name` (`organization_id`, `name`),
+  KEY `idx_tag_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `document_tag` (
+  `id` CHAR(36) NOT NULL,
+  `document_id` CHAR(36) NOT NULL,
+  `tag_id` CHAR(36) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`document_id`) REFERENCES document(`id`),
+  FOREIGN KEY (`tag_id`) REFERENCES tag(`id`),
+  KEY `idx_document_tag_document_id` (`document_id`),
+  KEY `idx_document_tag_tag_id` (`tag_id`),
+  KEY `idx_document_tag_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `document_tag`;
+DROP TABLE `tag`;
@@ -0,0 +1,23 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- the documents each draft links to, parsed out of its content when the draft is saved. The target is not a foreign key
+-- so that links to documents that never existed are kept and reported as broken
+CREATE TABLE IF NOT EXISTS `document_link` (
+  `id` CHAR(36) NOT NULL,
+  `document_id` CHAR(36) NOT NULL,
+  `document_draft_id` CHAR(36) NOT NULL,
+  `target_document_id` CHAR(36) NOT NULL,
+  `created_at` BIGINT NOT NULL,
+  `updated_at` BIGINT NOT NULL,
+  `deleted_at` BIGINT NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`document_id`) REFERENCES document(`id`),
+  FOREIGN KEY (`document_draft_id`) REFERENCES document_draft(`id`),
+  KEY `idx_document_link_document_draft_id` (`document_draft_id`),
+  KEY `idx_document_link_target_document_id` (`target_document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE `document_link`;
@@ -0,0 +1,92 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestIntegrationExplainGrantedAccess(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "test-name", "test content")
+	assert.Nil(t, err)
+
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   fmt.Sprintf("/acl/explain?resource=document:%s&action=view", doc.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		ResponseModel: &acl.AclExplanation{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	explanation := resp.(*acl.AclExplanation)
+	assert.True(t, explanation.Granted)
+	assert.Len(t, explanation.Grants, 1)
+	assert.Equal(t, "organization:owner", explanation.Grants[0].RoleName)
+	assert.Equal(t, "organization:folder:document", explanation.Grants[0].ResourcePath)
+	assert.Equal(t, authData.Organization.Id, explanation.Grants[0].ResourceId)
+	assert.Equal(t, "view", explanation.Grants[0].Action)
+}
+
+func TestIntegrationExplainDeniedAccessForAnotherUser(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "test-name", "test content")
+	assert.Nil(t, err)
+
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   fmt.Sprintf("/acl/explain?resource=document:%s&action=view&userId=%s", doc.Id, otherAuthData.User.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		ResponseModel: &acl.AclExplanation{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	explanation := resp.(*acl.AclExplanation)
+	assert.False(t, explanation.Granted)
+	assert.Equal(t, otherAuthData.User.Id, explanation.UserId)
+	assert.Len(t, explanation.Grants, 0)
+}
+
+func TestIntegrationExplainFailsWhenNotOwner(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	err := testData.TestServer.AclService.LinkUserToRole(otherAuthData.User, "organization:contributor", authData.Organization.Id)
+	assert.Nil(t, err)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "test-name", "test content")
+	assert.Nil(t, err)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   fmt.Sprintf("/acl/explain?resource=document:%s&action=view", doc.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", otherAuthData.AccessToken),
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+}
@@ -0,0 +1,83 @@
+package server_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+func TestIntegrationAttachments(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "setup", "# Setup")
+	assert.Nil(t, err)
+
+	upload := func(accessToken string, name string, content []byte, responseModel interface{}) (int, interface{}) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile("file", name)
+		assert.Nil(t, err)
+		_, err = part.Write(content)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Close())
+
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "POST",
+			Path:   fmt.Sprintf("/document/%s/attachment", doc.Id),
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+				"Content-Type":  writer.FormDataContentType(),
+			},
+			Body:          body.Bytes(),
+			ResponseModel: responseModel,
+		})
+		assert.Nil(t, err)
+		return status, resp
+	}
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+	status, resp := upload(authData.AccessToken, "../screens/login.png", png, &shared.Attachment{})
+	assert.Equal(t, http.StatusCreated, status)
+	created := resp.(*shared.Attachment)
+	assert.Equal(t, "login.png", created.Name)
+	assert.Equal(t, "image/png", created.ContentType)
+	assert.Equal(t, int64(len(png)), created.Size)
+
+	// the type comes from the content, not the name
+	status, _ = upload(authData.AccessToken, "logo.png", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"><script>alert(1)</script></svg>"), &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// attachments are checked through the document
+	status, _ = upload(otherAuthData.AccessToken, "login.png", png, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = test.Send(t, "GET", fmt.Sprintf("/attachment/%s", created.Id), otherAuthData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = test.Send(t, "DELETE", fmt.Sprintf("/attachment/%s", created.Id), otherAuthData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	attachments := make([]shared.Attachment, 0)
+	status, resp = test.Send(t, "GET", fmt.Sprintf("/document/%s/attachment/list", doc.Id), authData.AccessToken, nil, &attachments)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, *resp.(*[]shared.Attachment), 1)
+
+	status, resp = test.Send(t, "GET", fmt.Sprintf("/attachment/%s", created.Id), authData.AccessToken, nil, true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, png, resp.([]byte))
+
+	status, _ = test.Send(t, "DELETE", fmt.Sprintf("/attachment/%s", created.Id), authData.AccessToken, nil, &shared.Attachment{})
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = test.Send(t, "GET", fmt.Sprintf("/attachment/%s", created.Id), authData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusNotFound, status)
+	_, err = testData.TestServer.BlobStore.Get(fmt.Sprintf("attachments/%s/%s", doc.Id, created.Id))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,117 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestIntegrationCommentThreads(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	reviewerData := test.SetupAuthentication(t, testData)
+	viewerData := test.SetupAuthentication(t, testData)
+
+	folder, err := testData.TestServer.FolderService.Create(authData.User, "handbook", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, &folder.Id, "setup", "# Setup\nInstall go")
+	assert.Nil(t, err)
+	draftId := doc.Drafts[0].Id
+
+	err = testData.TestServer.AclService.LinkUserToRole(reviewerData.User, "organization:contributor", authData.Organization.Id)
+	assert.Nil(t, err)
+	err = testData.TestServer.AclService.LinkUserToRole(viewerData.User, "folder:viewer", folder.Id)
+	assert.Nil(t, err)
+
+	start := 2
+	end := 7
+	outside := 500
+
+	// the draft has not been published, so only its creator can comment on it
+	threadPath := fmt.Sprintf("/document/%s/draft/%s/thread", doc.Id, draftId)
+	status, _ := test.Send(t, "POST", threadPath, reviewerData.AccessToken, &request.CommentThreadCreateRequest{Content: "looks good"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	_, err = testData.TestServer.DocumentService.Update(authData.User, doc.Id, draftId, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+
+	status, resp := test.Send(t, "POST", threadPath, reviewerData.AccessToken, &request.CommentThreadCreateRequest{
+		Content: "which version?",
+		Start:   &start,
+		End:     &end,
+	}, &shared.CommentThread{})
+	assert.Equal(t, http.StatusCreated, status)
+	thread := resp.(*shared.CommentThread)
+	assert.Equal(t, "Setup", thread.Anchor.Text)
+	assert.Len(t, thread.Comments, 1)
+
+	status, _ = test.Send(t, "POST", threadPath, reviewerData.AccessToken, &request.CommentThreadCreateRequest{
+		Content: "out of range",
+		Start:   &start,
+		End:     &outside,
+	}, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, resp = test.Send(t, "POST", fmt.Sprintf("/thread/%s/comment", thread.Id), authData.AccessToken, &request.CommentCreateRequest{Content: "1.13"}, &shared.Comment{})
+	assert.Equal(t, http.StatusCreated, status)
+	reply := resp.(*shared.Comment)
+
+	// only the author can change a comment
+	status, _ = test.Send(t, "PUT", fmt.Sprintf("/comment/%s", reply.Id), reviewerData.AccessToken, &request.CommentUpdateRequest{Content: "1.12"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, resp = test.Send(t, "PUT", fmt.Sprintf("/comment/%s", reply.Id), authData.AccessToken, &request.CommentUpdateRequest{Content: "go 1.13"}, &shared.Comment{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotNil(t, resp.(*shared.Comment).EditedAt)
+
+	status, resp = test.Send(t, "PUT", fmt.Sprintf("/thread/%s/resolve", thread.Id), authData.AccessToken, nil, &shared.CommentThread{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, authData.User.Id, *resp.(*shared.CommentThread).ResolvedBy)
+	status, _ = test.Send(t, "PUT", fmt.Sprintf("/thread/%s/resolve", thread.Id), authData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	threads := make([]shared.CommentThread, 0)
+	status, resp = test.Send(t, "GET", fmt.Sprintf("/document/%s/thread/list?resolved=false", doc.Id), reviewerData.AccessToken, nil, &threads)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, *resp.(*[]shared.CommentThread), 0)
+
+	status, resp = test.Send(t, "PUT", fmt.Sprintf("/thread/%s/reopen", thread.Id), reviewerData.AccessToken, nil, &shared.CommentThread{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, resp.(*shared.CommentThread).ResolvedAt)
+
+	threads = make([]shared.CommentThread, 0)
+	status, resp = test.Send(t, "GET", fmt.Sprintf("/document/%s/thread/list?resolved=false", doc.Id), reviewerData.AccessToken, nil, &threads)
+	assert.Equal(t, http.StatusOK, status)
+	listed := *resp.(*[]shared.CommentThread)
+	assert.Len(t, listed, 1)
+	assert.Equal(t, []string{"which version?", "go 1.13"}, []string{listed[0].Comments[0].Content, listed[0].Comments[1].Content})
+
+	// viewers of the folder can see the document but not its comments
+	status, _ = test.Send(t, "GET", fmt.Sprintf("/document/%s/thread/list", doc.Id), viewerData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// deleting the last comment deletes the thread
+	status, _ = test.Send(t, "DELETE", fmt.Sprintf("/comment/%s", reply.Id), authData.AccessToken, nil, &shared.Comment{})
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = test.Send(t, "DELETE", fmt.Sprintf("/comment/%s", thread.Comments[0].Id), reviewerData.AccessToken, nil, &shared.Comment{})
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = test.Send(t, "PUT", fmt.Sprintf("/thread/%s/resolve", thread.Id), authData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	actions := make([]string, 0)
+	for _, userId := range []string{authData.User.Id, reviewerData.User.Id} {
+		history, err := testData.TestServer.ResourceHistoryService.FindByUserId(userId)
+		assert.Nil(t, err)
+		for _, entry := range history {
+			if entry.ResourceId == thread.Id {
+				actions = append(actions, entry.Action)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"created", "resolved", "reopened", "deleted"}, actions)
+}
@@ -0,0 +1,251 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestIntegrationCreateDenyFailsWithUnknownResource(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	// the folder belongs to another organization
+	folder, err := testData.TestServer.FolderService.Create(otherAuthData.User, "other", otherAuthData.Organization.Id, nil)
+	assert.Nil(t, err)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/organization/%s/deny", authData.Organization.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		Body: &request.DenyCreateRequest{
+			UserId:       &otherAuthData.User.Id,
+			ResourcePath: "folder",
+			ResourceId:   folder.Id,
+			Action:       "view",
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestIntegrationDenyTakesPrecedenceOverRoles(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+	aclService := testData.TestServer.AclService
+
+	err := aclService.LinkUserToRole(otherAuthData.User, "organization:contributor", authData.Organization.Id)
+	assert.Nil(t, err)
+
+	legal, err := testData.TestServer.FolderService.Create(authData.User, "legal", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+
+	// created by the contributor, so the drafts show up in their lists without publishing them
+	legalDoc, err := testData.TestServer.DocumentService.Create(otherAuthData.User, authData.Organization.Id, &legal.Id, "contract", "indemnification clauses")
+	assert.Nil(t, err)
+	otherDoc, err := testData.TestServer.DocumentService.Create(otherAuthData.User, authData.Organization.Id, nil, "notes", "meeting notes")
+	assert.Nil(t, err)
+
+	assert.True(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, legalDoc, "modify"))
+	documents, err := testData.TestServer.DocumentService.Search(otherAuthData.User, "indemnification", nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, documents, 1)
+
+	// contributors may edit everything except the legal folder
+	roleId := findRoleId(t, "organization:contributor")
+	deny := createDeny(t, headers, authData.Organization.Id, &request.DenyCreateRequest{
+		RoleId:       &roleId,
+		ResourcePath: "folder",
+		ResourceId:   legal.Id,
+		Action:       "modify",
+	})
+
+	assert.False(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, legal, "modify"))
+	assert.False(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, legalDoc, "modify"))
+	assert.True(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, legalDoc, "view"))
+	assert.True(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, otherDoc, "modify"))
+	assert.True(t, aclService.UserCanAccessResourceByModel(authData.User, legalDoc, "modify"))
+
+	wrapped, err := aclService.Wrap(otherAuthData.User, []shared.Document{*legalDoc, *otherDoc})
+	assert.Nil(t, err)
+	assert.NotContains(t, wrapped[0].Actions, "modify")
+	assert.Contains(t, wrapped[0].Actions, "view")
+	assert.Contains(t, wrapped[1].Actions, "modify")
+
+	explanation, err := aclService.Explain(otherAuthData.User, []string{"organization", "folder", "document"}, []string{authData.Organization.Id, legal.Id, legalDoc.Id}, "modify")
+	assert.Nil(t, err)
+	assert.False(t, explanation.Granted)
+	assert.Len(t, explanation.Denies, 1)
+	assert.Equal(t, deny.Id, explanation.Denies[0].Id)
+
+	// denying everything on the folder hides it and its documents from the lists and search
+	createDeny(t, headers, authData.Organization.Id, &request.DenyCreateRequest{
+		UserId:       &otherAuthData.User.Id,
+		ResourcePath: "folder",
+		ResourceId:   legal.Id,
+		Action:       acl.DenyAllActions,
+	})
+
+	// contributors can not list the root folders of an organization, so the folder itself is checked instead
+	assert.False(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, legal, "view"))
+
+	_, err = testData.TestServer.DocumentService.List(otherAuthData.User, authData.Organization.Id, &legal.Id, nil, nil)
+	assert.NotNil(t, err)
+
+	documents, err = testData.TestServer.DocumentService.Search(otherAuthData.User, "indemnification", nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, documents, 0)
+
+	documents, err = testData.TestServer.DocumentService.List(otherAuthData.User, authData.Organization.Id, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, documents, 1)
+	assert.Equal(t, otherDoc.Id, documents[0].Id)
+
+	// removing the deny gives the action back
+	status, _, err := test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/deny/%s", authData.Organization.Id, deny.Id),
+		Headers:       headers,
+		ResponseModel: &acl.AclDeny{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	denies := make([]acl.AclDeny, 0)
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "GET",
+		Path:          fmt.Sprintf("/organization/%s/deny/list", authData.Organization.Id),
+		Headers:       headers,
+		ResponseModel: &denies,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, denies, 1)
+}
+
+func TestIntegrationRoleDenyOnlyAppliesInItsOrganization(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	contributorAuthData := test.SetupAuthentication(t, testData)
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+	aclService := testData.TestServer.AclService
+
+	// the owner of the first organization is only a contributor in the second one
+	err := aclService.LinkUserToRole(authData.User, "organization:contributor", otherAuthData.Organization.Id)
+	assert.Nil(t, err)
+	err = aclService.LinkUserToRole(contributorAuthData.User, "organization:contributor", authData.Organization.Id)
+	assert.Nil(t, err)
+
+	legal, err := testData.TestServer.FolderService.Create(authData.User, "legal", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+
+	roleId := findRoleId(t, "organization:contributor")
+	createDeny(t, headers, authData.Organization.Id, &request.DenyCreateRequest{
+		RoleId:       &roleId,
+		ResourcePath: "folder",
+		ResourceId:   legal.Id,
+		Action:       "modify",
+	})
+
+	assert.False(t, aclService.UserCanAccessResourceByModel(contributorAuthData.User, legal, "modify"))
+	assert.True(t, aclService.UserCanAccessResourceByModel(authData.User, legal, "modify"))
+}
+
+func TestIntegrationViewDenyHidesOrganization(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+
+	org, err := testData.TestServer.OrganizationService.Create("wombat org")
+	assert.Nil(t, err)
+	err = testData.TestServer.AclService.LinkUserToRole(authData.User, "organization:owner", org.Id)
+	assert.Nil(t, err)
+	err = testData.TestServer.AclService.LinkUserToRole(otherAuthData.User, "organization:contributor", org.Id)
+	assert.Nil(t, err)
+
+	listIds := func() []string {
+		wrapped := make([]acl.AclWrappedModel, 0)
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "GET",
+			Path:   "/organization/list",
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", otherAuthData.AccessToken),
+			},
+			ResponseModel: &wrapped,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, status)
+
+		ids := make([]string, 0)
+		for _, model := range *resp.(*[]acl.AclWrappedModel) {
+			ids = append(ids, model.Model.(map[string]interface{})["id"].(string))
+		}
+		return ids
+	}
+	assert.Contains(t, listIds(), org.Id)
+
+	createDeny(t, headers, org.Id, &request.DenyCreateRequest{
+		UserId:       &otherAuthData.User.Id,
+		ResourcePath: "organization",
+		ResourceId:   org.Id,
+		Action:       "view",
+	})
+
+	// the denied organization is left out of the list and the search, while the others are still there
+	ids := listIds()
+	assert.NotContains(t, ids, org.Id)
+	assert.Contains(t, ids, otherAuthData.Organization.Id)
+
+	orgs, err := testData.TestServer.OrganizationService.Search(otherAuthData.User, "wombat", nil)
+	assert.Nil(t, err)
+	assert.Len(t, orgs, 0)
+}
+
+func createDeny(t *testing.T, headers map[string]string, organizationId string, body *request.DenyCreateRequest) *acl.AclDeny {
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/deny", organizationId),
+		Headers:       headers,
+		Body:          body,
+		ResponseModel: &acl.AclDeny{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	return resp.(*acl.AclDeny)
+}
+
+func findRoleId(t *testing.T, name string) string {
+	var id string
+	err := testData.TestServer.Db.QueryRow("select id from role where name = ? and organization_id is null and deleted_at is null", name).Scan(&id)
+	assert.Nil(t, err)
+
+	return id
+}
@@ -4,11 +4,19 @@ import (
 	"fmt"
 	"github.com/honerlaw/mentordoc/server/http/request"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
 	"github.com/honerlaw/mentordoc/server/test"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIntegrationCreateDocumentFailsBecauseNotAuthenticated(t *testing.T) {
@@ -113,7 +121,7 @@ func TestIntegrationCreateDocumentInOrganization(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, status)
 
 	r := resp.(*acl.AclWrappedModel)
-	assert.Equal(t, []string{"delete", "modify", "view"}, r.Actions)
+	assert.Equal(t, []string{"approve", "comment", "delete", "modify", "view", "view:comment"}, r.Actions)
 	assert.Nil(t, r.Model.(map[string]interface{})["folderId"])
 }
 
@@ -152,7 +160,7 @@ func TestIntegrationCreateDocumentInFolder(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, status)
 
 	r := resp.(*acl.AclWrappedModel)
-	assert.Equal(t, r.Actions, []string{"delete", "modify", "view"})
+	assert.Equal(t, r.Actions, []string{"approve", "comment", "delete", "modify", "view", "view:comment"})
 	assert.Equal(t, r.Model.(map[string]interface{})["folderId"], folder.Id)
 }
 
@@ -346,6 +354,8 @@ func TestIntegrationUpdateDocument(t *testing.T) {
 	assert.Equal(t, document.Drafts[0].Name, "test document")
 	assert.Equal(t, document.Drafts[0].Content.Content, "test content")
 
+	name := "new name"
+	content := "new content"
 	status, resp, err := test.Request(&test.RequestOptions{
 		Method: "PUT",
 		Path:   "/document",
@@ -354,8 +364,9 @@ func TestIntegrationUpdateDocument(t *testing.T) {
 		},
 		Body: &request.DocumentUpdateRequest{
 			DocumentId: document.Id,
-			Name:       "new name",
-			Content:    "new content",
+			DraftId:    document.Drafts[0].Id,
+			Name:       &name,
+			Content:    &content,
 		},
 		ResponseModel: &acl.AclWrappedModel{},
 	})
@@ -369,3 +380,570 @@ func TestIntegrationUpdateDocument(t *testing.T) {
 	assert.Equal(t, "new name", doc.Drafts[0].Name)
 	assert.Equal(t, "new content", doc.Drafts[0].Content.Content)
 }
+
+func TestIntegrationSearchDocumentsByRelevance(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	documentService := testData.TestServer.DocumentService
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+
+	folder, err := testData.TestServer.FolderService.Create(authData.User, "finance", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	named, err := documentService.Create(authData.User, authData.Organization.Id, nil, "zeppelin budget", "the zeppelin budget covers hangar rent")
+	assert.Nil(t, err)
+	mentioned, err := documentService.Create(authData.User, authData.Organization.Id, &folder.Id, "meeting notes", "we talked about the\n\nzeppelin for a while")
+	assert.Nil(t, err)
+	_, err = documentService.Create(authData.User, authData.Organization.Id, nil, "unrelated", "nothing to see here")
+	assert.Nil(t, err)
+
+	search := func(query string) (int, []shared.Document) {
+		aclWrappedModels := make([]acl.AclWrappedModel, 0)
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method:        "GET",
+			Path:          "/document/search?" + query,
+			Headers:       headers,
+			ResponseModel: &aclWrappedModels,
+		})
+		assert.Nil(t, err)
+
+		documents := make([]shared.Document, 0)
+		for _, model := range *resp.(*[]acl.AclWrappedModel) {
+			documents = append(documents, *test.ConvertModel(model.Model, &shared.Document{}).(*shared.Document))
+		}
+		return status, documents
+	}
+
+	// matches in the name weigh more than matches in the content
+	status, documents := search("query=zeppelin")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 2)
+	assert.Equal(t, named.Id, documents[0].Id)
+	assert.Equal(t, mentioned.Id, documents[1].Id)
+	assert.True(t, documents[0].Match.Score > documents[1].Match.Score)
+	assert.Equal(t, []shared.SearchHighlight{
+		{Field: "name", Snippet: "<mark>zeppelin</mark> budget"},
+		{Field: "content", Snippet: "the <mark>zeppelin</mark> budget covers hangar rent"},
+	}, documents[0].Match.Highlights)
+	assert.Equal(t, "we talked about the <mark>zeppelin</mark> for a while", documents[1].Match.Highlights[0].Snippet)
+
+	status, documents = search("query=zeppelin&page=1&count=1")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 1)
+	assert.Equal(t, mentioned.Id, documents[0].Id)
+
+	status, documents = search(fmt.Sprintf("query=zeppelin&folderId=%s", folder.Id))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 1)
+	assert.Equal(t, mentioned.Id, documents[0].Id)
+
+	// nothing has been published yet, so everything found is a draft of the user
+	status, documents = search("query=zeppelin&status=published")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 0)
+
+	_, err = documentService.Update(authData.User, mentioned.Id, mentioned.Drafts[0].Id, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+
+	status, documents = search("query=zeppelin&status=published")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 1)
+	assert.Equal(t, mentioned.Id, documents[0].Id)
+
+	status, documents = search(fmt.Sprintf("query=zeppelin&status=draft&creatorId=%s", authData.User.Id))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 1)
+	assert.Equal(t, named.Id, documents[0].Id)
+
+	status, documents = search(fmt.Sprintf("query=zeppelin&from=%d", util.NowUnix()))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 0)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "GET",
+		Path:          "/document/search?query=zeppelin&status=archived",
+		Headers:       headers,
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestIntegrationBleveSearchIndex(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	documentService := testData.TestServer.DocumentService
+
+	dir, err := ioutil.TempDir("", "search")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	searchIndex, err := document.NewBleveSearchIndex(filepath.Join(dir, "index.bleve"), testData.TestServer.DocumentRepository,
+		document.NewDocumentDraftRepository(testData.TestServer.Db, nil))
+	assert.Nil(t, err)
+	defer searchIndex.Close()
+
+	marathon, err := documentService.Create(authData.User, authData.Organization.Id, nil, "training plan", "running three marathons a year, the hangar rent is extra")
+	assert.Nil(t, err)
+	_, err = documentService.Update(authData.User, marathon.Id, marathon.Drafts[0].Id, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+	draft, err := documentService.Create(authData.User, authData.Organization.Id, nil, "marathon draft", "not ready yet")
+	assert.Nil(t, err)
+
+	err = searchIndex.Rebuild()
+	assert.Nil(t, err)
+
+	scope := &document.SearchScope{OrganizationIds: []string{authData.Organization.Id}}
+	search := func(userId string, query string) []string {
+		results, err := searchIndex.Search(userId, scope, query, nil, nil)
+		assert.Nil(t, err)
+
+		ids := make([]string, 0)
+		for _, result := range results {
+			ids = append(ids, result.Draft.DocumentId)
+		}
+		return ids
+	}
+
+	// stemming, fuzzy matching and phrases, with matches in the name first
+	assert.Equal(t, []string{draft.Id, marathon.Id}, search(authData.User.Id, "marathon"))
+	assert.Equal(t, []string{marathon.Id}, search(authData.User.Id, "plam"))
+	assert.Equal(t, []string{marathon.Id}, search(authData.User.Id, `"hangar rent"`))
+	assert.Len(t, search(authData.User.Id, `"rent hangar"`), 0)
+
+	// unpublished drafts are only found by their creator
+	assert.Equal(t, []string{marathon.Id}, search(otherAuthData.User.Id, "marathon"))
+
+	// deleted documents are removed once they are indexed again
+	_, err = documentService.Delete(authData.User, draft.Id)
+	assert.Nil(t, err)
+	err = searchIndex.IndexDocument(draft.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{marathon.Id}, search(authData.User.Id, "marathon"))
+
+	// a newer draft that no longer matches hides the published draft from its creator, the same as the MySQL index
+	_, err = documentService.CreateDraft(authData.User, marathon.Id, "training plan", "cycling instead")
+	assert.Nil(t, err)
+	err = searchIndex.IndexDocument(marathon.Id)
+	assert.Nil(t, err)
+	assert.Len(t, search(authData.User.Id, "marathon"), 0)
+	assert.Equal(t, []string{marathon.Id}, search(otherAuthData.User.Id, "marathon"))
+
+	results, err := searchIndex.Search(authData.User.Id, scope, "marathon", &shared.DocumentSearchFilter{Status: shared.DocumentSearchStatusPublished}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	// pages are cut from the documents that are left
+	results, err = searchIndex.Search(authData.User.Id, scope, "training", nil, &shared.Pagination{Page: 0, Count: 1})
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	results, err = searchIndex.Search(authData.User.Id, scope, "training", nil, &shared.Pagination{Page: 1, Count: 1})
+	assert.Nil(t, err)
+	assert.Len(t, results, 0)
+
+	// a query string bleve can not parse is a bad request
+	_, err = searchIndex.Search(authData.User.Id, scope, `"hangar rent`, nil, nil)
+	if assert.IsType(t, &shared.HttpError{}, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*shared.HttpError).Status)
+	}
+}
+
+func TestIntegrationSearchDocumentsWithQualifiers(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	documentService := testData.TestServer.DocumentService
+	folderService := testData.TestServer.FolderService
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+
+	engineering, err := folderService.Create(authData.User, "qualified engineering", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	backend, err := folderService.Create(authData.User, "backend", authData.Organization.Id, &engineering.Id)
+	assert.Nil(t, err)
+	onboarding, err := documentService.Create(authData.User, authData.Organization.Id, &backend.Id, "onboarding checklist", "laptops and accounts for a new hire")
+	assert.Nil(t, err)
+	_, err = documentService.Create(authData.User, authData.Organization.Id, nil, "onboarding overview", "laptops for everyone")
+	assert.Nil(t, err)
+
+	search := func(query string) (int, []shared.Document) {
+		aclWrappedModels := make([]acl.AclWrappedModel, 0)
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method:        "GET",
+			Path:          "/document/search?query=" + url.QueryEscape(query),
+			Headers:       headers,
+			ResponseModel: &aclWrappedModels,
+		})
+		assert.Nil(t, err)
+
+		documents := make([]shared.Document, 0)
+		for _, model := range *resp.(*[]acl.AclWrappedModel) {
+			documents = append(documents, *test.ConvertModel(model.Model, &shared.Document{}).(*shared.Document))
+		}
+		return status, documents
+	}
+
+	// the folder path includes the folders nested in it
+	status, documents := search(`title:"onboarding" in:"folder/qualified engineering" laptops`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 1)
+	assert.Equal(t, onboarding.Id, documents[0].Id)
+
+	// a query of only qualifiers finds everything that passes them
+	status, documents = search(fmt.Sprintf("author:%s is:draft title:checklist", authData.User.Email))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 1)
+	assert.Equal(t, onboarding.Id, documents[0].Id)
+
+	status, documents = search("author:nobody@example.com laptops")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 0)
+
+	status, documents = search("in:folder/missing laptops")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 0)
+
+	status, documents = search("updated:<2000-01-01 laptops")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, documents, 0)
+
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method:        "GET",
+		Path:          "/document/search?query=" + url.QueryEscape("is:archived laptops"),
+		Headers:       headers,
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, []shared.HttpErrorDetail{
+		{Message: "is: must be draft or published", Token: "is:archived", Position: 0},
+	}, resp.(*shared.HttpError).Details)
+}
+
+func TestIntegrationRenderDocument(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	documentService := testData.TestServer.DocumentService
+
+	doc, err := documentService.Create(authData.User, authData.Organization.Id, nil, "guide", "# Guide\n\n## Install\n\nrun it <script>alert(1)</script>")
+	assert.Nil(t, err)
+
+	render := func(accessToken string, query string, responseModel interface{}) (int, interface{}) {
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "GET",
+			Path:   fmt.Sprintf("/document/%s/render%s", doc.Id, query),
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+			},
+			ResponseModel: responseModel,
+		})
+		assert.Nil(t, err)
+		return status, resp
+	}
+
+	status, resp := render(authData.AccessToken, "?format=html", &shared.RenderedDocument{})
+	assert.Equal(t, http.StatusOK, status)
+	rendered := resp.(*shared.RenderedDocument)
+	assert.Equal(t, doc.Drafts[0].Id, rendered.DraftId)
+	assert.Equal(t, "<h1 id=\"guide\">Guide</h1>\n<h2 id=\"install\">Install</h2>\n<p>run it </p>\n", rendered.Content)
+	assert.Equal(t, []shared.TocEntry{
+		{Level: 1, Text: "Guide", Anchor: "guide"},
+		{Level: 2, Text: "Install", Anchor: "install"},
+	}, rendered.Toc)
+
+	// saving the content is a new revision, so it is rendered again
+	content := "# Updated"
+	_, err = documentService.Update(authData.User, doc.Id, doc.Drafts[0].Id, nil, &content, false, false, nil, nil)
+	assert.Nil(t, err)
+
+	status, resp = render(authData.AccessToken, "", &shared.RenderedDocument{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "<h1 id=\"updated\">Updated</h1>\n", resp.(*shared.RenderedDocument).Content)
+
+	status, _ = render(authData.AccessToken, "?format=pdf", &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = render(otherAuthData.AccessToken, "?format=html", &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestIntegrationExportDocument(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	folder, err := testData.TestServer.FolderService.Create(authData.User, "handbook", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, &folder.Id, "welcome", "# Welcome")
+	assert.Nil(t, err)
+
+	export := func(format string, responseModel interface{}) (int, interface{}) {
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "GET",
+			Path:   fmt.Sprintf("/document/%s/export?format=%s", doc.Id, format),
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+			},
+			ResponseModel: responseModel,
+		})
+		assert.Nil(t, err)
+		return status, resp
+	}
+
+	status, resp := export("md", true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(resp.([]byte)), fmt.Sprintf("title: \"welcome\"\npath: \"%s / handbook\"", authData.Organization.Name))
+	assert.Contains(t, string(resp.([]byte)), "# Welcome")
+
+	status, resp = export("html", true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(resp.([]byte)), "<h1 id=\"welcome\">Welcome</h1>")
+
+	status, resp = export("pdf", true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, strings.HasPrefix(string(resp.([]byte)), "%PDF-"))
+
+	status, _ = export("docx", &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestIntegrationScheduleDocument(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	err := testData.TestServer.AclService.LinkUserToRole(otherAuthData.User, "organization:contributor", authData.Organization.Id)
+	assert.Nil(t, err)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "release notes", "# 1.0")
+	assert.Nil(t, err)
+	draftId := doc.Drafts[0].Id
+
+	update := func(publishAt *int64, retractAt *int64, responseModel interface{}) (int, interface{}) {
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "PUT",
+			Path:   "/document",
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+			},
+			Body: &request.DocumentUpdateRequest{
+				DocumentId: doc.Id,
+				DraftId:    draftId,
+				PublishAt:  publishAt,
+				RetractAt:  retractAt,
+			},
+			ResponseModel: responseModel,
+		})
+		assert.Nil(t, err)
+		return status, resp
+	}
+	listSchedules := func(accessToken string) []shared.Document {
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "GET",
+			Path:   fmt.Sprintf("/organization/%s/schedule/list", authData.Organization.Id),
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+			},
+			ResponseModel: &[]shared.Document{},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, status)
+		return *resp.(*[]shared.Document)
+	}
+
+	past := util.NowUnix() - int64(time.Hour)
+	publishAt := util.NowUnix() + int64(time.Hour)
+	retractAt := publishAt + int64(time.Hour)
+
+	status, _ := update(&past, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = update(nil, &retractAt, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = update(&retractAt, &publishAt, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = update(&publishAt, &retractAt, &acl.AclWrappedModel{})
+	assert.Equal(t, http.StatusOK, status)
+
+	// the draft has not been published, so only its creator sees the schedule
+	scheduled := listSchedules(authData.AccessToken)
+	assert.Len(t, scheduled, 1)
+	assert.Equal(t, publishAt, *scheduled[0].Drafts[0].PublishAt)
+	assert.Equal(t, retractAt, *scheduled[0].Drafts[0].RetractAt)
+	assert.Len(t, listSchedules(otherAuthData.AccessToken), 0)
+
+	// nothing happens until the schedule is due
+	err = testData.TestServer.DocumentService.RunSchedule(authData.User, draftId, doc.Id, publishAt-1)
+	assert.Nil(t, err)
+	due, err := testData.TestServer.DocumentService.FindDueSchedules(publishAt)
+	assert.Nil(t, err)
+	dueIds := make([]string, 0)
+	for _, draft := range due {
+		dueIds = append(dueIds, draft.Id)
+	}
+	assert.Contains(t, dueIds, draftId)
+
+	err = testData.TestServer.DocumentService.RunSchedule(authData.User, draftId, doc.Id, publishAt)
+	assert.Nil(t, err)
+	found, err := testData.TestServer.DocumentService.FindDocument(otherAuthData.User, doc.Id)
+	assert.Nil(t, err)
+	assert.NotNil(t, found.Drafts[0].PublishedAt)
+	assert.Nil(t, found.Drafts[0].PublishAt)
+
+	// once published, everyone that can see the document sees when it is retracted
+	assert.Len(t, listSchedules(otherAuthData.AccessToken), 1)
+
+	err = testData.TestServer.DocumentService.RunSchedule(authData.User, draftId, doc.Id, retractAt)
+	assert.Nil(t, err)
+	_, err = testData.TestServer.DocumentService.FindDocument(otherAuthData.User, doc.Id)
+	assert.NotNil(t, err)
+	assert.Len(t, listSchedules(authData.AccessToken), 0)
+
+	// a draft whose creator can no longer modify the document is not published, and its schedule is cleared
+	blocked, err := testData.TestServer.DocumentService.Create(otherAuthData.User, authData.Organization.Id, nil, "roadmap", "# 2.0")
+	assert.Nil(t, err)
+	blockedAt := util.NowUnix() + int64(time.Hour)
+	_, err = testData.TestServer.DocumentService.Update(otherAuthData.User, blocked.Id, blocked.Drafts[0].Id, nil, nil, false, false, &blockedAt, nil)
+	assert.Nil(t, err)
+	assert.Len(t, listSchedules(otherAuthData.AccessToken), 1)
+
+	createDeny(t, map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}, authData.Organization.Id, &request.DenyCreateRequest{
+		UserId:       &otherAuthData.User.Id,
+		ResourcePath: "document",
+		ResourceId:   blocked.Id,
+		Action:       "modify",
+	})
+
+	err = testData.TestServer.DocumentService.RunSchedule(otherAuthData.User, blocked.Drafts[0].Id, blocked.Id, blockedAt)
+	assert.NotNil(t, err)
+	assert.Len(t, listSchedules(otherAuthData.AccessToken), 0)
+	found, err = testData.TestServer.DocumentService.FindDocument(otherAuthData.User, blocked.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, found.Drafts[0].PublishedAt)
+
+	// a creator that no longer exists cancels the schedule the same way
+	orphan, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "changelog", "# 3.0")
+	assert.Nil(t, err)
+	_, err = testData.TestServer.DocumentService.Update(authData.User, orphan.Id, orphan.Drafts[0].Id, nil, nil, false, false, &blockedAt, nil)
+	assert.Nil(t, err)
+	assert.Len(t, listSchedules(authData.AccessToken), 1)
+
+	err = testData.TestServer.DocumentService.RunSchedule(nil, orphan.Drafts[0].Id, orphan.Id, blockedAt)
+	assert.NotNil(t, err)
+	assert.Len(t, listSchedules(authData.AccessToken), 0)
+}
+
+func TestIntegrationDocumentLinks(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	service := testData.TestServer.DocumentService
+
+	target, err := service.Create(authData.User, authData.Organization.Id, nil, "runbook", "# deploy")
+	assert.Nil(t, err)
+	later, err := service.Create(authData.User, authData.Organization.Id, nil, "new runbook", "# deploy")
+	assert.Nil(t, err)
+	deleted, err := service.Create(authData.User, authData.Organization.Id, nil, "old runbook", "# deploy")
+	assert.Nil(t, err)
+	_, err = service.Delete(authData.User, deleted.Id)
+	assert.Nil(t, err)
+	hidden, err := service.Create(otherAuthData.User, otherAuthData.Organization.Id, nil, "secret", "# secret")
+	assert.Nil(t, err)
+
+	guides, err := testData.TestServer.FolderService.Create(authData.User, "guides", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+
+	// urls of other sites are not links, but links to documents that are gone or can not be viewed are recorded
+	source, err := service.Create(authData.User, authData.Organization.Id, &guides.Id, "onboarding",
+		fmt.Sprintf("read [[doc:%s]], /document/%s, [[doc:%s]], [[doc:%s]] and https://other.example/document/%s",
+			target.Id, later.Id, deleted.Id, hidden.Id, target.Id))
+	assert.Nil(t, err)
+	_, err = service.Delete(authData.User, later.Id)
+	assert.Nil(t, err)
+
+	get := func(path string, accessToken string, responseModel interface{}) (int, interface{}) {
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "GET",
+			Path:   path,
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+			},
+			ResponseModel: responseModel,
+		})
+		assert.Nil(t, err)
+		return status, resp
+	}
+
+	status, resp := get(fmt.Sprintf("/document/%s/backlinks", target.Id), authData.AccessToken, &[]acl.AclWrappedModel{})
+	assert.Equal(t, http.StatusOK, status)
+	backlinks := *resp.(*[]acl.AclWrappedModel)
+	if assert.Len(t, backlinks, 1) {
+		backlink := test.ConvertModel(backlinks[0].Model, &shared.Document{}).(*shared.Document)
+		assert.Equal(t, source.Id, backlink.Id)
+	}
+
+	// the document is only visible to the other user once they can view it
+	status, _ = get(fmt.Sprintf("/document/%s/backlinks", target.Id), otherAuthData.AccessToken, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, resp = get(fmt.Sprintf("/document/%s/links", source.Id), authData.AccessToken, &[]shared.DocumentLinkStatus{})
+	assert.Equal(t, http.StatusOK, status)
+	links := *resp.(*[]shared.DocumentLinkStatus)
+	if assert.Len(t, links, 4) {
+		assert.Equal(t, target.Id, links[0].TargetDocumentId)
+		assert.Equal(t, shared.DocumentLinkStatusOk, links[0].Status)
+		assert.False(t, links[0].Broken)
+		assert.Equal(t, target.Id, links[0].Target.Id)
+		assert.Equal(t, later.Id, links[1].TargetDocumentId)
+		assert.Equal(t, shared.DocumentLinkStatusDeleted, links[1].Status)
+		assert.True(t, links[1].Broken)
+		assert.Equal(t, deleted.Id, links[2].TargetDocumentId)
+		assert.Equal(t, shared.DocumentLinkStatusDeleted, links[2].Status)
+		assert.True(t, links[2].Broken)
+		assert.Equal(t, hidden.Id, links[3].TargetDocumentId)
+		assert.Equal(t, shared.DocumentLinkStatusInaccessible, links[3].Status)
+		assert.True(t, links[3].Broken)
+		assert.Nil(t, links[3].Target)
+	}
+
+	// a viewer of the folder can not view the target outside of it
+	err = testData.TestServer.AclService.LinkUserToRole(otherAuthData.User, "folder:viewer", guides.Id)
+	assert.Nil(t, err)
+	_, err = service.Update(authData.User, source.Id, source.Drafts[0].Id, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+	status, resp = get(fmt.Sprintf("/document/%s/links", source.Id), otherAuthData.AccessToken, &[]shared.DocumentLinkStatus{})
+	assert.Equal(t, http.StatusOK, status)
+	links = *resp.(*[]shared.DocumentLinkStatus)
+	if assert.Len(t, links, 4) {
+		assert.Equal(t, shared.DocumentLinkStatusInaccessible, links[0].Status)
+		assert.True(t, links[0].Broken)
+		assert.Nil(t, links[0].Target)
+		assert.Equal(t, shared.DocumentLinkStatusOk, links[3].Status)
+		assert.Equal(t, hidden.Id, links[3].Target.Id)
+	}
+
+	// saving the draft without the link removes the backlink
+	content := "nothing to read"
+	_, err = service.Update(authData.User, source.Id, source.Drafts[0].Id, nil, &content, false, false, nil, nil)
+	assert.Nil(t, err)
+	backlinksAfter, err := service.FindBacklinks(authData.User, target.Id)
+	assert.Nil(t, err)
+	assert.Len(t, backlinksAfter, 0)
+}
@@ -1,9 +1,12 @@
 package server_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
 	"github.com/honerlaw/mentordoc/server/http/request"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/test"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -151,3 +154,120 @@ func TestIntegrationListFolders(t *testing.T) {
 	assert.Len(t, r, 1)
 	assert.Equal(t, "test folder", r[0].Model.(map[string]interface{})["name"])
 }
+
+func TestIntegrationFolderGrantsReachNestedFolders(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	aclService := testData.TestServer.AclService
+	folderService := testData.TestServer.FolderService
+	documentService := testData.TestServer.DocumentService
+
+	// handbook > policies > travel
+	handbook, err := folderService.Create(authData.User, "handbook", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	policies, err := folderService.Create(authData.User, "policies", authData.Organization.Id, &handbook.Id)
+	assert.Nil(t, err)
+	travel, err := folderService.Create(authData.User, "travel", authData.Organization.Id, &policies.Id)
+	assert.Nil(t, err)
+
+	doc, err := documentService.Create(authData.User, authData.Organization.Id, &travel.Id, "expenses", "reimbursable mileage")
+	assert.Nil(t, err)
+	_, err = documentService.Update(authData.User, doc.Id, doc.Drafts[0].Id, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+
+	assert.False(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+
+	// sharing the top level folder shares everything nested in it
+	err = aclService.LinkUserToRole(otherAuthData.User, "folder:viewer", handbook.Id)
+	assert.Nil(t, err)
+
+	assert.True(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, travel, "view"))
+	assert.True(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+	assert.False(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "modify"))
+
+	wrapped, err := aclService.Wrap(otherAuthData.User, []shared.Document{*doc})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"view"}, wrapped[0].Actions)
+
+	folders, err := folderService.List(otherAuthData.User, authData.Organization.Id, &policies.Id, nil)
+	assert.Nil(t, err)
+	assert.Len(t, folders, 1)
+	assert.Equal(t, travel.Id, folders[0].Id)
+
+	documents, err := documentService.List(otherAuthData.User, authData.Organization.Id, &travel.Id, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, documents, 1)
+
+	documents, err = documentService.Search(otherAuthData.User, "mileage", nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, documents, 1)
+
+	// a deny on a folder in between hides everything below it again
+	createDeny(t, map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}, authData.Organization.Id, &request.DenyCreateRequest{
+		UserId:       &otherAuthData.User.Id,
+		ResourcePath: "folder",
+		ResourceId:   policies.Id,
+		Action:       acl.DenyAllActions,
+	})
+
+	assert.True(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, handbook, "view"))
+	assert.False(t, aclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+
+	documents, err = documentService.Search(otherAuthData.User, "mileage", nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, documents, 0)
+}
+
+func TestIntegrationExportFolder(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	folderService := testData.TestServer.FolderService
+	documentService := testData.TestServer.DocumentService
+
+	eng, err := folderService.Create(authData.User, "eng", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	runbooks, err := folderService.Create(authData.User, "runbooks", authData.Organization.Id, &eng.Id)
+	assert.Nil(t, err)
+	_, err = folderService.Create(authData.User, "empty", authData.Organization.Id, &eng.Id)
+	assert.Nil(t, err)
+	_, err = documentService.Create(authData.User, authData.Organization.Id, &eng.Id, "overview", "# Overview")
+	assert.Nil(t, err)
+	_, err = documentService.Create(authData.User, authData.Organization.Id, &runbooks.Id, "deploy", "# Deploy")
+	assert.Nil(t, err)
+
+	export := func(accessToken string, responseModel interface{}) (int, interface{}) {
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "GET",
+			Path:   fmt.Sprintf("/folder/%s/export?format=md", eng.Id),
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+			},
+			ResponseModel: responseModel,
+		})
+		assert.Nil(t, err)
+		return status, resp
+	}
+
+	status, resp := export(authData.AccessToken, true)
+	assert.Equal(t, http.StatusOK, status)
+
+	data := resp.([]byte)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"eng/", "eng/empty/", "eng/runbooks/", "eng/overview.md", "eng/runbooks/deploy.md"}, names)
+
+	status, _ = export(otherAuthData.AccessToken, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+}
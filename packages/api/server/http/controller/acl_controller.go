@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/folder"
+	"github.com/honerlaw/mentordoc/server/lib/organization"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/user"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+	"strings"
+)
+
+type AclController struct {
+	aclService               *acl.AclService
+	userService              *user.UserService
+	organizationService      *organization.OrganizationService
+	folderService            *folder.FolderService
+	documentService          *document.DocumentService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewAclController(
+	aclService *acl.AclService,
+	userService *user.UserService,
+	organizationService *organization.OrganizationService,
+	folderService *folder.FolderService,
+	documentService *document.DocumentService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *AclController {
+	return &AclController{
+		aclService:               aclService,
+		userService:              userService,
+		organizationService:      organizationService,
+		folderService:            folderService,
+		documentService:          documentService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *AclController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/acl/explain", controller.explain)
+}
+
+/*
+Explains why a user can or can not do an action on a resource, e.g. ?resource=document:12345&action=view. The user
+defaults to the current user, and can be changed with ?userId=. Only owners of the organization the resource belongs
+to can see this.
+*/
+func (controller *AclController) explain(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	query := req.URL.Query()
+
+	action := query.Get("action")
+	if len(action) == 0 {
+		util.WriteHttpError(w, shared.NewBadRequestError("action is required"))
+		return
+	}
+
+	model, err := controller.findResource(query.Get("resource"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	data, err := controller.aclService.GetResourceDataForModel(model)
+	if err != nil {
+		util.WriteHttpError(w, shared.NewInternalServerError("failed to find resource data"))
+		return
+	}
+
+	// the first id is always the organization the resource belongs to
+	if !controller.aclService.UserCanAccessResource(u, []string{"organization"}, data.ResourceIds[:1], "view:acl") {
+		util.WriteHttpError(w, shared.NewForbiddenError("can not view acl for resource"))
+		return
+	}
+
+	target := u
+	if userId := query.Get("userId"); len(userId) > 0 && userId != u.Id {
+		target = controller.userService.FindById(userId)
+		if target == nil {
+			util.WriteHttpError(w, shared.NewNotFoundError("could not find user"))
+			return
+		}
+	}
+
+	explanation, err := controller.aclService.Explain(target, data.ResourcePath, data.ResourceIds, action)
+	if err != nil {
+		util.WriteHttpError(w, shared.NewInternalServerError("failed to explain access"))
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, explanation)
+}
+
+/**
+Finds the model for a resource in the form of type:id, e.g. document:12345
+*/
+func (controller *AclController) findResource(resource string) (interface{}, error) {
+	parts := strings.SplitN(resource, ":", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, shared.NewBadRequestError("resource must be in the form of type:id")
+	}
+
+	var model interface{}
+	switch parts[0] {
+	case "organization":
+		if org := controller.organizationService.FindById(parts[1]); org != nil {
+			model = org
+		}
+	case "folder":
+		if f := controller.folderService.FindById(parts[1]); f != nil {
+			model = f
+		}
+	case "document":
+		if doc := controller.documentService.FindById(parts[1]); doc != nil {
+			model = doc
+		}
+	default:
+		return nil, shared.NewBadRequestError("resource must be an organization, folder, or document")
+	}
+
+	if model == nil {
+		return nil, shared.NewNotFoundError("could not find resource")
+	}
+
+	return model, nil
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/lib/attachment"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// room for the rest of the multipart form around the file
+const maxAttachmentRequestSize = attachment.MaxAttachmentSize + 1<<20
+
+type AttachmentController struct {
+	attachmentService        *attachment.AttachmentService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewAttachmentController(
+	attachmentService *attachment.AttachmentService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *AttachmentController {
+	return &AttachmentController{
+		attachmentService:        attachmentService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *AttachmentController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Post("/document/{id}/attachment", controller.create)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/document/{id}/attachment/list", controller.list)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/attachment/{id}", controller.download)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/attachment/{id}", controller.delete)
+}
+
+/**
+Attaches the file field of a multipart form to the document
+*/
+func (controller *AttachmentController) create(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	documentId := chi.URLParam(req, "id")
+
+	req.Body = http.MaxBytesReader(w, req.Body, maxAttachmentRequestSize)
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		util.WriteHttpError(w, shared.NewBadRequestError("a file of at most 10MB is required as the file field"))
+		return
+	}
+	defer file.Close()
+
+	created, err := controller.attachmentService.Create(user, documentId, header.Filename, file)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, created)
+}
+
+func (controller *AttachmentController) list(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	documentId := chi.URLParam(req, "id")
+
+	attachments, err := controller.attachmentService.List(user, documentId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, attachments)
+}
+
+func (controller *AttachmentController) download(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	attachmentId := chi.URLParam(req, "id")
+
+	found, body, err := controller.attachmentService.Open(user, attachmentId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+	defer body.Close()
+
+	util.WriteDownloadHeaders(w, found.ContentType, found.Name)
+	w.Header().Set("Content-Length", strconv.FormatInt(found.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, err = io.Copy(w, body)
+	if err != nil {
+		// the response has already started, so all that can be done is to cut it short
+		log.Print(err)
+	}
+}
+
+func (controller *AttachmentController) delete(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	attachmentId := chi.URLParam(req, "id")
+
+	deleted, err := controller.attachmentService.Delete(user, attachmentId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, deleted)
+}
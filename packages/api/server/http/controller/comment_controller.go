@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/comment"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+	"strconv"
+)
+
+type CommentController struct {
+	validatorService         *util.ValidatorService
+	commentService           *comment.CommentService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewCommentController(
+	validatorService *util.ValidatorService,
+	commentService *comment.CommentService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *CommentController {
+	return &CommentController{
+		validatorService:         validatorService,
+		commentService:           commentService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *CommentController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.validatorService.Middleware(request.CommentThreadCreateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/document/{id}/draft/{draftId}/thread", controller.createThread)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/document/{id}/thread/list", controller.listThreads)
+
+	router.
+		With(controller.validatorService.Middleware(request.CommentCreateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/thread/{id}/comment", controller.reply)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Put("/thread/{id}/resolve", controller.resolve)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Put("/thread/{id}/reopen", controller.reopen)
+
+	router.
+		With(controller.validatorService.Middleware(request.CommentUpdateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Put("/comment/{id}", controller.update)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/comment/{id}", controller.delete)
+}
+
+func (controller *CommentController) createThread(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.CommentThreadCreateRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	anchor := &shared.CommentAnchor{
+		Start: validReq.Start,
+		End:   validReq.End,
+		Line:  validReq.Line,
+	}
+
+	thread, err := controller.commentService.CreateThread(user, chi.URLParam(req, "id"), chi.URLParam(req, "draftId"), anchor, validReq.Content)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, thread)
+}
+
+/**
+Lists the threads of the document, optionally only the ones on the draft given as draftId and the ones that are or are
+not resolved
+*/
+func (controller *CommentController) listThreads(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	query := req.URL.Query()
+
+	var draftId *string
+	if queryDraftId := query.Get("draftId"); len(queryDraftId) > 0 {
+		draftId = &queryDraftId
+	}
+
+	var resolved *bool
+	if len(query.Get("resolved")) > 0 {
+		parsed, err := strconv.ParseBool(query.Get("resolved"))
+		if err != nil {
+			util.WriteHttpError(w, shared.NewBadRequestError("resolved must be true or false"))
+			return
+		}
+		resolved = &parsed
+	}
+
+	threads, err := controller.commentService.ListThreads(user, chi.URLParam(req, "id"), draftId, resolved)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, threads)
+}
+
+func (controller *CommentController) reply(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.CommentCreateRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	created, err := controller.commentService.Reply(user, chi.URLParam(req, "id"), validReq.Content)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, created)
+}
+
+func (controller *CommentController) resolve(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	thread, err := controller.commentService.Resolve(user, chi.URLParam(req, "id"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, thread)
+}
+
+func (controller *CommentController) reopen(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	thread, err := controller.commentService.Reopen(user, chi.URLParam(req, "id"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, thread)
+}
+
+func (controller *CommentController) update(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.CommentUpdateRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	updated, err := controller.commentService.UpdateComment(user, chi.URLParam(req, "id"), validReq.Content)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, updated)
+}
+
+func (controller *CommentController) delete(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	deleted, err := controller.commentService.DeleteComment(user, chi.URLParam(req, "id"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, deleted)
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/role"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/user"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+)
+
+type DenyController struct {
+	validatorService         *util.ValidatorService
+	denyService              *role.DenyService
+	userService              *user.UserService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewDenyController(
+	validatorService *util.ValidatorService,
+	denyService *role.DenyService,
+	userService *user.UserService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *DenyController {
+	return &DenyController{
+		validatorService:         validatorService,
+		denyService:              denyService,
+		userService:              userService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *DenyController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.validatorService.Middleware(request.DenyCreateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{organizationId}/deny", controller.create)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{organizationId}/deny/list", controller.list)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/organization/{organizationId}/deny/{id}", controller.delete)
+}
+
+func (controller *DenyController) create(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.DenyCreateRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+
+	var deniedUser *shared.User
+	if validReq.UserId != nil {
+		deniedUser = controller.userService.FindById(*validReq.UserId)
+		if deniedUser == nil {
+			util.WriteHttpError(w, shared.NewNotFoundError("could not find user"))
+			return
+		}
+	}
+
+	deny, err := controller.denyService.Create(u, organizationId, deniedUser, validReq.RoleId, validReq.ResourcePath,
+		validReq.ResourceId, validReq.Action)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, deny)
+}
+
+func (controller *DenyController) list(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+
+	denies, err := controller.denyService.List(u, organizationId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, denies)
+}
+
+func (controller *DenyController) delete(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	deny, err := controller.denyService.Delete(u, organizationId, id)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, deny)
+}
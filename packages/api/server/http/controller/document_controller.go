@@ -8,6 +8,7 @@ import (
 	"github.com/honerlaw/mentordoc/server/lib/document"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
 	"net/http"
 )
 
@@ -40,6 +41,18 @@ func (controller *DocumentController) RegisterRoutes(router chi.Router) {
 	router.
 		With(controller.authenticationMiddleware.HasAccessToken()).
 		Get("/document/path/{id}", controller.findPath)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/document/{id}/render", controller.render)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/document/{id}/export", controller.export)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/document/{id}/backlinks", controller.backlinks)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/document/{id}/links", controller.links)
 	router.
 		With(controller.authenticationMiddleware.HasAccessToken()).
 		Get("/document/search", controller.search)
@@ -79,6 +92,85 @@ func (controller *DocumentController) find(w http.ResponseWriter, req *http.Requ
 	util.WriteJsonToResponse(w, http.StatusCreated, wrapped[0])
 }
 
+func (controller *DocumentController) render(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	documentId := chi.URLParam(req, "id")
+
+	format := req.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = shared.RenderFormatHtml
+	}
+
+	rendered, err := controller.documentService.Render(user, documentId, format)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, rendered)
+}
+
+func (controller *DocumentController) export(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	documentId := chi.URLParam(req, "id")
+
+	format, err := document.ParseExportFormat(req.URL.Query().Get("format"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	export, err := controller.documentService.Export(user, documentId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteDownloadHeaders(w, document.ExportContentType(format), document.ExportFileName(export.Name, format))
+	err = document.WriteDocumentExport(w, export, format)
+	if err != nil {
+		// the response has already started, so all that can be done is to cut it short
+		log.Print(err)
+	}
+}
+
+func (controller *DocumentController) backlinks(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	documentId := chi.URLParam(req, "id")
+
+	documents, err := controller.documentService.FindBacklinks(user, documentId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	if len(documents) == 0 {
+		util.WriteJsonToResponse(w, http.StatusOK, documents)
+		return
+	}
+
+	wrapped, err := controller.aclService.Wrap(user, documents)
+	if err != nil {
+		util.WriteHttpError(w, shared.NewInternalServerError("found backlinks but failed to find user access"))
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, wrapped)
+}
+
+func (controller *DocumentController) links(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	documentId := chi.URLParam(req, "id")
+
+	links, err := controller.documentService.FindLinks(user, documentId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, links)
+}
+
 func (controller *DocumentController) findPath(w http.ResponseWriter, req *http.Request) {
 	user := controller.authenticationMiddleware.GetUserFromRequest(req)
 	documentId := chi.URLParam(req, "id")
@@ -141,7 +233,7 @@ func (controller *DocumentController) update(w http.ResponseWriter, req *http.Re
 	user := controller.authenticationMiddleware.GetUserFromRequest(req)
 
 	doc, err := controller.documentService.Update(user, validReq.DocumentId, validReq.DraftId,
-		validReq.Name, validReq.Content, validReq.ShouldPublish, validReq.ShouldRetract)
+		validReq.Name, validReq.Content, validReq.ShouldPublish, validReq.ShouldRetract, validReq.PublishAt, validReq.RetractAt)
 	if err != nil {
 		util.WriteHttpError(w, err)
 		return
@@ -191,7 +283,12 @@ func (controller *DocumentController) list(w http.ResponseWriter, req *http.Requ
 		folderId = &queryFolderId
 	}
 
-	documents, err := controller.documentService.List(user, organizationId, folderId, pagination)
+	var tag *string
+	if queryTag := req.URL.Query().Get("tag"); len(queryTag) > 0 {
+		tag = &queryTag
+	}
+
+	documents, err := controller.documentService.List(user, organizationId, folderId, tag, pagination)
 	if err != nil {
 		util.WriteHttpError(w, err)
 		return
@@ -220,9 +317,15 @@ func (controller *DocumentController) search(w http.ResponseWriter, req *http.Re
 		return
 	}
 
-	documents, err := controller.documentService.Search(user, searchQuery);
+	filter, err := shared.NewDocumentSearchFilter(req)
 	if err != nil {
-		util.WriteHttpError(w, shared.NewInternalServerError("failed to find documents"))
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	documents, err := controller.documentService.Search(user, searchQuery, filter, shared.NewPagination(req));
+	if err != nil {
+		util.WriteHttpError(w, err)
 		return
 	}
 
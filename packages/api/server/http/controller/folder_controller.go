@@ -5,15 +5,18 @@ import (
 	"github.com/honerlaw/mentordoc/server/http/middleware"
 	"github.com/honerlaw/mentordoc/server/http/request"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
 	"github.com/honerlaw/mentordoc/server/lib/folder"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
 	"net/http"
 )
 
 type FolderController struct {
 	validatorService         *util.ValidatorService
 	folderService            *folder.FolderService
+	documentService          *document.DocumentService
 	authenticationMiddleware *middleware.AuthenticationMiddleware
 	aclService               *acl.AclService
 }
@@ -21,6 +24,7 @@ type FolderController struct {
 func NewFolderController(
 	validatorService *util.ValidatorService,
 	folderService *folder.FolderService,
+	documentService *document.DocumentService,
 	authenticationMiddleware *middleware.AuthenticationMiddleware,
 	aclService *acl.AclService,
 ) *FolderController {
@@ -28,6 +32,7 @@ func NewFolderController(
 	return &FolderController{
 		validatorService:         validatorService,
 		folderService:            folderService,
+		documentService:          documentService,
 		authenticationMiddleware: authenticationMiddleware,
 		aclService:               aclService,
 	}
@@ -45,6 +50,9 @@ func (controller *FolderController) RegisterRoutes(router chi.Router) {
 	router.
 		With(controller.authenticationMiddleware.HasAccessToken()).
 		Get("/folder/list/{organizationId}", controller.list)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/folder/{id}/export", controller.export)
 
 	router.
 		With(controller.authenticationMiddleware.HasAccessToken()).
@@ -142,4 +150,27 @@ func (controller *FolderController) list(w http.ResponseWriter, req *http.Reques
 	}
 
 	util.WriteJsonToResponse(w, http.StatusOK, wrapped)
-}
\ No newline at end of file
+}
+func (controller *FolderController) export(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	folderId := chi.URLParam(req, "id")
+
+	format, err := document.ParseExportFormat(req.URL.Query().Get("format"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	export, err := controller.documentService.ExportFolder(user, folderId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteDownloadHeaders(w, "application/zip", document.ExportFileName(export.Name, "zip"))
+	err = document.WriteFolderExport(w, export, format)
+	if err != nil {
+		// the response has already started, so all that can be done is to cut it short
+		log.Print(err)
+	}
+}
@@ -1,28 +1,40 @@
 package controller
 
 import (
+	"archive/zip"
+	"bytes"
 	"github.com/go-chi/chi"
 	"github.com/honerlaw/mentordoc/server/http/middleware"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
 	"github.com/honerlaw/mentordoc/server/lib/organization"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/util"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+// the largest zip that can be imported
+const maxImportSize = 50 << 20
+
 type OrganizationController struct {
 	organizationService      *organization.OrganizationService
+	documentService          *document.DocumentService
 	authenticationMiddleware *middleware.AuthenticationMiddleware
 	aclService               *acl.AclService
 }
 
 func NewOrganizationController(
 	organizationService *organization.OrganizationService,
+	documentService *document.DocumentService,
 	authenticationMiddleware *middleware.AuthenticationMiddleware,
 	aclService *acl.AclService,
 ) *OrganizationController {
 	return &OrganizationController{
 		organizationService:      organizationService,
+		documentService:          documentService,
 		authenticationMiddleware: authenticationMiddleware,
 		aclService:               aclService,
 	}
@@ -32,6 +44,12 @@ func (controller *OrganizationController) RegisterRoutes(router chi.Router) {
 	router.
 		With(controller.authenticationMiddleware.HasAccessToken()).
 		Get("/organization/list", controller.list)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{id}/import", controller.importArchive)
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{id}/schedule/list", controller.listSchedules)
 }
 
 func (controller *OrganizationController) list(w http.ResponseWriter, req *http.Request) {
@@ -56,3 +74,79 @@ func (controller *OrganizationController) list(w http.ResponseWriter, req *http.
 
 	util.WriteJsonToResponse(w, http.StatusOK, wrapped)
 }
+
+/**
+Lists the drafts in the organization that are scheduled to be published or retracted
+*/
+func (controller *OrganizationController) listSchedules(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	documents, err := controller.documentService.ListSchedules(user, chi.URLParam(req, "id"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, documents)
+}
+
+/**
+Imports a zip, sent as the body or as the file field of a multipart form, into the organization
+*/
+func (controller *OrganizationController) importArchive(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "id")
+	query := req.URL.Query()
+
+	dryRun := false
+	if len(query.Get("dryRun")) > 0 {
+		parsed, err := strconv.ParseBool(query.Get("dryRun"))
+		if err != nil {
+			util.WriteHttpError(w, shared.NewBadRequestError("dryRun must be true or false"))
+			return
+		}
+		dryRun = parsed
+	}
+
+	var folderId *string
+	if queryFolderId := query.Get("folderId"); len(queryFolderId) > 0 {
+		folderId = &queryFolderId
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, maxImportSize)
+	var body io.Reader = req.Body
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := req.FormFile("file")
+		if err != nil {
+			util.WriteHttpError(w, shared.NewBadRequestError("a zip is required as the file field"))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	var buffer bytes.Buffer
+	_, err := buffer.ReadFrom(body)
+	if err != nil {
+		util.WriteHttpError(w, shared.NewBadRequestError("the zip can be at most 50MB"))
+		return
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		util.WriteHttpError(w, shared.NewBadRequestError("the body must be a zip"))
+		return
+	}
+
+	report, err := controller.documentService.Import(user, organizationId, folderId, archive, dryRun)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	util.WriteJsonToResponse(w, status, report)
+}
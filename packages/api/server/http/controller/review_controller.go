@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+)
+
+type ReviewController struct {
+	validatorService         *util.ValidatorService
+	documentService          *document.DocumentService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewReviewController(
+	validatorService *util.ValidatorService,
+	documentService *document.DocumentService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *ReviewController {
+	return &ReviewController{
+		validatorService:         validatorService,
+		documentService:          documentService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *ReviewController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.validatorService.Middleware(request.ReviewPolicySetRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Put("/organization/{id}/review-policy", controller.setOrganizationPolicy)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/organization/{id}/review-policy", controller.deleteOrganizationPolicy)
+
+	router.
+		With(controller.validatorService.Middleware(request.ReviewPolicySetRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Put("/folder/{id}/review-policy", controller.setFolderPolicy)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/folder/{id}/review-policy", controller.deleteFolderPolicy)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Post("/document/{id}/draft/{draftId}/review-request", controller.requestReview)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/document/{id}/draft/{draftId}/review-request", controller.withdrawReview)
+
+	router.
+		With(controller.validatorService.Middleware(request.ReviewCreateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/document/{id}/draft/{draftId}/review", controller.review)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/document/{id}/draft/{draftId}/review", controller.status)
+}
+
+func (controller *ReviewController) setOrganizationPolicy(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.ReviewPolicySetRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	policy, err := controller.documentService.SetOrganizationReviewPolicy(user, chi.URLParam(req, "id"), *validReq.RequiredApprovals)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, policy)
+}
+
+func (controller *ReviewController) deleteOrganizationPolicy(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	policy, err := controller.documentService.DeleteOrganizationReviewPolicy(user, chi.URLParam(req, "id"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, policy)
+}
+
+func (controller *ReviewController) setFolderPolicy(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.ReviewPolicySetRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	policy, err := controller.documentService.SetFolderReviewPolicy(user, chi.URLParam(req, "id"), *validReq.RequiredApprovals)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, policy)
+}
+
+func (controller *ReviewController) deleteFolderPolicy(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	policy, err := controller.documentService.DeleteFolderReviewPolicy(user, chi.URLParam(req, "id"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, policy)
+}
+
+func (controller *ReviewController) requestReview(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	status, err := controller.documentService.RequestReview(user, chi.URLParam(req, "id"), chi.URLParam(req, "draftId"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, status)
+}
+
+func (controller *ReviewController) withdrawReview(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	status, err := controller.documentService.WithdrawReview(user, chi.URLParam(req, "id"), chi.URLParam(req, "draftId"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, status)
+}
+
+func (controller *ReviewController) review(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.ReviewCreateRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	review, err := controller.documentService.Review(user, chi.URLParam(req, "id"), chi.URLParam(req, "draftId"),
+		validReq.Decision, validReq.Comment)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, review)
+}
+
+func (controller *ReviewController) status(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	status, err := controller.documentService.FindReviewStatus(user, chi.URLParam(req, "id"), chi.URLParam(req, "draftId"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, status)
+}
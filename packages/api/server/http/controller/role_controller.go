@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/role"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/team"
+	"github.com/honerlaw/mentordoc/server/lib/user"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+)
+
+type RoleController struct {
+	validatorService         *util.ValidatorService
+	roleService              *role.RoleService
+	userService              *user.UserService
+	teamService              *team.TeamService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewRoleController(
+	validatorService *util.ValidatorService,
+	roleService *role.RoleService,
+	userService *user.UserService,
+	teamService *team.TeamService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *RoleController {
+	return &RoleController{
+		validatorService:         validatorService,
+		roleService:              roleService,
+		userService:              userService,
+		teamService:              teamService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *RoleController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.validatorService.Middleware(request.RoleCreateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{organizationId}/role", controller.create)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{organizationId}/role/list", controller.list)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{organizationId}/role/{id}", controller.get)
+
+	router.
+		With(controller.validatorService.Middleware(request.RoleUpdateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Put("/organization/{organizationId}/role/{id}", controller.update)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/organization/{organizationId}/role/{id}", controller.delete)
+
+	router.
+		With(controller.validatorService.Middleware(request.RoleAssignRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{organizationId}/role/{id}/user", controller.assign)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/organization/{organizationId}/role/{id}/user/{userId}", controller.unassign)
+
+	router.
+		With(controller.validatorService.Middleware(request.RoleAssignTeamRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{organizationId}/role/{id}/team", controller.assignTeam)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/organization/{organizationId}/role/{id}/team/{teamId}", controller.unassignTeam)
+}
+
+func (controller *RoleController) create(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.RoleCreateRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+
+	r, err := controller.roleService.Create(u, organizationId, validReq.Name, validReq.Permissions)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, r)
+}
+
+func (controller *RoleController) list(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+
+	roles, err := controller.roleService.List(u, organizationId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, roles)
+}
+
+func (controller *RoleController) get(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	r, err := controller.roleService.Find(u, organizationId, id)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, r)
+}
+
+func (controller *RoleController) update(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.RoleUpdateRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	r, err := controller.roleService.Update(u, organizationId, id, validReq.Name, validReq.Permissions)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, r)
+}
+
+func (controller *RoleController) delete(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	r, err := controller.roleService.Delete(u, organizationId, id)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, r)
+}
+
+func (controller *RoleController) assign(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.RoleAssignRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	assignee := controller.userService.FindById(validReq.UserId)
+	if assignee == nil {
+		util.WriteHttpError(w, shared.NewNotFoundError("could not find user"))
+		return
+	}
+
+	r, err := controller.roleService.Assign(u, organizationId, id, assignee, validReq.ExpiresAt)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, r)
+}
+
+func (controller *RoleController) unassign(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	assignee := controller.userService.FindById(chi.URLParam(req, "userId"))
+	if assignee == nil {
+		util.WriteHttpError(w, shared.NewNotFoundError("could not find user"))
+		return
+	}
+
+	r, err := controller.roleService.Unassign(u, organizationId, id, assignee)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, r)
+}
+
+func (controller *RoleController) assignTeam(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.RoleAssignTeamRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	t := controller.teamService.FindById(validReq.TeamId)
+	if t == nil {
+		util.WriteHttpError(w, shared.NewNotFoundError("could not find team"))
+		return
+	}
+
+	r, err := controller.roleService.AssignTeam(u, organizationId, id, t, validReq.ResourceId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, r)
+}
+
+func (controller *RoleController) unassignTeam(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	t := controller.teamService.FindById(chi.URLParam(req, "teamId"))
+	if t == nil {
+		util.WriteHttpError(w, shared.NewNotFoundError("could not find team"))
+		return
+	}
+
+	var resourceId *string
+	if queryResourceId := req.URL.Query().Get("resourceId"); len(queryResourceId) > 0 {
+		resourceId = &queryResourceId
+	}
+
+	r, err := controller.roleService.UnassignTeam(u, organizationId, id, t, resourceId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, r)
+}
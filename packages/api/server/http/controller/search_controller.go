@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/lib/search"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+)
+
+type SearchController struct {
+	searchService            *search.SearchService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewSearchController(
+	searchService *search.SearchService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *SearchController {
+	return &SearchController{
+		searchService:            searchService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *SearchController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/search", controller.search)
+}
+
+func (controller *SearchController) search(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+	searchQuery := req.URL.Query().Get("query")
+
+	if len(searchQuery) == 0 {
+		util.WriteHttpError(w, shared.NewBadRequestError("a search query is required"))
+		return
+	}
+
+	types, err := search.ParseHitTypes(req.URL.Query().Get("types"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	hits, err := controller.searchService.Search(user, searchQuery, types, shared.NewPagination(req))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, hits)
+}
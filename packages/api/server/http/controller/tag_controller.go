@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+)
+
+type TagController struct {
+	validatorService         *util.ValidatorService
+	documentService          *document.DocumentService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewTagController(
+	validatorService *util.ValidatorService,
+	documentService *document.DocumentService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *TagController {
+	return &TagController{
+		validatorService:         validatorService,
+		documentService:          documentService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *TagController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.validatorService.Middleware(request.TagCreateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{id}/tag", controller.create)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{id}/tag/list", controller.list)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{id}/tag/autocomplete", controller.autocomplete)
+
+	router.
+		With(controller.validatorService.Middleware(request.TagUpdateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Put("/tag/{id}", controller.update)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/tag/{id}", controller.delete)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Post("/document/{id}/tag/{tagId}", controller.tagDocument)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/document/{id}/tag/{tagId}", controller.untagDocument)
+}
+
+func (controller *TagController) create(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.TagCreateRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tag, err := controller.documentService.CreateTag(user, chi.URLParam(req, "id"), validReq.Name)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, tag)
+}
+
+/**
+Lists the tags of the organization, optionally only the ones whose name starts with the prefix given as q
+*/
+func (controller *TagController) list(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tags, err := controller.documentService.ListTags(user, chi.URLParam(req, "id"), req.URL.Query().Get("q"), shared.NewPagination(req))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, tags)
+}
+
+func (controller *TagController) autocomplete(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tags, err := controller.documentService.AutocompleteTags(user, chi.URLParam(req, "id"), req.URL.Query().Get("q"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, tags)
+}
+
+func (controller *TagController) update(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.TagUpdateRequest)
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tag, err := controller.documentService.UpdateTag(user, chi.URLParam(req, "id"), validReq.Name)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, tag)
+}
+
+func (controller *TagController) delete(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tag, err := controller.documentService.DeleteTag(user, chi.URLParam(req, "id"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, tag)
+}
+
+func (controller *TagController) tagDocument(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tags, err := controller.documentService.TagDocument(user, chi.URLParam(req, "id"), chi.URLParam(req, "tagId"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, tags)
+}
+
+func (controller *TagController) untagDocument(w http.ResponseWriter, req *http.Request) {
+	user := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tags, err := controller.documentService.UntagDocument(user, chi.URLParam(req, "id"), chi.URLParam(req, "tagId"))
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, tags)
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/honerlaw/mentordoc/server/http/middleware"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/team"
+	"github.com/honerlaw/mentordoc/server/lib/user"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"net/http"
+)
+
+type TeamController struct {
+	validatorService         *util.ValidatorService
+	teamService              *team.TeamService
+	userService              *user.UserService
+	authenticationMiddleware *middleware.AuthenticationMiddleware
+}
+
+func NewTeamController(
+	validatorService *util.ValidatorService,
+	teamService *team.TeamService,
+	userService *user.UserService,
+	authenticationMiddleware *middleware.AuthenticationMiddleware,
+) *TeamController {
+	return &TeamController{
+		validatorService:         validatorService,
+		teamService:              teamService,
+		userService:              userService,
+		authenticationMiddleware: authenticationMiddleware,
+	}
+}
+
+func (controller *TeamController) RegisterRoutes(router chi.Router) {
+	router.
+		With(controller.validatorService.Middleware(request.TeamCreateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{organizationId}/team", controller.create)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{organizationId}/team/list", controller.list)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{organizationId}/team/{id}", controller.get)
+
+	router.
+		With(controller.validatorService.Middleware(request.TeamUpdateRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Put("/organization/{organizationId}/team/{id}", controller.update)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/organization/{organizationId}/team/{id}", controller.delete)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/organization/{organizationId}/team/{id}/member/list", controller.listMembers)
+
+	router.
+		With(controller.validatorService.Middleware(request.TeamMemberAddRequest{}),
+			controller.authenticationMiddleware.HasAccessToken()).
+		Post("/organization/{organizationId}/team/{id}/member", controller.addMember)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/organization/{organizationId}/team/{id}/member/{userId}", controller.removeMember)
+}
+
+func (controller *TeamController) create(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.TeamCreateRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+
+	t, err := controller.teamService.Create(u, organizationId, validReq.Name)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, t)
+}
+
+func (controller *TeamController) list(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+
+	teams, err := controller.teamService.List(u, organizationId)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, teams)
+}
+
+func (controller *TeamController) get(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	t, err := controller.teamService.Find(u, organizationId, id)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, t)
+}
+
+func (controller *TeamController) update(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.TeamUpdateRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	t, err := controller.teamService.Update(u, organizationId, id, validReq.Name)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, t)
+}
+
+func (controller *TeamController) delete(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	t, err := controller.teamService.Delete(u, organizationId, id)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, t)
+}
+
+func (controller *TeamController) listMembers(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	members, err := controller.teamService.ListMembers(u, organizationId, id)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, members)
+}
+
+func (controller *TeamController) addMember(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.TeamMemberAddRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	member := controller.userService.FindById(validReq.UserId)
+	if member == nil {
+		util.WriteHttpError(w, shared.NewNotFoundError("could not find user"))
+		return
+	}
+
+	t, err := controller.teamService.AddMember(u, organizationId, id, member)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, t)
+}
+
+func (controller *TeamController) removeMember(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	organizationId := chi.URLParam(req, "organizationId")
+	id := chi.URLParam(req, "id")
+
+	member := controller.userService.FindById(chi.URLParam(req, "userId"))
+	if member == nil {
+		util.WriteHttpError(w, shared.NewNotFoundError("could not find user"))
+		return
+	}
+
+	t, err := controller.teamService.RemoveMember(u, organizationId, id, member)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, t)
+}
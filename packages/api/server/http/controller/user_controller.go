@@ -1,33 +1,41 @@
 package controller
 
 import (
+	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/honerlaw/mentordoc/server/http/middleware"
 	"github.com/honerlaw/mentordoc/server/http/request"
 	"github.com/honerlaw/mentordoc/server/http/response"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/user"
 	"github.com/honerlaw/mentordoc/server/lib/util"
 	"net/http"
 )
 
 type UserController struct {
-	userService              *user.UserService
-	validatorService         *util.ValidatorService
-	tokenService    *util.TokenService
-	authenticationMiddleware *middleware.AuthenticationMiddleware
+	userService                *user.UserService
+	personalAccessTokenService *user.PersonalAccessTokenService
+	accountService             *user.AccountService
+	validatorService           *util.ValidatorService
+	tokenService               *util.TokenService
+	authenticationMiddleware   *middleware.AuthenticationMiddleware
 }
 
 func NewUserController(
 	userService *user.UserService,
+	personalAccessTokenService *user.PersonalAccessTokenService,
+	accountService *user.AccountService,
 	validatorService *util.ValidatorService,
 	tokenService *util.TokenService,
 	authenticationMiddleware *middleware.AuthenticationMiddleware,
 ) *UserController {
 	return &UserController{
-		userService:              userService,
-		validatorService:         validatorService,
-		tokenService:    tokenService,
-		authenticationMiddleware: authenticationMiddleware,
+		userService:                userService,
+		personalAccessTokenService: personalAccessTokenService,
+		accountService:             accountService,
+		validatorService:           validatorService,
+		tokenService:               tokenService,
+		authenticationMiddleware:   authenticationMiddleware,
 	}
 }
 
@@ -47,6 +55,30 @@ func (controller *UserController) RegisterRoutes(router chi.Router) {
 	router.
 		With(controller.authenticationMiddleware.HasRefreshToken()).
 		Post("/user/auth/refresh", controller.refreshToken)
+
+	router.Get("/user/auth/oidc", controller.oidcBegin)
+
+	router.Get("/user/auth/oidc/callback", controller.oidcCallback)
+
+	router.
+		With(controller.validatorService.Middleware(request.PersonalAccessTokenCreateRequest{}), controller.authenticationMiddleware.HasAccessToken()).
+		Post("/user/token", controller.createToken)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/user/token/list", controller.listTokens)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/user/token/{id}", controller.revokeToken)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Get("/user/export", controller.export)
+
+	router.
+		With(controller.authenticationMiddleware.HasAccessToken()).
+		Delete("/user", controller.delete)
 }
 
 func (controller *UserController) get(w http.ResponseWriter, req *http.Request) {
@@ -129,3 +161,116 @@ func (controller *UserController) signup(w http.ResponseWriter, req *http.Reques
 		RefreshToken: *refreshToken,
 	})
 }
+
+func (controller *UserController) oidcBegin(w http.ResponseWriter, req *http.Request) {
+	authorizationUrl, err := controller.userService.BeginOidcAuthentication()
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	http.Redirect(w, req, *authorizationUrl, http.StatusFound)
+}
+
+func (controller *UserController) oidcCallback(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	// the identity provider will pass back an error if the user did not sign in
+	if len(query.Get("error")) > 0 {
+		util.WriteHttpError(w, shared.NewUnauthorizedError(query.Get("error")))
+		return
+	}
+
+	code := query.Get("code")
+	state := query.Get("state")
+	if len(code) == 0 || len(state) == 0 {
+		util.WriteHttpError(w, shared.NewBadRequestError("code and state are required"))
+		return
+	}
+
+	u, err := controller.userService.CompleteOidcAuthentication(code, state)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	accessToken, err := controller.tokenService.GenerateToken(u.Id, util.TokenAccess)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return;
+	}
+
+	refreshToken, err := controller.tokenService.GenerateToken(u.Id, util.TokenRefresh)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return;
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, &response.AuthenticationResponse{
+		AccessToken:  *accessToken,
+		RefreshToken: *refreshToken,
+	})
+}
+
+func (controller *UserController) createToken(w http.ResponseWriter, req *http.Request) {
+	validReq := controller.validatorService.GetModelFromRequest(req).(*request.PersonalAccessTokenCreateRequest)
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	token, err := controller.personalAccessTokenService.Create(u, validReq.Name, validReq.ExpiresAt, validReq.Scopes)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusCreated, token)
+}
+
+func (controller *UserController) listTokens(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	tokens, err := controller.personalAccessTokenService.List(u)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, tokens)
+}
+
+func (controller *UserController) revokeToken(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+	id := chi.URLParam(req, "id")
+
+	token, err := controller.personalAccessTokenService.Revoke(u, id)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, token)
+}
+
+func (controller *UserController) export(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	export, err := controller.accountService.Export(u)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"mentordoc-export-%s.json\"", u.Id))
+	util.WriteJsonToResponse(w, http.StatusOK, export)
+}
+
+func (controller *UserController) delete(w http.ResponseWriter, req *http.Request) {
+	u := controller.authenticationMiddleware.GetUserFromRequest(req)
+
+	deleted, err := controller.accountService.Delete(u)
+	if err != nil {
+		util.WriteHttpError(w, err)
+		return
+	}
+
+	util.WriteJsonToResponse(w, http.StatusOK, deleted)
+}
@@ -11,16 +11,23 @@ import (
 )
 
 const AuthenticatedUserContextKey = "authenticated_user"
+const RequestCacheContextKey = "request_cache"
 
 type AuthenticationMiddleware struct {
-	tokenService *util.TokenService
-	userService  *user.UserService
+	tokenService               *util.TokenService
+	userService                *user.UserService
+	personalAccessTokenService *user.PersonalAccessTokenService
 }
 
-func NewAuthenticationMiddleware(tokenService *util.TokenService, userService *user.UserService) *AuthenticationMiddleware {
+func NewAuthenticationMiddleware(
+	tokenService *util.TokenService,
+	userService *user.UserService,
+	personalAccessTokenService *user.PersonalAccessTokenService,
+) *AuthenticationMiddleware {
 	return &AuthenticationMiddleware{
-		tokenService: tokenService,
-		userService:  userService,
+		tokenService:               tokenService,
+		userService:                userService,
+		personalAccessTokenService: personalAccessTokenService,
 	}
 }
 
@@ -36,6 +43,18 @@ func (middleware *AuthenticationMiddleware) HasAccessToken() func(next http.Hand
 			pieces := strings.Split(header, "Bearer ")
 			token := pieces[1]
 
+			// personal access tokens can be used anywhere an access token can, but are limited to their scopes
+			if middleware.personalAccessTokenService.IsPersonalAccessToken(token) {
+				u, err := middleware.personalAccessTokenService.Authenticate(token)
+				if err != nil {
+					util.WriteHttpError(w, err)
+					return
+				}
+
+				next.ServeHTTP(w, middleware.withUser(req, u))
+				return
+			}
+
 			claims, err := middleware.tokenService.ParseAndValidateToken(token)
 			if err != nil {
 				util.WriteHttpError(w, shared.NewUnauthorizedError("invalid token"))
@@ -58,8 +77,7 @@ func (middleware *AuthenticationMiddleware) HasAccessToken() func(next http.Hand
 			}
 
 			// store the user on the request context
-			ctx := context.WithValue(req.Context(), AuthenticatedUserContextKey, u)
-			next.ServeHTTP(w, req.WithContext(ctx))
+			next.ServeHTTP(w, middleware.withUser(req, u))
 		})
 	}
 }
@@ -98,8 +116,7 @@ func (middleware *AuthenticationMiddleware) HasRefreshToken() func(next http.Han
 			}
 
 			// store the user on the request context
-			ctx := context.WithValue(req.Context(), AuthenticatedUserContextKey, u)
-			next.ServeHTTP(w, req.WithContext(ctx))
+			next.ServeHTTP(w, middleware.withUser(req, u))
 		})
 	}
 }
@@ -107,3 +124,19 @@ func (middleware *AuthenticationMiddleware) HasRefreshToken() func(next http.Han
 func (middleware *AuthenticationMiddleware) GetUserFromRequest(req *http.Request) *shared.User {
 	return req.Context().Value(AuthenticatedUserContextKey).(*shared.User)
 }
+
+func (middleware *AuthenticationMiddleware) GetRequestCache(req *http.Request) *shared.RequestCache {
+	return req.Context().Value(RequestCacheContextKey).(*shared.RequestCache)
+}
+
+/**
+Stores the user on the request context along with a cache that lives as long as the request does
+*/
+func (middleware *AuthenticationMiddleware) withUser(req *http.Request, u *shared.User) *http.Request {
+	u.Cache = shared.NewRequestCache()
+
+	ctx := context.WithValue(req.Context(), AuthenticatedUserContextKey, u)
+	ctx = context.WithValue(ctx, RequestCacheContextKey, u.Cache)
+
+	return req.WithContext(ctx)
+}
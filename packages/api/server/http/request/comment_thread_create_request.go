@@ -0,0 +1,8 @@
+package request
+
+type CommentThreadCreateRequest struct {
+	Content string `json:"content" validate:"required,max=65535"`
+	Start   *int   `json:"start"`
+	End     *int   `json:"end"`
+	Line    *int   `json:"line"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type CommentUpdateRequest struct {
+	Content string `json:"content" validate:"required,max=65535"`
+}
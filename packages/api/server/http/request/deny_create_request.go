@@ -0,0 +1,9 @@
+package request
+
+type DenyCreateRequest struct {
+	UserId       *string `json:"userId"`
+	RoleId       *string `json:"roleId"`
+	ResourcePath string  `json:"resourcePath" validate:"required"`
+	ResourceId   string  `json:"resourceId" validate:"required"`
+	Action       string  `json:"action" validate:"required"`
+}
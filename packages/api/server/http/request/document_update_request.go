@@ -7,4 +7,6 @@ type DocumentUpdateRequest struct {
 	Content       *string `json:"content"`
 	ShouldPublish bool    `json:"shouldPublish"`
 	ShouldRetract bool    `json:"shouldRetract"`
+	PublishAt     *int64  `json:"publishAt" validate:"omitempty,min=0"`
+	RetractAt     *int64  `json:"retractAt" validate:"omitempty,min=0"`
 }
@@ -0,0 +1,7 @@
+package request
+
+type PersonalAccessTokenCreateRequest struct {
+	Name      string   `json:"name" validate:"required"`
+	ExpiresAt int64    `json:"expiresAt" validate:"required"`
+	Scopes    []string `json:"scopes" validate:"required,min=1"`
+}
@@ -0,0 +1,6 @@
+package request
+
+type ReviewCreateRequest struct {
+	Decision string  `json:"decision" validate:"required,oneof=approve request_changes"`
+	Comment  *string `json:"comment" validate:"omitempty,max=65535"`
+}
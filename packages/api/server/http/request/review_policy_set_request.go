@@ -0,0 +1,5 @@
+package request
+
+type ReviewPolicySetRequest struct {
+	RequiredApprovals *int `json:"requiredApprovals" validate:"required,min=0,max=100"`
+}
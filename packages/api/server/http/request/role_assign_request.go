@@ -0,0 +1,6 @@
+package request
+
+type RoleAssignRequest struct {
+	UserId    string `json:"userId" validate:"required"`
+	ExpiresAt *int64 `json:"expiresAt"`
+}
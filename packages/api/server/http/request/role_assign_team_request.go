@@ -0,0 +1,6 @@
+package request
+
+type RoleAssignTeamRequest struct {
+	TeamId     string  `json:"teamId" validate:"required"`
+	ResourceId *string `json:"resourceId"`
+}
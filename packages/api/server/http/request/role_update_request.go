@@ -0,0 +1,6 @@
+package request
+
+type RoleUpdateRequest struct {
+	Name        string              `json:"name" validate:"required,max=36"`
+	Permissions map[string][]string `json:"permissions" validate:"required,min=1"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type TagCreateRequest struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type TagUpdateRequest struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
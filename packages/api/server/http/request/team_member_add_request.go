@@ -0,0 +1,5 @@
+package request
+
+type TeamMemberAddRequest struct {
+	UserId string `json:"userId" validate:"required"`
+}
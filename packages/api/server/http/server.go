@@ -10,10 +10,16 @@ import (
 	"github.com/honerlaw/mentordoc/server/http/controller"
 	middleware2 "github.com/honerlaw/mentordoc/server/http/middleware"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/attachment"
+	"github.com/honerlaw/mentordoc/server/lib/comment"
 	"github.com/honerlaw/mentordoc/server/lib/document"
 	"github.com/honerlaw/mentordoc/server/lib/folder"
+	"github.com/honerlaw/mentordoc/server/lib/job"
 	"github.com/honerlaw/mentordoc/server/lib/organization"
 	"github.com/honerlaw/mentordoc/server/lib/resource_history"
+	"github.com/honerlaw/mentordoc/server/lib/role"
+	"github.com/honerlaw/mentordoc/server/lib/search"
+	"github.com/honerlaw/mentordoc/server/lib/team"
 	"github.com/honerlaw/mentordoc/server/lib/user"
 	"github.com/honerlaw/mentordoc/server/lib/util"
 	"log"
@@ -23,28 +29,53 @@ import (
 )
 
 type Server struct {
-	Db                        *sql.DB
-	HttpServer                *http.Server
-	TransactionManager        *util.TransactionManager
-	AclService                *acl.AclService
-	TokenService              *util.TokenService
-	ValidatorService          *util.ValidatorService
-	OrganizationRepository    *organization.OrganizationRepository
-	UserRepository            *user.UserRepository
-	FolderRepository          *folder.FolderRepository
-	DocumentRepository        *document.DocumentRepository
-	DocumentContentRepository *document.DocumentContentRepository
-	ResourceHistoryRepository *resource_history.ResourceHistoryRepository
-	ResourceHistoryService    *resource_history.ResourceHistoryService
-	OrganizationService       *organization.OrganizationService
-	UserService               *user.UserService
-	FolderService             *folder.FolderService
-	DocumentService           *document.DocumentService
-	AuthenticationMiddleware  *middleware2.AuthenticationMiddleware
-	UserController            *controller.UserController
-	FolderController          *controller.FolderController
-	DocumentController        *controller.DocumentController
-	OrganizationController    *controller.OrganizationController
+	Db                         *sql.DB
+	HttpServer                 *http.Server
+	TransactionManager         *util.TransactionManager
+	Scheduler                  *util.Scheduler
+	AclService                 *acl.AclService
+	AclPolicy                  *acl.Policy
+	TokenService               *util.TokenService
+	ValidatorService           *util.ValidatorService
+	OrganizationRepository     *organization.OrganizationRepository
+	UserRepository             *user.UserRepository
+	FolderRepository           *folder.FolderRepository
+	DocumentRepository         *document.DocumentRepository
+	DocumentContentRepository  *document.DocumentContentRepository
+	SearchIndex                document.SearchIndex
+	BlobStore                  util.BlobStore
+	AttachmentRepository       *attachment.AttachmentRepository
+	CommentRepository          *comment.CommentRepository
+	ResourceHistoryRepository  *resource_history.ResourceHistoryRepository
+	ResourceHistoryService     *resource_history.ResourceHistoryService
+	OrganizationService        *organization.OrganizationService
+	UserService                *user.UserService
+	PersonalAccessTokenService *user.PersonalAccessTokenService
+	AccountService             *user.AccountService
+	FolderService              *folder.FolderService
+	DocumentService            *document.DocumentService
+	SearchService              *search.SearchService
+	AttachmentService          *attachment.AttachmentService
+	CommentService             *comment.CommentService
+	RoleService                *role.RoleService
+	TeamService                *team.TeamService
+	DenyService                *role.DenyService
+	GrantExpiryJob             *job.GrantExpiryJob
+	DraftScheduleJob           *job.DraftScheduleJob
+	AuthenticationMiddleware   *middleware2.AuthenticationMiddleware
+	UserController             *controller.UserController
+	FolderController           *controller.FolderController
+	DocumentController         *controller.DocumentController
+	OrganizationController     *controller.OrganizationController
+	AclController              *controller.AclController
+	RoleController             *controller.RoleController
+	TeamController             *controller.TeamController
+	DenyController             *controller.DenyController
+	SearchController           *controller.SearchController
+	AttachmentController       *controller.AttachmentController
+	CommentController          *controller.CommentController
+	ReviewController           *controller.ReviewController
+	TagController              *controller.TagController
 }
 
 func StartServer(waitGroup *sync.WaitGroup) *Server {
@@ -52,41 +83,95 @@ func StartServer(waitGroup *sync.WaitGroup) *Server {
 
 	// utilities
 	transactionManager := util.NewTransactionManager(db, nil)
-	aclService := acl.NewAclService(transactionManager, db, nil)
+	aclPolicy, err := acl.NewPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	aclService := acl.NewAclService(transactionManager, aclPolicy, db, nil)
 	tokenService := util.NewTokenService()
 	validatorService := util.NewValidatorService()
+	oidcService := util.NewOidcService(util.NewOidcConfigFromEnv())
 
 	// repositories
 	organizationRepository := organization.NewOrganizationRepository(db, nil)
 	userRepository := user.NewUserRepository(db, nil)
+	oidcAuthRequestRepository := user.NewOidcAuthRequestRepository(db, nil)
+	personalAccessTokenRepository := user.NewPersonalAccessTokenRepository(db, nil)
 	folderRepository := folder.NewFolderRepository(db, nil)
 	documentRepository := document.NewDocumentRepository(db, nil)
 	documentDraftRepository := document.NewDocumentDraftRepository(db, nil)
 	documentContentRepository := document.NewDocumentContentRepository(db, nil)
+	documentReviewRepository := document.NewDocumentReviewRepository(db, nil)
+	tagRepository := document.NewTagRepository(db, nil)
+	documentLinkRepository := document.NewDocumentLinkRepository(db, nil)
 	resourceHistoryRepository := resource_history.NewResourceHistoryRepository(db, nil)
+	teamRepository := team.NewTeamRepository(db, nil)
+	attachmentRepository := attachment.NewAttachmentRepository(db, nil)
+	commentRepository := comment.NewCommentRepository(db, nil)
+
+	searchIndex, err := document.NewSearchIndexFromEnv(documentRepository, documentDraftRepository)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blobStore, err := util.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	renderCache := util.NewLruCache(document.RenderCacheSize)
 
 	// services
 	resourceHistoryService := resource_history.NewResourceHistoryService(resourceHistoryRepository)
 	organizationService := organization.NewOrganizationService(organizationRepository, aclService)
-	userService := user.NewUserService(userRepository, organizationService, transactionManager, aclService)
+	userService := user.NewUserService(userRepository, oidcAuthRequestRepository, organizationService, transactionManager, aclService, oidcService)
+	personalAccessTokenService := user.NewPersonalAccessTokenService(personalAccessTokenRepository, userRepository, aclService)
 	folderService := folder.NewFolderService(folderRepository, organizationService, aclService)
-	documentService := document.NewDocumentService(documentRepository, documentDraftRepository,
-		documentContentRepository, organizationService, folderService, aclService, transactionManager, resourceHistoryService)
+	aclService.RegisterHierarchy("folder", folderService)
+	documentService := document.NewDocumentService(documentRepository, documentDraftRepository, documentContentRepository,
+		documentReviewRepository, tagRepository, documentLinkRepository, organizationService, folderService, aclService, transactionManager,
+		resourceHistoryService, searchIndex, renderCache)
+	searchService := search.NewSearchService(organizationService, folderService, documentService, aclService)
+	attachmentService := attachment.NewAttachmentService(attachmentRepository, documentService, aclService, transactionManager,
+		resourceHistoryService, blobStore)
+	commentService := comment.NewCommentService(commentRepository, documentService, aclService, transactionManager, resourceHistoryService)
+	roleService := role.NewRoleService(organizationService, folderService, documentService, aclService)
+	teamService := team.NewTeamService(teamRepository, organizationService, aclService, transactionManager)
+	denyService := role.NewDenyService(organizationService, folderService, documentService, aclService)
+	accountService := user.NewAccountService(userRepository, personalAccessTokenRepository, documentService, commentService,
+		attachmentService, teamService, resourceHistoryService, aclService, transactionManager)
+
+	// jobs
+	grantExpiryJob := job.NewGrantExpiryJob(aclService, resourceHistoryService, transactionManager)
+	draftScheduleJob := job.NewDraftScheduleJob(documentService, userService)
 
 	// middlewares
-	authenticationMiddleware := middleware2.NewAuthenticationMiddleware(tokenService, userService)
+	authenticationMiddleware := middleware2.NewAuthenticationMiddleware(tokenService, userService, personalAccessTokenService)
 
 	// controllers
-	userController := controller.NewUserController(userService, validatorService, tokenService, authenticationMiddleware)
-	folderController := controller.NewFolderController(validatorService, folderService, authenticationMiddleware, aclService)
+	userController := controller.NewUserController(userService, personalAccessTokenService, accountService, validatorService, tokenService, authenticationMiddleware)
+	folderController := controller.NewFolderController(validatorService, folderService, documentService, authenticationMiddleware, aclService)
 	documentController := controller.NewDocumentController(validatorService, documentService, authenticationMiddleware, aclService)
-	organizationController := controller.NewOrganizationController(organizationService, authenticationMiddleware, aclService)
+	organizationController := controller.NewOrganizationController(organizationService, documentService, authenticationMiddleware, aclService)
+	aclController := controller.NewAclController(aclService, userService, organizationService, folderService, documentService, authenticationMiddleware)
+	roleController := controller.NewRoleController(validatorService, roleService, userService, teamService, authenticationMiddleware)
+	teamController := controller.NewTeamController(validatorService, teamService, userService, authenticationMiddleware)
+	denyController := controller.NewDenyController(validatorService, denyService, userService, authenticationMiddleware)
+	searchController := controller.NewSearchController(searchService, authenticationMiddleware)
+	attachmentController := controller.NewAttachmentController(attachmentService, authenticationMiddleware)
+	commentController := controller.NewCommentController(validatorService, commentService, authenticationMiddleware)
+	reviewController := controller.NewReviewController(validatorService, documentService, authenticationMiddleware)
+	tagController := controller.NewTagController(validatorService, documentService, authenticationMiddleware)
 
-	err := aclService.Init()
+	err = aclService.Init()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	scheduler := util.NewScheduler()
+	scheduler.Schedule("grant expiry", job.GrantExpiryInterval, grantExpiryJob.Run)
+	scheduler.Schedule("draft schedule", job.DraftScheduleInterval, draftScheduleJob.Run)
+
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
@@ -106,6 +191,15 @@ func StartServer(waitGroup *sync.WaitGroup) *Server {
 		folderController.RegisterRoutes(r)
 		documentController.RegisterRoutes(r)
 		organizationController.RegisterRoutes(r)
+		aclController.RegisterRoutes(r)
+		roleController.RegisterRoutes(r)
+		teamController.RegisterRoutes(r)
+		denyController.RegisterRoutes(r)
+		searchController.RegisterRoutes(r)
+		attachmentController.RegisterRoutes(r)
+		commentController.RegisterRoutes(r)
+		reviewController.RegisterRoutes(r)
+		tagController.RegisterRoutes(r)
 	})
 
 	httpServer := &http.Server{
@@ -126,33 +220,65 @@ func StartServer(waitGroup *sync.WaitGroup) *Server {
 	log.Print("successfully started server")
 
 	return &Server{
-		Db:                        db,
-		HttpServer:                httpServer,
-		TransactionManager:        transactionManager,
-		AclService:                aclService,
-		TokenService:              tokenService,
-		ValidatorService:          validatorService,
-		OrganizationRepository:    organizationRepository,
-		UserRepository:            userRepository,
-		FolderRepository:          folderRepository,
-		DocumentRepository:        documentRepository,
-		DocumentContentRepository: documentContentRepository,
-		ResourceHistoryRepository: resourceHistoryRepository,
-		ResourceHistoryService:    resourceHistoryService,
-		OrganizationService:       organizationService,
-		UserService:               userService,
-		FolderService:             folderService,
-		DocumentService:           documentService,
-		AuthenticationMiddleware:  authenticationMiddleware,
-		UserController:            userController,
-		FolderController:          folderController,
-		DocumentController:        documentController,
-		OrganizationController:    organizationController,
+		Db:                         db,
+		HttpServer:                 httpServer,
+		TransactionManager:         transactionManager,
+		Scheduler:                  scheduler,
+		AclService:                 aclService,
+		AclPolicy:                  aclPolicy,
+		TokenService:               tokenService,
+		ValidatorService:           validatorService,
+		OrganizationRepository:     organizationRepository,
+		UserRepository:             userRepository,
+		FolderRepository:           folderRepository,
+		DocumentRepository:         documentRepository,
+		DocumentContentRepository:  documentContentRepository,
+		SearchIndex:                searchIndex,
+		BlobStore:                  blobStore,
+		AttachmentRepository:       attachmentRepository,
+		CommentRepository:          commentRepository,
+		ResourceHistoryRepository:  resourceHistoryRepository,
+		ResourceHistoryService:     resourceHistoryService,
+		OrganizationService:        organizationService,
+		UserService:                userService,
+		PersonalAccessTokenService: personalAccessTokenService,
+		AccountService:             accountService,
+		FolderService:              folderService,
+		DocumentService:            documentService,
+		SearchService:              searchService,
+		AttachmentService:          attachmentService,
+		CommentService:             commentService,
+		RoleService:                roleService,
+		TeamService:                teamService,
+		DenyService:                denyService,
+		GrantExpiryJob:             grantExpiryJob,
+		DraftScheduleJob:           draftScheduleJob,
+		AuthenticationMiddleware:   authenticationMiddleware,
+		UserController:             userController,
+		FolderController:           folderController,
+		DocumentController:         documentController,
+		OrganizationController:     organizationController,
+		AclController:              aclController,
+		RoleController:             roleController,
+		TeamController:             teamController,
+		DenyController:             denyController,
+		SearchController:           searchController,
+		AttachmentController:       attachmentController,
+		CommentController:          commentController,
+		ReviewController:           reviewController,
+		TagController:              tagController,
 	}
 }
 
 func StopServer(server *Server) {
-	err := server.HttpServer.Shutdown(context.Background());
+	server.Scheduler.Stop()
+
+	err := server.HttpServer.Shutdown(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	err = server.SearchIndex.Close()
 	if err != nil {
 		panic(err)
 	}
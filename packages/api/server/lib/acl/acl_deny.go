@@ -0,0 +1,20 @@
+package acl
+
+// denies every action on the resource when used as the action of a deny
+const DenyAllActions = "*"
+
+/*
+Takes away an action from a user, or from every user holding a role, on the resource and everything below it. E.g. a deny
+of modify on folder 12345 means the user can not modify that folder or any document in it, even if a role allows it.
+*/
+type AclDeny struct {
+	Id             string  `json:"id"`
+	OrganizationId string  `json:"organizationId"`
+	UserId         *string `json:"userId"` // nil if the deny is for a role
+	RoleId         *string `json:"roleId"` // nil if the deny is for a user
+	ResourcePath   string  `json:"resourcePath"`
+	ResourceId     string  `json:"resourceId"`
+	Action         string  `json:"action"`
+	CreatedAt      int64   `json:"createdAt"`
+	UpdatedAt      int64   `json:"updatedAt"`
+}
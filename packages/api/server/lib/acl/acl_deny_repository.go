@@ -0,0 +1,141 @@
+package acl
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+const aclDenyColumns = "d.id, d.organization_id, d.user_id, d.role_id, d.resource_path, d.resource_id, d.action, d.created_at, d.updated_at"
+
+type AclDenyRepository struct {
+	util.Repository
+}
+
+func NewAclDenyRepository(db *sql.DB, tx *sql.Tx) *AclDenyRepository {
+	repo := &AclDenyRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *AclDenyRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewAclDenyRepository(repo.Db, tx)
+}
+
+func (repo *AclDenyRepository) Insert(deny *AclDeny) error {
+	deny.CreatedAt = util.NowUnix()
+	deny.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into acl_deny (id, organization_id, user_id, role_id, resource_path, resource_id, action, created_at, updated_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		deny.Id,
+		deny.OrganizationId,
+		deny.UserId,
+		deny.RoleId,
+		deny.ResourcePath,
+		deny.ResourceId,
+		deny.Action,
+		deny.CreatedAt,
+		deny.UpdatedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert deny")
+	}
+
+	return nil
+}
+
+func (repo *AclDenyRepository) Delete(deny *AclDeny) error {
+	_, err := repo.Exec("delete from acl_deny where id = ?", deny.Id)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to delete deny")
+	}
+
+	return nil
+}
+
+func (repo *AclDenyRepository) DeleteByUserId(userId string) error {
+	_, err := repo.Exec("delete from acl_deny where user_id = ?", userId)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to delete denies")
+	}
+
+	return nil
+}
+
+func (repo *AclDenyRepository) FindById(id string) *AclDeny {
+	rows, err := repo.Query("select "+aclDenyColumns+" from acl_deny d where d.id = ?", id)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	defer rows.Close()
+
+	denies, err := repo.scanRows(rows)
+	if err != nil || len(denies) == 0 {
+		return nil
+	}
+
+	return &denies[0]
+}
+
+func (repo *AclDenyRepository) FindByOrganizationId(organizationId string) ([]AclDeny, error) {
+	rows, err := repo.Query(
+		"select "+aclDenyColumns+" from acl_deny d where d.organization_id = ? ORDER BY d.resource_path ASC, d.action ASC",
+		organizationId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find denies")
+	}
+	defer rows.Close()
+
+	return repo.scanRows(rows)
+}
+
+/**
+Finds the denies of the user, along with the denies of every role the user holds directly or through a team. Built-in
+roles are shared between organizations, so a role deny only applies when the user holds the role on the organization
+of the deny or on one of its folders or documents.
+*/
+func (repo *AclDenyRepository) FindForUser(user *shared.User) ([]AclDeny, error) {
+	grantsTable, grantsParams := userGrantsTable(user.Id)
+	params := append([]interface{}{user.Id}, grantsParams...)
+
+	rows, err := repo.Query(
+		"select "+aclDenyColumns+" from acl_deny d where d.user_id = ? OR exists (select 1 from "+grantsTable+
+			" where ur.role_id = d.role_id AND (ur.resource_id = d.organization_id"+
+			" OR ur.resource_id in (select f.id from folder f where f.organization_id = d.organization_id)"+
+			" OR ur.resource_id in (select doc.id from document doc where doc.organization_id = d.organization_id)))",
+		params...,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find denies")
+	}
+	defer rows.Close()
+
+	return repo.scanRows(rows)
+}
+
+func (repo *AclDenyRepository) scanRows(rows *sql.Rows) ([]AclDeny, error) {
+	denies := make([]AclDeny, 0)
+	for rows.Next() {
+		var deny AclDeny
+		err := rows.Scan(&deny.Id, &deny.OrganizationId, &deny.UserId, &deny.RoleId, &deny.ResourcePath, &deny.ResourceId,
+			&deny.Action, &deny.CreatedAt, &deny.UpdatedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse deny")
+		}
+		denies = append(denies, deny)
+	}
+
+	return denies, nil
+}
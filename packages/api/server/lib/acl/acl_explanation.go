@@ -0,0 +1,26 @@
+package acl
+
+/*
+A single role on a resource that gives the user a permission, e.g. organization:owner on organization 12345 allows the
+view action on organization:folder:document
+*/
+type AclGrant struct {
+	RoleId       string  `json:"roleId"`
+	RoleName     string  `json:"roleName"`
+	TeamId       *string `json:"teamId"` // nil if the role was given to the user directly
+	PermissionId string  `json:"permissionId"`
+	ResourcePath string  `json:"resourcePath"`
+	ResourceId   string  `json:"resourceId"`
+	Action       string  `json:"action"`
+}
+
+type AclExplanation struct {
+	UserId       string     `json:"userId"`
+	ResourcePath []string   `json:"resourcePath"`
+	ResourceIds  []string   `json:"resourceIds"`
+	Action       string     `json:"action"`
+	Granted      bool       `json:"granted"`
+	Reason       string     `json:"reason"`
+	Grants       []AclGrant `json:"grants"`
+	Denies       []AclDeny  `json:"denies"`
+}
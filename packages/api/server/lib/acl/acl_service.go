@@ -2,6 +2,7 @@ package acl
 
 import (
 	"database/sql"
+	"errors"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/util"
 	"log"
@@ -10,8 +11,13 @@ import (
 type AclService struct {
 	rolePermissionService *RolePermissionService
 	userRoleService       *UserRoleService
+	teamRoleService       *TeamRoleService
+	permissionRepository  *PermissionRepository
+	aclDenyRepository     *AclDenyRepository
 	transactionManager    *util.TransactionManager
 	aclWrapperService     *AclWrapperService
+	hierarchies           *resourceHierarchies
+	policy                *Policy
 	db                    *sql.DB
 	tx                    *sql.Tx
 }
@@ -19,42 +25,235 @@ type AclService struct {
 /**
 ACL should only be accessible through this object and its exposed functions
 */
-func NewAclService(transactionManager *util.TransactionManager, db *sql.DB, tx *sql.Tx) *AclService {
+func NewAclService(transactionManager *util.TransactionManager, policy *Policy, db *sql.DB, tx *sql.Tx) *AclService {
+	return newAclService(transactionManager, policy, newResourceHierarchies(), db, tx)
+}
+
+/**
+The registered hierarchies are shared with every copy of the service that a transaction is injected into, and are given
+the transaction as well
+*/
+func newAclService(transactionManager *util.TransactionManager, policy *Policy, hierarchies *resourceHierarchies, db *sql.DB, tx *sql.Tx) *AclService {
 	// setup repositories
 	roleRepository := NewRoleRepository(db, tx)
 	rolePermissionRepository := NewRolePermissionRepository(db, tx)
 	permissionRepository := NewPermissionRepository(db, tx)
 	userRoleRepository := NewUserRoleRepository(db, tx)
+	teamRoleRepository := NewTeamRoleRepository(db, tx)
+	aclDenyRepository := NewAclDenyRepository(db, tx)
 
 	// setup the permissions
 	rolePermissionService := NewRolePermissionService(roleRepository, permissionRepository, rolePermissionRepository, transactionManager)
-	userRoleService := NewUserRoleService(roleRepository, userRoleRepository)
+	userRoleService := newUserRoleService(roleRepository, userRoleRepository, aclDenyRepository, hierarchies)
+	teamRoleService := NewTeamRoleService(roleRepository, teamRoleRepository)
 
 	aclService := &AclService{
 		rolePermissionService: rolePermissionService,
 		userRoleService:       userRoleService,
+		teamRoleService:       teamRoleService,
+		permissionRepository:  permissionRepository,
+		aclDenyRepository:     aclDenyRepository,
 		transactionManager:    transactionManager,
+		hierarchies:           hierarchies,
+		policy:                policy,
 		db:                    db,
 		tx:                    tx,
 	}
 
-	aclService.aclWrapperService = NewAclWrapperService(aclService)
+	aclService.aclWrapperService = NewAclWrapperService(aclService, policy.Models)
 
 	return aclService
 }
 
 func (service *AclService) InjectTransaction(tx *sql.Tx) interface{} {
-	return NewAclService(service.transactionManager.InjectTransaction(tx).(*util.TransactionManager), service.db, tx)
+	return newAclService(service.transactionManager.InjectTransaction(tx).(*util.TransactionManager), service.policy, service.hierarchies.inTransaction(tx), service.db, tx)
+}
+
+/**
+Registers the hierarchy of a resource that can be nested in itself, so that grants and denies on the resource also apply
+to everything nested below it
+*/
+func (service *AclService) RegisterHierarchy(resourceName string, hierarchy ResourceHierarchy) {
+	service.hierarchies.register(resourceName, hierarchy)
 }
 
+/**
+Brings the roles in the database in line with the policy
+*/
 func (service *AclService) Init() error {
-	return service.rolePermissionService.InitRoles()
+	changes, err := service.Reconcile(false)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		log.Print("acl policy change ", change)
+	}
+
+	return nil
+}
+
+/**
+Finds the differences between the roles in the policy and the database, and applies them unless dry run is set
+*/
+func (service *AclService) Reconcile(dryRun bool) ([]PolicyChange, error) {
+	return service.rolePermissionService.ReconcileRoles(service.policy.Roles, dryRun)
 }
 
 func (service *AclService) LinkUserToRole(user *shared.User, roleName string, resourceId string) error {
 	return service.userRoleService.LinkUserToRole(user, roleName, resourceId)
 }
 
+func (service *AclService) LinkUserToRoleById(user *shared.User, roleId string, resourceId string) error {
+	return service.userRoleService.LinkUserToRoleById(user, roleId, resourceId)
+}
+
+func (service *AclService) LinkUserToRoleUntil(user *shared.User, roleName string, resourceId string, expiresAt *int64) error {
+	return service.userRoleService.LinkUserToRoleUntil(user, roleName, resourceId, expiresAt)
+}
+
+func (service *AclService) LinkUserToRoleByIdUntil(user *shared.User, roleId string, resourceId string, expiresAt *int64) error {
+	return service.userRoleService.LinkUserToRoleByIdUntil(user, roleId, resourceId, expiresAt)
+}
+
+func (service *AclService) FindExpiredGrants(now int64) ([]UserRole, error) {
+	return service.userRoleService.FindExpired(now)
+}
+
+/**
+Deletes the expired grant, returns false if it no longer exists or was extended since it was found
+*/
+func (service *AclService) ExpireGrant(grant UserRole, now int64) (bool, error) {
+	return service.userRoleService.DeleteExpired(grant, now)
+}
+
+func (service *AclService) UnlinkUserFromRoleById(user *shared.User, roleId string, resourceId string) error {
+	return service.userRoleService.UnlinkUserFromRoleById(user, roleId, resourceId)
+}
+
+func (service *AclService) CountUsersWithRoleId(roleId string) (int, error) {
+	return service.userRoleService.CountUsersWithRoleId(roleId)
+}
+
+func (service *AclService) LinkTeamToRoleById(teamId string, roleId string, resourceId string) error {
+	return service.teamRoleService.LinkTeamToRoleById(teamId, roleId, resourceId)
+}
+
+func (service *AclService) UnlinkTeamFromRoleById(teamId string, roleId string, resourceId string) error {
+	return service.teamRoleService.UnlinkTeamFromRoleById(teamId, roleId, resourceId)
+}
+
+func (service *AclService) UnlinkTeamFromAllRoles(teamId string) error {
+	return service.teamRoleService.UnlinkTeamFromAllRoles(teamId)
+}
+
+func (service *AclService) FindTeamRoles(teamId string) ([]TeamRole, error) {
+	return service.teamRoleService.FindTeamRoles(teamId)
+}
+
+func (service *AclService) CountTeamsWithRoleId(roleId string) (int, error) {
+	return service.teamRoleService.CountTeamsWithRoleId(roleId)
+}
+
+func (service *AclService) CreateOrganizationRole(organizationId string, roleName string, permissionMap map[string][]string) (*Role, error) {
+	role, err := service.rolePermissionService.CreateOrganizationRole(organizationId, roleName, permissionMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.attachPermissions(role)
+}
+
+func (service *AclService) UpdateOrganizationRole(role *Role, roleName string, permissionMap map[string][]string) (*Role, error) {
+	role, err := service.rolePermissionService.UpdateRole(role, roleName, permissionMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.attachPermissions(role)
+}
+
+/**
+Deletes the role, unless a user or team still holds it
+*/
+func (service *AclService) DeleteOrganizationRole(role *Role) error {
+	_, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*AclService)
+
+		count, err := injectedService.userRoleService.CountUsersWithRoleId(role.Id)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("role is still held by users")
+		}
+
+		count, err = injectedService.teamRoleService.CountTeamsWithRoleId(role.Id)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("role is still held by teams")
+		}
+
+		return nil, injectedService.rolePermissionService.DeleteRole(role)
+	})
+
+	return err
+}
+
+/**
+Finds a role along with its permissions
+*/
+func (service *AclService) FindRoleById(id string) (*Role, error) {
+	role := service.rolePermissionService.FindRoleById(id)
+	if role == nil {
+		return nil, nil
+	}
+
+	return service.attachPermissions(role)
+}
+
+func (service *AclService) FindOrganizationRoles(organizationId string) ([]Role, error) {
+	roles, err := service.rolePermissionService.FindOrganizationRoles(organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(roles); i++ {
+		_, err := service.attachPermissions(&roles[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
+
+func (service *AclService) FindOrganizationRoleByName(organizationId string, roleName string) *Role {
+	return service.rolePermissionService.FindOrganizationRoleByName(organizationId, roleName)
+}
+
+func (service *AclService) PermissionExists(resourcePath string, action string) bool {
+	return service.rolePermissionService.PermissionExists(resourcePath, action)
+}
+
+func (service *AclService) FindUserRoles(user *shared.User) ([]UserRole, error) {
+	return service.userRoleService.FindUserRoles(user)
+}
+
+func (service *AclService) UnlinkUserFromAllRoles(user *shared.User) error {
+	return service.userRoleService.UnlinkUserFromAllRoles(user)
+}
+
+func (service *AclService) CountUsersWithRole(roleName string, resourceId string) (int, error) {
+	return service.userRoleService.CountUsersWithRole(roleName, resourceId)
+}
+
+func (service *AclService) CountUsersWithRoleForUpdate(roleName string, resourceId string) (int, error) {
+	return service.userRoleService.CountUsersWithRoleForUpdate(roleName, resourceId)
+}
+
 func (service *AclService) UserCanAccessResourceByModel(user *shared.User, model interface{}, actions ...string) bool {
 	data, err := service.GetResourceDataForModel(model)
 	if err != nil {
@@ -65,6 +264,11 @@ func (service *AclService) UserCanAccessResourceByModel(user *shared.User, model
 }
 
 func (service *AclService) UserCanAccessResource(user *shared.User, path []string, ids []string, actions ...string) bool {
+	actions = service.scopeActions(user, actions)
+	if len(actions) == 0 {
+		return false
+	}
+
 	canAccess, err := service.userRoleService.UserCanAccessResource(user, path, ids, actions...)
 	if err != nil {
 		log.Print(err)
@@ -74,11 +278,115 @@ func (service *AclService) UserCanAccessResource(user *shared.User, path []strin
 }
 
 func (service *AclService) UserActionableResourcesByPath(user *shared.User, path []string, actions ...string) ([]ResourceResponse, error) {
+	actions = service.scopeActions(user, actions)
+	if len(actions) == 0 {
+		return make([]ResourceResponse, 0), nil
+	}
+
 	return service.userRoleService.UserActionableResourcesByPath(user, path, actions...)
 }
 
 func (service *AclService) UserActionsForResources(user *shared.User, paths [][]string, ids [][]string) ([]ResourceResponse, error) {
-	return service.userRoleService.UserActionsForResources(user, paths, ids)
+	data, err := service.userRoleService.UserActionsForResources(user, paths, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Scopes == nil {
+		return data, nil
+	}
+
+	scoped := make([]ResourceResponse, 0)
+	for _, res := range data {
+		if len(service.scopeActions(user, []string{res.Action})) > 0 {
+			scoped = append(scoped, res)
+		}
+	}
+
+	return scoped, nil
+}
+
+/**
+Checks that the resource path is the path of a model in the policy, or the end of one
+*/
+func (service *AclService) IsResourcePath(path string) bool {
+	return service.policy.isKnownPath(path)
+}
+
+func (service *AclService) CreateDeny(deny *AclDeny) error {
+	return service.aclDenyRepository.Insert(deny)
+}
+
+func (service *AclService) DeleteDeny(deny *AclDeny) error {
+	return service.aclDenyRepository.Delete(deny)
+}
+
+/**
+Deletes the denies given to the user, the denies of their roles stay since other users hold the roles as well
+*/
+func (service *AclService) DeleteUserDenies(user *shared.User) error {
+	return service.aclDenyRepository.DeleteByUserId(user.Id)
+}
+
+func (service *AclService) FindDenyById(id string) *AclDeny {
+	return service.aclDenyRepository.FindById(id)
+}
+
+func (service *AclService) FindOrganizationDenies(organizationId string) ([]AclDeny, error) {
+	return service.aclDenyRepository.FindByOrganizationId(organizationId)
+}
+
+/**
+Finds the ids of the resources in the path that the action is denied on for the user, keyed by the resource name. List
+queries leave these out, since UserActionableResourcesByPath only returns what the roles of the user grant.
+*/
+func (service *AclService) DeniedResourceIds(user *shared.User, path []string, action string) (map[string][]string, error) {
+	return service.userRoleService.DeniedResourceIds(user, path, action)
+}
+
+/**
+All of the actions that can be granted by any role, e.g. to validate the scopes of a personal access token
+*/
+func (service *AclService) FindActions() ([]string, error) {
+	return service.permissionRepository.FindActions()
+}
+
+/*
+Explains why the user can or can not do the action on the resource by listing every grant that matched. This is the same
+check that UserCanAccessResource does, including the scopes of a personal access token.
+*/
+func (service *AclService) Explain(user *shared.User, path []string, ids []string, action string) (*AclExplanation, error) {
+	grants, err := service.userRoleService.FindGrants(user, path, ids, action)
+	if err != nil {
+		return nil, err
+	}
+
+	denies, err := service.userRoleService.FindDenies(user, path, ids, action)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &AclExplanation{
+		UserId:       user.Id,
+		ResourcePath: path,
+		ResourceIds:  ids,
+		Action:       action,
+		Grants:       grants,
+		Denies:       denies,
+	}
+
+	if len(service.scopeActions(user, []string{action})) == 0 {
+		explanation.Reason = "the action is outside of the scopes of the personal access token"
+	} else if len(grants) == 0 {
+		explanation.Reason = "no role grants the action on the resource"
+	} else if len(denies) > 0 {
+		explanation.Reason = "the listed denies take the action away on the resource"
+	} else {
+		explanation.Granted = true
+		explanation.Reason = "granted by the listed roles"
+	}
+
+	return explanation, nil
 }
 
 func (service *AclService) Wrap(user *shared.User, modelSlice interface{}) ([]AclWrappedModel, error) {
@@ -88,3 +396,35 @@ func (service *AclService) Wrap(user *shared.User, modelSlice interface{}) ([]Ac
 func (service *AclService) GetResourceDataForModel(model interface{}) (*ResourceData, error) {
 	return service.aclWrapperService.GetResourceDataForModel(model)
 }
+
+/*
+Users that authenticated with a personal access token are restricted to the actions the token was scoped to, so drop
+any of the given actions that fall outside of that scope
+*/
+func (service *AclService) scopeActions(user *shared.User, actions []string) []string {
+	if user.Scopes == nil {
+		return actions
+	}
+
+	scoped := make([]string, 0)
+	for _, action := range actions {
+		for _, scope := range user.Scopes {
+			if action == scope {
+				scoped = append(scoped, action)
+				break
+			}
+		}
+	}
+
+	return scoped
+}
+
+func (service *AclService) attachPermissions(role *Role) (*Role, error) {
+	permissions, err := service.rolePermissionService.FindPermissions(role)
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+
+	return role, nil
+}
@@ -1,11 +1,17 @@
 package acl_test
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/util"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"sync/atomic"
 	"testing"
 )
 
@@ -13,7 +19,7 @@ func TestIntegrationUserCanNotAccessWhenDoesNotExist(t *testing.T) {
 	if !*testData.Integration {
 		t.Skip("skipping integration test")
 	}
-	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.Db, nil)
+	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.AclPolicy, testData.TestServer.Db, nil)
 
 	user := &shared.User{}
 	user.Id = "5"
@@ -25,7 +31,7 @@ func TestIntegrationUserLinkToRole(t *testing.T) {
 	if !*testData.Integration {
 		t.Skip("skipping integration test")
 	}
-	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.Db, nil)
+	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.AclPolicy, testData.TestServer.Db, nil)
 
 	user := &shared.User{}
 	user.Id = uuid.NewV4().String()
@@ -41,7 +47,20 @@ func TestIntegrationUserAccessToDocumentInOrganization(t *testing.T) {
 	if !*testData.Integration {
 		t.Skip("skipping integration test")
 	}
-	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.Db, nil)
+	connector := &countingConnector{
+		driver: testData.TestServer.Db.Driver(),
+		dataSource: fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?autocommit=true&parseTime=true",
+			os.Getenv("DATABASE_USERNAME"),
+			os.Getenv("DATABASE_PASSWORD"),
+			os.Getenv("DATABASE_HOST"),
+			os.Getenv("DATABASE_PORT"),
+			os.Getenv("DATABASE_NAME"),
+		),
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	service := acl.NewAclService(util.NewTransactionManager(db, nil), testData.TestServer.AclPolicy, db, nil)
 
 	orgId := uuid.NewV4().String()
 
@@ -60,7 +79,20 @@ func TestIntegrationUserActionableResourcesByPath(t *testing.T) {
 	if !*testData.Integration {
 		t.Skip("skipping integration test")
 	}
-	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.Db, nil)
+	connector := &countingConnector{
+		driver: testData.TestServer.Db.Driver(),
+		dataSource: fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?autocommit=true&parseTime=true",
+			os.Getenv("DATABASE_USERNAME"),
+			os.Getenv("DATABASE_PASSWORD"),
+			os.Getenv("DATABASE_HOST"),
+			os.Getenv("DATABASE_PORT"),
+			os.Getenv("DATABASE_NAME"),
+		),
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	service := acl.NewAclService(util.NewTransactionManager(db, nil), testData.TestServer.AclPolicy, db, nil)
 
 	orgId := uuid.NewV4().String()
 	user := &shared.User{}
@@ -80,7 +112,7 @@ func TestIntegrationWrap(t *testing.T) {
 	if !*testData.Integration {
 		t.Skip("skipping integration test")
 	}
-	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.Db, nil)
+	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), testData.TestServer.AclPolicy, testData.TestServer.Db, nil)
 
 	user := &shared.User{}
 	user.Id = uuid.NewV4().String()
@@ -99,4 +131,142 @@ func TestIntegrationWrap(t *testing.T) {
 	assert.Len(t, data, 1)
 	assert.Equal(t, data[0].Model.(shared.Document).Id, document.Id)
 	assert.Len(t, data[0].Actions, 0)
-}
\ No newline at end of file
+}
+func TestIntegrationReconcilePolicy(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+
+	// the existing roles plus a new one, so the roles used by the other tests are left alone
+	policy := &acl.Policy{
+		Version: acl.PolicyVersion,
+		Models:  testData.TestServer.AclPolicy.Models,
+		Roles:   map[string]map[string][]string{},
+	}
+	for roleName, permissions := range testData.TestServer.AclPolicy.Roles {
+		policy.Roles[roleName] = permissions
+	}
+	policy.Roles["organization:viewer"] = map[string][]string{
+		"organization": {"view"},
+	}
+	service := acl.NewAclService(util.NewTransactionManager(testData.TestServer.Db, nil), policy, testData.TestServer.Db, nil)
+
+	// a dry run only reports the changes
+	changes, err := service.Reconcile(true)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "+ organization:viewer organization view", changes[0].String())
+
+	changes, err = service.Reconcile(true)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 1)
+
+	changes, err = service.Reconcile(false)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 1)
+
+	changes, err = service.Reconcile(true)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 0)
+
+	// removing the permission from the policy unlinks it again
+	policy.Roles["organization:viewer"] = map[string][]string{}
+	changes, err = service.Reconcile(false)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "- organization:viewer organization view", changes[0].String())
+}
+
+/*
+Opens connections through the driver of the database and counts the statements run on them. The connections only
+expose Prepare, so every statement is prepared first, even the ones without arguments.
+*/
+type countingConnector struct {
+	driver     driver.Driver
+	dataSource string
+	count      uint64
+}
+
+type countingConn struct {
+	driver.Conn
+	connector *countingConnector
+}
+
+func (connector *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := connector.driver.Open(connector.dataSource)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, connector: connector}, nil
+}
+
+func (connector *countingConnector) Driver() driver.Driver {
+	return connector.driver
+}
+
+func (conn *countingConn) Prepare(query string) (driver.Stmt, error) {
+	atomic.AddUint64(&conn.connector.count, 1)
+	return conn.Conn.Prepare(query)
+}
+
+/*
+Runs the same checks as listing documents does, with and without the request cache. The number of queries each run
+takes is logged, run with go test ./server/lib/acl/ -it -run none -bench Acl -v
+*/
+func BenchmarkIntegrationAclChecks(b *testing.B) {
+	if !*testData.Integration {
+		b.Skip("skipping integration test")
+	}
+	connector := &countingConnector{
+		driver: testData.TestServer.Db.Driver(),
+		dataSource: fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?autocommit=true&parseTime=true",
+			os.Getenv("DATABASE_USERNAME"),
+			os.Getenv("DATABASE_PASSWORD"),
+			os.Getenv("DATABASE_HOST"),
+			os.Getenv("DATABASE_PORT"),
+			os.Getenv("DATABASE_NAME"),
+		),
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	service := acl.NewAclService(util.NewTransactionManager(db, nil), testData.TestServer.AclPolicy, db, nil)
+
+	orgId := uuid.NewV4().String()
+	user := &shared.User{}
+	user.Id = uuid.NewV4().String()
+	_, err := testData.TestServer.Db.Exec("insert into user (id, email, password, created_at, updated_at) values (?, ?, 'hash', 0, 0)", user.Id, user.Id)
+	assert.Nil(b, err)
+	err = service.LinkUserToRole(user, "organization:owner", orgId)
+	assert.Nil(b, err)
+
+	documents := make([]shared.Document, 20)
+	for i := 0; i < len(documents); i++ {
+		documents[i].Id = uuid.NewV4().String()
+		documents[i].OrganizationId = orgId
+	}
+
+	run := func(b *testing.B, cached bool) {
+		start := atomic.LoadUint64(&connector.count)
+		for i := 0; i < b.N; i++ {
+			user.Cache = nil
+			if cached {
+				user.Cache = shared.NewRequestCache()
+			}
+
+			service.UserCanAccessResource(user, []string{"organization"}, []string{orgId}, "view:document")
+			_, err := service.UserActionableResourcesByPath(user, []string{"organization", "folder", "document"}, "view")
+			assert.Nil(b, err)
+			_, err = service.Wrap(user, documents)
+			assert.Nil(b, err)
+		}
+		b.Logf("%.1f queries per request", float64(atomic.LoadUint64(&connector.count)-start)/float64(b.N))
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		run(b, false)
+	})
+	b.Run("cached", func(b *testing.B) {
+		run(b, true)
+	})
+}
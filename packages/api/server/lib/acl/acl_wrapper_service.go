@@ -2,40 +2,38 @@ package acl
 
 import (
 	"errors"
+	"fmt"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"reflect"
 	"strings"
+	"sync"
 )
 
+/*
+Models can implement this instead of using struct tags when their resource data can not be read straight off of their
+fields. The path and ids must be the same length, and the model itself must be last.
+*/
+type AclResource interface {
+	AclResource() (path []string, ids []string)
+}
+
 type modelAclData struct {
 	Path         []string
-	StructFields []string
+	FieldIndexes [][]int
 }
 
 type AclWrapperService struct {
 	aclService *AclService
-	data       map[string]*modelAclData
+	models     map[string]ModelPolicy
+	data       map[reflect.Type]*modelAclData
+	lock       sync.RWMutex
 }
 
-func NewAclWrapperService(aclService *AclService) *AclWrapperService {
+func NewAclWrapperService(aclService *AclService, models map[string]ModelPolicy) *AclWrapperService {
 	return &AclWrapperService{
 		aclService: aclService,
-
-		// @todo move this data to be on the model itself, then we can just use reflection to get it all
-		data: map[string]*modelAclData{
-			"Organization": {
-				Path:         []string{"organization"},
-				StructFields: []string{"Id"},
-			},
-			"Folder": {
-				Path:         []string{"organization", "folder"},
-				StructFields: []string{"OrganizationId", "Id"},
-			},
-			"Document": {
-				Path:         []string{"organization", "folder", "document"},
-				StructFields: []string{"OrganizationId", "FolderId", "Id"},
-			},
-		},
+		models:     models,
+		data:       make(map[reflect.Type]*modelAclData),
 	}
 }
 
@@ -88,72 +86,255 @@ func (service *AclWrapperService) Wrap(user *shared.User, modelSlice interface{}
 			Actions: make([]string, 0),
 		}
 
-		// go over the responses and see if any belong to this modedl
+		// a grant on anything the model is nested in also belongs to it
+		variants, err := service.aclService.userRoleService.expandIds(user, paths[index], ids[index])
+		if err != nil {
+			return nil, err
+		}
+
+		// go over the responses and see if any belong to this modedl, grants are for a path ending in the models type
+		// since models of different types are wrapped together and their ancestors ids are in the variants as well
+		path := ":" + strings.Join(paths[index], ":")
 		for _, res := range resp {
-			for _, id := range ids[index] {
-				if id == res.ResourceId {
-					wrapper.Actions = append(wrapper.Actions, res.Action)
-				}
+			if strings.HasSuffix(path, ":"+res.ResourcePath) && variantsContainId(variants, res.ResourceId) {
+				wrapper.Actions = append(wrapper.Actions, res.Action)
 			}
 		}
 
+		// the same grant can apply to models that have a deny and models that do not
+		wrapper.Actions, err = service.aclService.userRoleService.RemoveDeniedActions(user, paths[index], ids[index], wrapper.Actions)
+		if err != nil {
+			return nil, err
+		}
+
 		wrappedSlice = append(wrappedSlice, *wrapper)
 	}
 
 	return wrappedSlice, nil
 }
 
+/*
+Finds the resource path and ids of the model. This is read from the AclResource method if the model has one, otherwise
+from the acl struct tags on the model, and otherwise from the models in the policy. For example the following document
+has the path organization:folder:document, the tagged embedded entity is always the resource itself so it comes last.
+
+type Document struct {
+	Entity         `acl:"document"`
+	OrganizationId string  `acl:"organization"`
+	FolderId       *string `acl:"folder"`
+}
+*/
 func (service *AclWrapperService) GetResourceDataForModel(m interface{}) (*ResourceData, error) {
-	modelName := reflect.TypeOf(m).String()
-	modelData, ok := service.data[strings.Split(strings.ReplaceAll(modelName, "*", ""), ".")[1]]
+	if m == nil {
+		return nil, errors.New("a model is required")
+	}
 
-	if !ok {
-		return nil, errors.New("could not find data for model")
+	if resource, ok := m.(AclResource); ok {
+		path, ids := resource.AclResource()
+		if len(path) == 0 || len(path) != len(ids) {
+			return nil, errors.New("acl resource path and ids must be the same length")
+		}
+		return &ResourceData{
+			ResourceIds:  ids,
+			ResourcePath: path,
+		}, nil
 	}
 
-	modelIds, err := service.getIdsForModel(m, modelData)
+	value := reflect.ValueOf(m)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, errors.New("a model is required")
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, errors.New("model must be a struct")
+	}
+
+	modelData, err := service.getModelData(value.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	modelIds, err := service.getIdsForModel(value, modelData)
 	if err != nil {
 		return nil, err
 	}
 	return &ResourceData{
-		ResourceIds: modelIds,
+		ResourceIds:  modelIds,
 		ResourcePath: modelData.Path,
 	}, nil
 }
 
 /**
-Grab the values of the id fields off the model to be used with the acl service
+Finds how to get the resource data for the type, the result is cached since it never changes for a type
 */
-func (service *AclWrapperService) getIdsForModel(model interface{}, data *modelAclData) ([]string, error) {
-	value := reflect.ValueOf(model)
+func (service *AclWrapperService) getModelData(modelType reflect.Type) (*modelAclData, error) {
+	service.lock.RLock()
+	data, ok := service.data[modelType]
+	service.lock.RUnlock()
+	if ok {
+		return data, nil
+	}
 
-	derefValue := value
-	if value.Kind() == reflect.Ptr {
-		derefValue = value.Elem()
+	data, err := parseAclTags(modelType)
+	if err != nil {
+		return nil, err
 	}
 
-	if derefValue.Kind() != reflect.Struct {
-		return nil, errors.New("model must be a struct")
+	if data == nil {
+		data, err = service.getModelDataFromPolicy(modelType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	service.lock.Lock()
+	service.data[modelType] = data
+	service.lock.Unlock()
+
+	return data, nil
+}
+
+func (service *AclWrapperService) getModelDataFromPolicy(modelType reflect.Type) (*modelAclData, error) {
+	model, ok := service.models[modelType.Name()]
+	if !ok {
+		return nil, fmt.Errorf("could not find acl data for model %s", modelType.Name())
+	}
+
+	data := &modelAclData{
+		Path:         model.Path,
+		FieldIndexes: make([][]int, len(model.Fields)),
+	}
+	for index, name := range model.Fields {
+		field, ok := modelType.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("model %s does not have acl field %s", modelType.Name(), name)
+		}
+		if !isAclIdType(field.Type) {
+			return nil, fmt.Errorf("acl field %s on model %s must be a string", name, modelType.Name())
+		}
+		data.FieldIndexes[index] = field.Index
+	}
+
+	return data, nil
+}
+
+/**
+Reads the acl struct tags off of the type, returns nil if the type does not have any
+*/
+func parseAclTags(modelType reflect.Type) (*modelAclData, error) {
+	data := &modelAclData{
+		Path:         make([]string, 0),
+		FieldIndexes: make([][]int, 0),
 	}
 
-	ids := make([]string, len(data.StructFields))
+	var selfPath string
+	var selfIndex []int
+
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		resource, ok := field.Tag.Lookup("acl")
+		if !ok {
+			continue
+		}
+		if len(resource) == 0 {
+			return nil, fmt.Errorf("acl tag on %s.%s must name a resource", modelType.Name(), field.Name)
+		}
+
+		// the embedded entity holds the id of the model itself
+		if field.Anonymous {
+			if selfIndex != nil {
+				return nil, fmt.Errorf("model %s can only have one tagged embedded field", modelType.Name())
+			}
+
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Ptr {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("tagged embedded field on %s must be a struct", modelType.Name())
+			}
+
+			idField, ok := embeddedType.FieldByName("Id")
+			if !ok || !isAclIdType(idField.Type) {
+				return nil, fmt.Errorf("tagged embedded field on %s must have a string Id", modelType.Name())
+			}
+
+			selfPath = resource
+			selfIndex = append([]int{i}, idField.Index...)
+			continue
+		}
 
-	for index, field := range data.StructFields {
-		fieldValue := derefValue.FieldByName(field)
+		if !isAclIdType(field.Type) {
+			return nil, fmt.Errorf("acl field %s on model %s must be a string", field.Name, modelType.Name())
+		}
+
+		data.Path = append(data.Path, resource)
+		data.FieldIndexes = append(data.FieldIndexes, field.Index)
+	}
+
+	if selfIndex != nil {
+		data.Path = append(data.Path, selfPath)
+		data.FieldIndexes = append(data.FieldIndexes, selfIndex)
+	}
+
+	if len(data.Path) == 0 {
+		return nil, nil
+	}
+
+	return data, nil
+}
+
+func isAclIdType(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.String
+}
+
+/**
+Grab the values of the id fields off the model to be used with the acl service, a nil id is treated as an empty one
+*/
+func (service *AclWrapperService) getIdsForModel(value reflect.Value, data *modelAclData) ([]string, error) {
+	ids := make([]string, len(data.FieldIndexes))
+
+	for index, fieldIndex := range data.FieldIndexes {
+		fieldValue := value
+		for _, i := range fieldIndex {
+			if fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					return nil, errors.New("could not read acl field of model")
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			fieldValue = fieldValue.Field(i)
+		}
 
-		var value string
 		if fieldValue.Kind() == reflect.Ptr {
 			if fieldValue.IsNil() {
-				value = ""
-			} else {
-				value = fieldValue.Elem().Interface().(string)
+				ids[index] = ""
+				continue
 			}
-		} else {
-			value = fieldValue.Interface().(string)
+			fieldValue = fieldValue.Elem()
 		}
 
-		ids[index] = value
+		// String works on unexported fields too, unlike Interface
+		ids[index] = fieldValue.String()
 	}
 
 	return ids, nil
 }
+
+func variantsContainId(variants [][]string, id string) bool {
+	for _, ids := range variants {
+		for _, variantId := range ids {
+			if variantId == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package acl_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type comment struct {
+	shared.Entity `acl:"comment"`
+
+	documentId     string `acl:"document"`
+	organizationId string `acl:"organization"`
+}
+
+type attachment struct {
+	id         string
+	documentId string
+}
+
+func (a attachment) AclResource() ([]string, []string) {
+	return []string{"document", "attachment"}, []string{a.documentId, a.id}
+}
+
+type badComment struct {
+	shared.Entity `acl:"comment"`
+
+	Position int `acl:"document"`
+}
+
+type Page struct {
+	Id   string
+	Book string
+}
+
+func TestGetResourceDataFromStructTags(t *testing.T) {
+	service := acl.NewAclWrapperService(nil, nil)
+
+	folderId := "folder-id"
+	document := &shared.Document{
+		OrganizationId: "organization-id",
+		FolderId:       &folderId,
+	}
+	document.Id = "document-id"
+
+	data, err := service.GetResourceDataForModel(document)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"organization", "folder", "document"}, data.ResourcePath)
+	assert.Equal(t, []string{"organization-id", "folder-id", "document-id"}, data.ResourceIds)
+
+	// the same type works by value, and a nil id is empty
+	document.FolderId = nil
+	data, err = service.GetResourceDataForModel(*document)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"organization-id", "", "document-id"}, data.ResourceIds)
+}
+
+func TestGetResourceDataFromUnexportedStructTags(t *testing.T) {
+	service := acl.NewAclWrapperService(nil, nil)
+
+	c := comment{documentId: "document-id", organizationId: "organization-id"}
+	c.Id = "comment-id"
+
+	data, err := service.GetResourceDataForModel(c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"document", "organization", "comment"}, data.ResourcePath)
+	assert.Equal(t, []string{"document-id", "organization-id", "comment-id"}, data.ResourceIds)
+}
+
+func TestGetResourceDataFromInterface(t *testing.T) {
+	service := acl.NewAclWrapperService(nil, nil)
+
+	data, err := service.GetResourceDataForModel(attachment{id: "attachment-id", documentId: "document-id"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"document", "attachment"}, data.ResourcePath)
+	assert.Equal(t, []string{"document-id", "attachment-id"}, data.ResourceIds)
+}
+
+func TestGetResourceDataFromPolicy(t *testing.T) {
+	service := acl.NewAclWrapperService(nil, map[string]acl.ModelPolicy{
+		"Page": {Path: []string{"book", "page"}, Fields: []string{"Book", "Id"}},
+	})
+
+	data, err := service.GetResourceDataForModel(&Page{Id: "page-id", Book: "book-id"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"book", "page"}, data.ResourcePath)
+	assert.Equal(t, []string{"book-id", "page-id"}, data.ResourceIds)
+}
+
+func TestGetResourceDataFailsInsteadOfPanicking(t *testing.T) {
+	service := acl.NewAclWrapperService(nil, nil)
+
+	_, err := service.GetResourceDataForModel(badComment{})
+	assert.NotNil(t, err)
+
+	_, err = service.GetResourceDataForModel(&Page{})
+	assert.NotNil(t, err)
+
+	_, err = service.GetResourceDataForModel("document")
+	assert.NotNil(t, err)
+
+	var document *shared.Document
+	_, err = service.GetResourceDataForModel(document)
+	assert.NotNil(t, err)
+
+	_, err = service.GetResourceDataForModel(nil)
+	assert.NotNil(t, err)
+}
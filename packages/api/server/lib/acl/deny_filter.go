@@ -0,0 +1,115 @@
+package acl
+
+import (
+	"strings"
+)
+
+/*
+Checks if any of the denies takes the action away on the resource. A deny applies to the resource its path starts at and
+everything below it, so a deny on folder 12345 matches organization:folder:document when the folder id is 12345.
+*/
+func isDenied(denies []AclDeny, path []string, ids []string, action string) bool {
+	if ids == nil || len(path) != len(ids) {
+		return false
+	}
+
+	for _, deny := range denies {
+		if deny.Action != DenyAllActions && deny.Action != action {
+			continue
+		}
+
+		for _, index := range denyIndexes(deny, path) {
+			if ids[index] == deny.ResourceId {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/**
+Checks if the action is denied on any of the variants of the resource ids, see expandIds
+*/
+func isDeniedOnAny(denies []AclDeny, path []string, variants [][]string, action string) bool {
+	for _, ids := range variants {
+		if isDenied(denies, path, ids, action) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/**
+Finds the ids that the action is denied on for each resource in the path, keyed by the resource name. This is what list
+queries exclude, e.g. the folder ids to leave out when listing documents.
+*/
+func deniedResourceIds(denies []AclDeny, path []string, action string) map[string][]string {
+	denied := make(map[string][]string)
+
+	for _, deny := range denies {
+		if deny.Action != DenyAllActions && deny.Action != action {
+			continue
+		}
+
+		for _, index := range denyIndexes(deny, path) {
+			denied[path[index]] = append(denied[path[index]], deny.ResourceId)
+		}
+	}
+
+	return denied
+}
+
+/**
+Removes the grants that are denied on every resource they match. Each group holds the requests for one resource, one for
+its own ids and one for every resource it is nested in. A deny on any request of the group denies the resource, and a grant
+matching at least one resource that is not denied is kept.
+*/
+func removeDeniedGrants(grants []ResourceResponse, groups [][]ResourceRequest, denies []AclDeny) []ResourceResponse {
+	if len(denies) == 0 {
+		return grants
+	}
+
+	results := make([]ResourceResponse, 0)
+	for _, grant := range grants {
+		for _, group := range groups {
+			if groupAllowsGrant(grant, group, denies) {
+				results = append(results, grant)
+				break
+			}
+		}
+	}
+
+	return results
+}
+
+func groupAllowsGrant(grant ResourceResponse, group []ResourceRequest, denies []AclDeny) bool {
+	matched := false
+	for _, request := range group {
+		if isDenied(denies, request.ResourcePath, request.ResourceIds, grant.Action) {
+			return false
+		}
+		if grantMatchesRequest(grant, request) {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+/**
+The indexes in the path where the path of the deny starts
+*/
+func denyIndexes(deny AclDeny, path []string) []int {
+	denyPath := strings.Split(deny.ResourcePath, ":")
+	indexes := make([]int, 0)
+
+	for index := 0; index+len(denyPath) <= len(path); index++ {
+		if strings.Join(path[index:index+len(denyPath)], ":") == deny.ResourcePath {
+			indexes = append(indexes, index)
+		}
+	}
+
+	return indexes
+}
@@ -0,0 +1,99 @@
+package acl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var documentPath = []string{"organization", "folder", "document"}
+
+func TestDenyOnOrganizationAppliesToEverythingInIt(t *testing.T) {
+	denies := []AclDeny{{ResourcePath: "organization", ResourceId: "org", Action: "view"}}
+
+	assert.True(t, isDenied(denies, []string{"organization"}, []string{"org"}, "view"))
+	assert.True(t, isDenied(denies, []string{"organization", "folder"}, []string{"org", "folder"}, "view"))
+	assert.True(t, isDenied(denies, documentPath, []string{"org", "folder", "document"}, "view"))
+	assert.False(t, isDenied(denies, documentPath, []string{"other-org", "folder", "document"}, "view"))
+	assert.False(t, isDenied(denies, documentPath, []string{"org", "folder", "document"}, "modify"))
+}
+
+func TestDenyOnFolderAppliesToItsDocuments(t *testing.T) {
+	denies := []AclDeny{{ResourcePath: "folder", ResourceId: "legal", Action: "modify"}}
+
+	assert.False(t, isDenied(denies, []string{"organization"}, []string{"org"}, "modify"))
+	assert.True(t, isDenied(denies, []string{"organization", "folder"}, []string{"org", "legal"}, "modify"))
+	assert.True(t, isDenied(denies, documentPath, []string{"org", "legal", "document"}, "modify"))
+	assert.False(t, isDenied(denies, documentPath, []string{"org", "other-folder", "document"}, "modify"))
+	assert.False(t, isDenied(denies, documentPath, []string{"org", "", "document"}, "modify"))
+}
+
+func TestDenyOnDocumentOnlyAppliesToIt(t *testing.T) {
+	denies := []AclDeny{{ResourcePath: "document", ResourceId: "document", Action: DenyAllActions}}
+
+	assert.False(t, isDenied(denies, []string{"organization", "folder"}, []string{"org", "folder"}, "view"))
+	assert.True(t, isDenied(denies, documentPath, []string{"org", "folder", "document"}, "view"))
+	assert.True(t, isDenied(denies, documentPath, []string{"org", "folder", "document"}, "delete"))
+	assert.False(t, isDenied(denies, documentPath, []string{"org", "folder", "other-document"}, "view"))
+}
+
+func TestDenyOnLongerPathOnlyAppliesBelowIt(t *testing.T) {
+	// documents in any folder of the organization, but not the organization or its folders
+	denies := []AclDeny{{ResourcePath: "organization:folder:document", ResourceId: "org", Action: "delete"}}
+
+	assert.False(t, isDenied(denies, []string{"organization"}, []string{"org"}, "delete"))
+	assert.False(t, isDenied(denies, []string{"organization", "folder"}, []string{"org", "folder"}, "delete"))
+	assert.True(t, isDenied(denies, documentPath, []string{"org", "folder", "document"}, "delete"))
+}
+
+func TestDeniedResourceIds(t *testing.T) {
+	denies := []AclDeny{
+		{ResourcePath: "organization", ResourceId: "org", Action: "view"},
+		{ResourcePath: "folder", ResourceId: "legal", Action: "view"},
+		{ResourcePath: "folder:document", ResourceId: "hr", Action: DenyAllActions},
+		{ResourcePath: "document", ResourceId: "document", Action: "modify"},
+	}
+
+	assert.Equal(t, map[string][]string{
+		"organization": {"org"},
+		"folder":       {"legal", "hr"},
+	}, deniedResourceIds(denies, documentPath, "view"))
+
+	// the folder:document deny does not apply to the folders themselves
+	assert.Equal(t, map[string][]string{
+		"organization": {"org"},
+		"folder":       {"legal"},
+	}, deniedResourceIds(denies, []string{"organization", "folder"}, "view"))
+}
+
+func TestRemoveDeniedGrantsKeepsGrantsForOtherRequests(t *testing.T) {
+	grants := []ResourceResponse{
+		{PermissionId: "1", ResourcePath: "organization:folder:document", ResourceId: "org", Action: "modify"},
+		{PermissionId: "2", ResourcePath: "organization:folder:document", ResourceId: "org", Action: "view"},
+	}
+	denies := []AclDeny{{ResourcePath: "folder", ResourceId: "legal", Action: "modify"}}
+
+	legal := ResourceRequest{ResourcePath: documentPath, ResourceIds: []string{"org", "legal", "document"}}
+	other := ResourceRequest{ResourcePath: documentPath, ResourceIds: []string{"org", "other", "document"}}
+
+	assert.Equal(t, []ResourceResponse{grants[1]}, removeDeniedGrants(grants, [][]ResourceRequest{{legal}}, denies))
+	assert.Equal(t, grants, removeDeniedGrants(grants, [][]ResourceRequest{{legal}, {other}}, denies))
+}
+
+func TestRemoveDeniedGrantsDeniesNestedResources(t *testing.T) {
+	grants := []ResourceResponse{
+		{PermissionId: "1", ResourcePath: "folder:document", ResourceId: "contracts", Action: "modify"},
+	}
+	denies := []AclDeny{{ResourcePath: "folder", ResourceId: "legal", Action: "modify"}}
+
+	// the document is in the contracts folder, which is nested in the legal folder
+	nested := []ResourceRequest{
+		{ResourcePath: documentPath, ResourceIds: []string{"org", "contracts", "document"}},
+		{ResourcePath: documentPath, ResourceIds: []string{"org", "legal", "document"}},
+	}
+	other := []ResourceRequest{
+		{ResourcePath: documentPath, ResourceIds: []string{"org", "contracts", "document"}},
+	}
+
+	assert.Equal(t, []ResourceResponse{}, removeDeniedGrants(grants, [][]ResourceRequest{nested}, denies))
+	assert.Equal(t, grants, removeDeniedGrants(grants, [][]ResourceRequest{other}, denies))
+}
@@ -0,0 +1,52 @@
+package acl
+
+import (
+	"errors"
+	"strings"
+)
+
+/*
+The in memory version of the where clause built by UserRoleRepository.buildWhereClause, finds the grants that match any
+of the requests. A grant is only returned once, even if it matches more than one request.
+*/
+func filterGrants(grants []ResourceResponse, requests []ResourceRequest) ([]ResourceResponse, error) {
+	if len(requests) == 0 {
+		return nil, errors.New("must supply at least one resource request")
+	}
+
+	for _, request := range requests {
+		if request.ResourceIds != nil && len(request.ResourcePath) != len(request.ResourceIds) {
+			return nil, errors.New("resource paths and ids must be the same length")
+		}
+	}
+
+	results := make([]ResourceResponse, 0)
+	for _, grant := range grants {
+		for _, request := range requests {
+			if grantMatchesRequest(grant, request) {
+				results = append(results, grant)
+				break
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func grantMatchesRequest(grant ResourceResponse, request ResourceRequest) bool {
+	if request.Action != nil && grant.Action != *request.Action {
+		return false
+	}
+
+	for pathIndex := 0; pathIndex < len(request.ResourcePath); pathIndex++ {
+		if grant.ResourcePath != strings.Join(request.ResourcePath[pathIndex:], ":") {
+			continue
+		}
+		if request.ResourceIds != nil && grant.ResourceId != request.ResourceIds[pathIndex] {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
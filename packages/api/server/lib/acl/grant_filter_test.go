@@ -0,0 +1,56 @@
+package acl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testGrants = []ResourceResponse{
+	{PermissionId: "1", ResourcePath: "organization", ResourceId: "org", Action: "view"},
+	{PermissionId: "2", ResourcePath: "organization:folder:document", ResourceId: "org", Action: "modify"},
+	{PermissionId: "3", ResourcePath: "organization:folder:document", ResourceId: "org", Action: "view"},
+	{PermissionId: "4", ResourcePath: "folder:document", ResourceId: "folder", Action: "view"},
+	{PermissionId: "3", ResourcePath: "organization:folder:document", ResourceId: "other-org", Action: "view"},
+}
+
+func TestFilterGrantsByPathIdsAndAction(t *testing.T) {
+	action := "view"
+	results, err := filterGrants(testGrants, []ResourceRequest{{
+		ResourcePath: []string{"organization", "folder", "document"},
+		ResourceIds:  []string{"org", "folder", "document"},
+		Action:       &action,
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []ResourceResponse{testGrants[2], testGrants[3]}, results)
+}
+
+func TestFilterGrantsByPathOnly(t *testing.T) {
+	results, err := filterGrants(testGrants, []ResourceRequest{{
+		ResourcePath: []string{"organization", "folder", "document"},
+	}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []ResourceResponse{testGrants[1], testGrants[2], testGrants[3], testGrants[4]}, results)
+}
+
+func TestFilterGrantsReturnsEachGrantOnce(t *testing.T) {
+	results, err := filterGrants(testGrants, []ResourceRequest{
+		{ResourcePath: []string{"organization"}, ResourceIds: []string{"org"}},
+		{ResourcePath: []string{"organization"}, ResourceIds: []string{"org"}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []ResourceResponse{testGrants[0]}, results)
+}
+
+func TestFilterGrantsFailsWithMismatchedIds(t *testing.T) {
+	_, err := filterGrants(testGrants, []ResourceRequest{{
+		ResourcePath: []string{"organization", "folder"},
+		ResourceIds:  []string{"org"},
+	}})
+	assert.NotNil(t, err)
+
+	_, err = filterGrants(testGrants, []ResourceRequest{})
+	assert.NotNil(t, err)
+}
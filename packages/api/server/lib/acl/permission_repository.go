@@ -64,4 +64,29 @@ func (repo *PermissionRepository) Insert(permission *Permission) (*Permission, e
 	}
 
 	return permission, nil;
-}
\ No newline at end of file
+}
+
+/**
+Finds every distinct action that exists across all permissions
+*/
+func (repo *PermissionRepository) FindActions() ([]string, error) {
+	rows, err := repo.Query("select distinct action from permission where deleted_at is null ORDER BY action ASC")
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find permission actions")
+	}
+	defer rows.Close()
+
+	actions := make([]string, 0)
+	for rows.Next() {
+		var action string
+		err := rows.Scan(&action)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse permission action")
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
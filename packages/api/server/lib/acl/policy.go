@@ -0,0 +1,164 @@
+package acl
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const PolicyVersion = 1
+
+type ModelPolicy struct {
+	Path   []string `yaml:"path" json:"path"`
+	Fields []string `yaml:"fields" json:"fields"`
+}
+
+/*
+The declarative acl policy, holds the built in roles and how to find the resource data for each model. The file can be
+either yaml or json, json is valid yaml so both are parsed the same way.
+*/
+type Policy struct {
+	Version int                            `yaml:"version" json:"version"`
+	Models  map[string]ModelPolicy         `yaml:"models" json:"models"`
+	Roles   map[string]map[string][]string `yaml:"roles" json:"roles"`
+}
+
+/**
+Loads the policy from the path in ACL_POLICY_PATH
+*/
+func NewPolicyFromEnv() (*Policy, error) {
+	path := os.Getenv("ACL_POLICY_PATH")
+	if len(path) == 0 {
+		return nil, errors.New("ACL_POLICY_PATH is required")
+	}
+
+	return LoadPolicy(path)
+}
+
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to read acl policy")
+	}
+
+	return ParsePolicy(data)
+}
+
+/**
+Parses and validates the policy
+*/
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	err := yaml.UnmarshalStrict(data, &policy)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse acl policy")
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+/**
+Makes sure the models and roles make sense, all of the problems are returned together
+*/
+func (policy *Policy) Validate() error {
+	problems := make([]string, 0)
+
+	if policy.Version != PolicyVersion {
+		problems = append(problems, fmt.Sprintf("unsupported version %d, expected %d", policy.Version, PolicyVersion))
+	}
+
+	if len(policy.Models) == 0 {
+		problems = append(problems, "at least one model is required")
+	}
+
+	for name, model := range policy.Models {
+		if len(model.Path) == 0 {
+			problems = append(problems, fmt.Sprintf("model %s must have a path", name))
+		}
+		if len(model.Path) != len(model.Fields) {
+			problems = append(problems, fmt.Sprintf("model %s must have one field for each resource in its path", name))
+		}
+	}
+
+	if len(policy.Roles) == 0 {
+		problems = append(problems, "at least one role is required")
+	}
+
+	for roleName, permissions := range policy.Roles {
+		if len(roleName) == 0 || len(roleName) > 36 {
+			problems = append(problems, fmt.Sprintf("role name %s must be between 1 and 36 characters", roleName))
+		}
+
+		for path, actions := range permissions {
+			if !policy.isKnownPath(path) {
+				problems = append(problems, fmt.Sprintf("role %s uses unknown resource path %s", roleName, path))
+			}
+
+			seen := make(map[string]bool)
+			for _, action := range actions {
+				if len(action) == 0 {
+					problems = append(problems, fmt.Sprintf("role %s has an empty action on %s", roleName, path))
+				}
+				if seen[action] {
+					problems = append(problems, fmt.Sprintf("role %s has duplicate action %s on %s", roleName, action, path))
+				}
+				seen[action] = true
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid acl policy: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+/**
+A resource path is known if it is the path of a model, or the end of one, e.g. folder:document for a document
+*/
+func (policy *Policy) isKnownPath(path string) bool {
+	for _, model := range policy.Models {
+		for i := 0; i < len(model.Path); i++ {
+			if strings.Join(model.Path[i:], ":") == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const (
+	PolicyChangeLink   = "link"
+	PolicyChangeUnlink = "unlink"
+)
+
+/**
+A single difference between the policy and the database
+*/
+type PolicyChange struct {
+	Type         string
+	RoleName     string
+	ResourcePath string
+	Action       string
+}
+
+func (change PolicyChange) String() string {
+	sign := "+"
+	if change.Type == PolicyChangeUnlink {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s %s %s %s", sign, change.RoleName, change.ResourcePath, change.Action)
+}
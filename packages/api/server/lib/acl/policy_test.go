@@ -0,0 +1,72 @@
+package acl_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := acl.LoadPolicy("../../../policy/acl.yaml")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"organization", "folder", "document"}, policy.Models["Document"].Path)
+	assert.Contains(t, policy.Roles, "organization:owner")
+	assert.Contains(t, policy.Roles, "organization:contributor")
+}
+
+func TestParsePolicyAcceptsJson(t *testing.T) {
+	policy, err := acl.ParsePolicy([]byte(`{
+		"version": 1,
+		"models": {"Organization": {"path": ["organization"], "fields": ["Id"]}},
+		"roles": {"organization:viewer": {"organization": ["view"]}}
+	}`))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"view"}, policy.Roles["organization:viewer"]["organization"])
+}
+
+func TestParsePolicyFailsWithUnknownResourcePath(t *testing.T) {
+	_, err := acl.ParsePolicy([]byte(`
+version: 1
+models:
+  Organization:
+    path: [organization]
+    fields: [Id]
+roles:
+  "organization:viewer":
+    "organization:team": [view]
+`))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown resource path organization:team")
+}
+
+func TestParsePolicyFailsWithMismatchedFields(t *testing.T) {
+	_, err := acl.ParsePolicy([]byte(`
+version: 1
+models:
+  Folder:
+    path: [organization, folder]
+    fields: [Id]
+roles:
+  "organization:viewer":
+    "organization:folder": [view]
+`))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "one field for each resource")
+}
+
+func TestParsePolicyFailsWithUnsupportedVersion(t *testing.T) {
+	_, err := acl.ParsePolicy([]byte(`
+version: 2
+models:
+  Organization:
+    path: [organization]
+    fields: [Id]
+roles:
+  "organization:viewer":
+    "organization": [view]
+`))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unsupported version 2")
+}
@@ -0,0 +1,107 @@
+package acl
+
+import (
+	"database/sql"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"sync"
+)
+
+/*
+Resources that can be nested in themselves, e.g. folders in folders, register their hierarchy so that a grant or deny on
+a resource also applies to everything nested below it.
+*/
+type ResourceHierarchy interface {
+	// the ids of the resources the given one is nested in, nearest first
+	FindAncestorIds(id string) ([]string, error)
+
+	// the ids of every resource nested below the given ones, at any depth
+	FindDescendantIds(ids []string) ([]string, error)
+}
+
+/*
+The registered hierarchies. A copy made for a transaction injects it into the hierarchies that can take one, so that
+resources created earlier in the transaction are found.
+*/
+type resourceHierarchies struct {
+	hierarchies map[string]ResourceHierarchy
+	lock        sync.RWMutex
+	root        *resourceHierarchies
+	tx          *sql.Tx
+}
+
+func newResourceHierarchies() *resourceHierarchies {
+	return &resourceHierarchies{
+		hierarchies: make(map[string]ResourceHierarchy),
+	}
+}
+
+func (h *resourceHierarchies) inTransaction(tx *sql.Tx) *resourceHierarchies {
+	return &resourceHierarchies{
+		root: h.registry(),
+		tx:   tx,
+	}
+}
+
+func (h *resourceHierarchies) registry() *resourceHierarchies {
+	if h.root != nil {
+		return h.root
+	}
+	return h
+}
+
+func (h *resourceHierarchies) register(resourceName string, hierarchy ResourceHierarchy) {
+	registry := h.registry()
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.hierarchies[resourceName] = hierarchy
+}
+
+func (h *resourceHierarchies) get(resourceName string) ResourceHierarchy {
+	if h == nil {
+		return nil
+	}
+
+	registry := h.registry()
+	registry.lock.RLock()
+	hierarchy := registry.hierarchies[resourceName]
+	registry.lock.RUnlock()
+
+	if transactionable, ok := hierarchy.(util.Transactionable); ok && h.tx != nil {
+		return transactionable.InjectTransaction(h.tx).(ResourceHierarchy)
+	}
+
+	return hierarchy
+}
+
+/**
+Expands the ids of a resource with its ancestry. The first ids are the given ones, followed by a copy for every ancestor
+where the nested resource is replaced with the ancestor. A grant on any of them is a grant on the resource, and a deny
+on any of them is a deny on the resource. findAncestorIds returns nothing for resources that can not be nested.
+*/
+func expandIds(path []string, ids []string, findAncestorIds func(resourceName string, id string) ([]string, error)) ([][]string, error) {
+	expanded := [][]string{ids}
+	if ids == nil || len(path) != len(ids) {
+		return expanded, nil
+	}
+
+	for index, resourceName := range path {
+		if ids[index] == "" {
+			continue
+		}
+
+		ancestorIds, err := findAncestorIds(resourceName, ids[index])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ancestorId := range ancestorIds {
+			variant := make([]string, len(ids))
+			copy(variant, ids)
+			variant[index] = ancestorId
+			expanded = append(expanded, variant)
+		}
+	}
+
+	return expanded, nil
+}
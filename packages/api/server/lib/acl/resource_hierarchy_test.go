@@ -0,0 +1,105 @@
+package acl
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// root > legal > contracts
+var folderAncestors = map[string][]string{
+	"root":      {},
+	"legal":     {"root"},
+	"contracts": {"legal", "root"},
+}
+
+func findFolderAncestorIds(resourceName string, id string) ([]string, error) {
+	if resourceName != "folder" {
+		return nil, nil
+	}
+	return folderAncestors[id], nil
+}
+
+func TestExpandIdsAddsEveryAncestor(t *testing.T) {
+	expanded, err := expandIds(documentPath, []string{"org", "contracts", "document"}, findFolderAncestorIds)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"org", "contracts", "document"},
+		{"org", "legal", "document"},
+		{"org", "root", "document"},
+	}, expanded)
+}
+
+func TestExpandIdsWithoutAncestors(t *testing.T) {
+	expanded, err := expandIds(documentPath, []string{"org", "root", "document"}, findFolderAncestorIds)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"org", "root", "document"}}, expanded)
+
+	// documents that are not in a folder
+	expanded, err = expandIds(documentPath, []string{"org", "", "document"}, findFolderAncestorIds)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"org", "", "document"}}, expanded)
+
+	expanded, err = expandIds(documentPath, nil, findFolderAncestorIds)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{nil}, expanded)
+}
+
+func TestExpandIdsReturnsLookupErrors(t *testing.T) {
+	_, err := expandIds(documentPath, []string{"org", "contracts", "document"}, func(resourceName string, id string) ([]string, error) {
+		return nil, errors.New("failed")
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestGrantOnAncestorMatchesNestedResource(t *testing.T) {
+	grants := []ResourceResponse{
+		{PermissionId: "1", ResourcePath: "folder:document", ResourceId: "root", Action: "view"},
+		{PermissionId: "2", ResourcePath: "folder:document", ResourceId: "other", Action: "view"},
+	}
+
+	expanded, _ := expandIds(documentPath, []string{"org", "contracts", "document"}, findFolderAncestorIds)
+	requests := make([]ResourceRequest, len(expanded))
+	for index, ids := range expanded {
+		requests[index] = ResourceRequest{ResourcePath: documentPath, ResourceIds: ids}
+	}
+
+	filtered, err := filterGrants(grants, requests)
+	assert.Nil(t, err)
+	assert.Equal(t, []ResourceResponse{grants[0]}, filtered)
+}
+
+type transactionalHierarchy struct {
+	tx *sql.Tx
+}
+
+func (h *transactionalHierarchy) InjectTransaction(tx *sql.Tx) interface{} {
+	return &transactionalHierarchy{tx: tx}
+}
+
+func (h *transactionalHierarchy) FindAncestorIds(id string) ([]string, error) {
+	return nil, nil
+}
+
+func (h *transactionalHierarchy) FindDescendantIds(ids []string) ([]string, error) {
+	return nil, nil
+}
+
+func TestHierarchiesAreGivenTheTransaction(t *testing.T) {
+	hierarchies := newResourceHierarchies()
+	hierarchies.register("folder", &transactionalHierarchy{})
+
+	tx := &sql.Tx{}
+	injected := hierarchies.inTransaction(tx)
+
+	// hierarchies registered after the copy is made are found as well
+	hierarchies.register("document", &transactionalHierarchy{})
+
+	assert.Nil(t, hierarchies.get("folder").(*transactionalHierarchy).tx)
+	assert.Equal(t, tx, injected.get("folder").(*transactionalHierarchy).tx)
+	assert.Equal(t, tx, injected.inTransaction(tx).get("document").(*transactionalHierarchy).tx)
+	assert.Nil(t, injected.get("team"))
+}
@@ -5,5 +5,7 @@ import "github.com/honerlaw/mentordoc/server/lib/shared"
 type Role struct {
 	shared.Entity
 
-	Name string `json:"name"`
-}
\ No newline at end of file
+	Name           string       `json:"name"`
+	OrganizationId *string      `json:"organizationId"` // nil for the built in roles
+	Permissions    []Permission `json:"permissions,omitempty"`
+}
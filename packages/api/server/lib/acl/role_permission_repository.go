@@ -59,4 +59,56 @@ func (repo *RolePermissionRepository) Link(role *Role, permission *Permission) e
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func (repo *RolePermissionRepository) Unlink(role *Role, permission *Permission) error {
+	_, err := repo.Exec(
+		"delete from role_permission where role_id = ? and permission_id = ?",
+		role.Id,
+		permission.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to unlink permission from role")
+	}
+
+	return nil
+}
+
+func (repo *RolePermissionRepository) UnlinkAll(role *Role) error {
+	_, err := repo.Exec(
+		"delete from role_permission where role_id = ?",
+		role.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to unlink permissions from role")
+	}
+
+	return nil
+}
+
+func (repo *RolePermissionRepository) FindPermissions(role *Role) ([]Permission, error) {
+	rows, err := repo.Query(
+		"select p.id, p.resource_path, p.action, p.created_at, p.updated_at, p.deleted_at from role_permission rp join permission p on p.id = rp.permission_id where rp.role_id = ? ORDER BY p.resource_path ASC, p.action ASC",
+		role.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find permissions for role")
+	}
+	defer rows.Close()
+
+	permissions := make([]Permission, 0)
+	for rows.Next() {
+		var permission Permission
+		err := rows.Scan(&permission.Id, &permission.ResourcePath, &permission.Action, &permission.CreatedAt, &permission.UpdatedAt, &permission.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse permission")
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, nil
+}
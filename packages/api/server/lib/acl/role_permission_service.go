@@ -2,8 +2,10 @@ package acl
 
 import (
 	"database/sql"
+	"errors"
 	"github.com/honerlaw/mentordoc/server/lib/util"
 	uuid "github.com/satori/go.uuid"
+	"sort"
 )
 
 type RolePermissionService struct {
@@ -33,24 +35,40 @@ func (service *RolePermissionService) InjectTransaction(tx *sql.Tx) interface{}
 		service.transactionManager.InjectTransaction(tx).(*util.TransactionManager))
 }
 
-func (service *RolePermissionService) InitRoles() error {
-	_, err := service.CreateRoleWithPermissions("organization:owner", map[string][]string {
-		"organization": {"view", "modify", "view:folder", "create:folder", "create:document", "view:document"},
-		"organization:folder": {"view", "modify", "delete", "view:folder", "create:folder", "view:document", "create:document"},
-		"organization:folder:document": {"view", "modify", "delete"},
-	});
+/*
+Reconciles the built in roles in the database with the roles in the policy. Permissions that are in the policy but not
+linked to the role are linked, and permissions that are linked to the role but no longer in the policy are unlinked.
+Built in roles that were removed from the policy lose all of their permissions. Custom roles of organizations are never
+touched. Nothing is changed when dry run is set, the changes that would be made are returned either way.
+*/
+func (service *RolePermissionService) ReconcileRoles(roles map[string]map[string][]string, dryRun bool) ([]PolicyChange, error) {
+	changes, err := service.diffRoles(roles)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = service.CreateRoleWithPermissions("organization:contributor", map[string][]string {
-		"organization": {"view", "create:folder", "create:document", "view:document"},
-		"organization:folder": {"view", "modify", "delete", "view:folder", "create:folder", "view:document", "create:document"},
-		"organization:folder:document": {"view", "modify", "delete"},
-	});
+
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*RolePermissionService)
+
+		for _, change := range changes {
+			err := injectedService.applyChange(change)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	return changes, nil
 }
 
 func (service *RolePermissionService) CreateRoleWithPermissions(roleName string, permissionMap map[string][]string) (*Role, error) {
@@ -93,4 +111,219 @@ func (service *RolePermissionService) CreateRoleWithPermissions(roleName string,
 	}
 
 	return role.(*Role), nil
-}
\ No newline at end of file
+}
+
+/*
+Creates a custom role for the organization. Unlike the built in roles, the permissions must already exist, so a custom
+role can only ever grant actions on resource paths that the application knows about.
+*/
+func (service *RolePermissionService) CreateOrganizationRole(organizationId string, roleName string, permissionMap map[string][]string) (*Role, error) {
+	role, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*RolePermissionService)
+
+		role := &Role{
+			Name:           roleName,
+			OrganizationId: &organizationId,
+		}
+		role.Id = uuid.NewV4().String()
+
+		role, err := injectedService.roleRepository.Insert(role)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.linkExistingPermissions(role, permissionMap)
+		if err != nil {
+			return nil, err
+		}
+
+		return role, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return role.(*Role), nil
+}
+
+/**
+Renames the role and replaces all of its permissions with the given ones
+*/
+func (service *RolePermissionService) UpdateRole(role *Role, roleName string, permissionMap map[string][]string) (*Role, error) {
+	updated, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*RolePermissionService)
+
+		role.Name = roleName
+		err := injectedService.roleRepository.Update(role)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.rolePermissionRepository.UnlinkAll(role)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.linkExistingPermissions(role, permissionMap)
+		if err != nil {
+			return nil, err
+		}
+
+		return role, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated.(*Role), nil
+}
+
+func (service *RolePermissionService) DeleteRole(role *Role) error {
+	err := service.rolePermissionRepository.UnlinkAll(role)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := util.NowUnix()
+	role.DeletedAt = &deletedAt
+
+	return service.roleRepository.Update(role)
+}
+
+func (service *RolePermissionService) FindRoleById(id string) *Role {
+	return service.roleRepository.FindById(id)
+}
+
+func (service *RolePermissionService) FindOrganizationRoles(organizationId string) ([]Role, error) {
+	return service.roleRepository.FindByOrganizationId(organizationId)
+}
+
+func (service *RolePermissionService) FindOrganizationRoleByName(organizationId string, roleName string) *Role {
+	return service.roleRepository.FindByOrganizationIdAndName(organizationId, roleName)
+}
+
+func (service *RolePermissionService) FindPermissions(role *Role) ([]Permission, error) {
+	return service.rolePermissionRepository.FindPermissions(role)
+}
+
+func (service *RolePermissionService) PermissionExists(resourcePath string, action string) bool {
+	return service.permissionRepository.Find(resourcePath, action) != nil
+}
+
+func (service *RolePermissionService) linkExistingPermissions(role *Role, permissionMap map[string][]string) error {
+	for path, actions := range permissionMap {
+		for _, action := range actions {
+			permission := service.permissionRepository.Find(path, action)
+			if permission == nil {
+				return errors.New("permission does not exist")
+			}
+			if err := service.rolePermissionRepository.Link(role, permission); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (service *RolePermissionService) diffRoles(roles map[string]map[string][]string) ([]PolicyChange, error) {
+	changes := make([]PolicyChange, 0)
+
+	for roleName, permissionMap := range roles {
+		permissions := make([]Permission, 0)
+		role := service.roleRepository.Find(roleName)
+		if role != nil {
+			linked, err := service.rolePermissionRepository.FindPermissions(role)
+			if err != nil {
+				return nil, err
+			}
+			permissions = linked
+		}
+
+		current := make(map[string]bool)
+		for _, permission := range permissions {
+			current[permission.ResourcePath+" "+permission.Action] = true
+		}
+
+		desired := make(map[string]bool)
+		for path, actions := range permissionMap {
+			for _, action := range actions {
+				desired[path+" "+action] = true
+				if !current[path+" "+action] {
+					changes = append(changes, PolicyChange{Type: PolicyChangeLink, RoleName: roleName, ResourcePath: path, Action: action})
+				}
+			}
+		}
+
+		for _, permission := range permissions {
+			if !desired[permission.ResourcePath+" "+permission.Action] {
+				changes = append(changes, PolicyChange{Type: PolicyChangeUnlink, RoleName: roleName, ResourcePath: permission.ResourcePath, Action: permission.Action})
+			}
+		}
+	}
+
+	// built in roles that are no longer in the policy should not grant anything
+	existingRoles, err := service.roleRepository.FindBuiltIn()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(existingRoles); i++ {
+		if _, ok := roles[existingRoles[i].Name]; ok {
+			continue
+		}
+
+		permissions, err := service.rolePermissionRepository.FindPermissions(&existingRoles[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range permissions {
+			changes = append(changes, PolicyChange{Type: PolicyChangeUnlink, RoleName: existingRoles[i].Name, ResourcePath: permission.ResourcePath, Action: permission.Action})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].String() < changes[j].String()
+	})
+
+	return changes, nil
+}
+
+func (service *RolePermissionService) applyChange(change PolicyChange) error {
+	switch change.Type {
+	case PolicyChangeLink:
+		role := &Role{
+			Name: change.RoleName,
+		}
+		role.Id = uuid.NewV4().String()
+
+		role, err := service.roleRepository.Insert(role)
+		if err != nil {
+			return err
+		}
+
+		permission := &Permission{
+			ResourcePath: change.ResourcePath,
+			Action:       change.Action,
+		}
+		permission.Id = uuid.NewV4().String()
+
+		permission, err = service.permissionRepository.Insert(permission)
+		if err != nil {
+			return err
+		}
+
+		return service.rolePermissionRepository.Link(role, permission)
+	case PolicyChangeUnlink:
+		role := service.roleRepository.Find(change.RoleName)
+		permission := service.permissionRepository.Find(change.ResourcePath, change.Action)
+		if role == nil || permission == nil {
+			return nil
+		}
+
+		return service.rolePermissionRepository.Unlink(role, permission)
+	}
+
+	return errors.New("unknown policy change")
+}
@@ -22,34 +22,95 @@ func (repo *RoleRepository) InjectTransaction(tx *sql.Tx) interface{} {
 	return NewRoleRepository(repo.Db, tx)
 }
 
+/**
+Finds one of the built in roles by name
+*/
 func (repo *RoleRepository) Find(name string) *Role {
 	row := repo.QueryRow(
-		"select id, name, created_at, updated_at, deleted_at from role where name = ?",
+		"select id, name, organization_id, created_at, updated_at, deleted_at from role where name = ? and organization_id is null and deleted_at is null",
 		name,
 	)
 
-	var role Role
-	err := row.Scan(&role.Id, &role.Name, &role.CreatedAt, &role.UpdatedAt, &role.DeletedAt)
+	return repo.scanRow(row)
+}
+
+func (repo *RoleRepository) FindById(id string) *Role {
+	row := repo.QueryRow(
+		"select id, name, organization_id, created_at, updated_at, deleted_at from role where id = ? and deleted_at is null",
+		id,
+	)
+
+	return repo.scanRow(row)
+}
+
+func (repo *RoleRepository) FindByOrganizationIdAndName(organizationId string, name string) *Role {
+	row := repo.QueryRow(
+		"select id, name, organization_id, created_at, updated_at, deleted_at from role where organization_id = ? and name = ? and deleted_at is null",
+		organizationId,
+		name,
+	)
+
+	return repo.scanRow(row)
+}
+
+func (repo *RoleRepository) FindByOrganizationId(organizationId string) ([]Role, error) {
+	rows, err := repo.Query(
+		"select id, name, organization_id, created_at, updated_at, deleted_at from role where organization_id = ? and deleted_at is null ORDER BY name ASC",
+		organizationId,
+	)
+
+	return repo.scanRows(rows, err)
+}
+
+/**
+Finds every role that does not belong to an organization
+*/
+func (repo *RoleRepository) FindBuiltIn() ([]Role, error) {
+	rows, err := repo.Query(
+		"select id, name, organization_id, created_at, updated_at, deleted_at from role where organization_id is null and deleted_at is null ORDER BY name ASC",
+	)
+
+	return repo.scanRows(rows, err)
+}
+
+func (repo *RoleRepository) scanRows(rows *sql.Rows, err error) ([]Role, error) {
 	if err != nil {
 		log.Print(err)
-		return nil
+		return nil, errors.New("failed to find roles")
 	}
+	defer rows.Close()
 
-	return &role
+	roles := make([]Role, 0)
+	for rows.Next() {
+		var role Role
+		err := rows.Scan(&role.Id, &role.Name, &role.OrganizationId, &role.CreatedAt, &role.UpdatedAt, &role.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse roles")
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, nil
 }
 
 func (repo *RoleRepository) Insert(role *Role) (*Role, error) {
-	existing := repo.Find(role.Name);
-	if existing != nil {
-		return existing, nil
+
+	// the built in roles are created every time the server starts
+	if role.OrganizationId == nil {
+		existing := repo.Find(role.Name);
+		if existing != nil {
+			return existing, nil
+		}
 	}
 	role.CreatedAt = util.NowUnix()
 	role.UpdatedAt = util.NowUnix()
 
 	_, err := repo.Exec(
-		"insert into role (id, name, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?)",
+		"insert into role (id, name, organization_id, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?)",
 		role.Id,
 		role.Name,
+		role.OrganizationId,
 		role.CreatedAt,
 		role.UpdatedAt,
 		role.DeletedAt,
@@ -61,4 +122,34 @@ func (repo *RoleRepository) Insert(role *Role) (*Role, error) {
 	}
 
 	return role, nil;
-}
\ No newline at end of file
+}
+
+func (repo *RoleRepository) Update(role *Role) error {
+	role.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update role set name = ?, updated_at = ?, deleted_at = ? where id = ?",
+		role.Name,
+		role.UpdatedAt,
+		role.DeletedAt,
+		role.Id,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update role")
+	}
+
+	return nil
+}
+
+func (repo *RoleRepository) scanRow(row *sql.Row) *Role {
+	var role Role
+	err := row.Scan(&role.Id, &role.Name, &role.OrganizationId, &role.CreatedAt, &role.UpdatedAt, &role.DeletedAt)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	return &role
+}
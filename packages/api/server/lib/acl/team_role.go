@@ -0,0 +1,8 @@
+package acl
+
+type TeamRole struct {
+	TeamId     string `json:"teamId"`
+	RoleId     string `json:"roleId"`
+	RoleName   string `json:"roleName"`
+	ResourceId string `json:"resourceId"`
+}
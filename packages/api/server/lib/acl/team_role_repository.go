@@ -0,0 +1,130 @@
+package acl
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+type TeamRoleRepository struct {
+	util.Repository
+}
+
+func NewTeamRoleRepository(db *sql.DB, tx *sql.Tx) *TeamRoleRepository {
+	repo := &TeamRoleRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *TeamRoleRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewTeamRoleRepository(repo.Db, tx)
+}
+
+func (repo *TeamRoleRepository) Link(teamId string, role *Role, resourceId string) error {
+	// check if its already been linked
+	row := repo.QueryRow(
+		"select count(*) from team_role where team_id = ? and role_id = ? and resource_id = ?",
+		teamId,
+		role.Id,
+		resourceId,
+	)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to link role to team")
+	}
+
+	// role is already linked, so nothing to do
+	if count > 0 {
+		return nil
+	}
+
+	_, err = repo.Exec(
+		"insert into team_role (team_id, role_id, resource_id) values (?, ?, ?)",
+		teamId,
+		role.Id,
+		resourceId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to link role to team")
+	}
+
+	return nil
+}
+
+func (repo *TeamRoleRepository) Unlink(teamId string, role *Role, resourceId string) error {
+	_, err := repo.Exec(
+		"delete from team_role where team_id = ? and role_id = ? and resource_id = ?",
+		teamId,
+		role.Id,
+		resourceId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to unlink role from team")
+	}
+
+	return nil
+}
+
+func (repo *TeamRoleRepository) UnlinkAll(teamId string) error {
+	_, err := repo.Exec(
+		"delete from team_role where team_id = ?",
+		teamId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to unlink roles from team")
+	}
+
+	return nil
+}
+
+func (repo *TeamRoleRepository) FindByTeamId(teamId string) ([]TeamRole, error) {
+	rows, err := repo.Query(
+		"select tr.team_id, tr.role_id, r.name, tr.resource_id from team_role tr join role r on r.id = tr.role_id where tr.team_id = ? ORDER BY r.name ASC",
+		teamId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find roles for team")
+	}
+	defer rows.Close()
+
+	teamRoles := make([]TeamRole, 0)
+	for rows.Next() {
+		var teamRole TeamRole
+		err := rows.Scan(&teamRole.TeamId, &teamRole.RoleId, &teamRole.RoleName, &teamRole.ResourceId)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse team role")
+		}
+		teamRoles = append(teamRoles, teamRole)
+	}
+
+	return teamRoles, nil
+}
+
+/**
+Counts the teams that hold the role on any resource, deleted teams no longer hold their roles
+*/
+func (repo *TeamRoleRepository) CountAllTeams(role *Role) (int, error) {
+	row := repo.QueryRow(
+		"select count(distinct tr.team_id) from team_role tr join team t on t.id = tr.team_id where tr.role_id = ? and t.deleted_at is null",
+		role.Id,
+	)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return 0, errors.New("failed to count teams with role")
+	}
+
+	return count, nil
+}
@@ -0,0 +1,61 @@
+package acl
+
+import (
+	"database/sql"
+	"github.com/pkg/errors"
+)
+
+/**
+Links roles to teams, every member of the team is granted the permissions of the role on the resource
+*/
+type TeamRoleService struct {
+	roleRepository     *RoleRepository
+	teamRoleRepository *TeamRoleRepository
+}
+
+func NewTeamRoleService(roleRepository *RoleRepository, teamRoleRepository *TeamRoleRepository) *TeamRoleService {
+	return &TeamRoleService{
+		roleRepository:     roleRepository,
+		teamRoleRepository: teamRoleRepository,
+	}
+}
+
+func (service *TeamRoleService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewTeamRoleService(service.roleRepository.InjectTransaction(tx).(*RoleRepository),
+		service.teamRoleRepository.InjectTransaction(tx).(*TeamRoleRepository))
+}
+
+func (service *TeamRoleService) LinkTeamToRoleById(teamId string, roleId string, resourceId string) error {
+	role := service.roleRepository.FindById(roleId)
+	if role == nil {
+		return errors.New("failed to find role")
+	}
+
+	return service.teamRoleRepository.Link(teamId, role, resourceId)
+}
+
+func (service *TeamRoleService) UnlinkTeamFromRoleById(teamId string, roleId string, resourceId string) error {
+	role := service.roleRepository.FindById(roleId)
+	if role == nil {
+		return errors.New("failed to find role")
+	}
+
+	return service.teamRoleRepository.Unlink(teamId, role, resourceId)
+}
+
+func (service *TeamRoleService) UnlinkTeamFromAllRoles(teamId string) error {
+	return service.teamRoleRepository.UnlinkAll(teamId)
+}
+
+func (service *TeamRoleService) FindTeamRoles(teamId string) ([]TeamRole, error) {
+	return service.teamRoleRepository.FindByTeamId(teamId)
+}
+
+func (service *TeamRoleService) CountTeamsWithRoleId(roleId string) (int, error) {
+	role := service.roleRepository.FindById(roleId)
+	if role == nil {
+		return 0, errors.New("failed to find role")
+	}
+
+	return service.teamRoleRepository.CountAllTeams(role)
+}
@@ -0,0 +1,9 @@
+package acl
+
+type UserRole struct {
+	UserId     string `json:"userId"`
+	RoleId     string `json:"roleId"`
+	RoleName   string `json:"roleName"`
+	ResourceId string `json:"resourceId"`
+	ExpiresAt  *int64 `json:"expiresAt"` // nil if the grant never expires
+}
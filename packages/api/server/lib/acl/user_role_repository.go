@@ -10,6 +10,20 @@ import (
 	"strings"
 )
 
+// grants past their expiration are ignored until they are deleted, expects the current time as a parameter
+const notExpiredClause = "(ur.expires_at IS NULL OR ur.expires_at > ?)"
+
+/**
+The grants of the user, both the roles linked to the user and the roles linked to the teams the user is a member of. It
+takes the place of the user_role table in the grant queries, so the rest of the query can keep using ur.
+*/
+func userGrantsTable(userId string) (string, []interface{}) {
+	table := "(select ur.user_id, ur.role_id, ur.resource_id, CAST(NULL AS CHAR(36)) as team_id from user_role ur where ur.user_id = ? AND " + notExpiredClause +
+		" UNION ALL select tm.user_id, tr.role_id, tr.resource_id, tr.team_id from team_role tr join team_member tm on tm.team_id = tr.team_id join team t on t.id = tr.team_id where tm.user_id = ? AND t.deleted_at IS NULL) ur"
+
+	return table, []interface{}{userId, util.NowUnix(), userId}
+}
+
 type UserRoleRepository struct {
 	util.Repository
 }
@@ -25,7 +39,11 @@ func (repo *UserRoleRepository) InjectTransaction(tx *sql.Tx) interface{} {
 	return NewUserRoleRepository(repo.Db, tx)
 }
 
-func (repo *UserRoleRepository) Link(user *shared.User, role *Role, resourceId string) error {
+/**
+Links the user to the role on the resource until the given time, or forever if it is nil. Linking a role that the user
+already has only changes when it expires.
+*/
+func (repo *UserRoleRepository) Link(user *shared.User, role *Role, resourceId string, expiresAt *int64) error {
 	// check if its already been linked
 	rows, err := repo.Query(
 		"select user_id, role_id, resource_id from user_role where user_id = ? and role_id = ? and resource_id = ?",
@@ -44,17 +62,29 @@ func (repo *UserRoleRepository) Link(user *shared.User, role *Role, resourceId s
 		count += 1
 	}
 
-	// role is already linked, so do nothing
+	// role is already linked, so only update when it expires
 	if count > 0 {
+		_, err = repo.Exec(
+			"update user_role set expires_at = ? where user_id = ? and role_id = ? and resource_id = ?",
+			expiresAt,
+			user.Id,
+			role.Id,
+			resourceId,
+		)
+		if err != nil {
+			log.Print(err)
+			return errors.New("failed to link role to user")
+		}
 		return nil
 	}
 
 	// otherwise attempt to link the role to the user
 	_, err = repo.Exec(
-		"insert into user_role (user_id, role_id, resource_id) values (?, ?, ?)",
+		"insert into user_role (user_id, role_id, resource_id, expires_at) values (?, ?, ?, ?)",
 		user.Id,
 		role.Id,
 		resourceId,
+		expiresAt,
 	)
 
 	if err != nil {
@@ -139,7 +169,7 @@ func (repo *UserRoleRepository) buildWhereClause(userId string, requests []Resou
 		for pathIndex := 0; pathIndex < len(request.ResourcePath); pathIndex++ {
 
 			// generate the path which is a slice from the idIndex to the cap
-			path := strings.Join(request.ResourcePath[pathIndex:cap(request.ResourcePath)], ":")
+			path := strings.Join(request.ResourcePath[pathIndex:], ":")
 
 			clause := "p.resource_path = ?"
 			params = append(params, path)
@@ -176,9 +206,10 @@ func (repo *UserRoleRepository) GetDataForResources(user *shared.User, requests
 		return nil, err
 	}
 
-	// add the user id
-	params = append(params, user.Id)
-	query := fmt.Sprintf("select distinct p.id, p.resource_path, ur.resource_id, p.action, ur.user_id from user_role ur join role_permission rp on rp.role_id = ur.role_id join permission p on p.id = rp.permission_id where %s AND ur.user_id = ?", *whereClause);
+	// the grants of the user come first since they are selected from
+	grantsTable, grantsParams := userGrantsTable(user.Id)
+	params = append(grantsParams, params...)
+	query := fmt.Sprintf("select distinct p.id, p.resource_path, ur.resource_id, p.action, ur.user_id from %s join role_permission rp on rp.role_id = ur.role_id join permission p on p.id = rp.permission_id where %s", grantsTable, *whereClause);
 
 	rows, err := repo.Query(
 		query,
@@ -206,3 +237,219 @@ func (repo *UserRoleRepository) GetDataForResources(user *shared.User, requests
 
 	return results, nil
 }
+
+/**
+Finds every permission the user has been granted on any resource, in the same form as GetDataForResources
+*/
+func (repo *UserRoleRepository) FindAllForUser(user *shared.User) ([]ResourceResponse, error) {
+	grantsTable, params := userGrantsTable(user.Id)
+	rows, err := repo.Query(
+		"select distinct p.id, p.resource_path, ur.resource_id, p.action, ur.user_id from "+grantsTable+" join role_permission rp on rp.role_id = ur.role_id join permission p on p.id = rp.permission_id ORDER BY p.resource_path ASC, p.action ASC",
+		params...,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to fetch resource data")
+	}
+	defer rows.Close()
+
+	results := make([]ResourceResponse, 0)
+	for rows.Next() {
+		var res ResourceResponse
+		err := rows.Scan(&res.PermissionId, &res.ResourcePath, &res.ResourceId, &res.Action, &res.UserId)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to fetch resource data")
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+func (repo *UserRoleRepository) FindByUserId(userId string) ([]UserRole, error) {
+	rows, err := repo.Query(
+		"select ur.user_id, ur.role_id, r.name, ur.resource_id, ur.expires_at from user_role ur join role r on r.id = ur.role_id where ur.user_id = ? AND "+notExpiredClause,
+		userId,
+		util.NowUnix(),
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find roles for user")
+	}
+	defer rows.Close()
+
+	userRoles := make([]UserRole, 0)
+	for rows.Next() {
+		var userRole UserRole
+		err := rows.Scan(&userRole.UserId, &userRole.RoleId, &userRole.RoleName, &userRole.ResourceId, &userRole.ExpiresAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse user role")
+		}
+		userRoles = append(userRoles, userRole)
+	}
+
+	return userRoles, nil
+}
+
+func (repo *UserRoleRepository) CountUsers(role *Role, resourceId string) (int, error) {
+	row := repo.QueryRow(
+		"select count(distinct ur.user_id) from user_role ur where ur.role_id = ? and ur.resource_id = ? AND "+notExpiredClause,
+		role.Id,
+		resourceId,
+		util.NowUnix(),
+	)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return 0, errors.New("failed to count users with role")
+	}
+
+	return count, nil
+}
+
+/**
+Counts the users that have been given the role on the resource, locking their grants until the transaction ends so that
+they can not be unlinked by another transaction in the meantime
+*/
+func (repo *UserRoleRepository) CountUsersForUpdate(role *Role, resourceId string) (int, error) {
+	row := repo.QueryRow(
+		"select count(distinct ur.user_id) from user_role ur where ur.role_id = ? and ur.resource_id = ? AND "+notExpiredClause+" for update",
+		role.Id,
+		resourceId,
+		util.NowUnix(),
+	)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return 0, errors.New("failed to count users with role")
+	}
+
+	return count, nil
+}
+
+/**
+Counts the users that hold the role on any resource
+*/
+func (repo *UserRoleRepository) CountAllUsers(role *Role) (int, error) {
+	row := repo.QueryRow(
+		"select count(distinct ur.user_id) from user_role ur where ur.role_id = ? AND "+notExpiredClause,
+		role.Id,
+		util.NowUnix(),
+	)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return 0, errors.New("failed to count users with role")
+	}
+
+	return count, nil
+}
+
+func (repo *UserRoleRepository) UnlinkAll(user *shared.User) error {
+	_, err := repo.Exec(
+		"delete from user_role where user_id = ?",
+		user.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to unlink roles from user")
+	}
+
+	return nil
+}
+
+/**
+Finds the exact roles and permissions that match the given requests, the same way GetDataForResources does
+*/
+func (repo *UserRoleRepository) FindGrants(user *shared.User, requests []ResourceRequest) ([]AclGrant, error) {
+	if len(requests) == 0 {
+		return nil, errors.New("must supply at least one resource request")
+	}
+
+	whereClause, params, err := repo.buildWhereClause(user.Id, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	grantsTable, grantsParams := userGrantsTable(user.Id)
+	params = append(grantsParams, params...)
+	query := fmt.Sprintf("select distinct r.id, r.name, ur.team_id, p.id, p.resource_path, ur.resource_id, p.action from %s join role r on r.id = ur.role_id join role_permission rp on rp.role_id = ur.role_id join permission p on p.id = rp.permission_id where %s ORDER BY r.name ASC, p.resource_path ASC", grantsTable, *whereClause)
+
+	rows, err := repo.Query(query, params...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find grants")
+	}
+	defer rows.Close()
+
+	grants := make([]AclGrant, 0)
+	for rows.Next() {
+		var grant AclGrant
+		err := rows.Scan(&grant.RoleId, &grant.RoleName, &grant.TeamId, &grant.PermissionId, &grant.ResourcePath, &grant.ResourceId, &grant.Action)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse grant")
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, nil
+}
+
+func (repo *UserRoleRepository) FindExpired(now int64) ([]UserRole, error) {
+	rows, err := repo.Query(
+		"select ur.user_id, ur.role_id, r.name, ur.resource_id, ur.expires_at from user_role ur join role r on r.id = ur.role_id where ur.expires_at <= ?",
+		now,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find expired roles")
+	}
+	defer rows.Close()
+
+	userRoles := make([]UserRole, 0)
+	for rows.Next() {
+		var userRole UserRole
+		err := rows.Scan(&userRole.UserId, &userRole.RoleId, &userRole.RoleName, &userRole.ResourceId, &userRole.ExpiresAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse user role")
+		}
+		userRoles = append(userRoles, userRole)
+	}
+
+	return userRoles, nil
+}
+
+/**
+Deletes the grant if it is still expired, returns false if it was extended or removed in the meantime
+*/
+func (repo *UserRoleRepository) DeleteExpired(userRole UserRole, now int64) (bool, error) {
+	result, err := repo.Exec(
+		"delete from user_role where user_id = ? and role_id = ? and resource_id = ? and expires_at <= ?",
+		userRole.UserId,
+		userRole.RoleId,
+		userRole.ResourceId,
+		now,
+	)
+	if err != nil {
+		log.Print(err)
+		return false, errors.New("failed to delete expired role")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Print(err)
+		return false, errors.New("failed to delete expired role")
+	}
+
+	return affected > 0, nil
+}
@@ -4,57 +4,181 @@ import (
 	"database/sql"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/pkg/errors"
+	"strings"
 )
 
+const grantsCacheKey = "acl:grants"
+const deniesCacheKey = "acl:denies"
+const ancestorsCacheKey = "acl:ancestors:"
+
 type UserRoleService struct {
 	roleRepository     *RoleRepository
 	userRoleRepository *UserRoleRepository
+	aclDenyRepository  *AclDenyRepository
+	hierarchies        *resourceHierarchies
+}
+
+func NewUserRoleService(roleRepository *RoleRepository, userRoleRepository *UserRoleRepository, aclDenyRepository *AclDenyRepository) *UserRoleService {
+	return newUserRoleService(roleRepository, userRoleRepository, aclDenyRepository, nil)
 }
 
-func NewUserRoleService(roleRepository *RoleRepository, userRoleRepository *UserRoleRepository) *UserRoleService {
+func newUserRoleService(roleRepository *RoleRepository, userRoleRepository *UserRoleRepository, aclDenyRepository *AclDenyRepository, hierarchies *resourceHierarchies) *UserRoleService {
 	return &UserRoleService{
 		roleRepository:     roleRepository,
 		userRoleRepository: userRoleRepository,
+		aclDenyRepository:  aclDenyRepository,
+		hierarchies:        hierarchies,
 	}
 }
 
 func (service *UserRoleService) InjectTransaction(tx *sql.Tx) interface{} {
-	return NewUserRoleService(service.roleRepository.InjectTransaction(tx).(*RoleRepository),
-		service.userRoleRepository.InjectTransaction(tx).(*UserRoleRepository))
+	return newUserRoleService(service.roleRepository.InjectTransaction(tx).(*RoleRepository),
+		service.userRoleRepository.InjectTransaction(tx).(*UserRoleRepository),
+		service.aclDenyRepository.InjectTransaction(tx).(*AclDenyRepository),
+		service.hierarchies)
 }
 
 func (service *UserRoleService) LinkUserToRole(user *shared.User, roleName string, resourceId string) error {
+	return service.LinkUserToRoleUntil(user, roleName, resourceId, nil)
+}
+
+/**
+Links the user to the role on the resource until expiresAt, the grant never expires if it is nil
+ */
+func (service *UserRoleService) LinkUserToRoleUntil(user *shared.User, roleName string, resourceId string, expiresAt *int64) error {
 	role := service.roleRepository.Find(roleName)
 	if role == nil {
 		return errors.New("failed to find role")
 	}
 
-	return service.userRoleRepository.Link(user, role, resourceId)
+	defer service.invalidateCachedGrants(user)
+
+	return service.userRoleRepository.Link(user, role, resourceId, expiresAt)
+}
+
+func (service *UserRoleService) LinkUserToRoleById(user *shared.User, roleId string, resourceId string) error {
+	return service.LinkUserToRoleByIdUntil(user, roleId, resourceId, nil)
+}
+
+func (service *UserRoleService) LinkUserToRoleByIdUntil(user *shared.User, roleId string, resourceId string, expiresAt *int64) error {
+	role := service.roleRepository.FindById(roleId)
+	if role == nil {
+		return errors.New("failed to find role")
+	}
+
+	defer service.invalidateCachedGrants(user)
+
+	return service.userRoleRepository.Link(user, role, resourceId, expiresAt)
+}
+
+func (service *UserRoleService) UnlinkUserFromRoleById(user *shared.User, roleId string, resourceId string) error {
+	role := service.roleRepository.FindById(roleId)
+	if role == nil {
+		return errors.New("failed to find role")
+	}
+
+	defer service.invalidateCachedGrants(user)
+
+	return service.userRoleRepository.Unlink(user, role, resourceId)
+}
+
+func (service *UserRoleService) FindUserRoles(user *shared.User) ([]UserRole, error) {
+	return service.userRoleRepository.FindByUserId(user.Id)
+}
+
+func (service *UserRoleService) UnlinkUserFromAllRoles(user *shared.User) error {
+	defer service.invalidateCachedGrants(user)
+
+	return service.userRoleRepository.UnlinkAll(user)
+}
+
+/**
+Finds the grants that expired at or before now and have not been deleted yet
+ */
+func (service *UserRoleService) FindExpired(now int64) ([]UserRole, error) {
+	return service.userRoleRepository.FindExpired(now)
+}
+
+func (service *UserRoleService) DeleteExpired(userRole UserRole, now int64) (bool, error) {
+	return service.userRoleRepository.DeleteExpired(userRole, now)
+}
+
+/**
+Counts the users that have been given the role on the resource, e.g. to find the number of owners of an organization
+ */
+func (service *UserRoleService) CountUsersWithRole(roleName string, resourceId string) (int, error) {
+	role := service.roleRepository.Find(roleName)
+	if role == nil {
+		return 0, errors.New("failed to find role")
+	}
+
+	return service.userRoleRepository.CountUsers(role, resourceId)
+}
+
+/**
+Counts the users that have been given the role on the resource the same way as CountUsersWithRole, and keeps their grants
+locked until the transaction ends, e.g. so two owners can not both leave an organization at the same time
+*/
+func (service *UserRoleService) CountUsersWithRoleForUpdate(roleName string, resourceId string) (int, error) {
+	role := service.roleRepository.Find(roleName)
+	if role == nil {
+		return 0, errors.New("failed to find role")
+	}
+
+	return service.userRoleRepository.CountUsersForUpdate(role, resourceId)
+}
+
+func (service *UserRoleService) CountUsersWithRoleId(roleId string) (int, error) {
+	role := service.roleRepository.FindById(roleId)
+	if role == nil {
+		return 0, errors.New("failed to find role")
+	}
+
+	return service.userRoleRepository.CountAllUsers(role)
 }
 
 /*
-Check if a user can access a specific resource for the given action
+Check if a user can access a specific resource for the given action, a grant on anything the resource is nested in counts
  */
 func (service *UserRoleService) UserCanAccessResource(user *shared.User, path []string, ids []string, actions ...string) (bool, error) {
+	variants, err := service.expandIds(user, path, ids)
+	if err != nil {
+		return false, err
+	}
+
 	requests := make([]ResourceRequest, 0)
-	for i := 0; i < len(actions); i++ {
-		requests = append(
-			requests,
-			ResourceRequest{
-				ResourcePath: path,
-				ResourceIds:  ids,
-				Action:       &actions[i],
-			},
-		);
+	for _, variant := range variants {
+		for i := 0; i < len(actions); i++ {
+			requests = append(
+				requests,
+				ResourceRequest{
+					ResourcePath: path,
+					ResourceIds:  variant,
+					Action:       &actions[i],
+				},
+			);
+		}
 	}
 
-	data, err := service.userRoleRepository.GetDataForResources(user, requests)
+	data, err := service.getDataForResources(user, requests)
 
 	if err != nil {
 		return false, err
 	}
 
-	return len(data) > 0, nil
+	denies, err := service.findDenies(user)
+	if err != nil {
+		return false, err
+	}
+
+	// a deny on the resource, or anything it is nested in, takes precedence over every grant of the action
+	for _, res := range data {
+		if !isDeniedOnAny(denies, path, variants, res.Action) {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
 /**
@@ -65,22 +189,36 @@ func (service *UserRoleService) UserActionsForResources(user *shared.User, paths
 		return nil, errors.New("path and ids must be the same length")
 	}
 
-	// build all of the requests
-	requests := make([]ResourceRequest, len(paths))
+	// build all of the requests, each resource gets a group of requests for itself and everything it is nested in
+	requests := make([]ResourceRequest, 0)
+	groups := make([][]ResourceRequest, len(paths))
 	for index, path := range paths {
-		requests[index] = ResourceRequest{
-			ResourcePath: path,
-			ResourceIds: ids[index],
+		variants, err := service.expandIds(user, path, ids[index])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, variant := range variants {
+			groups[index] = append(groups[index], ResourceRequest{
+				ResourcePath: path,
+				ResourceIds: variant,
+			})
 		}
+		requests = append(requests, groups[index]...)
 	}
 
-	data, err := service.userRoleRepository.GetDataForResources(user, requests)
+	data, err := service.getDataForResources(user, requests)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	denies, err := service.findDenies(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return removeDeniedGrants(data, groups, denies), nil
 }
 
 /**
@@ -99,11 +237,227 @@ func (service *UserRoleService) UserActionableResourcesByPath(user *shared.User,
 		);
 	}
 
-	data, err := service.userRoleRepository.GetDataForResources(user, requests)
+	data, err := service.getDataForResources(user, requests)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.addDescendantResources(data)
+}
+
+/**
+A grant on a resource that can be nested in itself also grants everything nested below it, so a copy of the grant is
+added for every descendant
+*/
+func (service *UserRoleService) addDescendantResources(data []ResourceResponse) ([]ResourceResponse, error) {
+	results := make([]ResourceResponse, 0, len(data))
+	for _, res := range data {
+		results = append(results, res)
+
+		hierarchy := service.hierarchies.get(strings.Split(res.ResourcePath, ":")[0])
+		if hierarchy == nil || res.ResourceId == "" {
+			continue
+		}
+
+		descendantIds, err := hierarchy.FindDescendantIds([]string{res.ResourceId})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, descendantId := range descendantIds {
+			descendant := res
+			descendant.ResourceId = descendantId
+			results = append(results, descendant)
+		}
+	}
+
+	return results, nil
+}
+
+/**
+Find every grant that allows the user to do the action on the resource
+ */
+func (service *UserRoleService) FindGrants(user *shared.User, path []string, ids []string, action string) ([]AclGrant, error) {
+	variants, err := service.expandIds(user, path, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]ResourceRequest, len(variants))
+	for index, variant := range variants {
+		requests[index] = ResourceRequest{
+			ResourcePath: path,
+			ResourceIds:  variant,
+			Action:       &action,
+		}
+	}
+
+	return service.userRoleRepository.FindGrants(user, requests)
+}
+
+/**
+Expands the ids of the resource with everything it is nested in, see expandIds
+*/
+func (service *UserRoleService) expandIds(user *shared.User, path []string, ids []string) ([][]string, error) {
+	return expandIds(path, ids, func(resourceName string, id string) ([]string, error) {
+		return service.findAncestorIds(user, resourceName, id)
+	})
+}
+
+/**
+Finds the ancestors of the resource, these are kept in the request cache since every model in a list shares them
+*/
+func (service *UserRoleService) findAncestorIds(user *shared.User, resourceName string, id string) ([]string, error) {
+	hierarchy := service.hierarchies.get(resourceName)
+	if hierarchy == nil {
+		return nil, nil
+	}
+
+	if user.Cache == nil {
+		return hierarchy.FindAncestorIds(id)
+	}
+
+	key := ancestorsCacheKey + resourceName + ":" + id
+	if cached, ok := user.Cache.Get(key); ok {
+		return cached.([]string), nil
+	}
+
+	ancestorIds, err := hierarchy.FindAncestorIds(id)
+	if err != nil {
+		return nil, err
+	}
+	user.Cache.Set(key, ancestorIds)
+
+	return ancestorIds, nil
+}
+
+/*
+Fetches the resource data for the requests. Within a request all of the grants of the user are loaded once and kept in
+the request cache, every check after that is answered from memory instead of running the query again.
+*/
+func (service *UserRoleService) getDataForResources(user *shared.User, requests []ResourceRequest) ([]ResourceResponse, error) {
+	if user.Cache == nil {
+		return service.userRoleRepository.GetDataForResources(user, requests)
+	}
+
+	var grants []ResourceResponse
+	if cached, ok := user.Cache.Get(grantsCacheKey); ok {
+		grants = cached.([]ResourceResponse)
+	} else {
+		found, err := service.userRoleRepository.FindAllForUser(user)
+		if err != nil {
+			return nil, err
+		}
+		grants = found
+		user.Cache.Set(grantsCacheKey, grants)
+	}
+
+	return filterGrants(grants, requests)
+}
+
+/**
+Finds the denies of the user, these are kept in the request cache along with the grants
+*/
+func (service *UserRoleService) findDenies(user *shared.User) ([]AclDeny, error) {
+	if user.Cache == nil {
+		return service.aclDenyRepository.FindForUser(user)
+	}
+
+	if cached, ok := user.Cache.Get(deniesCacheKey); ok {
+		return cached.([]AclDeny), nil
+	}
+
+	denies, err := service.aclDenyRepository.FindForUser(user)
+	if err != nil {
+		return nil, err
+	}
+	user.Cache.Set(deniesCacheKey, denies)
+
+	return denies, nil
+}
+
+/**
+Removes the actions that are denied to the user on the resource
+*/
+func (service *UserRoleService) RemoveDeniedActions(user *shared.User, path []string, ids []string, actions []string) ([]string, error) {
+	denies, err := service.findDenies(user)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := service.expandIds(user, path, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]string, 0)
+	for _, action := range actions {
+		if !isDeniedOnAny(denies, path, variants, action) {
+			allowed = append(allowed, action)
+		}
+	}
+
+	return allowed, nil
+}
 
+/**
+Finds the ids of the resources in the path that the action is denied on, keyed by the resource name. A deny on a resource
+that can be nested in itself also covers everything nested below it.
+*/
+func (service *UserRoleService) DeniedResourceIds(user *shared.User, path []string, action string) (map[string][]string, error) {
+	denies, err := service.findDenies(user)
 	if err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	denied := deniedResourceIds(denies, path, action)
+	for resourceName, deniedIds := range denied {
+		hierarchy := service.hierarchies.get(resourceName)
+		if hierarchy == nil {
+			continue
+		}
+
+		descendantIds, err := hierarchy.FindDescendantIds(deniedIds)
+		if err != nil {
+			return nil, err
+		}
+		denied[resourceName] = append(deniedIds, descendantIds...)
+	}
+
+	return denied, nil
+}
+
+/**
+Finds the denies that take the action away from the user on the resource
+*/
+func (service *UserRoleService) FindDenies(user *shared.User, path []string, ids []string, action string) ([]AclDeny, error) {
+	denies, err := service.findDenies(user)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := service.expandIds(user, path, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]AclDeny, 0)
+	for _, deny := range denies {
+		if isDeniedOnAny([]AclDeny{deny}, path, variants, action) {
+			matched = append(matched, deny)
+		}
+	}
+
+	return matched, nil
+}
+
+/**
+Role changes also change which role denies apply to the user, so both are cleared
+*/
+func (service *UserRoleService) invalidateCachedGrants(user *shared.User) {
+	if user.Cache != nil {
+		user.Cache.Delete(grantsCacheKey)
+		user.Cache.Delete(deniesCacheKey)
+	}
 }
@@ -0,0 +1,155 @@
+package attachment
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+type AttachmentRepository struct {
+	util.Repository
+}
+
+func NewAttachmentRepository(db *sql.DB, tx *sql.Tx) *AttachmentRepository {
+	repo := &AttachmentRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *AttachmentRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewAttachmentRepository(repo.Db, tx)
+}
+
+func (repo *AttachmentRepository) FindById(id string) *shared.Attachment {
+	row := repo.QueryRow(
+		"select id, document_id, creator_id, name, content_type, size, storage_key, created_at, updated_at, deleted_at from attachment where id = ? and deleted_at is null",
+		id,
+	)
+
+	var attachment shared.Attachment
+	err := row.Scan(&attachment.Id, &attachment.DocumentId, &attachment.CreatorId, &attachment.Name, &attachment.ContentType,
+		&attachment.Size, &attachment.StorageKey, &attachment.CreatedAt, &attachment.UpdatedAt, &attachment.DeletedAt)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	return &attachment
+}
+
+func (repo *AttachmentRepository) FindByDocumentId(documentId string) ([]shared.Attachment, error) {
+	rows, err := repo.Query(
+		"select id, document_id, creator_id, name, content_type, size, storage_key, created_at, updated_at, deleted_at from attachment where document_id = ? and deleted_at is null ORDER BY created_at ASC",
+		documentId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find attachments")
+	}
+	defer rows.Close()
+
+	attachments := make([]shared.Attachment, 0)
+	for rows.Next() {
+		var attachment shared.Attachment
+		err := rows.Scan(&attachment.Id, &attachment.DocumentId, &attachment.CreatorId, &attachment.Name, &attachment.ContentType,
+			&attachment.Size, &attachment.StorageKey, &attachment.CreatedAt, &attachment.UpdatedAt, &attachment.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse attachment")
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+/**
+Finds the attachments the user added, to any document
+*/
+func (repo *AttachmentRepository) FindByCreatorId(creatorId string) ([]shared.Attachment, error) {
+	rows, err := repo.Query(
+		"select id, document_id, creator_id, name, content_type, size, storage_key, created_at, updated_at, deleted_at from attachment where creator_id = ? and deleted_at is null ORDER BY created_at ASC",
+		creatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find attachments")
+	}
+	defer rows.Close()
+
+	attachments := make([]shared.Attachment, 0)
+	for rows.Next() {
+		var attachment shared.Attachment
+		err := rows.Scan(&attachment.Id, &attachment.DocumentId, &attachment.CreatorId, &attachment.Name, &attachment.ContentType,
+			&attachment.Size, &attachment.StorageKey, &attachment.CreatedAt, &attachment.UpdatedAt, &attachment.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse attachment")
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+func (repo *AttachmentRepository) ReassignCreator(fromCreatorId string, toCreatorId string) error {
+	_, err := repo.Exec(
+		"update attachment set creator_id = ?, updated_at = ? where creator_id = ?",
+		toCreatorId,
+		util.NowUnix(),
+		fromCreatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to reassign attachments")
+	}
+
+	return nil
+}
+
+func (repo *AttachmentRepository) Insert(attachment *shared.Attachment) error {
+	attachment.CreatedAt = util.NowUnix()
+	attachment.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into attachment (id, document_id, creator_id, name, content_type, size, storage_key, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		attachment.Id,
+		attachment.DocumentId,
+		attachment.CreatorId,
+		attachment.Name,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.StorageKey,
+		attachment.CreatedAt,
+		attachment.UpdatedAt,
+		attachment.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert attachment")
+	}
+
+	return nil
+}
+
+func (repo *AttachmentRepository) Delete(attachment *shared.Attachment) error {
+	deletedAt := util.NowUnix()
+	attachment.UpdatedAt = deletedAt
+	attachment.DeletedAt = &deletedAt
+
+	_, err := repo.Exec(
+		"update attachment set updated_at = ?, deleted_at = ? where id = ?",
+		attachment.UpdatedAt,
+		attachment.DeletedAt,
+		attachment.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to delete attachment")
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package attachment
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/resource_history"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	uuid "github.com/satori/go.uuid"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// the largest file that can be attached
+const MaxAttachmentSize = 10 << 20
+
+const maxAttachmentNameLength = 255
+
+/**
+The types of file that can be attached, found from the content of the file rather than what the client says it is. Svg
+and html are left out since they can run scripts when opened.
+*/
+var attachmentContentTypes = map[string]bool{
+	"image/png":                 true,
+	"image/jpeg":                true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"application/pdf":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+/*
+Files attached to documents, e.g. screenshots. Attachments have no access of their own, viewing the document allows
+downloading its attachments and modifying it allows adding and deleting them. The files are kept in the blob store and
+the database only has what is needed to find them.
+*/
+type AttachmentService struct {
+	attachmentRepository   *AttachmentRepository
+	documentService        *document.DocumentService
+	aclService             *acl.AclService
+	transactionManager     *util.TransactionManager
+	resourceHistoryService *resource_history.ResourceHistoryService
+	blobStore              util.BlobStore
+}
+
+func NewAttachmentService(
+	attachmentRepository *AttachmentRepository,
+	documentService *document.DocumentService,
+	aclService *acl.AclService,
+	transactionManager *util.TransactionManager,
+	resourceHistoryService *resource_history.ResourceHistoryService,
+	blobStore util.BlobStore,
+) *AttachmentService {
+	return &AttachmentService{
+		attachmentRepository:   attachmentRepository,
+		documentService:        documentService,
+		aclService:             aclService,
+		transactionManager:     transactionManager,
+		resourceHistoryService: resourceHistoryService,
+		blobStore:              blobStore,
+	}
+}
+
+func (service *AttachmentService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewAttachmentService(
+		service.attachmentRepository.InjectTransaction(tx).(*AttachmentRepository),
+		service.documentService.InjectTransaction(tx).(*document.DocumentService),
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+		service.transactionManager.InjectTransaction(tx).(*util.TransactionManager),
+		service.resourceHistoryService.InjectTransaction(tx).(*resource_history.ResourceHistoryService),
+		service.blobStore,
+	)
+}
+
+/**
+Stores the file and attaches it to the document. The file is stored before the attachment is saved, so an attachment
+never points at a file that is not there.
+*/
+func (service *AttachmentService) Create(user *shared.User, documentId string, name string, body io.Reader) (*shared.Attachment, error) {
+	_, err := service.findDocument(user, documentId, "modify")
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	_, err = buffer.ReadFrom(io.LimitReader(body, MaxAttachmentSize+1))
+	if err != nil {
+		log.Print(err)
+		return nil, shared.NewBadRequestError("failed to read the file")
+	}
+	if buffer.Len() == 0 {
+		return nil, shared.NewBadRequestError("the file is empty")
+	}
+	if buffer.Len() > MaxAttachmentSize {
+		return nil, shared.NewBadRequestError(fmt.Sprintf("attachments can be at most %d bytes", MaxAttachmentSize))
+	}
+
+	contentType := http.DetectContentType(buffer.Bytes())
+	if !attachmentContentTypes[contentType] {
+		return nil, shared.NewBadRequestError(fmt.Sprintf("%s files can not be attached, attachments must be images, pdfs or text", contentType))
+	}
+
+	// the sniffer only knows some markup, svg is taken for plain text, so text that starts with a tag is refused as well
+	if strings.HasPrefix(contentType, "text/plain") && bytes.HasPrefix(bytes.TrimLeft(buffer.Bytes(), " \t\r\n\ufeff"), []byte("<")) {
+		return nil, shared.NewBadRequestError("markup files can not be attached, attachments must be images, pdfs or text")
+	}
+
+	attachment := &shared.Attachment{
+		DocumentId:  documentId,
+		CreatorId:   user.Id,
+		Name:        attachmentName(name),
+		ContentType: contentType,
+		Size:        int64(buffer.Len()),
+	}
+	attachment.Id = uuid.NewV4().String()
+	attachment.StorageKey = fmt.Sprintf("attachments/%s/%s", documentId, attachment.Id)
+
+	err = service.blobStore.Put(attachment.StorageKey, bytes.NewReader(buffer.Bytes()), attachment.Size, attachment.ContentType)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to store attachment")
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*AttachmentService)
+
+		err := injectedService.attachmentRepository.Insert(attachment)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(attachment.Id, "attachment", user.Id, "created")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		service.deleteBlob(attachment.StorageKey)
+		return nil, shared.NewInternalServerError("failed to create attachment")
+	}
+
+	return attachment, nil
+}
+
+func (service *AttachmentService) List(user *shared.User, documentId string) ([]shared.Attachment, error) {
+	_, err := service.findDocument(user, documentId, "view", "modify")
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := service.attachmentRepository.FindByDocumentId(documentId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find attachments")
+	}
+
+	return attachments, nil
+}
+
+/**
+Finds the attachment and opens its file, the caller closes the file
+*/
+func (service *AttachmentService) Open(user *shared.User, attachmentId string) (*shared.Attachment, io.ReadCloser, error) {
+	attachment, err := service.findAttachment(user, attachmentId, "view", "modify")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := service.blobStore.Get(attachment.StorageKey)
+	if err == util.ErrBlobNotFound {
+		return nil, nil, shared.NewNotFoundError("could not find attachment file")
+	}
+	if err != nil {
+		return nil, nil, shared.NewInternalServerError("failed to read attachment")
+	}
+
+	return attachment, body, nil
+}
+
+/**
+Deletes the attachment, its file is only deleted once the attachment is, so a failed delete leaves both behind
+*/
+func (service *AttachmentService) Delete(user *shared.User, attachmentId string) (*shared.Attachment, error) {
+	attachment, err := service.findAttachment(user, attachmentId, "modify")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*AttachmentService)
+
+		err := injectedService.attachmentRepository.Delete(attachment)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(attachment.Id, "attachment", user.Id, "deleted")
+		if err != nil {
+			return nil, err
+		}
+
+		injectedService.transactionManager.AfterCommit(func() {
+			injectedService.deleteBlob(attachment.StorageKey)
+		})
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete attachment")
+	}
+
+	return attachment, nil
+}
+
+/**
+Finds the attachments the user added, regardless of whether the user can still access them
+*/
+func (service *AttachmentService) FindByCreator(creatorId string) ([]shared.Attachment, error) {
+	attachments, err := service.attachmentRepository.FindByCreatorId(creatorId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find attachments")
+	}
+	return attachments, nil
+}
+
+func (service *AttachmentService) ReassignCreator(fromCreatorId string, toCreatorId string) error {
+	return service.attachmentRepository.ReassignCreator(fromCreatorId, toCreatorId)
+}
+
+func (service *AttachmentService) findAttachment(user *shared.User, attachmentId string, actions ...string) (*shared.Attachment, error) {
+	attachment := service.attachmentRepository.FindById(attachmentId)
+	if attachment == nil {
+		return nil, shared.NewNotFoundError("could not find attachment")
+	}
+
+	_, err := service.findDocument(user, attachment.DocumentId, actions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+/**
+Attachments are checked through the document they are attached to
+*/
+func (service *AttachmentService) findDocument(user *shared.User, documentId string, actions ...string) (*shared.Document, error) {
+	doc := service.documentService.FindById(documentId)
+	if doc == nil {
+		return nil, shared.NewNotFoundError("could not find document")
+	}
+
+	if !service.aclService.UserCanAccessResourceByModel(user, doc, actions...) {
+		return nil, shared.NewForbiddenError(fmt.Sprintf("can not %s document", actions[0]))
+	}
+
+	return doc, nil
+}
+
+/**
+A file that could not be deleted only takes up space, so the failure is logged instead of failing the request
+*/
+func (service *AttachmentService) deleteBlob(key string) {
+	err := service.blobStore.Delete(key)
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+/**
+The name of the file without any directories the client sent along with it
+*/
+func attachmentName(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" || len(name) == 0 {
+		return "attachment"
+	}
+
+	for utf8.RuneCountInString(name) > maxAttachmentNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return name
+}
@@ -0,0 +1,374 @@
+package comment
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+const threadColumns = "id, document_id, document_draft_id, creator_id, anchor_start, anchor_end, anchor_line, anchor_text, resolved_at, resolved_by, created_at, updated_at, deleted_at"
+const commentColumns = "id, comment_thread_id, creator_id, content, edited_at, created_at, updated_at, deleted_at"
+
+type CommentRepository struct {
+	util.Repository
+}
+
+func NewCommentRepository(db *sql.DB, tx *sql.Tx) *CommentRepository {
+	repo := &CommentRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *CommentRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewCommentRepository(repo.Db, tx)
+}
+
+func (repo *CommentRepository) FindThreadById(id string) *shared.CommentThread {
+	row := repo.QueryRow(
+		fmt.Sprintf("select %s from comment_thread where id = ? and deleted_at is null", threadColumns),
+		id,
+	)
+
+	thread, err := scanThread(row)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	return thread
+}
+
+/**
+Finds the threads on the document, oldest first, optionally only the ones on a draft and the ones that are or are not
+resolved
+*/
+func (repo *CommentRepository) FindThreadsByDocumentId(documentId string, draftId *string, resolved *bool) ([]shared.CommentThread, error) {
+	query := fmt.Sprintf("select %s from comment_thread where document_id = ? and deleted_at is null", threadColumns)
+	params := []interface{}{documentId}
+
+	if draftId != nil {
+		query += " and document_draft_id = ?"
+		params = append(params, *draftId)
+	}
+	if resolved != nil && *resolved {
+		query += " and resolved_at is not null"
+	}
+	if resolved != nil && !*resolved {
+		query += " and resolved_at is null"
+	}
+	query += " ORDER BY created_at ASC"
+
+	rows, err := repo.Query(query, params...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find comment threads")
+	}
+	defer rows.Close()
+
+	threads := make([]shared.CommentThread, 0)
+	for rows.Next() {
+		thread, err := scanThread(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse comment thread")
+		}
+		threads = append(threads, *thread)
+	}
+
+	return threads, nil
+}
+
+func (repo *CommentRepository) InsertThread(thread *shared.CommentThread) error {
+	thread.CreatedAt = util.NowUnix()
+	thread.UpdatedAt = util.NowUnix()
+
+	var start, end, line *int
+	var text *string
+	if thread.Anchor != nil {
+		start = thread.Anchor.Start
+		end = thread.Anchor.End
+		line = thread.Anchor.Line
+		if len(thread.Anchor.Text) > 0 {
+			text = &thread.Anchor.Text
+		}
+	}
+
+	_, err := repo.Exec(
+		fmt.Sprintf("insert into comment_thread (%s) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", threadColumns),
+		thread.Id,
+		thread.DocumentId,
+		thread.DocumentDraftId,
+		thread.CreatorId,
+		start,
+		end,
+		line,
+		text,
+		thread.ResolvedAt,
+		thread.ResolvedBy,
+		thread.CreatedAt,
+		thread.UpdatedAt,
+		thread.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert comment thread")
+	}
+
+	return nil
+}
+
+/**
+Updates whether the thread is resolved, and whether it is deleted
+*/
+func (repo *CommentRepository) UpdateThread(thread *shared.CommentThread) error {
+	thread.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update comment_thread set resolved_at = ?, resolved_by = ?, updated_at = ?, deleted_at = ? where id = ?",
+		thread.ResolvedAt,
+		thread.ResolvedBy,
+		thread.UpdatedAt,
+		thread.DeletedAt,
+		thread.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update comment thread")
+	}
+
+	return nil
+}
+
+func (repo *CommentRepository) FindCommentById(id string) *shared.Comment {
+	row := repo.QueryRow(
+		fmt.Sprintf("select %s from comment where id = ? and deleted_at is null", commentColumns),
+		id,
+	)
+
+	var comment shared.Comment
+	err := row.Scan(&comment.Id, &comment.CommentThreadId, &comment.CreatorId, &comment.Content, &comment.EditedAt,
+		&comment.CreatedAt, &comment.UpdatedAt, &comment.DeletedAt)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	return &comment
+}
+
+/**
+Finds the comments in the threads, oldest first
+*/
+func (repo *CommentRepository) FindCommentsByThreadIds(threadIds []string) ([]shared.Comment, error) {
+	if len(threadIds) == 0 {
+		return make([]shared.Comment, 0), nil
+	}
+
+	rows, err := repo.Query(
+		fmt.Sprintf(
+			"select %s from comment where comment_thread_id in (%s) and deleted_at is null ORDER BY created_at ASC",
+			commentColumns,
+			util.BuildSqlPlaceholderArray(threadIds),
+		),
+		util.ConvertStringArrayToInterfaceArray(threadIds)...,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find comments")
+	}
+	defer rows.Close()
+
+	comments := make([]shared.Comment, 0)
+	for rows.Next() {
+		var comment shared.Comment
+		err := rows.Scan(&comment.Id, &comment.CommentThreadId, &comment.CreatorId, &comment.Content, &comment.EditedAt,
+			&comment.CreatedAt, &comment.UpdatedAt, &comment.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse comment")
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+func (repo *CommentRepository) InsertComment(comment *shared.Comment) error {
+	comment.CreatedAt = util.NowUnix()
+	comment.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		fmt.Sprintf("insert into comment (%s) values (?, ?, ?, ?, ?, ?, ?, ?)", commentColumns),
+		comment.Id,
+		comment.CommentThreadId,
+		comment.CreatorId,
+		comment.Content,
+		comment.EditedAt,
+		comment.CreatedAt,
+		comment.UpdatedAt,
+		comment.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert comment")
+	}
+
+	return nil
+}
+
+func (repo *CommentRepository) UpdateComment(comment *shared.Comment) error {
+	comment.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update comment set content = ?, edited_at = ?, updated_at = ?, deleted_at = ? where id = ?",
+		comment.Content,
+		comment.EditedAt,
+		comment.UpdatedAt,
+		comment.DeletedAt,
+		comment.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update comment")
+	}
+
+	return nil
+}
+
+/**
+Finds the threads the user started, in any document, oldest first
+*/
+func (repo *CommentRepository) FindThreadsByCreatorId(creatorId string) ([]shared.CommentThread, error) {
+	rows, err := repo.Query(
+		fmt.Sprintf("select %s from comment_thread where creator_id = ? and deleted_at is null ORDER BY created_at ASC", threadColumns),
+		creatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find comment threads")
+	}
+	defer rows.Close()
+
+	threads := make([]shared.CommentThread, 0)
+	for rows.Next() {
+		thread, err := scanThread(rows)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse comment thread")
+		}
+		threads = append(threads, *thread)
+	}
+
+	return threads, nil
+}
+
+/**
+Finds the comments the user wrote, in any thread, oldest first
+*/
+func (repo *CommentRepository) FindCommentsByCreatorId(creatorId string) ([]shared.Comment, error) {
+	rows, err := repo.Query(
+		fmt.Sprintf("select %s from comment where creator_id = ? and deleted_at is null ORDER BY created_at ASC", commentColumns),
+		creatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find comments")
+	}
+	defer rows.Close()
+
+	comments := make([]shared.Comment, 0)
+	for rows.Next() {
+		var comment shared.Comment
+		err := rows.Scan(&comment.Id, &comment.CommentThreadId, &comment.CreatorId, &comment.Content, &comment.EditedAt,
+			&comment.CreatedAt, &comment.UpdatedAt, &comment.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse comment")
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+/**
+Hands the threads and comments of one user over to another, along with the threads they resolved
+*/
+func (repo *CommentRepository) ReassignCreator(fromCreatorId string, toCreatorId string) error {
+	now := util.NowUnix()
+
+	_, err := repo.Exec(
+		"update comment_thread set creator_id = ?, updated_at = ? where creator_id = ?",
+		toCreatorId,
+		now,
+		fromCreatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to reassign comment threads")
+	}
+
+	_, err = repo.Exec(
+		"update comment_thread set resolved_by = ?, updated_at = ? where resolved_by = ?",
+		toCreatorId,
+		now,
+		fromCreatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to reassign comment threads")
+	}
+
+	_, err = repo.Exec(
+		"update comment set creator_id = ?, updated_at = ? where creator_id = ?",
+		toCreatorId,
+		now,
+		fromCreatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to reassign comments")
+	}
+
+	return nil
+}
+
+type threadScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanThread(row threadScanner) (*shared.CommentThread, error) {
+	var thread shared.CommentThread
+	var start, end, line sql.NullInt64
+	var text sql.NullString
+
+	err := row.Scan(&thread.Id, &thread.DocumentId, &thread.DocumentDraftId, &thread.CreatorId, &start, &end, &line, &text,
+		&thread.ResolvedAt, &thread.ResolvedBy, &thread.CreatedAt, &thread.UpdatedAt, &thread.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if start.Valid || line.Valid {
+		thread.Anchor = &shared.CommentAnchor{
+			Start: nullableInt(start),
+			End:   nullableInt(end),
+			Line:  nullableInt(line),
+			Text:  text.String,
+		}
+	}
+	thread.Comments = make([]shared.Comment, 0)
+
+	return &thread, nil
+}
+
+func nullableInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	converted := int(value.Int64)
+	return &converted
+}
@@ -0,0 +1,477 @@
+package comment
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/resource_history"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	uuid "github.com/satori/go.uuid"
+	"strings"
+)
+
+/*
+Comment threads on the drafts of documents. Viewing the threads of a document requires the view:comment action on it,
+and starting, replying to, resolving and reopening them requires the comment action. Comments can only be edited and
+deleted by the user that wrote them.
+*/
+type CommentService struct {
+	commentRepository      *CommentRepository
+	documentService        *document.DocumentService
+	aclService             *acl.AclService
+	transactionManager     *util.TransactionManager
+	resourceHistoryService *resource_history.ResourceHistoryService
+}
+
+func NewCommentService(
+	commentRepository *CommentRepository,
+	documentService *document.DocumentService,
+	aclService *acl.AclService,
+	transactionManager *util.TransactionManager,
+	resourceHistoryService *resource_history.ResourceHistoryService,
+) *CommentService {
+	return &CommentService{
+		commentRepository:      commentRepository,
+		documentService:        documentService,
+		aclService:             aclService,
+		transactionManager:     transactionManager,
+		resourceHistoryService: resourceHistoryService,
+	}
+}
+
+func (service *CommentService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewCommentService(
+		service.commentRepository.InjectTransaction(tx).(*CommentRepository),
+		service.documentService.InjectTransaction(tx).(*document.DocumentService),
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+		service.transactionManager.InjectTransaction(tx).(*util.TransactionManager),
+		service.resourceHistoryService.InjectTransaction(tx).(*resource_history.ResourceHistoryService),
+	)
+}
+
+/**
+Checks the anchor against the content of the draft. A range keeps the text in it, so the thread still shows what it was
+about once the content changes.
+*/
+func ResolveAnchor(anchor *shared.CommentAnchor, content string) (*shared.CommentAnchor, error) {
+	if anchor == nil || (anchor.Start == nil && anchor.End == nil && anchor.Line == nil) {
+		return nil, nil
+	}
+
+	if anchor.Line != nil {
+		if anchor.Start != nil || anchor.End != nil {
+			return nil, shared.NewBadRequestError("a comment can be anchored to a range or a line, not both")
+		}
+
+		lines := strings.Count(content, "\n") + 1
+		if *anchor.Line < 1 || *anchor.Line > lines {
+			return nil, shared.NewBadRequestError(fmt.Sprintf("line must be between 1 and %d", lines))
+		}
+
+		return &shared.CommentAnchor{Line: anchor.Line}, nil
+	}
+
+	if anchor.Start == nil || anchor.End == nil {
+		return nil, shared.NewBadRequestError("a range needs both a start and an end")
+	}
+
+	runes := []rune(content)
+	if *anchor.Start < 0 || *anchor.End > len(runes) || *anchor.Start >= *anchor.End {
+		return nil, shared.NewBadRequestError(fmt.Sprintf("the range must be within the %d characters of the draft", len(runes)))
+	}
+
+	return &shared.CommentAnchor{
+		Start: anchor.Start,
+		End:   anchor.End,
+		Text:  string(runes[*anchor.Start:*anchor.End]),
+	}, nil
+}
+
+/**
+Starts a thread on the draft with its first comment
+*/
+func (service *CommentService) CreateThread(
+	user *shared.User, documentId string, draftId string, anchor *shared.CommentAnchor, content string,
+) (*shared.CommentThread, error) {
+	_, err := service.findDocument(user, documentId, "comment")
+	if err != nil {
+		return nil, err
+	}
+
+	draft, err := service.documentService.FindDraft(user, documentId, draftId)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor, err = ResolveAnchor(anchor, draft.Content.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &shared.CommentThread{
+		DocumentId:      documentId,
+		DocumentDraftId: draft.Id,
+		CreatorId:       user.Id,
+		Anchor:          anchor,
+	}
+	thread.Id = uuid.NewV4().String()
+
+	comment := &shared.Comment{
+		CommentThreadId: thread.Id,
+		CreatorId:       user.Id,
+		Content:         content,
+	}
+	comment.Id = uuid.NewV4().String()
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*CommentService)
+
+		err := injectedService.commentRepository.InsertThread(thread)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.commentRepository.InsertComment(comment)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(thread.Id, "comment_thread", user.Id, "created")
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(comment.Id, "comment", user.Id, "created")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create comment thread")
+	}
+
+	thread.Comments = []shared.Comment{*comment}
+
+	return thread, nil
+}
+
+/**
+Finds the threads on the document with their comments, leaving out the threads on drafts the user can not see
+*/
+func (service *CommentService) ListThreads(user *shared.User, documentId string, draftId *string, resolved *bool) ([]shared.CommentThread, error) {
+	_, err := service.findDocument(user, documentId, "view:comment")
+	if err != nil {
+		return nil, err
+	}
+
+	threads, err := service.commentRepository.FindThreadsByDocumentId(documentId, draftId, resolved)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find comment threads")
+	}
+
+	visibleDrafts := make(map[string]bool)
+	visible := make([]shared.CommentThread, 0)
+	for _, thread := range threads {
+		canSee, ok := visibleDrafts[thread.DocumentDraftId]
+		if !ok {
+			_, err := service.documentService.FindDraft(user, documentId, thread.DocumentDraftId)
+			canSee = err == nil
+			visibleDrafts[thread.DocumentDraftId] = canSee
+		}
+		if canSee {
+			visible = append(visible, thread)
+		}
+	}
+
+	err = service.attachComments(visible)
+	if err != nil {
+		return nil, err
+	}
+
+	return visible, nil
+}
+
+func (service *CommentService) Reply(user *shared.User, threadId string, content string) (*shared.Comment, error) {
+	thread, err := service.findThread(user, threadId, "comment")
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &shared.Comment{
+		CommentThreadId: thread.Id,
+		CreatorId:       user.Id,
+		Content:         content,
+	}
+	comment.Id = uuid.NewV4().String()
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*CommentService)
+
+		err := injectedService.commentRepository.InsertComment(comment)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(comment.Id, "comment", user.Id, "created")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create comment")
+	}
+
+	return comment, nil
+}
+
+func (service *CommentService) Resolve(user *shared.User, threadId string) (*shared.CommentThread, error) {
+	thread, err := service.findThread(user, threadId, "comment")
+	if err != nil {
+		return nil, err
+	}
+	if thread.ResolvedAt != nil {
+		return nil, shared.NewBadRequestError("thread is already resolved")
+	}
+
+	resolvedAt := util.NowUnix()
+	thread.ResolvedAt = &resolvedAt
+	thread.ResolvedBy = &user.Id
+
+	return service.updateThread(user, thread, "resolved")
+}
+
+func (service *CommentService) Reopen(user *shared.User, threadId string) (*shared.CommentThread, error) {
+	thread, err := service.findThread(user, threadId, "comment")
+	if err != nil {
+		return nil, err
+	}
+	if thread.ResolvedAt == nil {
+		return nil, shared.NewBadRequestError("thread is not resolved")
+	}
+
+	thread.ResolvedAt = nil
+	thread.ResolvedBy = nil
+
+	return service.updateThread(user, thread, "reopened")
+}
+
+func (service *CommentService) UpdateComment(user *shared.User, commentId string, content string) (*shared.Comment, error) {
+	comment, _, err := service.findOwnComment(user, commentId)
+	if err != nil {
+		return nil, err
+	}
+
+	editedAt := util.NowUnix()
+	comment.Content = content
+	comment.EditedAt = &editedAt
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*CommentService)
+
+		err := injectedService.commentRepository.UpdateComment(comment)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(comment.Id, "comment", user.Id, "updated")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to update comment")
+	}
+
+	return comment, nil
+}
+
+/**
+Deletes the comment, along with its thread when it was the last comment left in it
+*/
+func (service *CommentService) DeleteComment(user *shared.User, commentId string) (*shared.Comment, error) {
+	comment, thread, err := service.findOwnComment(user, commentId)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := service.commentRepository.FindCommentsByThreadIds([]string{thread.Id})
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find comments")
+	}
+
+	deletedAt := util.NowUnix()
+	comment.DeletedAt = &deletedAt
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*CommentService)
+
+		err := injectedService.commentRepository.UpdateComment(comment)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(comment.Id, "comment", user.Id, "deleted")
+		if err != nil {
+			return nil, err
+		}
+
+		if len(remaining) > 1 {
+			return nil, nil
+		}
+
+		thread.DeletedAt = &deletedAt
+		err = injectedService.commentRepository.UpdateThread(thread)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(thread.Id, "comment_thread", user.Id, "deleted")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete comment")
+	}
+
+	return comment, nil
+}
+
+/**
+Finds the threads the user started and the comments they wrote, regardless of whether the user can still access them
+*/
+func (service *CommentService) FindByCreator(creatorId string) ([]shared.CommentThread, []shared.Comment, error) {
+	threads, err := service.commentRepository.FindThreadsByCreatorId(creatorId)
+	if err != nil {
+		return nil, nil, shared.NewInternalServerError("failed to find comment threads")
+	}
+
+	comments, err := service.commentRepository.FindCommentsByCreatorId(creatorId)
+	if err != nil {
+		return nil, nil, shared.NewInternalServerError("failed to find comments")
+	}
+
+	return threads, comments, nil
+}
+
+func (service *CommentService) ReassignCreator(fromCreatorId string, toCreatorId string) error {
+	return service.commentRepository.ReassignCreator(fromCreatorId, toCreatorId)
+}
+
+func (service *CommentService) updateThread(user *shared.User, thread *shared.CommentThread, action string) (*shared.CommentThread, error) {
+	_, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*CommentService)
+
+		err := injectedService.commentRepository.UpdateThread(thread)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(thread.Id, "comment_thread", user.Id, action)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to update comment thread")
+	}
+
+	threads := []shared.CommentThread{*thread}
+	err = service.attachComments(threads)
+	if err != nil {
+		return nil, err
+	}
+
+	return &threads[0], nil
+}
+
+func (service *CommentService) attachComments(threads []shared.CommentThread) error {
+	threadIds := make([]string, len(threads))
+	threadIndexes := make(map[string]int)
+	for i, thread := range threads {
+		threadIds[i] = thread.Id
+		threadIndexes[thread.Id] = i
+	}
+
+	comments, err := service.commentRepository.FindCommentsByThreadIds(threadIds)
+	if err != nil {
+		return shared.NewInternalServerError("failed to find comments")
+	}
+
+	for _, comment := range comments {
+		thread := &threads[threadIndexes[comment.CommentThreadId]]
+		thread.Comments = append(thread.Comments, comment)
+	}
+
+	return nil
+}
+
+func (service *CommentService) findOwnComment(user *shared.User, commentId string) (*shared.Comment, *shared.CommentThread, error) {
+	comment := service.commentRepository.FindCommentById(commentId)
+	if comment == nil {
+		return nil, nil, shared.NewNotFoundError("could not find comment")
+	}
+
+	thread, err := service.findThread(user, comment.CommentThreadId, "comment")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if comment.CreatorId != user.Id {
+		return nil, nil, shared.NewForbiddenError("only the author of a comment can change it")
+	}
+
+	return comment, thread, nil
+}
+
+/**
+Finds the thread as long as the user can take the action on its document and see its draft
+*/
+func (service *CommentService) findThread(user *shared.User, threadId string, action string) (*shared.CommentThread, error) {
+	thread := service.commentRepository.FindThreadById(threadId)
+	if thread == nil {
+		return nil, shared.NewNotFoundError("could not find comment thread")
+	}
+
+	_, err := service.findDocument(user, thread.DocumentId, action)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = service.documentService.FindDraft(user, thread.DocumentId, thread.DocumentDraftId)
+	if err != nil {
+		return nil, err
+	}
+
+	return thread, nil
+}
+
+func (service *CommentService) findDocument(user *shared.User, documentId string, action string) (*shared.Document, error) {
+	doc := service.documentService.FindById(documentId)
+	if doc == nil {
+		return nil, shared.NewNotFoundError("could not find document")
+	}
+
+	if !service.aclService.UserCanAccessResourceByModel(user, doc, action) {
+		return nil, shared.NewForbiddenError(fmt.Sprintf("can not %s on document", action))
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,54 @@
+package comment_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/comment"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func intPointer(value int) *int {
+	return &value
+}
+
+func TestResolveAnchorKeepsTheTextOfARange(t *testing.T) {
+	anchor, err := comment.ResolveAnchor(&shared.CommentAnchor{Start: intPointer(2), End: intPointer(7), Text: "ignored"}, "# Café setup")
+	assert.Nil(t, err)
+	assert.Equal(t, "Café ", anchor.Text)
+	assert.Nil(t, anchor.Line)
+}
+
+func TestResolveAnchorToALine(t *testing.T) {
+	anchor, err := comment.ResolveAnchor(&shared.CommentAnchor{Line: intPointer(3)}, "one\ntwo\nthree")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, *anchor.Line)
+	assert.Equal(t, "", anchor.Text)
+}
+
+func TestResolveAnchorWithoutAnAnchor(t *testing.T) {
+	anchor, err := comment.ResolveAnchor(nil, "content")
+	assert.Nil(t, err)
+	assert.Nil(t, anchor)
+
+	anchor, err = comment.ResolveAnchor(&shared.CommentAnchor{}, "content")
+	assert.Nil(t, err)
+	assert.Nil(t, anchor)
+}
+
+func TestResolveAnchorFailsOutsideOfTheContent(t *testing.T) {
+	invalid := []*shared.CommentAnchor{
+		{Start: intPointer(0), End: intPointer(8)},
+		{Start: intPointer(-1), End: intPointer(2)},
+		{Start: intPointer(3), End: intPointer(3)},
+		{Start: intPointer(1)},
+		{Line: intPointer(0)},
+		{Line: intPointer(2)},
+		{Start: intPointer(0), End: intPointer(1), Line: intPointer(1)},
+	}
+
+	for _, anchor := range invalid {
+		_, err := comment.ResolveAnchor(anchor, "content")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(*shared.HttpError).Status)
+	}
+}
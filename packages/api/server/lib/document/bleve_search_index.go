@@ -0,0 +1,457 @@
+package document
+
+import (
+	"fmt"
+	"github.com/blevesearch/bleve"
+	keywordAnalyzer "github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/mapping"
+	"github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/pkg/errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// the hits are read from the index in pages of this size, until there are enough documents for the page of results
+const bleveSearchLimit = 1000
+
+// queries with any of these characters use the bleve query string syntax, e.g. "exact phrase", +required or fuzzy~2
+const bleveQuerySyntax = "\"+-~*:^"
+
+// the fields of a draft in the index, keyed by the resource name for the ones the acl scope and denies use
+var bleveScopeFields = map[string]string{
+	"organization": "organizationId",
+	"folder":       "folderId",
+	"document":     "documentId",
+}
+
+/*
+An embedded bleve index, which adds stemming, fuzzy matching and phrase queries on top of what the MySQL index can do.
+The index only holds what is needed to find drafts, the drafts themselves are loaded from the database.
+*/
+type BleveSearchIndex struct {
+	index                   bleve.Index
+	documentRepository      *DocumentRepository
+	documentDraftRepository *DocumentDraftRepository
+}
+
+/**
+Opens the index at the path, it is created if it does not exist yet
+*/
+func NewBleveSearchIndex(path string, documentRepository *DocumentRepository, documentDraftRepository *DocumentDraftRepository) (*BleveSearchIndex, error) {
+	var index bleve.Index
+	var err error
+	if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+		index, err = bleve.New(path, newBleveIndexMapping())
+	} else {
+		index, err = bleve.Open(path)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the bleve search index")
+	}
+
+	return &BleveSearchIndex{
+		index:                   index,
+		documentRepository:      documentRepository,
+		documentDraftRepository: documentDraftRepository,
+	}, nil
+}
+
+func newBleveIndexMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = en.AnalyzerName
+
+	keyword := bleve.NewTextFieldMapping()
+	keyword.Analyzer = keywordAnalyzer.Name
+	keyword.IncludeInAll = false
+
+	boolean := bleve.NewBooleanFieldMapping()
+	boolean.IncludeInAll = false
+
+	numeric := bleve.NewNumericFieldMapping()
+	numeric.IncludeInAll = false
+
+	draftMapping := bleve.NewDocumentStaticMapping()
+	draftMapping.AddFieldMappingsAt("name", text)
+	draftMapping.AddFieldMappingsAt("content", text)
+	draftMapping.AddFieldMappingsAt("documentId", keyword)
+	draftMapping.AddFieldMappingsAt("organizationId", keyword)
+	draftMapping.AddFieldMappingsAt("folderId", keyword)
+	draftMapping.AddFieldMappingsAt("creatorId", keyword)
+	draftMapping.AddFieldMappingsAt("published", boolean)
+	draftMapping.AddFieldMappingsAt("updatedAt", numeric)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = draftMapping
+	indexMapping.DefaultAnalyzer = en.AnalyzerName
+
+	return indexMapping
+}
+
+func (index *BleveSearchIndex) Search(userId string, scope *SearchScope, searchQuery string, filter *shared.DocumentSearchFilter, pagination *shared.Pagination) ([]DraftSearchResult, error) {
+	if filter == nil {
+		filter = &shared.DocumentSearchFilter{}
+	}
+
+	scopeQuery := bleveScopeQuery(scope)
+	if scopeQuery == nil {
+		return make([]DraftSearchResult, 0), nil
+	}
+
+	textQuery, err := bleveTextQuery(searchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	must := []query.Query{textQuery, scopeQuery, bleveAccessQuery(userId, filter.Status)}
+	mustNot := make([]query.Query, 0)
+
+	// leave out the documents that a deny takes away
+	for name, field := range bleveScopeFields {
+		for _, id := range scope.DeniedIds[name] {
+			mustNot = append(mustNot, bleveTermQuery(field, id))
+		}
+	}
+
+	// narrow down the results with the filter
+	if filter.FolderIds != nil {
+		if len(filter.FolderIds) == 0 {
+			return make([]DraftSearchResult, 0), nil
+		}
+		folderQueries := make([]query.Query, len(filter.FolderIds))
+		for i, id := range filter.FolderIds {
+			folderQueries[i] = bleveTermQuery("folderId", id)
+		}
+		must = append(must, bleve.NewDisjunctionQuery(folderQueries...))
+	}
+	if filter.DocumentIds != nil {
+		if len(filter.DocumentIds) == 0 {
+			return make([]DraftSearchResult, 0), nil
+		}
+		documentQueries := make([]query.Query, len(filter.DocumentIds))
+		for i, id := range filter.DocumentIds {
+			documentQueries[i] = bleveTermQuery("documentId", id)
+		}
+		must = append(must, bleve.NewDisjunctionQuery(documentQueries...))
+	}
+	if filter.CreatorId != nil {
+		must = append(must, bleveTermQuery("creatorId", *filter.CreatorId))
+	}
+	if filter.Author != nil {
+		// the index only knows the id of the creator, so the email is looked up first
+		creatorId, err := index.documentDraftRepository.FindUserIdByEmail(*filter.Author)
+		if err != nil {
+			return nil, err
+		}
+		if creatorId == nil {
+			return make([]DraftSearchResult, 0), nil
+		}
+		must = append(must, bleveTermQuery("creatorId", *creatorId))
+	}
+	if filter.Title != nil {
+		title := bleve.NewMatchPhraseQuery(*filter.Title)
+		title.SetField("name")
+		must = append(must, title)
+	}
+	if filter.From != nil || filter.To != nil {
+		var from, to *float64
+		if filter.From != nil {
+			value := float64(*filter.From)
+			from = &value
+		}
+		if filter.To != nil {
+			value := float64(*filter.To)
+			to = &value
+		}
+		inclusive := true
+		dateQuery := bleve.NewNumericRangeInclusiveQuery(from, to, &inclusive, &inclusive)
+		dateQuery.SetField("updatedAt")
+		must = append(must, dateQuery)
+	}
+
+	booleanQuery := bleve.NewBooleanQuery()
+	booleanQuery.AddMust(must...)
+	booleanQuery.AddMustNot(mustNot...)
+
+	// without pagination every hit is needed, otherwise only enough for the page
+	needed := -1
+	if pagination != nil {
+		needed = (pagination.Page + 1) * pagination.Count
+	}
+
+	results := make([]DraftSearchResult, 0)
+	for from := 0; ; from += bleveSearchLimit {
+		request := bleve.NewSearchRequestOptions(booleanQuery, bleveSearchLimit, from, false)
+		response, err := index.index.Search(request)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to search the bleve search index")
+		}
+
+		latest, err := index.findLatestDrafts(userId, filter.Status, response.Hits)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, latest...)
+
+		if len(response.Hits) < bleveSearchLimit || (needed >= 0 && len(results) >= needed) {
+			break
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Draft.CreatedAt > results[j].Draft.CreatedAt
+	})
+
+	if pagination != nil {
+		start := pagination.Page * pagination.Count
+		if start > len(results) {
+			start = len(results)
+		}
+		end := start + pagination.Count
+		if end > len(results) {
+			end = len(results)
+		}
+		results = results[start:end]
+	}
+
+	return results, nil
+}
+
+/**
+Loads the drafts of the hits, keeping only the ones that are the latest draft of their document the user can see. An
+older published draft is not found when a newer draft of the user no longer matches, the same as the MySQL index.
+*/
+func (index *BleveSearchIndex) findLatestDrafts(userId string, status string, hits search.DocumentMatchCollection) ([]DraftSearchResult, error) {
+	draftIds := make([]string, len(hits))
+	scores := make(map[string]float64)
+	for i, hit := range hits {
+		draftIds[i] = hit.ID
+		scores[hit.ID] = hit.Score
+	}
+
+	drafts, err := index.documentDraftRepository.FindSearchResultsByIds(draftIds)
+	if err != nil {
+		return nil, err
+	}
+
+	documentIds := make([]string, 0)
+	for _, result := range drafts {
+		documentIds = append(documentIds, result.Draft.DocumentId)
+	}
+	latestDraftIds, err := index.documentDraftRepository.FindLatestDraftIds(userId, documentIds, status)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]DraftSearchResult, 0)
+	for _, result := range drafts {
+		if latestDraftIds[result.Draft.DocumentId] != result.Draft.Id {
+			continue
+		}
+		result.Score = scores[result.Draft.Id]
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (index *BleveSearchIndex) IndexDocument(documentId string) error {
+	batch := index.index.NewBatch()
+
+	// remove whatever the index holds for the document, the drafts that should still be there are added back below
+	existing, err := index.findDraftIds(bleveTermQuery("documentId", documentId))
+	if err != nil {
+		return err
+	}
+	for _, id := range existing {
+		batch.Delete(id)
+	}
+
+	document := index.documentRepository.FindById(documentId)
+	if document != nil {
+		drafts, err := index.documentDraftRepository.FindIndexableDrafts(documentId)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range drafts {
+			err = batch.Index(result.Draft.Id, newBleveDraft(document, result))
+			if err != nil {
+				log.Print(err)
+				return errors.New("failed to index document draft")
+			}
+		}
+	}
+
+	err = index.index.Batch(batch)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update the bleve search index")
+	}
+
+	return nil
+}
+
+func (index *BleveSearchIndex) Rebuild() error {
+	for {
+		ids, err := index.findDraftIds(bleve.NewMatchAllQuery())
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		batch := index.index.NewBatch()
+		for _, id := range ids {
+			batch.Delete(id)
+		}
+		err = index.index.Batch(batch)
+		if err != nil {
+			log.Print(err)
+			return errors.New("failed to clear the bleve search index")
+		}
+	}
+
+	documentIds, err := index.documentRepository.FindAllIds()
+	if err != nil {
+		return err
+	}
+
+	for _, documentId := range documentIds {
+		err = index.IndexDocument(documentId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (index *BleveSearchIndex) Close() error {
+	return index.index.Close()
+}
+
+/**
+Finds up to bleveSearchLimit ids of the drafts in the index that match the query
+*/
+func (index *BleveSearchIndex) findDraftIds(q query.Query) ([]string, error) {
+	response, err := index.index.Search(bleve.NewSearchRequestOptions(q, bleveSearchLimit, 0, false))
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to search the bleve search index")
+	}
+
+	ids := make([]string, len(response.Hits))
+	for i, hit := range response.Hits {
+		ids[i] = hit.ID
+	}
+
+	return ids, nil
+}
+
+func newBleveDraft(document *shared.Document, result DraftSearchResult) map[string]interface{} {
+	folderId := ""
+	if document.FolderId != nil {
+		folderId = *document.FolderId
+	}
+
+	return map[string]interface{}{
+		"name":           result.Draft.Name,
+		"content":        result.Content,
+		"documentId":     document.Id,
+		"organizationId": document.OrganizationId,
+		"folderId":       folderId,
+		"creatorId":      result.Draft.CreatorId,
+		"published":      result.Draft.PublishedAt != nil,
+		"updatedAt":      float64(result.Draft.UpdatedAt),
+	}
+}
+
+/**
+Plain queries match the name and content with a little fuzziness, the name weighing twice as much as the content. Queries
+using the query string syntax are handed to bleve as they are, and are a bad request when bleve can not parse them. An
+empty query matches everything.
+*/
+func bleveTextQuery(searchQuery string) (query.Query, error) {
+	if len(strings.TrimSpace(searchQuery)) == 0 {
+		return bleve.NewMatchAllQuery(), nil
+	}
+
+	if strings.ContainsAny(searchQuery, bleveQuerySyntax) {
+		queryString := bleve.NewQueryStringQuery(searchQuery)
+		err := queryString.Validate()
+		if err != nil {
+			return nil, shared.NewBadRequestError(fmt.Sprintf("invalid search query: %s", err.Error()))
+		}
+		return queryString, nil
+	}
+
+	name := bleve.NewMatchQuery(searchQuery)
+	name.SetField("name")
+	name.SetFuzziness(1)
+	name.SetBoost(2)
+
+	content := bleve.NewMatchQuery(searchQuery)
+	content.SetField("content")
+	content.SetFuzziness(1)
+
+	return bleve.NewDisjunctionQuery(name, content), nil
+}
+
+/**
+Matches the drafts in any of the organizations, folders or documents of the scope, nil if the scope is empty
+*/
+func bleveScopeQuery(scope *SearchScope) query.Query {
+	queries := make([]query.Query, 0)
+	for _, id := range scope.OrganizationIds {
+		queries = append(queries, bleveTermQuery("organizationId", id))
+	}
+	for _, id := range scope.FolderIds {
+		queries = append(queries, bleveTermQuery("folderId", id))
+	}
+	for _, id := range scope.DocumentIds {
+		queries = append(queries, bleveTermQuery("documentId", id))
+	}
+
+	if len(queries) == 0 {
+		return nil
+	}
+
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+/**
+The same drafts that draftAccessClause allows, published drafts OR the unpublished drafts of the user
+*/
+func bleveAccessQuery(userId string, status string) query.Query {
+	published := bleve.NewBoolFieldQuery(true)
+	published.SetField("published")
+
+	unpublished := bleve.NewBoolFieldQuery(false)
+	unpublished.SetField("published")
+	ownDraft := bleve.NewConjunctionQuery(unpublished, bleveTermQuery("creatorId", userId))
+
+	switch status {
+	case shared.DocumentSearchStatusPublished:
+		return published
+	case shared.DocumentSearchStatusDraft:
+		return ownDraft
+	}
+
+	return bleve.NewDisjunctionQuery(published, ownDraft)
+}
+
+func bleveTermQuery(field string, term string) query.Query {
+	q := bleve.NewTermQuery(term)
+	q.SetField(field)
+	return q
+}
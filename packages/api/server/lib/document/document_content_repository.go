@@ -3,6 +3,7 @@ package document
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/util"
 	"log"
@@ -77,3 +78,34 @@ func (repo *DocumentContentRepository) Update(document *shared.DocumentContent)
 
 	return nil;
 }
+
+func (repo *DocumentContentRepository) FindByDocumentDraftIds(documentDraftIds []string) ([]shared.DocumentContent, error) {
+	if len(documentDraftIds) == 0 {
+		return make([]shared.DocumentContent, 0), nil
+	}
+
+	query := fmt.Sprintf(
+		"select id, content, document_draft_id, created_at, updated_at, deleted_at from document_draft_content where document_draft_id in (%s)",
+		util.BuildSqlPlaceholderArray(documentDraftIds),
+	)
+
+	rows, err := repo.Query(query, util.ConvertStringArrayToInterfaceArray(documentDraftIds)...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find document content")
+	}
+	defer rows.Close()
+
+	contents := make([]shared.DocumentContent, 0)
+	for rows.Next() {
+		var content shared.DocumentContent
+		err := rows.Scan(&content.Id, &content.Content, &content.DocumentDraftId, &content.CreatedAt, &content.UpdatedAt, &content.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document content")
+		}
+		contents = append(contents, content)
+	}
+
+	return contents, nil
+}
@@ -26,12 +26,44 @@ func (repo *DocumentDraftRepository) InjectTransaction(tx *sql.Tx) interface{} {
 }
 
 /*
-Given the organizations / documents / folders that the user has access to, search for documents in it
+A draft that matched a search, along with its content so the matches can be highlighted
+*/
+type DraftSearchResult struct {
+	Draft   shared.DocumentDraft
+	Content string
+	Score   float64
+}
+
+/*
+Given the organizations / documents / folders that the user has access to, search for documents in it. Only the latest
+draft of each document that the user can see is searched, and the results are ordered by relevance with matches in the
+name weighing twice as much as matches in the content.
  */
-func (repo *DocumentDraftRepository) Search(userId string, organizationIds []string, folderIds []string, documentIds []string, searchQuery string) ([]shared.DocumentDraft, error) {
-	query := "SELECT DISTINCT d1.id, d1.document_id, d1.name, d1.creator_id, d1.published_at, d1.retracted_at, d1.created_at, d1.updated_at, d1.deleted_at FROM document_draft d1 JOIN document d3 ON d3.id = d1.document_id JOIN document_draft_content d2 ON d2.document_draft_id = d1.id WHERE (MATCH(d1.name) AGAINST(?) OR MATCH(d2.content) AGAINST(?)) AND ((d1.published_at IS NOT NULL AND d1.retracted_at IS NULL AND d1.deleted_at IS NULL) OR (d1.published_at IS NULL AND d1.creator_id = ? AND d1.retracted_at IS NULL AND d1.deleted_at IS NULL))";
+func (repo *DocumentDraftRepository) Search(
+	userId string, organizationIds []string, folderIds []string, documentIds []string, deniedIds map[string][]string,
+	searchQuery string, filter *shared.DocumentSearchFilter, pagination *shared.Pagination,
+) ([]DraftSearchResult, error) {
+	if filter == nil {
+		filter = &shared.DocumentSearchFilter{}
+	}
+
+	accessClause, accessParams := draftAccessClause("d1", userId, filter.Status)
+	newerAccessClause, newerAccessParams := draftAccessClause("d4", userId, filter.Status)
+
+	// a query of only qualifiers has no text to match, so every draft that passes the filter is found
+	scoreClause := "0"
+	matchClause := "1 = 1"
+	params := make([]interface{}, 0)
+	if len(strings.TrimSpace(searchQuery)) > 0 {
+		scoreClause = "MATCH(d1.name) AGAINST(?) * 2 + MATCH(d2.content) AGAINST(?)"
+		matchClause = "(MATCH(d1.name) AGAINST(?) OR MATCH(d2.content) AGAINST(?))"
+		params = util.ConvertStringArrayToInterfaceArray([]string{searchQuery, searchQuery, searchQuery, searchQuery});
+	}
 
-	params := util.ConvertStringArrayToInterfaceArray([]string{searchQuery, searchQuery, userId});
+	query := fmt.Sprintf("SELECT d1.id, d1.document_id, d1.name, d1.creator_id, d1.published_at, d1.retracted_at, d1.publish_at, d1.retract_at, d1.created_at, d1.updated_at, d1.deleted_at, d2.content, (%s) AS score FROM document_draft d1 JOIN document d3 ON d3.id = d1.document_id JOIN document_draft_content d2 ON d2.document_draft_id = d1.id WHERE %s AND %s AND NOT EXISTS (SELECT 1 FROM document_draft d4 WHERE d4.document_id = d1.document_id AND d4.created_at > d1.created_at AND %s)", scoreClause, matchClause, accessClause, newerAccessClause)
+
+	params = append(params, accessParams...)
+	params = append(params, newerAccessParams...)
 
 	// build the in queries
 	inQueries := make([]string, 0)
@@ -51,8 +83,61 @@ func (repo *DocumentDraftRepository) Search(userId string, organizationIds []str
 	// tack on the in query
 	query = fmt.Sprintf("%s AND (%s)", query, strings.Join(inQueries, " OR "))
 
-	// order the results
-	query = fmt.Sprintf("%s %s", query, "ORDER BY d1.created_at DESC");
+	// leave out the documents that a deny takes away
+	exclusionClause, exclusionParams := util.BuildSqlExclusionClause(deniedIds, map[string]string{
+		"organization": "d3.organization_id",
+		"folder":       "d3.folder_id",
+		"document":     "d3.id",
+	})
+	if len(exclusionClause) > 0 {
+		query = fmt.Sprintf("%s AND %s", query, exclusionClause)
+		params = append(params, exclusionParams...)
+	}
+
+	// narrow down the results with the filter
+	if filter.FolderIds != nil {
+		if len(filter.FolderIds) == 0 {
+			return make([]DraftSearchResult, 0), nil
+		}
+		query = fmt.Sprintf("%s AND d3.folder_id in (%s)", query, util.BuildSqlPlaceholderArray(filter.FolderIds))
+		params = append(params, util.ConvertStringArrayToInterfaceArray(filter.FolderIds)...)
+	}
+	if filter.DocumentIds != nil {
+		if len(filter.DocumentIds) == 0 {
+			return make([]DraftSearchResult, 0), nil
+		}
+		query = fmt.Sprintf("%s AND d3.id in (%s)", query, util.BuildSqlPlaceholderArray(filter.DocumentIds))
+		params = append(params, util.ConvertStringArrayToInterfaceArray(filter.DocumentIds)...)
+	}
+	if filter.CreatorId != nil {
+		query = fmt.Sprintf("%s AND d1.creator_id = ?", query)
+		params = append(params, *filter.CreatorId)
+	}
+	if filter.Author != nil {
+		query = fmt.Sprintf("%s AND d1.creator_id in (SELECT u.id FROM user u WHERE u.email = ? AND u.deleted_at IS NULL)", query)
+		params = append(params, *filter.Author)
+	}
+	if filter.Title != nil {
+		query = fmt.Sprintf("%s AND d1.name LIKE ?", query)
+		params = append(params, "%"+util.EscapeSqlLike(*filter.Title)+"%")
+	}
+	if filter.From != nil {
+		query = fmt.Sprintf("%s AND d1.updated_at >= ?", query)
+		params = append(params, *filter.From)
+	}
+	if filter.To != nil {
+		query = fmt.Sprintf("%s AND d1.updated_at <= ?", query)
+		params = append(params, *filter.To)
+	}
+
+	// order the results, most relevant first
+	query = fmt.Sprintf("%s %s", query, "ORDER BY score DESC, d1.created_at DESC");
+
+	// add the pagination portion of the query
+	if pagination != nil {
+		query = fmt.Sprintf("%s LIMIT ?, ?", query)
+		params = append(params, pagination.Page * pagination.Count, pagination.Count)
+	}
 
 	rows, err := repo.Query(query, params...)
 	if err != nil {
@@ -61,14 +146,14 @@ func (repo *DocumentDraftRepository) Search(userId string, organizationIds []str
 	}
 	defer rows.Close()
 
-	// basically, the query above will find the latest valid drafts, so it should return at most 2 drafts per document
-	// the publish draft, or the active draft that the current user can view, the results are ordered, latest first,
-	// so we simply need to only add the first occurrence of the draft to the drafts array
+	// the query only returns the latest draft of each document, but two drafts created at the same time would both be
+	// returned, so only the first occurrence of the document is kept
 	idMap := make(map[string]bool);
-	drafts := make([]shared.DocumentDraft, 0)
+	results := make([]DraftSearchResult, 0)
 	for rows.Next() {
-		var draft shared.DocumentDraft
-		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
+		var result DraftSearchResult
+		draft := &result.Draft
+		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt, &result.Content, &result.Score)
 		if err != nil {
 			log.Print(err)
 			return nil, errors.New("failed to parse document drafts")
@@ -80,10 +165,95 @@ func (repo *DocumentDraftRepository) Search(userId string, organizationIds []str
 		}
 
 		idMap[draft.DocumentId] = true;
-		drafts = append(drafts, draft)
+		results = append(results, result)
 	}
 
-	return drafts, nil
+	return results, nil
+}
+
+/*
+Finds the drafts along with their content, leaving out the ones that have been retracted or deleted since they were found
+*/
+func (repo *DocumentDraftRepository) FindSearchResultsByIds(ids []string) ([]DraftSearchResult, error) {
+	if len(ids) == 0 {
+		return make([]DraftSearchResult, 0), nil
+	}
+
+	query := fmt.Sprintf("select d1.id, d1.document_id, d1.name, d1.creator_id, d1.published_at, d1.retracted_at, d1.publish_at, d1.retract_at, d1.created_at, d1.updated_at, d1.deleted_at, d2.content from document_draft d1 join document_draft_content d2 on d2.document_draft_id = d1.id where d1.id in (%s) and d1.retracted_at is null and d1.deleted_at is null", util.BuildSqlPlaceholderArray(ids))
+	rows, err := repo.Query(query, util.ConvertStringArrayToInterfaceArray(ids)...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find document drafts")
+	}
+	defer rows.Close()
+
+	results := make([]DraftSearchResult, 0)
+	for rows.Next() {
+		var result DraftSearchResult
+		draft := &result.Draft
+		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt, &result.Content)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document drafts")
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+/*
+The drafts that the user can see, published drafts OR drafts the user created that are not published yet, limited to one
+of the two by the status of a search filter
+*/
+func draftAccessClause(alias string, userId string, status string) (string, []interface{}) {
+	switch status {
+	case shared.DocumentSearchStatusPublished:
+		return fmt.Sprintf("(%[1]s.published_at IS NOT NULL AND %[1]s.retracted_at IS NULL AND %[1]s.deleted_at IS NULL)", alias), []interface{}{}
+	case shared.DocumentSearchStatusDraft:
+		return fmt.Sprintf("(%[1]s.published_at IS NULL AND %[1]s.creator_id = ? AND %[1]s.retracted_at IS NULL AND %[1]s.deleted_at IS NULL)", alias), []interface{}{userId}
+	}
+
+	return fmt.Sprintf("((%[1]s.published_at IS NOT NULL AND %[1]s.retracted_at IS NULL AND %[1]s.deleted_at IS NULL) OR (%[1]s.published_at IS NULL AND %[1]s.creator_id = ? AND %[1]s.retracted_at IS NULL AND %[1]s.deleted_at IS NULL))", alias), []interface{}{userId}
+}
+
+/**
+Finds the id of the latest draft of each document that the user can see with the search status, keyed by the document id
+*/
+func (repo *DocumentDraftRepository) FindLatestDraftIds(userId string, documentIds []string, status string) (map[string]string, error) {
+	latest := make(map[string]string)
+	if len(documentIds) == 0 {
+		return latest, nil
+	}
+
+	accessClause, accessParams := draftAccessClause("d1", userId, status)
+	query := fmt.Sprintf("SELECT d1.document_id, d1.id FROM document_draft d1 WHERE d1.document_id in (%s) AND %s ORDER BY d1.created_at DESC", util.BuildSqlPlaceholderArray(documentIds), accessClause)
+
+	params := util.ConvertStringArrayToInterfaceArray(documentIds)
+	params = append(params, accessParams...)
+
+	rows, err := repo.Query(query, params...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find latest drafts for documents")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var documentId, draftId string
+		err := rows.Scan(&documentId, &draftId)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document drafts")
+		}
+
+		// the drafts are latest first
+		if _, ok := latest[documentId]; !ok {
+			latest[documentId] = draftId
+		}
+	}
+
+	return latest, nil
 }
 
 func (repo *DocumentDraftRepository) FindLatestAccessibleDraftForDocuments(userId string, documentIds []string) ([]shared.DocumentDraft, error) {
@@ -96,7 +266,7 @@ func (repo *DocumentDraftRepository) FindLatestAccessibleDraftForDocuments(userI
 	// this query will find the latest version of a draft for each document that is either
 	// published (so we can view it) OR not published but we are the initial draft creator
 	// @todo this should be optimized to return exactly what we want
-	query := fmt.Sprintf("SELECT DISTINCT d1.id, d1.document_id, d1.name, d1.creator_id, d1.published_at, d1.retracted_at, d1.created_at, d1.updated_at, d1.deleted_at FROM document_draft d1 WHERE d1.document_id in (%s) AND ((d1.published_at IS NOT NULL AND d1.retracted_at IS NULL AND d1.deleted_at IS NULL) OR (d1.published_at IS NULL AND d1.creator_id = ? AND d1.retracted_at IS NULL AND d1.deleted_at IS NULL)) ORDER BY d1.created_at DESC", placeholders);
+	query := fmt.Sprintf("SELECT DISTINCT d1.id, d1.document_id, d1.name, d1.creator_id, d1.published_at, d1.retracted_at, d1.publish_at, d1.retract_at, d1.created_at, d1.updated_at, d1.deleted_at FROM document_draft d1 WHERE d1.document_id in (%s) AND ((d1.published_at IS NOT NULL AND d1.retracted_at IS NULL AND d1.deleted_at IS NULL) OR (d1.published_at IS NULL AND d1.creator_id = ? AND d1.retracted_at IS NULL AND d1.deleted_at IS NULL)) ORDER BY d1.created_at DESC", placeholders);
 
 	params := util.ConvertStringArrayToInterfaceArray(documentIds)
 	params = append(params, userId)
@@ -115,7 +285,7 @@ func (repo *DocumentDraftRepository) FindLatestAccessibleDraftForDocuments(userI
 	drafts := make([]shared.DocumentDraft, 0)
 	for rows.Next() {
 		var draft shared.DocumentDraft
-		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
+		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
 		if err != nil {
 			log.Print(err)
 			return nil, errors.New("failed to parse document drafts")
@@ -170,12 +340,12 @@ func (repo *DocumentDraftRepository) FindAndAttachLatestAccessibleDraftForDocume
 
 func (repo *DocumentDraftRepository) FindPublishedDraftByDocumentId(documentId string) *shared.DocumentDraft {
 	row := repo.QueryRow(
-		"select id, document_id, name, creator_id, published_at, retracted_at, created_at, updated_at, deleted_at from document_draft where document_id = ? and deleted_at is null and published_at is not null and retracted_at is null",
+		"select id, document_id, name, creator_id, published_at, retracted_at, publish_at, retract_at, created_at, updated_at, deleted_at from document_draft where document_id = ? and deleted_at is null and published_at is not null and retracted_at is null",
 		documentId,
 	)
 
 	var draft shared.DocumentDraft
-	err := row.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
+	err := row.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
 	if err != nil {
 		log.Print(err)
 		return nil
@@ -183,9 +353,56 @@ func (repo *DocumentDraftRepository) FindPublishedDraftByDocumentId(documentId s
 	return &draft
 }
 
+/*
+Finds the id of the user with the email, so that searches can be narrowed down to the drafts an author created. The user
+table is queried directly, the user package depends on this one. Nil if there is no such user.
+*/
+func (repo *DocumentDraftRepository) FindUserIdByEmail(email string) (*string, error) {
+	var id string
+	err := repo.QueryRow("select id from user where email = ? and deleted_at is null", email).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find user by email")
+	}
+
+	return &id, nil
+}
+
 func (repo *DocumentDraftRepository) FindByDocumentId(documentId string) ([]shared.DocumentDraft, error) {
 	rows, err := repo.Query(
-		"select id, document_id, name, creator_id, published_at, retracted_at, created_at, updated_at, deleted_at from document_draft where document_id = ? and deleted_at is null",
+		"select id, document_id, name, creator_id, published_at, retracted_at, publish_at, retract_at, created_at, updated_at, deleted_at from document_draft where document_id = ? and deleted_at is null",
+		documentId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find document drafts")
+	}
+	defer rows.Close()
+
+	drafts := make([]shared.DocumentDraft, 0)
+	for rows.Next() {
+		var draft shared.DocumentDraft
+		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document drafts")
+		}
+		drafts = append(drafts, draft)
+	}
+
+	return drafts, nil
+}
+
+/*
+Finds the drafts of the document that a search index should hold along with their content, the latest published draft
+and the latest unpublished draft of each creator
+*/
+func (repo *DocumentDraftRepository) FindIndexableDrafts(documentId string) ([]DraftSearchResult, error) {
+	rows, err := repo.Query(
+		"select d1.id, d1.document_id, d1.name, d1.creator_id, d1.published_at, d1.retracted_at, d1.publish_at, d1.retract_at, d1.created_at, d1.updated_at, d1.deleted_at, d2.content from document_draft d1 join document_draft_content d2 on d2.document_draft_id = d1.id where d1.document_id = ? and d1.retracted_at is null and d1.deleted_at is null order by d1.created_at desc",
 		documentId,
 	)
 	if err != nil {
@@ -194,10 +411,51 @@ func (repo *DocumentDraftRepository) FindByDocumentId(documentId string) ([]shar
 	}
 	defer rows.Close()
 
+	published := false
+	creators := make(map[string]bool)
+	results := make([]DraftSearchResult, 0)
+	for rows.Next() {
+		var result DraftSearchResult
+		draft := &result.Draft
+		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt, &result.Content)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document drafts")
+		}
+
+		// the drafts are ordered latest first, so only the first of each kind is kept
+		if draft.PublishedAt != nil && !published {
+			published = true
+			results = append(results, result)
+		}
+		if draft.PublishedAt == nil && !creators[draft.CreatorId] {
+			creators[draft.CreatorId] = true
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+/**
+Finds the drafts that are due to be published or retracted, oldest first
+*/
+func (repo *DocumentDraftRepository) FindDueScheduled(now int64) ([]shared.DocumentDraft, error) {
+	rows, err := repo.Query(
+		"select id, document_id, name, creator_id, published_at, retracted_at, publish_at, retract_at, created_at, updated_at, deleted_at from document_draft where deleted_at is null and retracted_at is null and (publish_at <= ? or retract_at <= ?) ORDER BY created_at ASC",
+		now,
+		now,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find scheduled document drafts")
+	}
+	defer rows.Close()
+
 	drafts := make([]shared.DocumentDraft, 0)
 	for rows.Next() {
 		var draft shared.DocumentDraft
-		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
+		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
 		if err != nil {
 			log.Print(err)
 			return nil, errors.New("failed to parse document drafts")
@@ -208,18 +466,52 @@ func (repo *DocumentDraftRepository) FindByDocumentId(documentId string) ([]shar
 	return drafts, nil
 }
 
+/**
+Finds the drafts of the documents in the organization that are waiting to be published or retracted, along with the
+organization and folder of their document
+*/
+func (repo *DocumentDraftRepository) FindScheduledByOrganizationId(organizationId string) ([]shared.Document, error) {
+	rows, err := repo.Query(
+		"select d2.id, d2.organization_id, d2.folder_id, d2.created_at, d2.updated_at, d2.deleted_at, d1.id, d1.document_id, d1.name, d1.creator_id, d1.published_at, d1.retracted_at, d1.publish_at, d1.retract_at, d1.created_at, d1.updated_at, d1.deleted_at from document_draft d1 join document d2 on d2.id = d1.document_id where d2.organization_id = ? and d2.deleted_at is null and d1.deleted_at is null and d1.retracted_at is null and (d1.publish_at is not null or d1.retract_at is not null) ORDER BY d1.created_at ASC",
+		organizationId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find scheduled document drafts")
+	}
+	defer rows.Close()
+
+	documents := make([]shared.Document, 0)
+	for rows.Next() {
+		var document shared.Document
+		var draft shared.DocumentDraft
+		err := rows.Scan(&document.Id, &document.OrganizationId, &document.FolderId, &document.CreatedAt, &document.UpdatedAt, &document.DeletedAt,
+			&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document drafts")
+		}
+		document.Drafts = []shared.DocumentDraft{draft}
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
 func (repo *DocumentDraftRepository) Insert(draft *shared.DocumentDraft) error {
 	draft.CreatedAt = util.NowUnix()
 	draft.UpdatedAt = util.NowUnix()
 
 	_, err := repo.Exec(
-		"insert into document_draft (id, document_id, name, creator_id, published_at, retracted_at, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		"insert into document_draft (id, document_id, name, creator_id, published_at, retracted_at, publish_at, retract_at, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 		draft.Id,
 		draft.DocumentId,
 		draft.Name,
 		draft.CreatorId,
 		draft.PublishedAt,
 		draft.RetractedAt,
+		draft.PublishAt,
+		draft.RetractAt,
 		draft.CreatedAt,
 		draft.UpdatedAt,
 		draft.DeletedAt,
@@ -237,10 +529,12 @@ func (repo *DocumentDraftRepository) Update(draft *shared.DocumentDraft) error {
 	draft.UpdatedAt = util.NowUnix()
 
 	_, err := repo.Exec(
-		"update document_draft set name = ?, published_at = ?, retracted_at = ?, updated_at = ?, deleted_at = ? where id = ?",
+		"update document_draft set name = ?, published_at = ?, retracted_at = ?, publish_at = ?, retract_at = ?, updated_at = ?, deleted_at = ? where id = ?",
 		draft.Name,
 		draft.PublishedAt,
 		draft.RetractedAt,
+		draft.PublishAt,
+		draft.RetractAt,
 		draft.UpdatedAt,
 		draft.DeletedAt,
 		draft.Id,
@@ -270,3 +564,44 @@ func (repo *DocumentDraftRepository) Delete(documentId string) error {
 
 	return nil;
 }
+
+func (repo *DocumentDraftRepository) FindByCreatorId(creatorId string) ([]shared.DocumentDraft, error) {
+	rows, err := repo.Query(
+		"select id, document_id, name, creator_id, published_at, retracted_at, publish_at, retract_at, created_at, updated_at, deleted_at from document_draft where creator_id = ? and deleted_at is null ORDER BY created_at ASC",
+		creatorId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find document drafts")
+	}
+	defer rows.Close()
+
+	drafts := make([]shared.DocumentDraft, 0)
+	for rows.Next() {
+		var draft shared.DocumentDraft
+		err := rows.Scan(&draft.Id, &draft.DocumentId, &draft.Name, &draft.CreatorId, &draft.PublishedAt, &draft.RetractedAt, &draft.PublishAt, &draft.RetractAt, &draft.CreatedAt, &draft.UpdatedAt, &draft.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document drafts")
+		}
+		drafts = append(drafts, draft)
+	}
+
+	return drafts, nil
+}
+
+func (repo *DocumentDraftRepository) ReassignCreator(fromCreatorId string, toCreatorId string) error {
+	_, err := repo.Exec(
+		"update document_draft set creator_id = ?, updated_at = ? where creator_id = ?",
+		toCreatorId,
+		util.NowUnix(),
+		fromCreatorId,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to reassign document drafts")
+	}
+
+	return nil
+}
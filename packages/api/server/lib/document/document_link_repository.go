@@ -0,0 +1,101 @@
+package document
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	uuid "github.com/satori/go.uuid"
+	"log"
+)
+
+type DocumentLinkRepository struct {
+	util.Repository
+}
+
+func NewDocumentLinkRepository(db *sql.DB, tx *sql.Tx) *DocumentLinkRepository {
+	repo := &DocumentLinkRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *DocumentLinkRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewDocumentLinkRepository(repo.Db, tx)
+}
+
+/**
+Replaces the links of the draft with links to the targets. The links are derived from the content of the draft, so the
+old ones are removed outright instead of being kept around as deleted.
+*/
+func (repo *DocumentLinkRepository) ReplaceForDraft(documentId string, draftId string, targetDocumentIds []string) error {
+	_, err := repo.Exec("delete from document_link where document_draft_id = ?", draftId)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to remove document links")
+	}
+
+	for _, targetDocumentId := range targetDocumentIds {
+		link := &shared.DocumentLink{
+			DocumentId:       documentId,
+			DocumentDraftId:  draftId,
+			TargetDocumentId: targetDocumentId,
+		}
+		link.Id = uuid.NewV4().String()
+		link.CreatedAt = util.NowUnix()
+		link.UpdatedAt = util.NowUnix()
+
+		_, err := repo.Exec(
+			"insert into document_link (id, document_id, document_draft_id, target_document_id, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?)",
+			link.Id,
+			link.DocumentId,
+			link.DocumentDraftId,
+			link.TargetDocumentId,
+			link.CreatedAt,
+			link.UpdatedAt,
+			link.DeletedAt,
+		)
+		if err != nil {
+			log.Print(err)
+			return errors.New("failed to insert document link")
+		}
+	}
+
+	return nil
+}
+
+/**
+Finds the links of the draft, in the order they appear in its content
+*/
+func (repo *DocumentLinkRepository) FindByDraftId(draftId string) ([]shared.DocumentLink, error) {
+	return repo.find("select id, document_id, document_draft_id, target_document_id, created_at, updated_at, deleted_at from document_link where document_draft_id = ? and deleted_at is null ORDER BY created_at ASC", draftId)
+}
+
+/**
+Finds the links from any draft of any document to the target
+*/
+func (repo *DocumentLinkRepository) FindByTargetDocumentId(targetDocumentId string) ([]shared.DocumentLink, error) {
+	return repo.find("select id, document_id, document_draft_id, target_document_id, created_at, updated_at, deleted_at from document_link where target_document_id = ? and deleted_at is null ORDER BY created_at ASC", targetDocumentId)
+}
+
+func (repo *DocumentLinkRepository) find(query string, id string) ([]shared.DocumentLink, error) {
+	rows, err := repo.Query(query, id)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find document links")
+	}
+	defer rows.Close()
+
+	links := make([]shared.DocumentLink, 0)
+	for rows.Next() {
+		var link shared.DocumentLink
+		err := rows.Scan(&link.Id, &link.DocumentId, &link.DocumentDraftId, &link.TargetDocumentId, &link.CreatedAt, &link.UpdatedAt, &link.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document link")
+		}
+		links = append(links, link)
+	}
+
+	return links, nil
+}
@@ -71,7 +71,42 @@ func (repo *DocumentRepository) FindByIds(ids ...string) ([]shared.Document, err
 	return documents, nil
 }
 
-func (repo *DocumentRepository) Find(userId string, organizationIds []string, folderIds []string, documentIds []string, folderId *string, pagination *shared.Pagination) ([]shared.Document, error) {
+/**
+Finds the documents directly in any of the folders
+*/
+func (repo *DocumentRepository) FindByFolderIds(folderIds []string) ([]shared.Document, error) {
+	if len(folderIds) == 0 {
+		return make([]shared.Document, 0), nil
+	}
+
+	query := fmt.Sprintf("select id, folder_id, organization_id, created_at, updated_at, deleted_at from document where folder_id IN (%s) and deleted_at is null", util.BuildSqlPlaceholderArray(folderIds))
+
+	rows, err := repo.Query(query, util.ConvertStringArrayToInterfaceArray(folderIds)...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find documents by folder")
+	}
+	defer rows.Close()
+
+	documents := make([]shared.Document, 0)
+	for rows.Next() {
+		var document shared.Document
+		err := rows.Scan(&document.Id, &document.FolderId, &document.OrganizationId, &document.CreatedAt, &document.UpdatedAt, &document.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document result")
+		}
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
+/**
+Finds the documents in the folder, or at the root of the organization. Documents with the tag are found in any folder
+unless a folder is given.
+*/
+func (repo *DocumentRepository) Find(userId string, organizationIds []string, folderIds []string, documentIds []string, deniedIds map[string][]string, folderId *string, tagId *string, pagination *shared.Pagination) ([]shared.Document, error) {
 	query := "select distinct d.id, d.folder_id, d.organization_id, d.created_at, d.updated_at, d.deleted_at from document d WHERE "
 
 	params := make([]interface{}, 0)
@@ -94,14 +129,30 @@ func (repo *DocumentRepository) Find(userId string, organizationIds []string, fo
 	// tack on the in query
 	query = fmt.Sprintf("%s (%s)", query, strings.Join(inQueries, " OR "))
 
+	// leave out the documents that a deny takes away
+	exclusionClause, exclusionParams := util.BuildSqlExclusionClause(deniedIds, map[string]string{
+		"organization": "d.organization_id",
+		"folder":       "d.folder_id",
+		"document":     "d.id",
+	})
+	if len(exclusionClause) > 0 {
+		query = fmt.Sprintf("%s AND %s", query, exclusionClause)
+		params = append(params, exclusionParams...)
+	}
+
 	// add the specific check for a specific folder
 	if folderId != nil {
 		query = fmt.Sprintf("%s AND d.folder_id = ?", query)
 		params = append(params, *folderId)
-	} else {
+	} else if tagId == nil {
 		query = fmt.Sprintf("%s AND d.folder_id is null", query)
 	}
 
+	if tagId != nil {
+		query = fmt.Sprintf("%s AND d.id in (SELECT dt.document_id FROM document_tag dt WHERE dt.tag_id = ? AND dt.deleted_at IS NULL)", query)
+		params = append(params, *tagId)
+	}
+
 	query = fmt.Sprintf("%s AND d.deleted_at is null ORDER BY d.created_at ASC", query)
 
 	// add the pagination portion of the query
@@ -131,6 +182,31 @@ func (repo *DocumentRepository) Find(userId string, organizationIds []string, fo
 	return documents, nil
 }
 
+/**
+Finds the ids of every document that has not been deleted, e.g. to rebuild the search index
+*/
+func (repo *DocumentRepository) FindAllIds() ([]string, error) {
+	rows, err := repo.Query("select id from document where deleted_at is null")
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find documents")
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		err := rows.Scan(&id)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document result")
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 func (repo *DocumentRepository) Insert(document *shared.Document) error {
 	document.CreatedAt = util.NowUnix()
 	document.UpdatedAt = util.NowUnix()
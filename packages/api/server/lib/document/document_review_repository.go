@@ -0,0 +1,230 @@
+package document
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+/*
+Stores the review policies of organizations and folders, along with the review requests and reviews of drafts
+*/
+type DocumentReviewRepository struct {
+	util.Repository
+}
+
+func NewDocumentReviewRepository(db *sql.DB, tx *sql.Tx) *DocumentReviewRepository {
+	repo := &DocumentReviewRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *DocumentReviewRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewDocumentReviewRepository(repo.Db, tx)
+}
+
+/**
+Finds the policy of the organization, and the policies of the folders given, in no particular order
+*/
+func (repo *DocumentReviewRepository) FindPolicies(organizationId string, folderIds []string) ([]shared.ReviewPolicy, error) {
+	query := "select id, organization_id, folder_id, required_approvals, created_at, updated_at, deleted_at from review_policy where organization_id = ? and deleted_at is null and (folder_id is null"
+	params := []interface{}{organizationId}
+	if len(folderIds) > 0 {
+		query += fmt.Sprintf(" or folder_id in (%s)", util.BuildSqlPlaceholderArray(folderIds))
+		params = append(params, util.ConvertStringArrayToInterfaceArray(folderIds)...)
+	}
+	query += ")"
+
+	rows, err := repo.Query(query, params...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find review policies")
+	}
+	defer rows.Close()
+
+	policies := make([]shared.ReviewPolicy, 0)
+	for rows.Next() {
+		var policy shared.ReviewPolicy
+		err := rows.Scan(&policy.Id, &policy.OrganizationId, &policy.FolderId, &policy.RequiredApprovals, &policy.CreatedAt, &policy.UpdatedAt, &policy.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse review policy")
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+func (repo *DocumentReviewRepository) InsertPolicy(policy *shared.ReviewPolicy) error {
+	policy.CreatedAt = util.NowUnix()
+	policy.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into review_policy (id, organization_id, folder_id, required_approvals, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?)",
+		policy.Id,
+		policy.OrganizationId,
+		policy.FolderId,
+		policy.RequiredApprovals,
+		policy.CreatedAt,
+		policy.UpdatedAt,
+		policy.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert review policy")
+	}
+
+	return nil
+}
+
+func (repo *DocumentReviewRepository) UpdatePolicy(policy *shared.ReviewPolicy) error {
+	policy.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update review_policy set required_approvals = ?, updated_at = ?, deleted_at = ? where id = ?",
+		policy.RequiredApprovals,
+		policy.UpdatedAt,
+		policy.DeletedAt,
+		policy.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update review policy")
+	}
+
+	return nil
+}
+
+func (repo *DocumentReviewRepository) FindRequestByDraftId(draftId string) *shared.DocumentReviewRequest {
+	row := repo.QueryRow(
+		"select id, document_draft_id, requester_id, created_at, updated_at, deleted_at from document_review_request where document_draft_id = ? and deleted_at is null",
+		draftId,
+	)
+
+	var request shared.DocumentReviewRequest
+	err := row.Scan(&request.Id, &request.DocumentDraftId, &request.RequesterId, &request.CreatedAt, &request.UpdatedAt, &request.DeletedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Print(err)
+		}
+		return nil
+	}
+
+	return &request
+}
+
+func (repo *DocumentReviewRepository) InsertRequest(request *shared.DocumentReviewRequest) error {
+	request.CreatedAt = util.NowUnix()
+	request.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into document_review_request (id, document_draft_id, requester_id, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?)",
+		request.Id,
+		request.DocumentDraftId,
+		request.RequesterId,
+		request.CreatedAt,
+		request.UpdatedAt,
+		request.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert review request")
+	}
+
+	return nil
+}
+
+func (repo *DocumentReviewRepository) DeleteRequest(request *shared.DocumentReviewRequest) error {
+	deletedAt := util.NowUnix()
+	request.UpdatedAt = deletedAt
+	request.DeletedAt = &deletedAt
+
+	_, err := repo.Exec(
+		"update document_review_request set updated_at = ?, deleted_at = ? where id = ?",
+		request.UpdatedAt,
+		request.DeletedAt,
+		request.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to delete review request")
+	}
+
+	return nil
+}
+
+/**
+Finds the reviews of the draft, oldest first
+*/
+func (repo *DocumentReviewRepository) FindReviewsByDraftId(draftId string) ([]shared.DocumentReview, error) {
+	rows, err := repo.Query(
+		"select id, document_draft_id, reviewer_id, decision, comment, created_at, updated_at, deleted_at from document_review where document_draft_id = ? and deleted_at is null ORDER BY created_at ASC",
+		draftId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find reviews")
+	}
+	defer rows.Close()
+
+	reviews := make([]shared.DocumentReview, 0)
+	for rows.Next() {
+		var review shared.DocumentReview
+		err := rows.Scan(&review.Id, &review.DocumentDraftId, &review.ReviewerId, &review.Decision, &review.Comment, &review.CreatedAt, &review.UpdatedAt, &review.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse review")
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+func (repo *DocumentReviewRepository) InsertReview(review *shared.DocumentReview) error {
+	review.CreatedAt = util.NowUnix()
+	review.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into document_review (id, document_draft_id, reviewer_id, decision, comment, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?, ?)",
+		review.Id,
+		review.DocumentDraftId,
+		review.ReviewerId,
+		review.Decision,
+		review.Comment,
+		review.CreatedAt,
+		review.UpdatedAt,
+		review.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert review")
+	}
+
+	return nil
+}
+
+/**
+Dismisses every review of the draft, e.g. once its content changes
+*/
+func (repo *DocumentReviewRepository) DeleteReviewsByDraftId(draftId string) error {
+	deletedAt := util.NowUnix()
+
+	_, err := repo.Exec(
+		"update document_review set updated_at = ?, deleted_at = ? where document_draft_id = ? and deleted_at is null",
+		deletedAt,
+		deletedAt,
+		draftId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to dismiss reviews")
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package document
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
 	"github.com/honerlaw/mentordoc/server/lib/folder"
 	"github.com/honerlaw/mentordoc/server/lib/organization"
@@ -9,39 +10,64 @@ import (
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/lib/util"
 	uuid "github.com/satori/go.uuid"
+	"log"
+	"net/http"
+	"sort"
 	"strings"
 )
 
+// the number of content snippets returned for each search result, and the characters around the matches in them
+const searchSnippetCount = 3
+const searchSnippetRadius = 60
+
+// the number of rendered revisions to keep in the render cache the service is created with
+const RenderCacheSize = 500
+
 type DocumentService struct {
 	documentRepository        *DocumentRepository
 	documentDraftRepository   *DocumentDraftRepository
 	documentContentRepository *DocumentContentRepository
+	documentReviewRepository  *DocumentReviewRepository
+	tagRepository             *TagRepository
+	documentLinkRepository    *DocumentLinkRepository
 	organizationService       *organization.OrganizationService
 	folderService             *folder.FolderService
 	aclService                *acl.AclService
 	transactionManager        *util.TransactionManager
 	resourceHistoryService    *resource_history.ResourceHistoryService
+	searchIndex               SearchIndex
+	renderCache               *util.LruCache
 }
 
 func NewDocumentService(
 	documentRepository *DocumentRepository,
 	documentDraftRepository *DocumentDraftRepository,
 	documentContentRepository *DocumentContentRepository,
+	documentReviewRepository *DocumentReviewRepository,
+	tagRepository *TagRepository,
+	documentLinkRepository *DocumentLinkRepository,
 	organizationService *organization.OrganizationService,
 	folderService *folder.FolderService,
 	aclService *acl.AclService,
 	transactionManager *util.TransactionManager,
 	resourceHistoryService *resource_history.ResourceHistoryService,
+	searchIndex SearchIndex,
+	renderCache *util.LruCache,
 ) *DocumentService {
 	return &DocumentService{
 		documentRepository:        documentRepository,
 		documentDraftRepository:   documentDraftRepository,
 		documentContentRepository: documentContentRepository,
+		documentReviewRepository:  documentReviewRepository,
+		tagRepository:             tagRepository,
+		documentLinkRepository:    documentLinkRepository,
 		organizationService:       organizationService,
 		folderService:             folderService,
 		aclService:                aclService,
 		transactionManager:        transactionManager,
 		resourceHistoryService:    resourceHistoryService,
+		searchIndex:               searchIndex,
+		renderCache:               renderCache,
 	}
 }
 
@@ -50,14 +76,23 @@ func (service *DocumentService) InjectTransaction(tx *sql.Tx) interface{} {
 		service.documentRepository.InjectTransaction(tx).(*DocumentRepository),
 		service.documentDraftRepository.InjectTransaction(tx).(*DocumentDraftRepository),
 		service.documentContentRepository.InjectTransaction(tx).(*DocumentContentRepository),
+		service.documentReviewRepository.InjectTransaction(tx).(*DocumentReviewRepository),
+		service.tagRepository.InjectTransaction(tx).(*TagRepository),
+		service.documentLinkRepository.InjectTransaction(tx).(*DocumentLinkRepository),
 		service.organizationService.InjectTransaction(tx).(*organization.OrganizationService),
 		service.folderService.InjectTransaction(tx).(*folder.FolderService),
 		service.aclService.InjectTransaction(tx).(*acl.AclService),
 		service.transactionManager.InjectTransaction(tx).(*util.TransactionManager),
 		service.resourceHistoryService.InjectTransaction(tx).(*resource_history.ResourceHistoryService),
+		service.searchIndex,
+		service.renderCache,
 	)
 }
 
+func (service *DocumentService) FindById(id string) *shared.Document {
+	return service.documentRepository.FindById(id)
+}
+
 func (service *DocumentService) FindDocument(user *shared.User, documentId string) (*shared.Document, error) {
 	document := service.documentRepository.FindById(documentId)
 	if document == nil {
@@ -90,9 +125,270 @@ func (service *DocumentService) FindDocument(user *shared.User, documentId strin
 	draft.Content = content
 	document.Drafts = drafts
 
+	document.Tags, err = service.findTags(document.Id)
+	if err != nil {
+		return nil, err
+	}
+
 	return document, nil
 }
 
+/**
+Finds a draft of the document along with its content, as long as the user can see it: published drafts that have not
+been retracted, the drafts the user created that have not been published yet, and drafts in review when the user can
+approve the document. Access to the document itself is left to the caller.
+*/
+func (service *DocumentService) FindDraft(user *shared.User, documentId string, draftId string) (*shared.DocumentDraft, error) {
+	drafts, err := service.documentDraftRepository.FindByDocumentId(documentId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document drafts")
+	}
+
+	for i := range drafts {
+		draft := &drafts[i]
+		if draft.Id != draftId {
+			continue
+		}
+
+		published := draft.PublishedAt != nil && draft.RetractedAt == nil
+		ownDraft := draft.PublishedAt == nil && draft.RetractedAt == nil && draft.CreatorId == user.Id
+		if !published && !ownDraft && !service.canReviewDraft(user, draft) {
+			return nil, shared.NewForbiddenError("can not access draft")
+		}
+
+		content := service.documentContentRepository.FindByDocumentDraftId(draft.Id)
+		if content == nil {
+			return nil, shared.NewNotFoundError("could not find document content")
+		}
+		draft.Content = content
+
+		return draft, nil
+	}
+
+	return nil, shared.NewNotFoundError("could not find draft")
+}
+
+/*
+Renders the content of the latest draft the user can view. The output is cached by the revision of the content, which
+changes whenever the content is saved, so the cache never has to be cleared.
+*/
+func (service *DocumentService) Render(user *shared.User, documentId string, format string) (*shared.RenderedDocument, error) {
+	if format != shared.RenderFormatHtml {
+		return nil, shared.NewBadRequestError("format must be html")
+	}
+
+	document, err := service.FindDocument(user, documentId)
+	if err != nil {
+		return nil, err
+	}
+	draft := document.Drafts[0]
+
+	key := fmt.Sprintf("%s:%s:%d", format, draft.Content.Id, draft.Content.UpdatedAt)
+	if cached, ok := service.renderCache.Get(key); ok {
+		rendered := *cached.(*shared.RenderedDocument)
+		return &rendered, nil
+	}
+
+	html, toc, err := util.RenderMarkdown(draft.Content.Content)
+	if err != nil {
+		log.Print(err)
+		return nil, shared.NewInternalServerError("failed to render document")
+	}
+
+	rendered := &shared.RenderedDocument{
+		DocumentId: document.Id,
+		DraftId:    draft.Id,
+		Format:     format,
+		Content:    html,
+		Toc:        toc,
+	}
+	service.renderCache.Set(key, rendered)
+
+	return rendered, nil
+}
+
+/**
+Finds the latest draft of the document the user can view, along with where it is, to export it
+*/
+func (service *DocumentService) Export(user *shared.User, documentId string) (*DocumentExport, error) {
+	document, err := service.FindDocument(user, documentId)
+	if err != nil {
+		return nil, err
+	}
+	draft := document.Drafts[0]
+
+	path, err := service.findExportPath(document.OrganizationId, document.FolderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocumentExport{
+		Name:      draft.Name,
+		Path:      path,
+		Content:   draft.Content.Content,
+		UpdatedAt: draft.Content.UpdatedAt,
+	}, nil
+}
+
+/*
+Finds the folder and every folder and document nested in it that the user can view, to export them. A folder the user can
+not view is left out along with everything in it.
+*/
+func (service *DocumentService) ExportFolder(user *shared.User, folderId string) (*FolderExport, error) {
+	root := service.folderService.FindById(folderId)
+	if root == nil {
+		return nil, shared.NewNotFoundError("could not find folder")
+	}
+	if !service.aclService.UserCanAccessResourceByModel(user, root, "view") {
+		return nil, shared.NewForbiddenError("can not view folder")
+	}
+
+	basePath, err := service.findExportPath(root.OrganizationId, root.ParentFolderId)
+	if err != nil {
+		return nil, err
+	}
+
+	descendantIds, err := service.folderService.FindDescendantIds([]string{root.Id})
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find folders")
+	}
+	descendants, err := service.folderService.FindByIds(descendantIds)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find folders")
+	}
+
+	// the names of the folders down to each folder, parents are always found before their children
+	folderMap := make(map[string]shared.Folder)
+	for _, f := range descendants {
+		folderMap[f.Id] = f
+	}
+	paths := map[string][]string{root.Id: {root.Name}}
+	var findPath func(id string) []string
+	findPath = func(id string) []string {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+
+		f, ok := folderMap[id]
+		paths[id] = nil
+		if !ok || f.ParentFolderId == nil || !service.aclService.UserCanAccessResourceByModel(user, &f, "view") {
+			return nil
+		}
+		parentPath := findPath(*f.ParentFolderId)
+		if parentPath == nil {
+			return nil
+		}
+
+		path := make([]string, len(parentPath), len(parentPath)+1)
+		copy(path, parentPath)
+		paths[id] = append(path, f.Name)
+		return paths[id]
+	}
+
+	export := &FolderExport{
+		Name:      root.Name,
+		Folders:   [][]string{paths[root.Id]},
+		Documents: make([]FolderExportDocument, 0),
+	}
+	folderIds := []string{root.Id}
+	for _, id := range descendantIds {
+		if path := findPath(id); path != nil {
+			export.Folders = append(export.Folders, path)
+			folderIds = append(folderIds, id)
+		}
+	}
+
+	documents, err := service.documentRepository.FindByFolderIds(folderIds)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find documents")
+	}
+	viewable := make([]shared.Document, 0)
+	for i := range documents {
+		if service.aclService.UserCanAccessResourceByModel(user, &documents[i], "view", "modify") {
+			viewable = append(viewable, documents[i])
+		}
+	}
+
+	err = service.documentDraftRepository.FindAndAttachLatestAccessibleDraftForDocuments(user.Id, viewable)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document drafts")
+	}
+
+	draftIds := make([]string, 0)
+	for _, doc := range viewable {
+		if len(doc.Drafts) > 0 {
+			draftIds = append(draftIds, doc.Drafts[0].Id)
+		}
+	}
+	contents, err := service.documentContentRepository.FindByDocumentDraftIds(draftIds)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document content")
+	}
+	contentMap := make(map[string]shared.DocumentContent)
+	for _, content := range contents {
+		contentMap[content.DocumentDraftId] = content
+	}
+
+	for _, doc := range viewable {
+		if len(doc.Drafts) == 0 {
+			continue
+		}
+		content, ok := contentMap[doc.Drafts[0].Id]
+		if !ok {
+			continue
+		}
+
+		folderPath := paths[*doc.FolderId]
+		export.Documents = append(export.Documents, FolderExportDocument{
+			Folder: folderPath,
+			Document: &DocumentExport{
+				Name:      doc.Drafts[0].Name,
+				Path:      append(append([]string{}, basePath...), folderPath...),
+				Content:   content.Content,
+				UpdatedAt: content.UpdatedAt,
+			},
+		})
+	}
+
+	// keep the zip the same from one export to the next
+	sort.Slice(export.Folders, func(i, j int) bool {
+		return strings.Join(export.Folders[i], "/") < strings.Join(export.Folders[j], "/")
+	})
+	sort.SliceStable(export.Documents, func(i, j int) bool {
+		left := strings.Join(export.Documents[i].Folder, "/") + "/" + export.Documents[i].Document.Name
+		right := strings.Join(export.Documents[j].Folder, "/") + "/" + export.Documents[j].Document.Name
+		return left < right
+	})
+
+	return export, nil
+}
+
+/**
+The names of the organization and the folders down to the folder, if there is one
+*/
+func (service *DocumentService) findExportPath(organizationId string, folderId *string) ([]string, error) {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return nil, shared.NewNotFoundError("could not find organization")
+	}
+
+	path := []string{org.Name}
+	if folderId == nil {
+		return path, nil
+	}
+
+	// the ancestry is the folder first, then its parents
+	folders, err := service.folderService.FindAncestry(*folderId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document path")
+	}
+	for i := len(folders) - 1; i >= 0; i-- {
+		path = append(path, folders[i].Name)
+	}
+
+	return path, nil
+}
+
 func (service *DocumentService) FindDocumentAncestry(user *shared.User, documentId string) ([]interface{}, error) {
 	document, err := service.FindDocument(user, documentId)
 	if err != nil {
@@ -164,11 +460,18 @@ func (service *DocumentService) CreateDraft(user *shared.User, documentId string
 			return nil, err
 		}
 
+		err = injectedService.replaceLinks(document.Id, documentDraft.Id, documentContent.Content)
+		if err != nil {
+			return nil, err
+		}
+
 		_, err = injectedService.resourceHistoryService.Create(documentDraft.Id, "document_draft", user.Id, "created")
 		if err != nil {
 			return nil, err
 		}
 
+		injectedService.indexAfterCommit(document.Id)
+
 		return nil, nil
 	})
 
@@ -225,6 +528,11 @@ func (service *DocumentService) Create(user *shared.User, organizationId string,
 			return nil, err
 		}
 
+		err = injectedService.replaceLinks(document.Id, documentDraft.Id, documentContent.Content)
+		if err != nil {
+			return nil, err
+		}
+
 		_, err = injectedService.resourceHistoryService.Create(document.Id, "document", user.Id, "created")
 		if err != nil {
 			return nil, err
@@ -235,6 +543,8 @@ func (service *DocumentService) Create(user *shared.User, organizationId string,
 			return nil, err
 		}
 
+		injectedService.indexAfterCommit(document.Id)
+
 		return nil, nil
 	})
 
@@ -248,9 +558,13 @@ func (service *DocumentService) Create(user *shared.User, organizationId string,
 	return document, nil
 }
 
+/**
+Updates the draft, publishing or retracting it now, or scheduling when that happens with publishAt and retractAt. A
+schedule of 0 clears it.
+*/
 func (service *DocumentService) Update(
 	user *shared.User, documentId string, draftId string,
-	name *string, content *string, shouldPublish bool, shouldRetract bool,
+	name *string, content *string, shouldPublish bool, shouldRetract bool, publishAt *int64, retractAt *int64,
 ) (*shared.Document, error) {
 	document := service.documentRepository.FindById(documentId)
 	if document == nil {
@@ -273,37 +587,281 @@ func (service *DocumentService) Update(
 		return nil, shared.NewBadRequestError("target draft and current draft are not the same")
 	}
 
+	err = validateSchedule(documentDraft, publishAt, retractAt, util.NowUnix())
+	if err != nil {
+		return nil, err
+	}
+
+	return service.update(user, document, documentDraft, name, content, shouldPublish, shouldRetract, publishAt, retractAt)
+}
+
+func (service *DocumentService) FindDueSchedules(now int64) ([]shared.DocumentDraft, error) {
+	drafts, err := service.documentDraftRepository.FindDueScheduled(now)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find scheduled drafts")
+	}
+	return drafts, nil
+}
+
+/**
+Publishes or retracts the draft if its schedule is due, acting as the creator of the draft. The draft is found again so
+that changes made since it was found are not lost. The creator has to still be able to modify the document, and when the
+draft can not be published or retracted, e.g. the creator lost access or the draft is missing approvals, the schedule is
+cleared instead of being tried again on every run.
+*/
+func (service *DocumentService) RunSchedule(creator *shared.User, draftId string, documentId string, now int64) error {
+	document := service.documentRepository.FindById(documentId)
+	if document == nil {
+		return shared.NewNotFoundError("could not find document")
+	}
+
+	drafts, err := service.documentDraftRepository.FindByDocumentId(documentId)
+	if err != nil {
+		return shared.NewInternalServerError("failed to find document drafts")
+	}
+
+	for i := range drafts {
+		documentDraft := &drafts[i]
+		if documentDraft.Id != draftId || documentDraft.RetractedAt != nil {
+			continue
+		}
+
+		// retracting wins when the draft was scheduled to be published and retracted before the scheduler got to it
+		shouldRetract := documentDraft.RetractAt != nil && *documentDraft.RetractAt <= now
+		shouldPublish := !shouldRetract && documentDraft.PublishAt != nil && *documentDraft.PublishAt <= now
+		if !shouldRetract && !shouldPublish {
+			return nil
+		}
+
+		if creator == nil || creator.Id != documentDraft.CreatorId {
+			return service.cancelSchedule(documentDraft, documentDraft.CreatorId, shared.NewForbiddenError("creator of the draft no longer exists"))
+		}
+
+		canAccess := service.aclService.UserCanAccessResourceByModel(creator, document, "modify")
+		if !canAccess {
+			return service.cancelSchedule(documentDraft, creator.Id, shared.NewForbiddenError("can not modify document"))
+		}
+
+		_, err := service.update(creator, document, documentDraft, nil, nil, shouldPublish, shouldRetract, nil, nil)
+		if httpErr, ok := err.(*shared.HttpError); ok && httpErr.Status != http.StatusInternalServerError {
+			return service.cancelSchedule(documentDraft, creator.Id, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+/**
+Clears the schedule of a draft that could not be published or retracted, the reason is returned so the scheduler can
+log it
+*/
+func (service *DocumentService) cancelSchedule(documentDraft *shared.DocumentDraft, userId string, reason error) error {
+	_, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		documentDraft.PublishAt = nil
+		documentDraft.RetractAt = nil
+		err := injectedService.documentDraftRepository.Update(documentDraft)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(documentDraft.Id, "document_draft", userId, "schedule_cancelled")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return shared.NewInternalServerError("failed to cancel draft schedule")
+	}
+
+	return reason
+}
+
+/**
+Lists the drafts in the organization that are waiting to be published or retracted, leaving out the ones the user can
+not see. Each document only holds its scheduled draft.
+*/
+func (service *DocumentService) ListSchedules(user *shared.User, organizationId string) ([]shared.Document, error) {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return nil, shared.NewNotFoundError("could not find organization")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, org, "view")
+	if !canAccess {
+		return nil, shared.NewForbiddenError("can not view organization")
+	}
+
+	documents, err := service.documentDraftRepository.FindScheduledByOrganizationId(org.Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find scheduled drafts")
+	}
+
+	visible := make([]shared.Document, 0)
+	for i := range documents {
+		document := &documents[i]
+		draft := &document.Drafts[0]
+
+		if !service.aclService.UserCanAccessResourceByModel(user, document, "view") {
+			continue
+		}
+
+		published := draft.PublishedAt != nil
+		ownDraft := draft.PublishedAt == nil && draft.CreatorId == user.Id
+		if !published && !ownDraft && !service.canReviewDraft(user, draft) {
+			continue
+		}
+
+		visible = append(visible, *document)
+	}
+
+	return visible, nil
+}
+
+/**
+The schedule has to be in the future, drafts can only be scheduled to be published before they are, and a draft can only
+be retracted once it is published
+*/
+func validateSchedule(draft *shared.DocumentDraft, publishAt *int64, retractAt *int64, now int64) error {
+	if publishAt != nil && *publishAt != 0 {
+		if draft.PublishedAt != nil {
+			return shared.NewBadRequestError("draft is already published")
+		}
+		if *publishAt <= now {
+			return shared.NewBadRequestError("publishAt must be in the future")
+		}
+	}
+	if retractAt != nil && *retractAt != 0 && *retractAt <= now {
+		return shared.NewBadRequestError("retractAt must be in the future")
+	}
+
+	scheduledPublish := draft.PublishAt
+	if publishAt != nil {
+		scheduledPublish = nil
+		if *publishAt != 0 {
+			scheduledPublish = publishAt
+		}
+	}
+	scheduledRetract := draft.RetractAt
+	if retractAt != nil {
+		scheduledRetract = nil
+		if *retractAt != 0 {
+			scheduledRetract = retractAt
+		}
+	}
+
+	if scheduledRetract != nil && draft.PublishedAt == nil {
+		if scheduledPublish == nil {
+			return shared.NewBadRequestError("retractAt requires the draft to be published or scheduled to be published")
+		}
+		if *scheduledRetract <= *scheduledPublish {
+			return shared.NewBadRequestError("retractAt must be after publishAt")
+		}
+	}
+
+	return nil
+}
+
+/**
+Applies the changes to the draft, the same way for users and for the scheduler
+*/
+func (service *DocumentService) update(
+	user *shared.User, document *shared.Document, documentDraft *shared.DocumentDraft,
+	name *string, content *string, shouldPublish bool, shouldRetract bool, publishAt *int64, retractAt *int64,
+) (*shared.Document, error) {
 	documentContent := service.documentContentRepository.FindByDocumentDraftId(documentDraft.Id)
 	if documentContent == nil {
 		return nil, shared.NewNotFoundError("could not find document content")
 	}
 
+	changed := (name != nil && *name != documentDraft.Name) || (content != nil && *content != documentContent.Content)
+
+	// drafts under a review policy can only be published once enough reviewers approved them, and the approvals are for
+	// the draft as it was, so changing it and publishing it at once is not allowed
+	if shouldPublish && documentDraft.PublishedAt == nil {
+		status, err := service.findReviewStatus(document, documentDraft)
+		if err != nil {
+			return nil, err
+		}
+		if !status.CanPublish {
+			return nil, shared.NewBadRequestError(fmt.Sprintf("draft needs %d approvals before it can be published", status.RequiredApprovals))
+		}
+		if changed && status.RequiredApprovals > 0 {
+			return nil, shared.NewBadRequestError("draft was changed since it was reviewed, save it and request another review before publishing")
+		}
+	}
+
 	res, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
 		injectedService := injected.(*DocumentService)
 
+		// the reviews only apply to what the reviewers saw, and the review is over once the draft is published
+		reviewRequest := injectedService.documentReviewRepository.FindRequestByDraftId(documentDraft.Id)
+		if reviewRequest != nil && changed {
+			err := injectedService.documentReviewRepository.DeleteReviewsByDraftId(documentDraft.Id)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if reviewRequest != nil && shouldPublish {
+			err := injectedService.documentReviewRepository.DeleteRequest(reviewRequest)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		if name != nil {
 			documentDraft.Name = *name
 		}
+		if publishAt != nil {
+			documentDraft.PublishAt = nil
+			if *publishAt != 0 {
+				documentDraft.PublishAt = publishAt
+			}
+		}
+		if retractAt != nil {
+			documentDraft.RetractAt = nil
+			if *retractAt != 0 {
+				documentDraft.RetractAt = retractAt
+			}
+		}
 		if shouldPublish {
 			publishedAt := util.NowUnix()
 			documentDraft.PublishedAt = &publishedAt
+			documentDraft.PublishAt = nil
 		}
 		if shouldRetract {
 			retractedAt := util.NowUnix()
 			documentDraft.RetractedAt = &retractedAt
 			documentDraft.DeletedAt = &retractedAt
+			documentDraft.PublishAt = nil
+			documentDraft.RetractAt = nil
 		}
 		err := injectedService.documentDraftRepository.Update(documentDraft)
 		if err != nil {
 			return nil, err
 		}
 
-		if content != nil {
-			documentContent.Content = *content
+		// the content is only touched when the draft is revised, so its updated_at tells which reviews are stale
+		if changed {
+			if content != nil {
+				documentContent.Content = *content
+			}
+			err = injectedService.documentContentRepository.Update(documentContent)
+			if err != nil {
+				return nil, err
+			}
 		}
-		err = injectedService.documentContentRepository.Update(documentContent)
-		if err != nil {
-			return nil, err
+
+		if content != nil {
+			err = injectedService.replaceLinks(document.Id, documentDraft.Id, documentContent.Content)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		_, err = injectedService.resourceHistoryService.Create(document.Id, "document", user.Id, "updated")
@@ -316,6 +874,8 @@ func (service *DocumentService) Update(
 			return nil, err
 		}
 
+		injectedService.indexAfterCommit(document.Id)
+
 		documentDraft.Content = documentContent
 		document.Drafts = []shared.DocumentDraft{*documentDraft}
 
@@ -362,6 +922,8 @@ func (service *DocumentService) Delete(user *shared.User, documentId string) (*s
 			return nil, err
 		}
 
+		injectedService.indexAfterCommit(document.Id)
+
 		return document, nil
 	})
 
@@ -372,12 +934,25 @@ func (service *DocumentService) Delete(user *shared.User, documentId string) (*s
 	return res.(*shared.Document), nil
 }
 
-func (service *DocumentService) List(user *shared.User, organizationId string, folderId *string, pagination *shared.Pagination) ([]shared.Document, error) {
+/**
+Lists the documents in the folder, or at the root of the organization. Given the name of a tag, only the documents with
+the tag are listed, from every folder unless a folder is given.
+*/
+func (service *DocumentService) List(user *shared.User, organizationId string, folderId *string, tag *string, pagination *shared.Pagination) ([]shared.Document, error) {
 	organizationId, folderId, err := service.hasAccessToOrganizationOrFolder(user, organizationId, folderId, "view:document");
 	if err != nil {
 		return nil, err
 	}
 
+	var tagId *string
+	if tag != nil {
+		found := service.tagRepository.FindByName(organizationId, *tag)
+		if found == nil {
+			return make([]shared.Document, 0), nil
+		}
+		tagId = &found.Id
+	}
+
 	documentResourceData, err := service.aclService.GetResourceDataForModel(&shared.Document{})
 	if err != nil {
 		return nil, shared.NewInternalServerError("failed to find document information")
@@ -404,8 +979,13 @@ func (service *DocumentService) List(user *shared.User, organizationId string, f
 		}
 	}
 
+	deniedIds, err := service.aclService.DeniedResourceIds(user, documentResourceData.ResourcePath, "view")
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find accessible documents")
+	}
+
 	// this will find all of the documents that you are able to view, but does not take into account drafts that are not tied to you
-	documents, err := service.documentRepository.Find(user.Id, organizationIds, folderIds, documentIds, folderId, pagination)
+	documents, err := service.documentRepository.Find(user.Id, organizationIds, folderIds, documentIds, deniedIds, folderId, tagId, pagination)
 	if err != nil {
 		return nil, shared.NewInternalServerError("failed to find documents")
 	}
@@ -425,11 +1005,32 @@ func (service *DocumentService) List(user *shared.User, organizationId string, f
 		}
 	}
 
+	err = service.attachTags(validDocuments)
+	if err != nil {
+		return nil, err
+	}
+
 	return validDocuments, nil
 }
 
 
-func (service *DocumentService) Search(user *shared.User, searchQuery string) ([]shared.Document, error) {
+/*
+Searches the documents the user can view, most relevant first. Each document comes with the draft that matched and the
+highlighted snippets of its name and content. The qualifiers in the query (see ParseSearchQuery) take precedence over the
+filter.
+*/
+func (service *DocumentService) Search(user *shared.User, searchQuery string, filter *shared.DocumentSearchFilter, pagination *shared.Pagination) ([]shared.Document, error) {
+	parsed, err := ParseSearchQuery(searchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err = service.applySearchQuery(user, parsed, filter)
+	if err != nil {
+		return nil, err
+	}
+	searchQuery = parsed.Text
+
 	documentResourceData, err := service.aclService.GetResourceDataForModel(&shared.Document{})
 	if err != nil {
 		return nil, shared.NewInternalServerError("failed to find document information")
@@ -456,21 +1057,35 @@ func (service *DocumentService) Search(user *shared.User, searchQuery string) ([
 		}
 	}
 
+	deniedIds, err := service.aclService.DeniedResourceIds(user, documentResourceData.ResourcePath, "view")
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find accessible documents")
+	}
+
 	// find all of the drafts that you have access to AND match the search criteria
-	drafts, err := service.documentDraftRepository.Search(user.Id, organizationIds, folderIds, documentIds, searchQuery)
+	scope := &SearchScope{
+		OrganizationIds: organizationIds,
+		FolderIds:       folderIds,
+		DocumentIds:     documentIds,
+		DeniedIds:       deniedIds,
+	}
+	results, err := service.searchIndex.Search(user.Id, scope, searchQuery, filter, pagination)
+	if httpErr, ok := err.(*shared.HttpError); ok {
+		return nil, httpErr
+	}
 	if err != nil {
 		return nil, shared.NewInternalServerError("failed to find documents")
 	}
 
 	// din't find anything, so return nothing found
-	if len(drafts) == 0 {
+	if len(results) == 0 {
 		return make([]shared.Document, 0), nil
 	}
 
 	// get the document ids
-	foundDocumentIds := make([]string, len(drafts))
-	for i := 0; i < len(drafts); i++ {
-		foundDocumentIds[i] = drafts[i].DocumentId
+	foundDocumentIds := make([]string, len(results))
+	for i := 0; i < len(results); i++ {
+		foundDocumentIds[i] = results[i].Draft.DocumentId
 	}
 
 	// find the documents
@@ -479,22 +1094,171 @@ func (service *DocumentService) Search(user *shared.User, searchQuery string) ([
 		return nil, shared.NewInternalServerError("failed to find documents")
 	}
 
-	// attach the found draft to the document
-	for j := 0; j < len(drafts); j++ {
-		draft := drafts[j];
+	documentMap := make(map[string]shared.Document)
+	for _, doc := range documents {
+		documentMap[doc.Id] = doc
+	}
+
+	// attach the found draft to the document, keeping the order of the results
+	terms := util.SearchTerms(searchQuery)
+	if filter.Title != nil {
+		terms = append(terms, util.SearchTerms(*filter.Title)...)
+	}
+	found := make([]shared.Document, 0)
+	for _, result := range results {
+		doc, ok := documentMap[result.Draft.DocumentId]
+		if !ok {
+			continue
+		}
+
+		doc.Drafts = []shared.DocumentDraft{result.Draft}
+		doc.Match = &shared.SearchMatch{
+			Score:      result.Score,
+			Highlights: make([]shared.SearchHighlight, 0),
+		}
+		for _, snippet := range util.HighlightSnippets(result.Draft.Name, terms, 1, len(result.Draft.Name)) {
+			doc.Match.Highlights = append(doc.Match.Highlights, shared.SearchHighlight{Field: "name", Snippet: snippet})
+		}
+		for _, snippet := range util.HighlightSnippets(result.Content, terms, searchSnippetCount, searchSnippetRadius) {
+			doc.Match.Highlights = append(doc.Match.Highlights, shared.SearchHighlight{Field: "content", Snippet: snippet})
+		}
+
+		found = append(found, doc)
+	}
+
+	err = service.attachTags(found)
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+/**
+Copies the filter with the qualifiers of the parsed query on top of it, the folder path is resolved to the folders at the
+path and every folder nested in them, and the tag to the documents tagged with it in the organizations of the user
+*/
+func (service *DocumentService) applySearchQuery(user *shared.User, parsed *SearchQuery, filter *shared.DocumentSearchFilter) (*shared.DocumentSearchFilter, error) {
+	applied := shared.DocumentSearchFilter{}
+	if filter != nil {
+		applied = *filter
+	}
+
+	if parsed.Title != nil {
+		applied.Title = parsed.Title
+	}
+	if parsed.Author != nil {
+		applied.Author = parsed.Author
+	}
+	if parsed.Tag != nil {
+		applied.Tag = parsed.Tag
+	}
+	if len(parsed.Status) > 0 {
+		applied.Status = parsed.Status
+	}
+	if parsed.From != nil {
+		applied.From = parsed.From
+	}
+	if parsed.To != nil {
+		applied.To = parsed.To
+	}
+
+	if parsed.FolderPath != nil {
+		folderIds, err := service.folderService.FindIdsByPath(parsed.FolderPath)
+		if err != nil {
+			return nil, shared.NewInternalServerError("failed to find folders")
+		}
+		applied.FolderIds = folderIds
+	}
 
-		for i := 0; i < len(documents); i++ {
-			doc := &documents[i]
+	if applied.Tag != nil {
+		orgs, err := service.organizationService.List(user)
+		if err != nil {
+			return nil, err
+		}
+		organizationIds := make([]string, len(orgs))
+		for i := range orgs {
+			organizationIds[i] = orgs[i].Id
+		}
 
-			if draft.DocumentId == doc.Id {
-				doc.Drafts = []shared.DocumentDraft{draft}
+		documentIds, err := service.tagRepository.FindDocumentIdsByTagName(organizationIds, *applied.Tag)
+		if err != nil {
+			return nil, shared.NewInternalServerError("failed to find tagged documents")
+		}
+		applied.DocumentIds = documentIds
+	}
+
+	return &applied, nil
+}
+
+/**
+Finds every draft the user has created along with its content, regardless of whether the user can still access it
+ */
+func (service *DocumentService) FindDraftsByCreator(creatorId string) ([]shared.DocumentDraft, error) {
+	drafts, err := service.documentDraftRepository.FindByCreatorId(creatorId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document drafts")
+	}
+
+	draftIds := make([]string, len(drafts))
+	for i := 0; i < len(drafts); i++ {
+		draftIds[i] = drafts[i].Id
+	}
+
+	contents, err := service.documentContentRepository.FindByDocumentDraftIds(draftIds)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document content")
+	}
+
+	for i := 0; i < len(drafts); i++ {
+		for j := 0; j < len(contents); j++ {
+			if contents[j].DocumentDraftId == drafts[i].Id {
+				drafts[i].Content = &contents[j]
 			}
 		}
 	}
 
-	return documents, nil
+	return drafts, nil
+}
+
+/*
+Hands all of the drafts of one creator over to another, including the ones that were never published so that the documents
+they belong to keep their drafts
+ */
+func (service *DocumentService) ReassignCreator(fromCreatorId string, toCreatorId string) error {
+	drafts, err := service.documentDraftRepository.FindByCreatorId(fromCreatorId)
+	if err != nil {
+		return err
+	}
+
+	err = service.documentDraftRepository.ReassignCreator(fromCreatorId, toCreatorId)
+	if err != nil {
+		return err
+	}
+
+	indexed := make(map[string]bool)
+	for _, draft := range drafts {
+		if !indexed[draft.DocumentId] {
+			indexed[draft.DocumentId] = true
+			service.indexAfterCommit(draft.DocumentId)
+		}
+	}
+
+	return nil
 }
 
+/*
+Updates the search index once the current transaction commits, so the index never holds changes that were rolled back. A
+failure only leaves the index behind until the next rebuild, so it is logged instead of failing the request.
+*/
+func (service *DocumentService) indexAfterCommit(documentId string) {
+	service.transactionManager.AfterCommit(func() {
+		err := service.searchIndex.IndexDocument(documentId)
+		if err != nil {
+			log.Print(err)
+		}
+	})
+}
 
 func (service *DocumentService) hasAccessToOrganizationOrFolder(user *shared.User, organizationId string, folderId *string, action string) (string, *string, error) {
 	org := service.organizationService.FindById(organizationId)
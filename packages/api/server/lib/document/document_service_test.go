@@ -0,0 +1,40 @@
+package document
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func assertScheduleError(t *testing.T, err error, message string) {
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*shared.HttpError).Status)
+		assert.Equal(t, []string{message}, err.(*shared.HttpError).Errors)
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	now := int64(1000)
+	past := int64(999)
+	publishAt := int64(2000)
+	retractAt := int64(3000)
+	unset := int64(0)
+
+	draft := &shared.DocumentDraft{}
+	assert.Nil(t, validateSchedule(draft, &publishAt, &retractAt, now))
+	assert.Nil(t, validateSchedule(draft, &unset, &unset, now))
+	assertScheduleError(t, validateSchedule(draft, &past, nil, now), "publishAt must be in the future")
+	assertScheduleError(t, validateSchedule(draft, nil, &retractAt, now), "retractAt requires the draft to be published or scheduled to be published")
+	assertScheduleError(t, validateSchedule(draft, &retractAt, &publishAt, now), "retractAt must be after publishAt")
+
+	// the schedule already on the draft counts unless it is changed
+	scheduled := &shared.DocumentDraft{PublishAt: &publishAt}
+	assert.Nil(t, validateSchedule(scheduled, nil, &retractAt, now))
+	assertScheduleError(t, validateSchedule(scheduled, &unset, &retractAt, now), "retractAt requires the draft to be published or scheduled to be published")
+
+	published := &shared.DocumentDraft{PublishedAt: &past}
+	assert.Nil(t, validateSchedule(published, nil, &retractAt, now))
+	assertScheduleError(t, validateSchedule(published, &publishAt, nil, now), "draft is already published")
+	assertScheduleError(t, validateSchedule(published, nil, &past, now), "retractAt must be in the future")
+}
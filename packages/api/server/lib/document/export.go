@@ -0,0 +1,199 @@
+package document
+
+import (
+	"archive/zip"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ExportFormatMarkdown = "md"
+const ExportFormatHtml = "html"
+const ExportFormatPdf = "pdf"
+
+var exportContentTypes = map[string]string{
+	ExportFormatMarkdown: "text/markdown; charset=utf-8",
+	ExportFormatHtml:     "text/html; charset=utf-8",
+	ExportFormatPdf:      "application/pdf",
+}
+
+/*
+The latest draft of a document the user can view, with the names of the organization and folders it is in
+*/
+type DocumentExport struct {
+	Name      string
+	Path      []string
+	Content   string
+	UpdatedAt int64
+}
+
+/*
+A folder and everything in it that the user can view. The folders and documents are kept with the names of the folders
+they are in, starting with the exported folder, so that the zip mirrors the folder tree.
+*/
+type FolderExport struct {
+	Name      string
+	Folders   [][]string
+	Documents []FolderExportDocument
+}
+
+type FolderExportDocument struct {
+	Folder   []string
+	Document *DocumentExport
+}
+
+/**
+Checks the format of an export, markdown is the default
+*/
+func ParseExportFormat(format string) (string, error) {
+	if len(format) == 0 {
+		return ExportFormatMarkdown, nil
+	}
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", shared.NewBadRequestError("format must be md, html or pdf")
+	}
+
+	return format, nil
+}
+
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+/**
+The name of the file for the export, with anything that can not be in a file name replaced
+*/
+func ExportFileName(name string, extension string) string {
+	return fmt.Sprintf("%s.%s", exportFileName(name), extension)
+}
+
+/**
+Writes the document in the format, each format starts with the name and path of the document
+*/
+func WriteDocumentExport(w io.Writer, export *DocumentExport, format string) error {
+	path := strings.Join(export.Path, " / ")
+	updated := time.Unix(0, export.UpdatedAt).UTC().Format(time.RFC3339)
+
+	switch format {
+	case ExportFormatHtml:
+		body, _, err := util.RenderMarkdown(export.Content)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(
+			w,
+			"<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<meta name=\"path\" content=\"%s\">\n<meta name=\"updated\" content=\"%s\">\n</head>\n<body>\n<h1>%s</h1>\n<p class=\"path\">%s</p>\n%s</body>\n</html>\n",
+			html.EscapeString(export.Name), html.EscapeString(path), updated, html.EscapeString(export.Name), html.EscapeString(path), body,
+		)
+		return err
+	case ExportFormatPdf:
+		return util.WriteMarkdownPdf(w, export.Name, path, export.Content)
+	}
+
+	// markdown, with the metadata as front matter
+	_, err := fmt.Fprintf(w, "---\ntitle: %s\npath: %s\nupdated: %s\n---\n\n%s\n", strconv.Quote(export.Name), strconv.Quote(path), updated, export.Content)
+	return err
+}
+
+/**
+Writes a zip of the folder, with a directory for each folder and a file in the format for each document
+*/
+func WriteFolderExport(w io.Writer, export *FolderExport, format string) error {
+	archive := zip.NewWriter(w)
+	directories := newExportDirectories()
+
+	for _, folder := range export.Folders {
+		_, err := archive.Create(directories.directory(folder) + "/")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, doc := range export.Documents {
+		directory := directories.directory(doc.Folder)
+		name := directories.file(directory, doc.Document.Name, format)
+
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     directory + "/" + name,
+			Method:   zip.Deflate,
+			Modified: time.Unix(0, doc.Document.UpdatedAt),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = WriteDocumentExport(file, doc.Document, format)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+/*
+Folders and documents can share a name, but the entries of a zip can not, so the names are made unique within each
+directory by adding a number to the ones that come later, e.g. notes (2).md
+*/
+type exportDirectories struct {
+	directories map[string]string
+	used        map[string]bool
+}
+
+func newExportDirectories() *exportDirectories {
+	return &exportDirectories{
+		directories: make(map[string]string),
+		used:        make(map[string]bool),
+	}
+}
+
+func (directories *exportDirectories) directory(folder []string) string {
+	key := strings.Join(folder, "\x00")
+	if directory, ok := directories.directories[key]; ok {
+		return directory
+	}
+
+	parent := ""
+	if len(folder) > 1 {
+		parent = directories.directory(folder[:len(folder)-1]) + "/"
+	}
+	directory := parent + directories.unique(parent, exportFileName(folder[len(folder)-1]), "")
+	directories.directories[key] = directory
+
+	return directory
+}
+
+func (directories *exportDirectories) file(directory string, name string, extension string) string {
+	return directories.unique(directory+"/", exportFileName(name), "."+extension)
+}
+
+func (directories *exportDirectories) unique(parent string, name string, extension string) string {
+	candidate := name + extension
+	for count := 2; directories.used[strings.ToLower(parent+candidate)]; count++ {
+		candidate = fmt.Sprintf("%s (%d)%s", name, count, extension)
+	}
+	directories.used[strings.ToLower(parent+candidate)] = true
+
+	return candidate
+}
+
+func exportFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+
+	if len(name) == 0 {
+		return "untitled"
+	}
+
+	return name
+}
@@ -0,0 +1,64 @@
+package document_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestWriteDocumentExportAsMarkdown(t *testing.T) {
+	var buffer bytes.Buffer
+	err := document.WriteDocumentExport(&buffer, &document.DocumentExport{
+		Name:      "Deploy \"prod\"",
+		Path:      []string{"Org", "Eng"},
+		Content:   "# Steps",
+		UpdatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano(),
+	}, document.ExportFormatMarkdown)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "---\ntitle: \"Deploy \\\"prod\\\"\"\npath: \"Org / Eng\"\nupdated: 2024-01-02T03:04:05Z\n---\n\n# Steps\n", buffer.String())
+}
+
+func TestWriteFolderExportMirrorsTheFolders(t *testing.T) {
+	newExport := func(name string, content string) *document.DocumentExport {
+		return &document.DocumentExport{Name: name, Path: []string{"Org"}, Content: content}
+	}
+
+	var buffer bytes.Buffer
+	err := document.WriteFolderExport(&buffer, &document.FolderExport{
+		Name:    "Eng",
+		Folders: [][]string{{"Eng"}, {"Eng", "Run/books"}, {"Eng", "empty"}},
+		Documents: []document.FolderExportDocument{
+			{Folder: []string{"Eng"}, Document: newExport("notes", "first")},
+			{Folder: []string{"Eng"}, Document: newExport("Notes", "second")},
+			{Folder: []string{"Eng", "Run/books"}, Document: newExport("deploy?", "third")},
+		},
+	}, document.ExportFormatMarkdown)
+	assert.Nil(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.Nil(t, err)
+
+	names := make([]string, 0)
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{
+		"Eng/",
+		"Eng/Run-books/",
+		"Eng/empty/",
+		"Eng/notes.md",
+		"Eng/Notes (2).md",
+		"Eng/Run-books/deploy-.md",
+	}, names)
+
+	reader, err := archive.File[4].Open()
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "second")
+}
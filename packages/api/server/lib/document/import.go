@@ -0,0 +1,358 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"gopkg.in/yaml.v2"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxImportFiles = 2000
+const maxImportFileSize = 5 << 20
+const maxImportSize = 50 << 20
+const maxImportNameLength = 255
+
+/*
+What an import created, or would create when it is a dry run. The paths are the paths in the zip, folders are listed
+before the folders and documents in them.
+*/
+type ImportReport struct {
+	DryRun    bool               `json:"dryRun"`
+	Folders   []ImportedFolder   `json:"folders"`
+	Documents []ImportedDocument `json:"documents"`
+	Skipped   []string           `json:"skipped"`
+}
+
+type ImportedFolder struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Id   string `json:"id,omitempty"`
+}
+
+type ImportedDocument struct {
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Published bool   `json:"published"`
+	Id        string `json:"id,omitempty"`
+}
+
+/*
+The front matter of an imported markdown file, anything else in it is ignored
+*/
+type importFrontMatter struct {
+	Title   string `yaml:"title"`
+	Publish bool   `yaml:"publish"`
+}
+
+type importDocument struct {
+	path    string
+	folder  string
+	name    string
+	content string
+	publish bool
+}
+
+type importPlan struct {
+	folders   []string
+	documents []importDocument
+	skipped   []string
+}
+
+/*
+Recreates a zip of directories and markdown files as folders and documents in the organization, inside of the folder if
+one is given. Everything is created in one transaction, so either the whole zip is imported or nothing is. A dry run only
+checks the zip and the user's access, and reports what would be created.
+*/
+func (service *DocumentService) Import(
+	user *shared.User, organizationId string, folderId *string, archive *zip.Reader, dryRun bool,
+) (*ImportReport, error) {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return nil, shared.NewNotFoundError("could not find organization")
+	}
+	// everything is created in the folder when one is given, so that is where the access is checked
+	var target interface{} = org
+	if folderId != nil {
+		parent := service.folderService.FindById(*folderId)
+		if parent == nil || parent.OrganizationId != org.Id {
+			return nil, shared.NewNotFoundError("could not find folder")
+		}
+		target = parent
+	}
+
+	plan, details := planImport(archive)
+	if len(details) > 0 {
+		return nil, shared.NewBadRequestErrorWithDetails(details...)
+	}
+
+	// check access up front so a dry run fails the same way the import would
+	if len(plan.folders) > 0 && !service.aclService.UserCanAccessResourceByModel(user, target, "create:folder") {
+		return nil, shared.NewForbiddenError("you do not have permission to create a folder")
+	}
+	if len(plan.documents) > 0 {
+		_, _, err := service.hasAccessToOrganizationOrFolder(user, org.Id, folderId, "create:document")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := newImportReport(plan, dryRun)
+	if dryRun {
+		return report, nil
+	}
+
+	_, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		// the folders are sorted so the parent of each folder is always created first
+		folderIds := map[string]*string{"": folderId}
+		for i, folderPath := range plan.folders {
+			parentPath := path.Dir(folderPath)
+			if parentPath == "." {
+				parentPath = ""
+			}
+
+			f, err := injectedService.folderService.Create(user, path.Base(folderPath), org.Id, folderIds[parentPath])
+			if err != nil {
+				return nil, err
+			}
+			folderIds[folderPath] = &f.Id
+			report.Folders[i].Id = f.Id
+		}
+
+		for i, doc := range plan.documents {
+			created, err := injectedService.Create(user, org.Id, folderIds[doc.folder], doc.name, doc.content)
+			if err != nil {
+				return nil, err
+			}
+			report.Documents[i].Id = created.Id
+
+			if doc.publish {
+				_, err = injectedService.Update(user, created.Id, created.Drafts[0].Id, nil, nil, true, false, nil, nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		if _, ok := err.(*shared.HttpError); ok {
+			return nil, err
+		}
+		return nil, shared.NewInternalServerError("failed to import")
+	}
+
+	return report, nil
+}
+
+/**
+Reads the zip into the folders and documents to create. Every problem with the files is returned at once, with the path
+of the file as the token.
+*/
+func planImport(archive *zip.Reader) (*importPlan, []shared.HttpErrorDetail) {
+	plan := &importPlan{
+		folders:   make([]string, 0),
+		documents: make([]importDocument, 0),
+		skipped:   make([]string, 0),
+	}
+	details := make([]shared.HttpErrorDetail, 0)
+	folders := make(map[string]bool)
+	var size uint64
+
+	addFolder := func(folder string) {
+		for ; folder != "." && folder != "" && !folders[folder]; folder = path.Dir(folder) {
+			folders[folder] = true
+		}
+	}
+
+	for _, file := range archive.File {
+		name := strings.Trim(strings.ReplaceAll(file.Name, "\\", "/"), "/")
+		if len(name) == 0 || isHiddenImportPath(name) {
+			plan.skipped = append(plan.skipped, file.Name)
+			continue
+		}
+
+		if file.FileInfo().IsDir() {
+			addFolder(name)
+			continue
+		}
+
+		extension := strings.ToLower(path.Ext(name))
+		if extension != ".md" && extension != ".markdown" {
+			plan.skipped = append(plan.skipped, file.Name)
+			continue
+		}
+
+		if len(plan.documents) == maxImportFiles {
+			details = append(details, newImportErrorDetail(file.Name, fmt.Sprintf("an import can have at most %d documents", maxImportFiles)))
+			break
+		}
+		if file.UncompressedSize64 > maxImportFileSize {
+			details = append(details, newImportErrorDetail(file.Name, fmt.Sprintf("files can be at most %d bytes", maxImportFileSize)))
+			continue
+		}
+
+		// the whole zip is held in memory until it is imported, so the files together are limited as well
+		if size+file.UncompressedSize64 > maxImportSize {
+			details = append(details, newImportErrorDetail(file.Name, fmt.Sprintf("an import can be at most %d bytes uncompressed", maxImportSize)))
+			break
+		}
+
+		content, err := readImportFile(file)
+		if err != nil {
+			details = append(details, newImportErrorDetail(file.Name, "could not read the file"))
+			continue
+		}
+
+		size += uint64(len(content))
+		if size > maxImportSize {
+			details = append(details, newImportErrorDetail(file.Name, fmt.Sprintf("an import can be at most %d bytes uncompressed", maxImportSize)))
+			break
+		}
+		if !utf8.Valid(content) {
+			details = append(details, newImportErrorDetail(file.Name, "files must be utf-8"))
+			continue
+		}
+
+		frontMatter, body, err := parseFrontMatter(string(content))
+		if err != nil {
+			details = append(details, newImportErrorDetail(file.Name, fmt.Sprintf("invalid front matter: %s", err.Error())))
+			continue
+		}
+
+		doc := importDocument{
+			path:    name,
+			folder:  path.Dir(name),
+			name:    strings.TrimSpace(frontMatter.Title),
+			content: body,
+			publish: frontMatter.Publish,
+		}
+		if doc.folder == "." {
+			doc.folder = ""
+		}
+		if len(doc.name) == 0 {
+			doc.name = strings.TrimSuffix(path.Base(name), path.Ext(name))
+		}
+		if utf8.RuneCountInString(doc.name) > maxImportNameLength {
+			details = append(details, newImportErrorDetail(file.Name, fmt.Sprintf("titles can be at most %d characters", maxImportNameLength)))
+			continue
+		}
+
+		addFolder(doc.folder)
+		plan.documents = append(plan.documents, doc)
+	}
+
+	for folder := range folders {
+		if utf8.RuneCountInString(path.Base(folder)) > maxImportNameLength {
+			details = append(details, newImportErrorDetail(folder, fmt.Sprintf("folder names can be at most %d characters", maxImportNameLength)))
+		}
+		plan.folders = append(plan.folders, folder)
+	}
+
+	// sorting puts every folder before the folders in it
+	sort.Strings(plan.folders)
+	sort.SliceStable(plan.documents, func(i, j int) bool {
+		return plan.documents[i].path < plan.documents[j].path
+	})
+
+	return plan, details
+}
+
+/**
+Splits the yaml front matter, between --- lines at the very start of the file, from the markdown after it. Files without
+front matter are all markdown.
+*/
+func parseFrontMatter(content string) (*importFrontMatter, string, error) {
+	frontMatter := &importFrontMatter{}
+
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return frontMatter, content, nil
+	}
+
+	end := strings.Index(normalized[4:], "\n---")
+	if end == -1 {
+		return nil, "", fmt.Errorf("the front matter is never closed with ---")
+	}
+	end += 4
+
+	err := yaml.Unmarshal([]byte(normalized[4:end]), frontMatter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// skip the closing --- and the rest of its line
+	body := normalized[end+4:]
+	if index := strings.Index(body, "\n"); index != -1 {
+		body = body[index+1:]
+	} else {
+		body = ""
+	}
+
+	return frontMatter, strings.TrimLeft(body, "\n"), nil
+}
+
+/**
+Leaves out hidden files and directories, e.g. .git, along with the metadata macOS adds to zips
+*/
+func isHiddenImportPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") || segment == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+func readImportFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// the size in the header can not be trusted, so never read more than the limit
+	var buffer bytes.Buffer
+	_, err = buffer.ReadFrom(io.LimitReader(reader, maxImportFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if buffer.Len() > maxImportFileSize {
+		return nil, fmt.Errorf("file is larger than its header says")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func newImportErrorDetail(name string, message string) shared.HttpErrorDetail {
+	return shared.HttpErrorDetail{
+		Message: message,
+		Token:   name,
+	}
+}
+
+func newImportReport(plan *importPlan, dryRun bool) *ImportReport {
+	report := &ImportReport{
+		DryRun:    dryRun,
+		Folders:   make([]ImportedFolder, len(plan.folders)),
+		Documents: make([]ImportedDocument, len(plan.documents)),
+		Skipped:   plan.skipped,
+	}
+	for i, folder := range plan.folders {
+		report.Folders[i] = ImportedFolder{Path: folder, Name: path.Base(folder)}
+	}
+	for i, doc := range plan.documents {
+		report.Documents[i] = ImportedDocument{Path: doc.path, Name: doc.name, Published: doc.publish}
+	}
+
+	return report
+}
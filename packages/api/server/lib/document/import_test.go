@@ -0,0 +1,106 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func newImportArchive(t *testing.T, names []string, files map[string]string) *zip.Reader {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, name := range names {
+		file, err := writer.Create(name)
+		assert.Nil(t, err)
+		_, err = file.Write([]byte(files[name]))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	assert.Nil(t, err)
+	return archive
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	frontMatter, body, err := parseFrontMatter("---\r\ntitle: \"Deploy: prod\"\r\npublish: true\r\ntags: [ops]\r\n---\r\n\r\n# Deploy\r\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "Deploy: prod", frontMatter.Title)
+	assert.True(t, frontMatter.Publish)
+	assert.Equal(t, "# Deploy\n", body)
+}
+
+func TestParseFrontMatterWithoutFrontMatter(t *testing.T) {
+	frontMatter, body, err := parseFrontMatter("# Deploy\n\n---\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "", frontMatter.Title)
+	assert.False(t, frontMatter.Publish)
+	assert.Equal(t, "# Deploy\n\n---\n", body)
+}
+
+func TestParseFrontMatterFailsWhenNotClosed(t *testing.T) {
+	_, _, err := parseFrontMatter("---\ntitle: Deploy\n# Deploy\n")
+	assert.NotNil(t, err)
+}
+
+func TestPlanImport(t *testing.T) {
+	names := []string{
+		"eng/runbooks/deploy.md",
+		"eng/overview.MD",
+		"eng/empty/",
+		"eng/diagram.png",
+		"__MACOSX/eng/._overview.md",
+		".git/config",
+		"readme.md",
+	}
+	plan, details := planImport(newImportArchive(t, names, map[string]string{
+		"eng/runbooks/deploy.md": "---\ntitle: Deploy to prod\npublish: true\n---\n# Deploy",
+		"eng/overview.MD":        "# Overview",
+		"readme.md":              "---\ntitle: \"  \"\n---\nhello",
+	}))
+
+	assert.Len(t, details, 0)
+	assert.Equal(t, []string{"eng", "eng/empty", "eng/runbooks"}, plan.folders)
+	assert.Equal(t, []importDocument{
+		{path: "eng/overview.MD", folder: "eng", name: "overview", content: "# Overview"},
+		{path: "eng/runbooks/deploy.md", folder: "eng/runbooks", name: "Deploy to prod", content: "# Deploy", publish: true},
+		{path: "readme.md", folder: "", name: "readme", content: "hello"},
+	}, plan.documents)
+	assert.Equal(t, []string{"eng/diagram.png", "__MACOSX/eng/._overview.md", ".git/config"}, plan.skipped)
+}
+
+func TestPlanImportReportsEveryBrokenFile(t *testing.T) {
+	names := []string{"ok.md", "latin1.md", "broken.md", "../escape.md"}
+	_, details := planImport(newImportArchive(t, names, map[string]string{
+		"ok.md":        "fine",
+		"latin1.md":    "caf\xe9",
+		"broken.md":    "---\ntitle: [\n---\n",
+		"../escape.md": "nope",
+	}))
+
+	assert.Len(t, details, 2)
+	assert.Equal(t, "latin1.md", details[0].Token)
+	assert.Equal(t, "files must be utf-8", details[0].Message)
+	assert.Equal(t, "broken.md", details[1].Token)
+	assert.Contains(t, details[1].Message, "invalid front matter")
+}
+
+func TestPlanImportLimitsTheUnzippedSize(t *testing.T) {
+	content := strings.Repeat("a", maxImportFileSize)
+	names := make([]string, 0)
+	files := make(map[string]string)
+	for i := 0; i <= maxImportSize/maxImportFileSize; i++ {
+		name := fmt.Sprintf("%d.md", i)
+		names = append(names, name)
+		files[name] = content
+	}
+
+	_, details := planImport(newImportArchive(t, names, files))
+
+	assert.Len(t, details, 1)
+	assert.Equal(t, names[len(names)-1], details[0].Token)
+	assert.Contains(t, details[0].Message, "uncompressed")
+}
@@ -0,0 +1,283 @@
+package document
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const documentLinkIdPattern = `([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`
+
+/*
+Internal links are written as [[doc:<id>]] or as a url to /document/<id>. The url has to be relative, or go to the
+origin of the app, so a url of another site that happens to have the same path is not a link.
+*/
+var documentLinkPattern = regexp.MustCompile(`\[\[doc:` + documentLinkIdPattern + `\]\]`)
+var documentUrlPattern = regexp.MustCompile(`(?:^|[\s(<"'])((?i:https?://[^\s/()<>"']+)?)/document/` + documentLinkIdPattern + `\b`)
+
+/**
+Parses the ids of the documents the content links to, in the order they first appear. Links of the document to itself
+are left out, and so are the links in code, which are only examples. Urls with a host only count when it is the origin.
+*/
+func ParseDocumentLinks(content string, documentId string, origin string) []string {
+	type match struct {
+		position int
+		id       string
+	}
+
+	content = blankCode(content)
+	origin = strings.TrimSuffix(origin, "/")
+
+	matches := make([]match, 0)
+	for _, indexes := range documentLinkPattern.FindAllStringSubmatchIndex(content, -1) {
+		matches = append(matches, match{
+			position: indexes[0],
+			id:       strings.ToLower(content[indexes[2]:indexes[3]]),
+		})
+	}
+	for _, indexes := range documentUrlPattern.FindAllStringSubmatchIndex(content, -1) {
+		host := content[indexes[2]:indexes[3]]
+		if len(host) > 0 && (len(origin) == 0 || !strings.EqualFold(host, origin)) {
+			continue
+		}
+		matches = append(matches, match{
+			position: indexes[2],
+			id:       strings.ToLower(content[indexes[4]:indexes[5]]),
+		})
+	}
+
+	// the patterns are matched one after the other, so put the matches back in the order of the content
+	for i := 1; i < len(matches); i++ {
+		for j := i; j > 0 && matches[j].position < matches[j-1].position; j-- {
+			matches[j], matches[j-1] = matches[j-1], matches[j]
+		}
+	}
+
+	seen := map[string]bool{strings.ToLower(documentId): true}
+	ids := make([]string, 0)
+	for _, m := range matches {
+		if seen[m.id] {
+			continue
+		}
+		seen[m.id] = true
+		ids = append(ids, m.id)
+	}
+
+	return ids
+}
+
+/**
+Replaces the fenced code blocks and the code spans of the markdown with spaces, keeping the line breaks so the rest of
+the content stays where it was
+*/
+func blankCode(content string) string {
+	lines := strings.SplitAfter(content, "\n")
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if len(fence) == 0 {
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				fence = trimmed[:3]
+				lines[i] = blank(line)
+				continue
+			}
+			lines[i] = blankCodeSpans(line)
+			continue
+		}
+
+		// a fence that is never closed runs to the end of the content
+		if strings.HasPrefix(trimmed, fence) {
+			fence = ""
+		}
+		lines[i] = blank(line)
+	}
+
+	return strings.Join(lines, "")
+}
+
+/**
+A code span starts with a run of backticks and ends with the next run of the same length
+*/
+func blankCodeSpans(line string) string {
+	runes := []rune(line)
+	for start := 0; start < len(runes); {
+		if runes[start] != '`' {
+			start++
+			continue
+		}
+
+		length := backtickRun(runes, start)
+		end := -1
+		for i := start + length; i < len(runes); {
+			if runes[i] != '`' {
+				i++
+				continue
+			}
+			closing := backtickRun(runes, i)
+			if closing == length {
+				end = i + closing
+				break
+			}
+			i += closing
+		}
+
+		if end < 0 {
+			start += length
+			continue
+		}
+		for i := start; i < end; i++ {
+			runes[i] = ' '
+		}
+		start = end
+	}
+
+	return string(runes)
+}
+
+func backtickRun(runes []rune, start int) int {
+	length := 0
+	for start+length < len(runes) && runes[start+length] == '`' {
+		length++
+	}
+	return length
+}
+
+func blank(line string) string {
+	return strings.Repeat(" ", len(strings.TrimRight(line, "\r\n"))) + line[len(strings.TrimRight(line, "\r\n")):]
+}
+
+/**
+Finds the documents the user can view whose latest draft, as the user sees it, links to the document
+*/
+func (service *DocumentService) FindBacklinks(user *shared.User, documentId string) ([]shared.Document, error) {
+	document := service.documentRepository.FindById(documentId)
+	if document == nil {
+		return nil, shared.NewNotFoundError("could not find document")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, document, "view")
+	if !canAccess {
+		return nil, shared.NewForbiddenError("can not view document")
+	}
+
+	links, err := service.documentLinkRepository.FindByTargetDocumentId(document.Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find backlinks")
+	}
+
+	// links are kept for every draft, so only the drafts that are linking now count
+	linkingDraftIds := make(map[string]bool)
+	sources := make(map[string]bool)
+	sourceIds := make([]string, 0)
+	for _, link := range links {
+		linkingDraftIds[link.DocumentDraftId] = true
+		if !sources[link.DocumentId] {
+			sources[link.DocumentId] = true
+			sourceIds = append(sourceIds, link.DocumentId)
+		}
+	}
+
+	backlinks := make([]shared.Document, 0)
+	if len(sourceIds) == 0 {
+		return backlinks, nil
+	}
+
+	// deleted documents are not found, so they no longer link anywhere
+	documents, err := service.documentRepository.FindByIds(sourceIds...)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find backlinks")
+	}
+
+	accessible := make([]shared.Document, 0)
+	for i := range documents {
+		if service.aclService.UserCanAccessResourceByModel(user, &documents[i], "view") {
+			accessible = append(accessible, documents[i])
+		}
+	}
+
+	err = service.documentDraftRepository.FindAndAttachLatestAccessibleDraftForDocuments(user.Id, accessible)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find backlinks")
+	}
+
+	for _, source := range accessible {
+		if len(source.Drafts) == 0 || !linkingDraftIds[source.Drafts[0].Id] {
+			continue
+		}
+		backlinks = append(backlinks, source)
+	}
+
+	err = service.attachTags(backlinks)
+	if err != nil {
+		return nil, err
+	}
+
+	return backlinks, nil
+}
+
+/**
+Finds where each link in the latest draft the user can view goes. A link is broken when the document it goes to was
+deleted or the user can not view it.
+*/
+func (service *DocumentService) FindLinks(user *shared.User, documentId string) ([]shared.DocumentLinkStatus, error) {
+	document, err := service.FindDocument(user, documentId)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := service.documentLinkRepository.FindByDraftId(document.Drafts[0].Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document links")
+	}
+
+	statuses := make([]shared.DocumentLinkStatus, len(links))
+	for i, link := range links {
+		statuses[i] = service.findLinkStatus(user, link.TargetDocumentId)
+	}
+
+	return statuses, nil
+}
+
+func (service *DocumentService) findLinkStatus(user *shared.User, targetDocumentId string) shared.DocumentLinkStatus {
+	status := shared.DocumentLinkStatus{
+		TargetDocumentId: targetDocumentId,
+		Status:           shared.DocumentLinkStatusOk,
+	}
+
+	target := service.documentRepository.FindById(targetDocumentId)
+	if target == nil {
+		status.Status = shared.DocumentLinkStatusDeleted
+		status.Broken = true
+		return status
+	}
+
+	// the name of a document the user can not view is not shown, so the target is left out as well
+	if !service.aclService.UserCanAccessResourceByModel(user, target, "view") {
+		status.Status = shared.DocumentLinkStatusInaccessible
+		status.Broken = true
+		return status
+	}
+
+	targets := []shared.Document{*target}
+	err := service.documentDraftRepository.FindAndAttachLatestAccessibleDraftForDocuments(user.Id, targets)
+	if err != nil || len(targets[0].Drafts) == 0 {
+		status.Status = shared.DocumentLinkStatusInaccessible
+		status.Broken = true
+		return status
+	}
+
+	status.Target = &targets[0]
+
+	return status
+}
+
+/**
+Records the links in the content of the draft, replacing the ones it had before. Links to documents that are deleted or
+that the reader can not view are kept as well, since that is only known once the links are looked at.
+*/
+func (service *DocumentService) replaceLinks(documentId string, draftId string, content string) error {
+	ids := ParseDocumentLinks(content, documentId, os.Getenv("APP_ORIGIN"))
+
+	return service.documentLinkRepository.ReplaceForDraft(documentId, draftId, ids)
+}
@@ -0,0 +1,64 @@
+package document
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseDocumentLinks(t *testing.T) {
+	self := "6f1c9b62-3f0a-4c43-9d0e-6a0e0c0f8a11"
+	first := "0b8a4a7e-2c55-4f0f-9a0b-1f4e4e3c2d10"
+	second := "d2f0e1a4-7b6c-4d3e-8f9a-0a1b2c3d4e5f"
+
+	origin := "https://app.mentordoc.com"
+
+	content := "see https://app.mentordoc.com/document/" + second + "/render and [[doc:" + first + "]], " +
+		"again [[doc:" + second + "]] and this one [[doc:" + self + "]]"
+	assert.Equal(t, []string{second, first}, ParseDocumentLinks(content, self, origin))
+
+	// ids are compared in lower case
+	assert.Equal(t, []string{first}, ParseDocumentLinks("[[doc:0B8A4A7E-2C55-4F0F-9A0B-1F4E4E3C2D10]]", self, origin))
+
+	// anything that is not a full id is not a link
+	assert.Equal(t, []string{}, ParseDocumentLinks("[[doc:0b8a4a7e]] /document/list /document/"+first+"0", self, origin))
+	assert.Equal(t, []string{}, ParseDocumentLinks("", self, origin))
+}
+
+func TestParseDocumentLinksOnlyFollowsUrlsOfTheApp(t *testing.T) {
+	self := "6f1c9b62-3f0a-4c43-9d0e-6a0e0c0f8a11"
+	first := "0b8a4a7e-2c55-4f0f-9a0b-1f4e4e3c2d10"
+	second := "d2f0e1a4-7b6c-4d3e-8f9a-0a1b2c3d4e5f"
+	origin := "https://app.mentordoc.com/"
+
+	// relative urls are always links
+	assert.Equal(t, []string{first, second}, ParseDocumentLinks("[runbook](/document/"+first+") <a href=\"/document/"+second+"\">", self, origin))
+
+	// urls of other sites are not, even with the same path
+	content := "https://other.example/document/" + first + " //other.example/document/" + first +
+		" https://app.mentordoc.com.other.example/document/" + first + " other.example/document/" + first
+	assert.Equal(t, []string{}, ParseDocumentLinks(content, self, origin))
+
+	assert.Equal(t, []string{second}, ParseDocumentLinks("HTTPS://APP.MENTORDOC.COM/document/"+second, self, origin))
+
+	// without an origin only the relative urls count
+	assert.Equal(t, []string{first}, ParseDocumentLinks("https://app.mentordoc.com/document/"+second+" /document/"+first, self, ""))
+}
+
+func TestParseDocumentLinksSkipsCode(t *testing.T) {
+	self := "6f1c9b62-3f0a-4c43-9d0e-6a0e0c0f8a11"
+	first := "0b8a4a7e-2c55-4f0f-9a0b-1f4e4e3c2d10"
+	second := "d2f0e1a4-7b6c-4d3e-8f9a-0a1b2c3d4e5f"
+
+	content := "# Linking\n" +
+		"write `[[doc:" + first + "]]` to link, or ``/document/" + first + "``\n" +
+		"```markdown\n" +
+		"[[doc:" + first + "]]\n" +
+		"```\n" +
+		"see [[doc:" + second + "]]\n" +
+		"~~~\n" +
+		"/document/" + first + "\n"
+	assert.Equal(t, []string{second}, ParseDocumentLinks(content, self, ""))
+
+	// a lone backtick does not start a code span
+	assert.Equal(t, []string{first}, ParseDocumentLinks("it's a ` then [[doc:"+first+"]]", self, ""))
+}
@@ -0,0 +1,34 @@
+package document
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+)
+
+/*
+Searches with the FULLTEXT indexes on the draft name and content, MySQL keeps those up to date by itself
+*/
+type MySqlSearchIndex struct {
+	documentDraftRepository *DocumentDraftRepository
+}
+
+func NewMySqlSearchIndex(documentDraftRepository *DocumentDraftRepository) *MySqlSearchIndex {
+	return &MySqlSearchIndex{
+		documentDraftRepository: documentDraftRepository,
+	}
+}
+
+func (index *MySqlSearchIndex) Search(userId string, scope *SearchScope, searchQuery string, filter *shared.DocumentSearchFilter, pagination *shared.Pagination) ([]DraftSearchResult, error) {
+	return index.documentDraftRepository.Search(userId, scope.OrganizationIds, scope.FolderIds, scope.DocumentIds, scope.DeniedIds, searchQuery, filter, pagination)
+}
+
+func (index *MySqlSearchIndex) IndexDocument(documentId string) error {
+	return nil
+}
+
+func (index *MySqlSearchIndex) Rebuild() error {
+	return nil
+}
+
+func (index *MySqlSearchIndex) Close() error {
+	return nil
+}
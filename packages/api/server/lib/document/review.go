@@ -0,0 +1,465 @@
+package document
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	uuid "github.com/satori/go.uuid"
+)
+
+func (service *DocumentService) SetOrganizationReviewPolicy(user *shared.User, organizationId string, requiredApprovals int) (*shared.ReviewPolicy, error) {
+	return service.setReviewPolicy(user, organizationId, nil, requiredApprovals)
+}
+
+func (service *DocumentService) SetFolderReviewPolicy(user *shared.User, folderId string, requiredApprovals int) (*shared.ReviewPolicy, error) {
+	fold := service.folderService.FindById(folderId)
+	if fold == nil {
+		return nil, shared.NewNotFoundError("could not find folder")
+	}
+
+	return service.setReviewPolicy(user, fold.OrganizationId, &fold.Id, requiredApprovals)
+}
+
+func (service *DocumentService) DeleteOrganizationReviewPolicy(user *shared.User, organizationId string) (*shared.ReviewPolicy, error) {
+	return service.deleteReviewPolicy(user, organizationId, nil)
+}
+
+func (service *DocumentService) DeleteFolderReviewPolicy(user *shared.User, folderId string) (*shared.ReviewPolicy, error) {
+	fold := service.folderService.FindById(folderId)
+	if fold == nil {
+		return nil, shared.NewNotFoundError("could not find folder")
+	}
+
+	return service.deleteReviewPolicy(user, fold.OrganizationId, &fold.Id)
+}
+
+/**
+Submits a draft for review, only the creator of the draft can do so before it is published
+*/
+func (service *DocumentService) RequestReview(user *shared.User, documentId string, draftId string) (*shared.ReviewStatus, error) {
+	document, draft, err := service.findReviewableDraft(user, documentId, draftId, "modify")
+	if err != nil {
+		return nil, err
+	}
+
+	if draft.CreatorId != user.Id {
+		return nil, shared.NewForbiddenError("can not request a review of draft")
+	}
+
+	if service.documentReviewRepository.FindRequestByDraftId(draft.Id) != nil {
+		return nil, shared.NewBadRequestError("draft is already in review")
+	}
+
+	request := &shared.DocumentReviewRequest{
+		DocumentDraftId: draft.Id,
+		RequesterId:     user.Id,
+	}
+	request.Id = uuid.NewV4().String()
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		err := injectedService.documentReviewRepository.InsertRequest(request)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(draft.Id, "document_draft", user.Id, "review_requested")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to request review")
+	}
+
+	return service.findReviewStatus(document, draft)
+}
+
+/**
+Takes a draft out of review, dismissing the reviews it got so far
+*/
+func (service *DocumentService) WithdrawReview(user *shared.User, documentId string, draftId string) (*shared.ReviewStatus, error) {
+	document, draft, err := service.findReviewableDraft(user, documentId, draftId, "modify")
+	if err != nil {
+		return nil, err
+	}
+
+	if draft.CreatorId != user.Id {
+		return nil, shared.NewForbiddenError("can not withdraw review of draft")
+	}
+
+	request := service.documentReviewRepository.FindRequestByDraftId(draft.Id)
+	if request == nil {
+		return nil, shared.NewBadRequestError("draft is not in review")
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		err := injectedService.documentReviewRepository.DeleteRequest(request)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.documentReviewRepository.DeleteReviewsByDraftId(draft.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(draft.Id, "document_draft", user.Id, "review_withdrawn")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to withdraw review")
+	}
+
+	return service.findReviewStatus(document, draft)
+}
+
+/**
+Approves, or requests changes to, a draft in review. Reviewers can review again, only their latest review counts.
+*/
+func (service *DocumentService) Review(user *shared.User, documentId string, draftId string, decision string, comment *string) (*shared.DocumentReview, error) {
+	if decision != shared.ReviewDecisionApprove && decision != shared.ReviewDecisionRequestChanges {
+		return nil, shared.NewBadRequestError("invalid review decision")
+	}
+
+	_, draft, err := service.findReviewableDraft(user, documentId, draftId, "approve")
+	if err != nil {
+		return nil, err
+	}
+
+	if service.documentReviewRepository.FindRequestByDraftId(draft.Id) == nil {
+		return nil, shared.NewBadRequestError("draft is not in review")
+	}
+
+	if draft.CreatorId == user.Id {
+		return nil, shared.NewForbiddenError("can not review own draft")
+	}
+
+	review := &shared.DocumentReview{
+		DocumentDraftId: draft.Id,
+		ReviewerId:      user.Id,
+		Decision:        decision,
+		Comment:         comment,
+	}
+	review.Id = uuid.NewV4().String()
+
+	action := "approved"
+	if decision == shared.ReviewDecisionRequestChanges {
+		action = "changes_requested"
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		err := injectedService.documentReviewRepository.InsertReview(review)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(draft.Id, "document_draft", user.Id, action)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to review draft")
+	}
+
+	return review, nil
+}
+
+func (service *DocumentService) FindReviewStatus(user *shared.User, documentId string, draftId string) (*shared.ReviewStatus, error) {
+	document, draft, err := service.findReviewableDraft(user, documentId, draftId, "view")
+	if err != nil {
+		return nil, err
+	}
+
+	return service.findReviewStatus(document, draft)
+}
+
+/**
+The policy that applies to the document: the policy of the nearest folder the document is in, otherwise the policy of
+the organization. A folder policy that requires no approvals lifts the policy of the organization for that folder.
+*/
+func (service *DocumentService) FindReviewPolicy(document *shared.Document) (*shared.ReviewPolicy, error) {
+	folderIds := make([]string, 0)
+	if document.FolderId != nil {
+		// the ancestry is the folder first, then its parents
+		folders, err := service.folderService.FindAncestry(*document.FolderId)
+		if err != nil {
+			return nil, shared.NewInternalServerError("failed to find review policy")
+		}
+		for _, fold := range folders {
+			folderIds = append(folderIds, fold.Id)
+		}
+	}
+
+	policies, err := service.documentReviewRepository.FindPolicies(document.OrganizationId, folderIds)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find review policy")
+	}
+
+	for _, folderId := range folderIds {
+		for i := range policies {
+			if policies[i].FolderId != nil && *policies[i].FolderId == folderId {
+				return &policies[i], nil
+			}
+		}
+	}
+	for i := range policies {
+		if policies[i].FolderId == nil {
+			return &policies[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (service *DocumentService) setReviewPolicy(user *shared.User, organizationId string, folderId *string, requiredApprovals int) (*shared.ReviewPolicy, error) {
+	if requiredApprovals < 0 {
+		return nil, shared.NewBadRequestError("required approvals can not be negative")
+	}
+
+	policy, err := service.findPolicyToManage(user, organizationId, folderId)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		if policy != nil {
+			policy.RequiredApprovals = requiredApprovals
+			err := injectedService.documentReviewRepository.UpdatePolicy(policy)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = injectedService.resourceHistoryService.Create(policy.Id, "review_policy", user.Id, "updated")
+			if err != nil {
+				return nil, err
+			}
+
+			return policy, nil
+		}
+
+		created := &shared.ReviewPolicy{
+			OrganizationId:    organizationId,
+			FolderId:          folderId,
+			RequiredApprovals: requiredApprovals,
+		}
+		created.Id = uuid.NewV4().String()
+
+		err := injectedService.documentReviewRepository.InsertPolicy(created)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(created.Id, "review_policy", user.Id, "created")
+		if err != nil {
+			return nil, err
+		}
+
+		return created, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to set review policy")
+	}
+
+	return res.(*shared.ReviewPolicy), nil
+}
+
+func (service *DocumentService) deleteReviewPolicy(user *shared.User, organizationId string, folderId *string) (*shared.ReviewPolicy, error) {
+	policy, err := service.findPolicyToManage(user, organizationId, folderId)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, shared.NewNotFoundError("could not find review policy")
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		deletedAt := util.NowUnix()
+		policy.DeletedAt = &deletedAt
+		err := injectedService.documentReviewRepository.UpdatePolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = injectedService.resourceHistoryService.Create(policy.Id, "review_policy", user.Id, "deleted")
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete review policy")
+	}
+
+	return policy, nil
+}
+
+/**
+Finds the policy set on exactly the organization or folder, if there is one. Policies are managed by the users that can
+modify the organization, so contributors can not lift the policy of their own folders.
+*/
+func (service *DocumentService) findPolicyToManage(user *shared.User, organizationId string, folderId *string) (*shared.ReviewPolicy, error) {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return nil, shared.NewNotFoundError("could not find organization")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, org, "modify")
+	if !canAccess {
+		return nil, shared.NewForbiddenError("can not manage review policy")
+	}
+
+	folderIds := make([]string, 0)
+	if folderId != nil {
+		folderIds = append(folderIds, *folderId)
+	}
+
+	policies, err := service.documentReviewRepository.FindPolicies(organizationId, folderIds)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find review policy")
+	}
+
+	for i := range policies {
+		samePolicy := (folderId == nil && policies[i].FolderId == nil) ||
+			(folderId != nil && policies[i].FolderId != nil && *policies[i].FolderId == *folderId)
+		if samePolicy {
+			return &policies[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+/**
+Finds the document and a draft of it that has not been published yet, as long as the user can take the action on the
+document and see the draft
+*/
+func (service *DocumentService) findReviewableDraft(user *shared.User, documentId string, draftId string, action string) (*shared.Document, *shared.DocumentDraft, error) {
+	document := service.documentRepository.FindById(documentId)
+	if document == nil {
+		return nil, nil, shared.NewNotFoundError("could not find document")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, document, action)
+	if !canAccess {
+		return nil, nil, shared.NewForbiddenError("can not review document")
+	}
+
+	draft, err := service.FindDraft(user, document.Id, draftId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if draft.PublishedAt != nil {
+		return nil, nil, shared.NewBadRequestError("draft is already published")
+	}
+
+	return document, draft, nil
+}
+
+func (service *DocumentService) findReviewStatus(document *shared.Document, draft *shared.DocumentDraft) (*shared.ReviewStatus, error) {
+	policy, err := service.FindReviewPolicy(document)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := service.documentReviewRepository.FindReviewsByDraftId(draft.Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find reviews")
+	}
+
+	// only the reviews of the current request on the latest revision of the draft count
+	request := service.documentReviewRepository.FindRequestByDraftId(draft.Id)
+	var since int64
+	if request != nil {
+		since = request.CreatedAt
+	}
+	if content := service.documentContentRepository.FindByDocumentDraftId(draft.Id); content != nil && content.UpdatedAt > since {
+		since = content.UpdatedAt
+	}
+
+	status := summarizeReviews(policy, reviews, since)
+	status.Draft = draft
+	status.Request = request
+
+	return status, nil
+}
+
+/**
+Whether the user can see the draft because it is waiting on their review
+*/
+func (service *DocumentService) canReviewDraft(user *shared.User, draft *shared.DocumentDraft) bool {
+	if draft.PublishedAt != nil || draft.RetractedAt != nil {
+		return false
+	}
+
+	if service.documentReviewRepository.FindRequestByDraftId(draft.Id) == nil {
+		return false
+	}
+
+	document := service.documentRepository.FindById(draft.DocumentId)
+	if document == nil {
+		return false
+	}
+
+	return service.aclService.UserCanAccessResourceByModel(user, document, "approve")
+}
+
+/**
+Keeps the latest review of each reviewer made since the given time, and counts them against the policy
+*/
+func summarizeReviews(policy *shared.ReviewPolicy, reviews []shared.DocumentReview, since int64) *shared.ReviewStatus {
+	status := &shared.ReviewStatus{
+		Policy:  policy,
+		Reviews: make([]shared.DocumentReview, 0),
+	}
+	if policy != nil {
+		status.RequiredApprovals = policy.RequiredApprovals
+	}
+
+	// the reviews are oldest first, so walk them backwards to find the latest of each reviewer
+	seen := make(map[string]bool)
+	for i := len(reviews) - 1; i >= 0; i-- {
+		review := reviews[i]
+		if review.CreatedAt < since || seen[review.ReviewerId] {
+			continue
+		}
+		seen[review.ReviewerId] = true
+
+		status.Reviews = append([]shared.DocumentReview{review}, status.Reviews...)
+		switch review.Decision {
+		case shared.ReviewDecisionApprove:
+			status.Approvals++
+		case shared.ReviewDecisionRequestChanges:
+			status.ChangesRequested++
+		}
+	}
+
+	status.CanPublish = status.RequiredApprovals == 0 ||
+		(status.Approvals >= status.RequiredApprovals && status.ChangesRequested == 0)
+
+	return status
+}
@@ -0,0 +1,73 @@
+package document
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newReview(reviewerId string, decision string) shared.DocumentReview {
+	return shared.DocumentReview{ReviewerId: reviewerId, Decision: decision}
+}
+
+func TestSummarizeReviewsWithoutPolicy(t *testing.T) {
+	status := summarizeReviews(nil, []shared.DocumentReview{newReview("a", shared.ReviewDecisionRequestChanges)}, 0)
+	assert.Equal(t, 0, status.RequiredApprovals)
+	assert.Equal(t, 1, status.ChangesRequested)
+	assert.True(t, status.CanPublish)
+}
+
+func TestSummarizeReviewsKeepsLatestReviewOfEachReviewer(t *testing.T) {
+	policy := &shared.ReviewPolicy{RequiredApprovals: 2}
+	reviews := []shared.DocumentReview{
+		newReview("a", shared.ReviewDecisionRequestChanges),
+		newReview("b", shared.ReviewDecisionApprove),
+		newReview("a", shared.ReviewDecisionApprove),
+	}
+
+	status := summarizeReviews(policy, reviews, 0)
+	assert.Equal(t, 2, status.Approvals)
+	assert.Equal(t, 0, status.ChangesRequested)
+	assert.Equal(t, []string{"b", "a"}, []string{status.Reviews[0].ReviewerId, status.Reviews[1].ReviewerId})
+	assert.True(t, status.CanPublish)
+}
+
+func TestSummarizeReviewsBlocksOnRequestedChanges(t *testing.T) {
+	policy := &shared.ReviewPolicy{RequiredApprovals: 1}
+	reviews := []shared.DocumentReview{
+		newReview("a", shared.ReviewDecisionApprove),
+		newReview("b", shared.ReviewDecisionApprove),
+		newReview("b", shared.ReviewDecisionRequestChanges),
+	}
+
+	status := summarizeReviews(policy, reviews, 0)
+	assert.Equal(t, 1, status.Approvals)
+	assert.Equal(t, 1, status.ChangesRequested)
+	assert.False(t, status.CanPublish)
+
+	status = summarizeReviews(policy, reviews[:0], 0)
+	assert.False(t, status.CanPublish)
+}
+
+func TestSummarizeReviewsIgnoresReviewsBeforeResubmitting(t *testing.T) {
+	policy := &shared.ReviewPolicy{RequiredApprovals: 1}
+	reviews := []shared.DocumentReview{
+		newReview("a", shared.ReviewDecisionApprove),
+		newReview("b", shared.ReviewDecisionRequestChanges),
+		newReview("b", shared.ReviewDecisionRequestChanges),
+	}
+	reviews[0].CreatedAt = 10
+	reviews[1].CreatedAt = 20
+	reviews[2].CreatedAt = 30
+
+	// the draft was submitted again after the approval, so only the latest review counts
+	status := summarizeReviews(policy, reviews, 25)
+	assert.Equal(t, 0, status.Approvals)
+	assert.Equal(t, 1, status.ChangesRequested)
+	assert.Len(t, status.Reviews, 1)
+	assert.False(t, status.CanPublish)
+
+	status = summarizeReviews(policy, reviews, 35)
+	assert.Len(t, status.Reviews, 0)
+	assert.False(t, status.CanPublish)
+}
@@ -0,0 +1,56 @@
+package document
+
+import (
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"os"
+)
+
+const SearchIndexMySql = "mysql"
+const SearchIndexBleve = "bleve"
+
+/*
+The documents that a user can search, from the acl grants of the user. A document is in scope if its organization, folder
+or id is listed, and it is not in one of the denied resources.
+*/
+type SearchScope struct {
+	OrganizationIds []string
+	FolderIds       []string
+	DocumentIds     []string
+	DeniedIds       map[string][]string
+}
+
+/*
+Finds the drafts that match a search. The database is the source of truth, an index only has to be told which documents
+changed, and can be rebuilt from the database at any time.
+*/
+type SearchIndex interface {
+	// the latest draft of each matching document that the user can see, most relevant first
+	Search(userId string, scope *SearchScope, searchQuery string, filter *shared.DocumentSearchFilter, pagination *shared.Pagination) ([]DraftSearchResult, error)
+
+	// brings the index in line with the document in the database, deleted documents are removed from the index
+	IndexDocument(documentId string) error
+
+	// throws away the index and indexes every document in the database again
+	Rebuild() error
+
+	Close() error
+}
+
+/**
+Creates the search index named by SEARCH_INDEX, mysql by default. The bleve index is stored at SEARCH_INDEX_PATH.
+*/
+func NewSearchIndexFromEnv(documentRepository *DocumentRepository, documentDraftRepository *DocumentDraftRepository) (SearchIndex, error) {
+	switch os.Getenv("SEARCH_INDEX") {
+	case "", SearchIndexMySql:
+		return NewMySqlSearchIndex(documentDraftRepository), nil
+	case SearchIndexBleve:
+		path := os.Getenv("SEARCH_INDEX_PATH")
+		if len(path) == 0 {
+			return nil, errors.New("SEARCH_INDEX_PATH is required for the bleve search index")
+		}
+		return NewBleveSearchIndex(path, documentRepository, documentDraftRepository)
+	}
+
+	return nil, errors.New("SEARCH_INDEX must be mysql or bleve")
+}
@@ -0,0 +1,288 @@
+package document
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const searchDateLayout = "2006-01-02"
+const searchFolderPrefix = "folder/"
+
+/*
+A parsed search query, e.g. title:"onboarding" author:alice@x.com in:folder/Engineering is:draft updated:>2024-01-01
+followed by free text. The text is what is left once the qualifiers are taken out, and is matched against the name and
+content of the drafts like any other search.
+*/
+type SearchQuery struct {
+	Text       string
+	Title      *string
+	Author     *string
+	FolderPath []string
+	Tag        *string
+	Status     string
+	From       *int64
+	To         *int64
+}
+
+type searchToken struct {
+	value    string
+	position int
+}
+
+type searchQualifierParser func(parsed *SearchQuery, value string) string
+
+/**
+The qualifiers that can be used in a query, anything else that looks like key:value (e.g. a url) is left as free text
+*/
+var searchQualifiers = map[string]searchQualifierParser{
+	"title":   parseTitleQualifier,
+	"author":  parseAuthorQualifier,
+	"in":      parseInQualifier,
+	"tag":     parseTagQualifier,
+	"is":      parseIsQualifier,
+	"updated": parseUpdatedQualifier,
+}
+
+/**
+Parses the qualifiers out of a search query. Every problem in the query is returned at once as a bad request, with a detail
+pointing at the token that caused it.
+*/
+func ParseSearchQuery(input string) (*SearchQuery, error) {
+	parsed := &SearchQuery{}
+	details := make([]shared.HttpErrorDetail, 0)
+	seen := make(map[string]bool)
+	text := make([]string, 0)
+
+	tokens, detail := tokenizeSearchQuery(input)
+	if detail != nil {
+		details = append(details, *detail)
+	}
+
+	for _, token := range tokens {
+		key, value, ok := splitSearchQualifier(token.value)
+		if !ok {
+			text = append(text, token.value)
+			continue
+		}
+
+		if seen[key] {
+			details = append(details, newSearchQueryErrorDetail(token, fmt.Sprintf("%s: can only be used once", key)))
+			continue
+		}
+		seen[key] = true
+
+		if len(value) == 0 {
+			details = append(details, newSearchQueryErrorDetail(token, fmt.Sprintf("%s: requires a value", key)))
+			continue
+		}
+
+		if message := searchQualifiers[key](parsed, value); len(message) > 0 {
+			details = append(details, newSearchQueryErrorDetail(token, message))
+		}
+	}
+
+	if len(details) > 0 {
+		return nil, shared.NewBadRequestErrorWithDetails(details...)
+	}
+
+	parsed.Text = strings.Join(text, " ")
+
+	return parsed, nil
+}
+
+/**
+Splits the input on whitespace, keeping quoted values together so that title:"new hire" is one token
+*/
+func tokenizeSearchQuery(input string) ([]searchToken, *shared.HttpErrorDetail) {
+	tokens := make([]searchToken, 0)
+	start := -1
+	quote := -1
+
+	for position, r := range input {
+		if r == '"' {
+			if quote == -1 {
+				quote = position
+			} else {
+				quote = -1
+			}
+		}
+
+		if unicode.IsSpace(r) && quote == -1 {
+			if start != -1 {
+				tokens = append(tokens, searchToken{value: input[start:position], position: start})
+				start = -1
+			}
+			continue
+		}
+
+		if start == -1 {
+			start = position
+		}
+	}
+
+	if quote != -1 {
+		return tokens, &shared.HttpErrorDetail{
+			Message:  "unterminated quote",
+			Token:    input[quote:],
+			Position: quote,
+		}
+	}
+
+	if start != -1 {
+		tokens = append(tokens, searchToken{value: input[start:], position: start})
+	}
+
+	return tokens, nil
+}
+
+/**
+Splits a token into the qualifier and its value, the value is unquoted. Not ok if the token does not start with one of the
+qualifiers.
+*/
+func splitSearchQualifier(token string) (string, string, bool) {
+	index := strings.Index(token, ":")
+	if index == -1 {
+		return "", "", false
+	}
+
+	key := strings.ToLower(token[:index])
+	if _, ok := searchQualifiers[key]; !ok {
+		return "", "", false
+	}
+
+	value := token[index+1:]
+	if len(value) >= 2 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+		value = value[1 : len(value)-1]
+	}
+
+	return key, strings.TrimSpace(value), true
+}
+
+func newSearchQueryErrorDetail(token searchToken, message string) shared.HttpErrorDetail {
+	return shared.HttpErrorDetail{
+		Message:  message,
+		Token:    token.value,
+		Position: token.position,
+	}
+}
+
+func parseTitleQualifier(parsed *SearchQuery, value string) string {
+	parsed.Title = &value
+	return ""
+}
+
+func parseTagQualifier(parsed *SearchQuery, value string) string {
+	parsed.Tag = &value
+	return ""
+}
+
+func parseAuthorQualifier(parsed *SearchQuery, value string) string {
+	if !strings.Contains(value, "@") {
+		return "author: must be an email address"
+	}
+
+	parsed.Author = &value
+	return ""
+}
+
+/**
+in:folder/Engineering/Backend finds the documents in the Backend folder inside of the root Engineering folder
+*/
+func parseInQualifier(parsed *SearchQuery, value string) string {
+	if !strings.HasPrefix(value, searchFolderPrefix) {
+		return "in: must be a folder path, e.g. in:folder/Engineering"
+	}
+
+	path := strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, searchFolderPrefix), "/"), "/")
+	for _, name := range path {
+		if len(strings.TrimSpace(name)) == 0 {
+			return "in: folder names can not be empty"
+		}
+	}
+
+	parsed.FolderPath = path
+	return ""
+}
+
+func parseIsQualifier(parsed *SearchQuery, value string) string {
+	status := strings.ToLower(value)
+	if status != shared.DocumentSearchStatusPublished && status != shared.DocumentSearchStatusDraft {
+		return "is: must be draft or published"
+	}
+
+	parsed.Status = status
+	return ""
+}
+
+/**
+Dates are days in UTC. updated:2024-01-01 is the whole day, updated:2024-01-01..2024-01-31 is a range of days, and the
+comparisons (>, >=, <, <=) are against whole days as well, so updated:>2024-01-01 starts on the 2nd.
+*/
+func parseUpdatedQualifier(parsed *SearchQuery, value string) string {
+	const message = "updated: must be a date (YYYY-MM-DD), a comparison such as >2024-01-01 or a range such as 2024-01-01..2024-01-31"
+
+	if parts := strings.Split(value, ".."); len(parts) == 2 {
+		from, fromErr := time.Parse(searchDateLayout, parts[0])
+		to, toErr := time.Parse(searchDateLayout, parts[1])
+		if fromErr != nil || toErr != nil {
+			return message
+		}
+		if from.After(to) {
+			return "updated: the start of the range must be before the end"
+		}
+
+		parsed.From = startOfSearchDay(from)
+		parsed.To = endOfSearchDay(to)
+		return ""
+	}
+
+	operator := ""
+	for _, candidate := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, candidate) {
+			operator = candidate
+			break
+		}
+	}
+
+	date, err := time.Parse(searchDateLayout, strings.TrimPrefix(value, operator))
+	if err != nil {
+		return message
+	}
+
+	switch operator {
+	case ">":
+		parsed.From = startOfSearchDay(date.AddDate(0, 0, 1))
+	case ">=":
+		parsed.From = startOfSearchDay(date)
+	case "<":
+		parsed.To = endOfSearchDay(date.AddDate(0, 0, -1))
+	case "<=":
+		parsed.To = endOfSearchDay(date)
+	default:
+		parsed.From = startOfSearchDay(date)
+		parsed.To = endOfSearchDay(date)
+	}
+
+	return ""
+}
+
+func startOfSearchDay(date time.Time) *int64 {
+	value := date.UnixNano()
+	return &value
+}
+
+func endOfSearchDay(date time.Time) *int64 {
+	value := date.AddDate(0, 0, 1).UnixNano() - 1
+	return &value
+}
+
+/**
+Qualifiers only describe documents, so other kinds of results are left out of searches using them
+*/
+func (query *SearchQuery) HasQualifiers() bool {
+	return query.Title != nil || query.Author != nil || query.Tag != nil || query.FolderPath != nil ||
+		len(query.Status) > 0 || query.From != nil || query.To != nil
+}
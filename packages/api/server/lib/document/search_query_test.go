@@ -0,0 +1,92 @@
+package document_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	parsed, err := document.ParseSearchQuery(`title:"new hire" author:alice@x.com in:folder/Engineering/Backend tag:onboarding is:draft updated:>2024-01-01 travel policy`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "travel policy", parsed.Text)
+	assert.Equal(t, "new hire", *parsed.Title)
+	assert.Equal(t, "alice@x.com", *parsed.Author)
+	assert.Equal(t, []string{"Engineering", "Backend"}, parsed.FolderPath)
+	assert.Equal(t, "onboarding", *parsed.Tag)
+	assert.Equal(t, shared.DocumentSearchStatusDraft, parsed.Status)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC).UnixNano(), *parsed.From)
+	assert.Nil(t, parsed.To)
+}
+
+func TestSearchQueryHasQualifiers(t *testing.T) {
+	for _, query := range []string{"title:roadmap", "tag:onboarding travel", "in:folder/Engineering", "is:draft", "updated:2024-01-01"} {
+		parsed, err := document.ParseSearchQuery(query)
+		assert.Nil(t, err)
+		assert.True(t, parsed.HasQualifiers(), query)
+	}
+
+	parsed, err := document.ParseSearchQuery("travel policy")
+	assert.Nil(t, err)
+	assert.False(t, parsed.HasQualifiers())
+}
+
+func TestParseSearchQueryKeepsUnknownQualifiersAsText(t *testing.T) {
+	parsed, err := document.ParseSearchQuery(`see https://example.com "exact phrase"`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `see https://example.com "exact phrase"`, parsed.Text)
+	assert.Nil(t, parsed.Title)
+	assert.Nil(t, parsed.FolderPath)
+}
+
+func TestParseSearchQueryDates(t *testing.T) {
+	day := func(year int, month time.Month, date int) int64 {
+		return time.Date(year, month, date, 0, 0, 0, 0, time.UTC).UnixNano()
+	}
+
+	parsed, err := document.ParseSearchQuery("updated:2024-01-15")
+	assert.Nil(t, err)
+	assert.Equal(t, day(2024, 1, 15), *parsed.From)
+	assert.Equal(t, day(2024, 1, 16)-1, *parsed.To)
+
+	parsed, err = document.ParseSearchQuery("updated:<2024-01-15")
+	assert.Nil(t, err)
+	assert.Nil(t, parsed.From)
+	assert.Equal(t, day(2024, 1, 15)-1, *parsed.To)
+
+	parsed, err = document.ParseSearchQuery("updated:2024-01-01..2024-01-31")
+	assert.Nil(t, err)
+	assert.Equal(t, day(2024, 1, 1), *parsed.From)
+	assert.Equal(t, day(2024, 2, 1)-1, *parsed.To)
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	_, err := document.ParseSearchQuery(`is:archived in:Engineering title:"unterminated`)
+
+	httpErr, ok := err.(*shared.HttpError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Status)
+	assert.Equal(t, []shared.HttpErrorDetail{
+		{Message: "unterminated quote", Token: `"unterminated`, Position: 33},
+		{Message: "is: must be draft or published", Token: "is:archived", Position: 0},
+		{Message: "in: must be a folder path, e.g. in:folder/Engineering", Token: "in:Engineering", Position: 12},
+	}, httpErr.Details)
+	assert.Len(t, httpErr.Errors, 3)
+}
+
+func TestParseSearchQueryDuplicateAndEmptyQualifiers(t *testing.T) {
+	_, err := document.ParseSearchQuery("is:draft is:published author: updated:yesterday")
+
+	httpErr := err.(*shared.HttpError)
+	assert.Equal(t, []string{
+		"is: can only be used once",
+		"author: requires a value",
+		"updated: must be a date (YYYY-MM-DD), a comparison such as >2024-01-01 or a range such as 2024-01-01..2024-01-31",
+	}, httpErr.Errors)
+	assert.Equal(t, 9, httpErr.Details[0].Position)
+}
@@ -0,0 +1,294 @@
+package document
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	uuid "github.com/satori/go.uuid"
+	"strings"
+)
+
+// the most tags the autocomplete returns
+const tagAutocompleteCount = 10
+
+/**
+Creates a tag in the organization, anyone that can create documents in the organization can create tags in it
+*/
+func (service *DocumentService) CreateTag(user *shared.User, organizationId string, name string) (*shared.Tag, error) {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return nil, shared.NewNotFoundError("could not find organization")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, org, "create:document")
+	if !canAccess {
+		return nil, shared.NewForbiddenError("can not create tag in organization")
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return nil, shared.NewBadRequestError("tag name is required")
+	}
+	if service.tagRepository.FindByName(org.Id, name) != nil {
+		return nil, shared.NewBadRequestError("tag already exists")
+	}
+
+	tag := &shared.Tag{
+		OrganizationId: org.Id,
+		Name:           name,
+	}
+	tag.Id = uuid.NewV4().String()
+
+	_, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		err := injectedService.tagRepository.Insert(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		return injectedService.resourceHistoryService.Create(tag.Id, "tag", user.Id, "created")
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create tag")
+	}
+
+	return tag, nil
+}
+
+/**
+Lists the tags of the organization by name, optionally only the ones whose name starts with the prefix
+*/
+func (service *DocumentService) ListTags(user *shared.User, organizationId string, prefix string, pagination *shared.Pagination) ([]shared.Tag, error) {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return nil, shared.NewNotFoundError("could not find organization")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, org, "view")
+	if !canAccess {
+		return nil, shared.NewForbiddenError("can not view organization")
+	}
+
+	tags, err := service.tagRepository.FindByOrganizationId(org.Id, strings.TrimSpace(prefix), pagination)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find tags")
+	}
+
+	return tags, nil
+}
+
+/**
+The first few tags of the organization that start with the prefix
+*/
+func (service *DocumentService) AutocompleteTags(user *shared.User, organizationId string, prefix string) ([]shared.Tag, error) {
+	return service.ListTags(user, organizationId, prefix, &shared.Pagination{Page: 0, Count: tagAutocompleteCount})
+}
+
+func (service *DocumentService) UpdateTag(user *shared.User, tagId string, name string) (*shared.Tag, error) {
+	tag, err := service.findTagToManage(user, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return nil, shared.NewBadRequestError("tag name is required")
+	}
+	existing := service.tagRepository.FindByName(tag.OrganizationId, name)
+	if existing != nil && existing.Id != tag.Id {
+		return nil, shared.NewBadRequestError("tag already exists")
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		tag.Name = name
+		err := injectedService.tagRepository.Update(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		return injectedService.resourceHistoryService.Create(tag.Id, "tag", user.Id, "updated")
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to update tag")
+	}
+
+	return tag, nil
+}
+
+/**
+Deletes the tag and takes it off of every document it is on
+*/
+func (service *DocumentService) DeleteTag(user *shared.User, tagId string) (*shared.Tag, error) {
+	tag, err := service.findTagToManage(user, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		err := injectedService.tagRepository.DeleteLinksByTagId(tag.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		deletedAt := util.NowUnix()
+		tag.DeletedAt = &deletedAt
+		err = injectedService.tagRepository.Update(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		return injectedService.resourceHistoryService.Create(tag.Id, "tag", user.Id, "deleted")
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete tag")
+	}
+
+	return tag, nil
+}
+
+/**
+Puts the tag on the document, returning the tags of the document. Tagging a document twice does nothing.
+*/
+func (service *DocumentService) TagDocument(user *shared.User, documentId string, tagId string) ([]shared.Tag, error) {
+	document, tag, err := service.findDocumentToTag(user, documentId, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	if service.tagRepository.FindLink(document.Id, tag.Id) == nil {
+		link := &shared.DocumentTag{
+			DocumentId: document.Id,
+			TagId:      tag.Id,
+		}
+		link.Id = uuid.NewV4().String()
+
+		_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+			injectedService := injected.(*DocumentService)
+
+			err := injectedService.tagRepository.InsertLink(link)
+			if err != nil {
+				return nil, err
+			}
+
+			return injectedService.resourceHistoryService.Create(link.Id, "document_tag", user.Id, "created")
+		})
+
+		if err != nil {
+			return nil, shared.NewInternalServerError("failed to tag document")
+		}
+	}
+
+	return service.findTags(document.Id)
+}
+
+/**
+Takes the tag off of the document, returning the tags that are left on it
+*/
+func (service *DocumentService) UntagDocument(user *shared.User, documentId string, tagId string) ([]shared.Tag, error) {
+	document, tag, err := service.findDocumentToTag(user, documentId, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	link := service.tagRepository.FindLink(document.Id, tag.Id)
+	if link == nil {
+		return nil, shared.NewNotFoundError("document does not have tag")
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*DocumentService)
+
+		err := injectedService.tagRepository.DeleteLink(link)
+		if err != nil {
+			return nil, err
+		}
+
+		return injectedService.resourceHistoryService.Create(link.Id, "document_tag", user.Id, "deleted")
+	})
+
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to untag document")
+	}
+
+	return service.findTags(document.Id)
+}
+
+/**
+Finds the tag, as long as the user can modify its organization, renaming and deleting tags changes every document they
+are on
+*/
+func (service *DocumentService) findTagToManage(user *shared.User, tagId string) (*shared.Tag, error) {
+	tag := service.tagRepository.FindById(tagId)
+	if tag == nil {
+		return nil, shared.NewNotFoundError("could not find tag")
+	}
+
+	org := service.organizationService.FindById(tag.OrganizationId)
+	if org == nil {
+		return nil, shared.NewNotFoundError("could not find organization")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, org, "modify")
+	if !canAccess {
+		return nil, shared.NewForbiddenError("can not manage tag")
+	}
+
+	return tag, nil
+}
+
+func (service *DocumentService) findDocumentToTag(user *shared.User, documentId string, tagId string) (*shared.Document, *shared.Tag, error) {
+	document := service.documentRepository.FindById(documentId)
+	if document == nil {
+		return nil, nil, shared.NewNotFoundError("could not find document")
+	}
+
+	canAccess := service.aclService.UserCanAccessResourceByModel(user, document, "modify")
+	if !canAccess {
+		return nil, nil, shared.NewForbiddenError("can not modify document")
+	}
+
+	// tags are scoped to the organization, so a tag of another organization is treated as missing
+	tag := service.tagRepository.FindById(tagId)
+	if tag == nil || tag.OrganizationId != document.OrganizationId {
+		return nil, nil, shared.NewNotFoundError("could not find tag")
+	}
+
+	return document, tag, nil
+}
+
+func (service *DocumentService) findTags(documentId string) ([]shared.Tag, error) {
+	tags, err := service.tagRepository.FindByDocumentIds([]string{documentId})
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find document tags")
+	}
+
+	return append(make([]shared.Tag, 0), tags[documentId]...), nil
+}
+
+/**
+Attaches the tags of each document to it, documents without tags get an empty list
+*/
+func (service *DocumentService) attachTags(documents []shared.Document) error {
+	ids := make([]string, len(documents))
+	for i := range documents {
+		ids[i] = documents[i].Id
+	}
+
+	tags, err := service.tagRepository.FindByDocumentIds(ids)
+	if err != nil {
+		return shared.NewInternalServerError("failed to find document tags")
+	}
+
+	for i := range documents {
+		documents[i].Tags = append(make([]shared.Tag, 0), tags[documents[i].Id]...)
+	}
+
+	return nil
+}
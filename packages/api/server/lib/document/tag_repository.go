@@ -0,0 +1,295 @@
+package document
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+/*
+Stores the tags of organizations and the links between the tags and documents
+*/
+type TagRepository struct {
+	util.Repository
+}
+
+func NewTagRepository(db *sql.DB, tx *sql.Tx) *TagRepository {
+	repo := &TagRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *TagRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewTagRepository(repo.Db, tx)
+}
+
+func (repo *TagRepository) FindById(id string) *shared.Tag {
+	row := repo.QueryRow(
+		"select id, organization_id, name, created_at, updated_at, deleted_at from tag where id = ? and deleted_at is null",
+		id,
+	)
+
+	var tag shared.Tag
+	err := row.Scan(&tag.Id, &tag.OrganizationId, &tag.Name, &tag.CreatedAt, &tag.UpdatedAt, &tag.DeletedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Print(err)
+		}
+		return nil
+	}
+
+	return &tag
+}
+
+func (repo *TagRepository) FindByName(organizationId string, name string) *shared.Tag {
+	row := repo.QueryRow(
+		"select id, organization_id, name, created_at, updated_at, deleted_at from tag where organization_id = ? and name = ? and deleted_at is null",
+		organizationId,
+		name,
+	)
+
+	var tag shared.Tag
+	err := row.Scan(&tag.Id, &tag.OrganizationId, &tag.Name, &tag.CreatedAt, &tag.UpdatedAt, &tag.DeletedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Print(err)
+		}
+		return nil
+	}
+
+	return &tag
+}
+
+/**
+Finds the tags of the organization ordered by name, optionally only the ones whose name starts with the prefix
+*/
+func (repo *TagRepository) FindByOrganizationId(organizationId string, prefix string, pagination *shared.Pagination) ([]shared.Tag, error) {
+	query := "select id, organization_id, name, created_at, updated_at, deleted_at from tag where organization_id = ? and deleted_at is null"
+	params := []interface{}{organizationId}
+
+	if len(prefix) > 0 {
+		query += " and name like ?"
+		params = append(params, util.EscapeSqlLike(prefix)+"%")
+	}
+	query += " ORDER BY name ASC"
+
+	if pagination != nil {
+		query = fmt.Sprintf("%s LIMIT ?, ?", query)
+		params = append(params, pagination.Page*pagination.Count, pagination.Count)
+	}
+
+	rows, err := repo.Query(query, params...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find tags")
+	}
+	defer rows.Close()
+
+	tags := make([]shared.Tag, 0)
+	for rows.Next() {
+		var tag shared.Tag
+		err := rows.Scan(&tag.Id, &tag.OrganizationId, &tag.Name, &tag.CreatedAt, &tag.UpdatedAt, &tag.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse tag")
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+func (repo *TagRepository) Insert(tag *shared.Tag) error {
+	tag.CreatedAt = util.NowUnix()
+	tag.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into tag (id, organization_id, name, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?)",
+		tag.Id,
+		tag.OrganizationId,
+		tag.Name,
+		tag.CreatedAt,
+		tag.UpdatedAt,
+		tag.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert tag")
+	}
+
+	return nil
+}
+
+func (repo *TagRepository) Update(tag *shared.Tag) error {
+	tag.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update tag set name = ?, updated_at = ?, deleted_at = ? where id = ?",
+		tag.Name,
+		tag.UpdatedAt,
+		tag.DeletedAt,
+		tag.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update tag")
+	}
+
+	return nil
+}
+
+/**
+Finds the tags on each of the documents, ordered by name, keyed by the id of the document
+*/
+func (repo *TagRepository) FindByDocumentIds(documentIds []string) (map[string][]shared.Tag, error) {
+	tags := make(map[string][]shared.Tag)
+	if len(documentIds) == 0 {
+		return tags, nil
+	}
+
+	rows, err := repo.Query(
+		fmt.Sprintf(
+			"select dt.document_id, t.id, t.organization_id, t.name, t.created_at, t.updated_at, t.deleted_at from document_tag dt join tag t on t.id = dt.tag_id where dt.document_id in (%s) and dt.deleted_at is null and t.deleted_at is null ORDER BY t.name ASC",
+			util.BuildSqlPlaceholderArray(documentIds),
+		),
+		util.ConvertStringArrayToInterfaceArray(documentIds)...,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find document tags")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var documentId string
+		var tag shared.Tag
+		err := rows.Scan(&documentId, &tag.Id, &tag.OrganizationId, &tag.Name, &tag.CreatedAt, &tag.UpdatedAt, &tag.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse document tag")
+		}
+		tags[documentId] = append(tags[documentId], tag)
+	}
+
+	return tags, nil
+}
+
+/**
+Finds the documents tagged with a tag of the name in one of the organizations, tags with the same name in other
+organizations are not the same tag
+*/
+func (repo *TagRepository) FindDocumentIdsByTagName(organizationIds []string, name string) ([]string, error) {
+	ids := make([]string, 0)
+	if len(organizationIds) == 0 {
+		return ids, nil
+	}
+
+	params := append([]interface{}{name}, util.ConvertStringArrayToInterfaceArray(organizationIds)...)
+	rows, err := repo.Query(
+		fmt.Sprintf(
+			"select distinct dt.document_id from document_tag dt join tag t on t.id = dt.tag_id where t.name = ? and t.organization_id in (%s) and dt.deleted_at is null and t.deleted_at is null",
+			util.BuildSqlPlaceholderArray(organizationIds),
+		),
+		params...,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find tagged documents")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		err := rows.Scan(&id)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse tagged document")
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (repo *TagRepository) FindLink(documentId string, tagId string) *shared.DocumentTag {
+	row := repo.QueryRow(
+		"select id, document_id, tag_id, created_at, updated_at, deleted_at from document_tag where document_id = ? and tag_id = ? and deleted_at is null",
+		documentId,
+		tagId,
+	)
+
+	var link shared.DocumentTag
+	err := row.Scan(&link.Id, &link.DocumentId, &link.TagId, &link.CreatedAt, &link.UpdatedAt, &link.DeletedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Print(err)
+		}
+		return nil
+	}
+
+	return &link
+}
+
+func (repo *TagRepository) InsertLink(link *shared.DocumentTag) error {
+	link.CreatedAt = util.NowUnix()
+	link.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into document_tag (id, document_id, tag_id, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?)",
+		link.Id,
+		link.DocumentId,
+		link.TagId,
+		link.CreatedAt,
+		link.UpdatedAt,
+		link.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to tag document")
+	}
+
+	return nil
+}
+
+func (repo *TagRepository) DeleteLink(link *shared.DocumentTag) error {
+	deletedAt := util.NowUnix()
+	link.UpdatedAt = deletedAt
+	link.DeletedAt = &deletedAt
+
+	_, err := repo.Exec(
+		"update document_tag set updated_at = ?, deleted_at = ? where id = ?",
+		link.UpdatedAt,
+		link.DeletedAt,
+		link.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to untag document")
+	}
+
+	return nil
+}
+
+/**
+Removes the tag from every document it is on, e.g. once the tag is deleted
+*/
+func (repo *TagRepository) DeleteLinksByTagId(tagId string) error {
+	deletedAt := util.NowUnix()
+
+	_, err := repo.Exec(
+		"update document_tag set updated_at = ?, deleted_at = ? where tag_id = ? and deleted_at is null",
+		deletedAt,
+		deletedAt,
+		tagId,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to untag documents")
+	}
+
+	return nil
+}
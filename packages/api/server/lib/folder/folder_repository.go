@@ -131,7 +131,7 @@ func (repo *FolderRepository) ChildCount(folders []shared.Folder) error {
 	return nil
 }
 
-func (repo *FolderRepository) Find(organizationIds []string, folderIds []string, parentFolderId *string, pagination *shared.Pagination) ([]shared.Folder, error) {
+func (repo *FolderRepository) Find(organizationIds []string, folderIds []string, deniedIds map[string][]string, parentFolderId *string, pagination *shared.Pagination) ([]shared.Folder, error) {
 	query := "select id, name, parent_folder_id, organization_id, created_at, updated_at, deleted_at from folder where"
 
 	params := make([]interface{}, 0)
@@ -150,6 +150,16 @@ func (repo *FolderRepository) Find(organizationIds []string, folderIds []string,
 	// tack on the in query
 	query = fmt.Sprintf("%s (%s)", query, strings.Join(inQueries, " OR "))
 
+	// leave out the folders that a deny takes away
+	exclusionClause, exclusionParams := util.BuildSqlExclusionClause(deniedIds, map[string]string{
+		"organization": "organization_id",
+		"folder":       "id",
+	})
+	if len(exclusionClause) > 0 {
+		query = fmt.Sprintf("%s AND %s", query, exclusionClause)
+		params = append(params, exclusionParams...)
+	}
+
 	// add the parent folder portion of the where clause
 	if parentFolderId != nil {
 		query = fmt.Sprintf("%s AND parent_folder_id = ?", query)
@@ -192,6 +202,85 @@ func (repo *FolderRepository) Find(organizationIds []string, folderIds []string,
 	return folders, nil
 }
 
+/**
+Finds the folders with the name in theirs, exact matches first, then the ones starting with the name. Unlike Find, the
+folders can be anywhere in the folder tree.
+*/
+func (repo *FolderRepository) Search(organizationIds []string, folderIds []string, deniedIds map[string][]string, name string, pagination *shared.Pagination) ([]shared.Folder, error) {
+	if len(organizationIds) == 0 && len(folderIds) == 0 {
+		return make([]shared.Folder, 0), nil
+	}
+
+	query := "select id, name, parent_folder_id, organization_id, created_at, updated_at, deleted_at from folder where"
+	params := make([]interface{}, 0)
+
+	// build the in queries
+	inQueries := make([]string, 0)
+	if len(organizationIds) > 0 {
+		inQueries = append(inQueries, fmt.Sprintf("organization_id in (%s)", util.BuildSqlPlaceholderArray(organizationIds)))
+		params = append(params, util.ConvertStringArrayToInterfaceArray(organizationIds)...)
+	}
+	if len(folderIds) > 0 {
+		inQueries = append(inQueries, fmt.Sprintf("id in (%s)", util.BuildSqlPlaceholderArray(folderIds)))
+		params = append(params, util.ConvertStringArrayToInterfaceArray(folderIds)...)
+	}
+	query = fmt.Sprintf("%s (%s)", query, strings.Join(inQueries, " OR "))
+
+	// leave out the folders that a deny takes away
+	exclusionClause, exclusionParams := util.BuildSqlExclusionClause(deniedIds, map[string]string{
+		"organization": "organization_id",
+		"folder":       "id",
+	})
+	if len(exclusionClause) > 0 {
+		query = fmt.Sprintf("%s AND %s", query, exclusionClause)
+		params = append(params, exclusionParams...)
+	}
+
+	escaped := util.EscapeSqlLike(name)
+	query = fmt.Sprintf("%s AND name LIKE ? AND deleted_at is null ORDER BY name = ? DESC, name LIKE ? DESC, name ASC", query)
+	params = append(params, "%"+escaped+"%", name, escaped+"%")
+
+	// add the pagination portion of the query
+	if pagination != nil {
+		query = fmt.Sprintf("%s LIMIT ?, ?", query)
+		params = append(params, pagination.Page*pagination.Count, pagination.Count)
+	}
+
+	return repo.findFolders(query, params...)
+}
+
+func (repo *FolderRepository) FindByIds(ids []string) ([]shared.Folder, error) {
+	if len(ids) == 0 {
+		return make([]shared.Folder, 0), nil
+	}
+
+	query := fmt.Sprintf("select id, name, parent_folder_id, organization_id, created_at, updated_at, deleted_at from folder where id in (%s) and deleted_at is null", util.BuildSqlPlaceholderArray(ids))
+
+	return repo.findFolders(query, util.ConvertStringArrayToInterfaceArray(ids)...)
+}
+
+func (repo *FolderRepository) findFolders(query string, params ...interface{}) ([]shared.Folder, error) {
+	rows, err := repo.Query(query, params...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find folders")
+	}
+	defer rows.Close()
+
+	folders := make([]shared.Folder, 0)
+	for rows.Next() {
+		var folder shared.Folder
+		err := rows.Scan(&folder.Id, &folder.Name, &folder.ParentFolderId, &folder.OrganizationId, &folder.CreatedAt, &folder.UpdatedAt, &folder.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse folder")
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, nil
+}
+
 func (repo *FolderRepository) FindById(id string) *shared.Folder {
 	row := repo.QueryRow(
 		"select id, name, parent_folder_id, organization_id, created_at, updated_at, deleted_at from folder where id = ? and deleted_at is null",
@@ -268,7 +357,9 @@ func (repo *FolderRepository) FindAncestry(id string) ([]shared.Folder, error) {
 		return nil, errors.New("found ancestry and folder data does not match")
 	}
 
-	sortedFolders := make([]shared.Folder, len(folders))
+	// the current folder first, then its parents from the nearest one up
+	sortedFolders := make([]shared.Folder, 0, len(folders))
+	sortedFolders = append(sortedFolders, *currentFolder)
 	for i := 0; i < len(ids); i++ {
 		id := ids[i];
 		for j := 0; j < len(folders); j++ {
@@ -281,3 +372,107 @@ func (repo *FolderRepository) FindAncestry(id string) ([]shared.Folder, error) {
 
 	return sortedFolders, nil
 }
+
+/**
+Finds the ids of the folders the given folder is nested in, nearest parent first
+*/
+func (repo *FolderRepository) FindAncestorIds(id string) ([]string, error) {
+	row := repo.QueryRow("select GetFolderAncestry(?)", id)
+
+	var path string
+	err := row.Scan(&path)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("could not find folder ancestry")
+	}
+
+	if path == "" {
+		return make([]string, 0), nil
+	}
+
+	return strings.Split(path, ","), nil
+}
+
+/**
+Finds the ids of every folder nested below the given folders, one level at a time
+*/
+func (repo *FolderRepository) FindDescendantIds(ids []string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		seen[id] = true
+	}
+
+	descendantIds := make([]string, 0)
+	parentIds := ids
+	for len(parentIds) > 0 {
+		query := fmt.Sprintf("select id from folder where parent_folder_id in (%s) and deleted_at is null", util.BuildSqlPlaceholderArray(parentIds))
+		rows, err := repo.Query(query, util.ConvertStringArrayToInterfaceArray(parentIds)...)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("could not find folder descendants")
+		}
+
+		childIds := make([]string, 0)
+		for rows.Next() {
+			var id string
+			err := rows.Scan(&id)
+			if err != nil {
+				rows.Close()
+				log.Print(err)
+				return nil, errors.New("failed to parse folder")
+			}
+
+			// guards against cycles in the folder tree
+			if !seen[id] {
+				seen[id] = true
+				childIds = append(childIds, id)
+			}
+		}
+		rows.Close()
+
+		descendantIds = append(descendantIds, childIds...)
+		parentIds = childIds
+	}
+
+	return descendantIds, nil
+}
+
+/**
+Finds the folders at the path of folder names, starting from the root folders. Folder names are not unique, so more than
+one folder can be at the same path.
+*/
+func (repo *FolderRepository) FindIdsByPath(names []string) ([]string, error) {
+	ids := make([]string, 0)
+	for index, name := range names {
+		query := "select id from folder where parent_folder_id is null and name = ? and deleted_at is null"
+		params := []interface{}{name}
+		if index > 0 {
+			if len(ids) == 0 {
+				break
+			}
+			query = fmt.Sprintf("select id from folder where parent_folder_id in (%s) and name = ? and deleted_at is null", util.BuildSqlPlaceholderArray(ids))
+			params = append(util.ConvertStringArrayToInterfaceArray(ids), name)
+		}
+
+		rows, err := repo.Query(query, params...)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("could not find folders by path")
+		}
+
+		ids = make([]string, 0)
+		for rows.Next() {
+			var id string
+			err := rows.Scan(&id)
+			if err != nil {
+				rows.Close()
+				log.Print(err)
+				return nil, errors.New("failed to parse folder")
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+	}
+
+	return ids, nil
+}
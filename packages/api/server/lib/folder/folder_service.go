@@ -129,20 +129,67 @@ func (service *FolderService) List(user *shared.User, organizationId string, par
 		return nil, shared.NewNotFoundError("could not find organization")
 	}
 
-	canAccess := service.aclService.UserCanAccessResourceByModel(user, org, "view:folder")
-	if !canAccess {
-		return nil, shared.NewForbiddenError("you can not view folders in this organization")
+	// the folders nested in a folder can be listed by anyone it was shared with, even without access to the organization
+	if parentFolderId != nil {
+		parentFolder := service.folderRepository.FindById(*parentFolderId)
+		if parentFolder == nil || parentFolder.OrganizationId != org.Id {
+			return nil, shared.NewNotFoundError("could not find folder")
+		}
+
+		canAccess := service.aclService.UserCanAccessResourceByModel(user, parentFolder, "view:folder")
+		if !canAccess {
+			return nil, shared.NewForbiddenError("you can not view folders in this folder")
+		}
+	} else {
+		canAccess := service.aclService.UserCanAccessResourceByModel(user, org, "view:folder")
+		if !canAccess {
+			return nil, shared.NewForbiddenError("you can not view folders in this organization")
+		}
+	}
+
+	organizationIds, folderIds, deniedIds, err := service.findViewableResources(user)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := service.folderRepository.Find(organizationIds, folderIds, deniedIds, parentFolderId, pagination)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find folders")
+	}
+
+	return folders, nil
+}
+
+/**
+Finds the folders the user can view with the name in theirs, in any organization
+*/
+func (service *FolderService) Search(user *shared.User, name string, pagination *shared.Pagination) ([]shared.Folder, error) {
+	organizationIds, folderIds, deniedIds, err := service.findViewableResources(user)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := service.folderRepository.Search(organizationIds, folderIds, deniedIds, name, pagination)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to search folders")
 	}
 
+	return folders, nil
+}
+
+/**
+Finds the organizations and folders the user can view the folders in, along with the ids a deny takes away
+*/
+func (service *FolderService) findViewableResources(user *shared.User) ([]string, []string, map[string][]string, error) {
 	folderResourceData, err := service.aclService.GetResourceDataForModel(&shared.Folder{})
 	if err != nil {
-		return nil, shared.NewInternalServerError("failed to find folder information")
+		return nil, nil, nil, shared.NewInternalServerError("failed to find folder information")
 	}
 
 	// find all of the resources that you can view
 	resp, err := service.aclService.UserActionableResourcesByPath(user, folderResourceData.ResourcePath, "view")
 	if err != nil {
-		return nil, shared.NewInternalServerError("failed to find accessible folders")
+		return nil, nil, nil, shared.NewInternalServerError("failed to find accessible folders")
 	}
 
 	organizationIds := make([]string, 0)
@@ -156,14 +203,49 @@ func (service *FolderService) List(user *shared.User, organizationId string, par
 		}
 	}
 
-	folders, err := service.folderRepository.Find(organizationIds, folderIds, parentFolderId, pagination)
+	deniedIds, err := service.aclService.DeniedResourceIds(user, folderResourceData.ResourcePath, "view")
 	if err != nil {
-		return nil, shared.NewInternalServerError("failed to find folders")
+		return nil, nil, nil, shared.NewInternalServerError("failed to find accessible folders")
 	}
 
-	return folders, nil
+	return organizationIds, folderIds, deniedIds, nil
 }
 
 func (service *FolderService) FindAncestry(id string) ([]shared.Folder, error) {
 	return service.folderRepository.FindAncestry(id)
 }
+
+/**
+Folders can be nested in other folders, so the service is registered as the acl hierarchy of the folder resource
+*/
+func (service *FolderService) FindAncestorIds(id string) ([]string, error) {
+	return service.folderRepository.FindAncestorIds(id)
+}
+
+func (service *FolderService) FindDescendantIds(ids []string) ([]string, error) {
+	return service.folderRepository.FindDescendantIds(ids)
+}
+
+/**
+Finds the folders at the path of folder names along with every folder nested in them
+*/
+func (service *FolderService) FindIdsByPath(names []string) ([]string, error) {
+	ids, err := service.folderRepository.FindIdsByPath(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	descendantIds, err := service.folderRepository.FindDescendantIds(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ids, descendantIds...), nil
+}
+
+func (service *FolderService) FindByIds(ids []string) ([]shared.Folder, error) {
+	return service.folderRepository.FindByIds(ids)
+}
@@ -0,0 +1,48 @@
+package job
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/user"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+	"time"
+)
+
+const DraftScheduleInterval = time.Minute
+
+/**
+Publishes and retracts the drafts that were scheduled to be, through the same path as updating a draft. A draft that can
+not be published, e.g. because it is waiting on approvals or its creator lost access, has its schedule cleared and is
+logged.
+*/
+type DraftScheduleJob struct {
+	documentService *document.DocumentService
+	userService     *user.UserService
+}
+
+func NewDraftScheduleJob(documentService *document.DocumentService, userService *user.UserService) *DraftScheduleJob {
+	return &DraftScheduleJob{
+		documentService: documentService,
+		userService:     userService,
+	}
+}
+
+func (job *DraftScheduleJob) Run() error {
+	now := util.NowUnix()
+
+	drafts, err := job.documentService.FindDueSchedules(now)
+	if err != nil {
+		return err
+	}
+
+	for _, draft := range drafts {
+		// the creator is nil when they no longer exist, which cancels the schedule
+		creator := job.userService.FindById(draft.CreatorId)
+		err := job.documentService.RunSchedule(creator, draft.Id, draft.DocumentId, now)
+		if err != nil {
+			log.Printf("failed to run schedule of draft %s: %s", draft.Id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package job
+
+import (
+	"database/sql"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/resource_history"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"time"
+)
+
+const GrantExpiryInterval = time.Minute
+
+/**
+Deletes role grants that have expired and records an expired event for each one. Expired grants are already ignored by
+the acl, so this only cleans them up.
+*/
+type GrantExpiryJob struct {
+	aclService             *acl.AclService
+	resourceHistoryService *resource_history.ResourceHistoryService
+	transactionManager     *util.TransactionManager
+}
+
+func NewGrantExpiryJob(
+	aclService *acl.AclService,
+	resourceHistoryService *resource_history.ResourceHistoryService,
+	transactionManager *util.TransactionManager,
+) *GrantExpiryJob {
+	return &GrantExpiryJob{
+		aclService:             aclService,
+		resourceHistoryService: resourceHistoryService,
+		transactionManager:     transactionManager,
+	}
+}
+
+func (job *GrantExpiryJob) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewGrantExpiryJob(
+		job.aclService.InjectTransaction(tx).(*acl.AclService),
+		job.resourceHistoryService.InjectTransaction(tx).(*resource_history.ResourceHistoryService),
+		job.transactionManager.InjectTransaction(tx).(*util.TransactionManager),
+	)
+}
+
+func (job *GrantExpiryJob) Run() error {
+	now := util.NowUnix()
+
+	grants, err := job.aclService.FindExpiredGrants(now)
+	if err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		err := job.expire(grant, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+Deletes the grant and records the event together, so the history only has grants that were actually removed
+*/
+func (job *GrantExpiryJob) expire(grant acl.UserRole, now int64) error {
+	_, err := job.transactionManager.Transact(job, func(injected interface{}) (interface{}, error) {
+		injectedJob := injected.(*GrantExpiryJob)
+
+		deleted, err := injectedJob.aclService.ExpireGrant(grant, now)
+		if err != nil {
+			return nil, err
+		}
+
+		// the grant was extended or removed since it was found
+		if !deleted {
+			return nil, nil
+		}
+
+		return injectedJob.resourceHistoryService.Create(grant.ResourceId, "user_role", grant.UserId, "expired")
+	})
+
+	return err
+}
@@ -109,4 +109,43 @@ func (repo *OrganizationRepository) Find(organizationIds []string) ([]shared.Org
 	}
 
 	return orgs, nil
-}
\ No newline at end of file
+}
+/**
+Finds the organizations with the name in theirs, exact matches first, then the ones starting with the name
+*/
+func (repo *OrganizationRepository) Search(organizationIds []string, name string, pagination *shared.Pagination) ([]shared.Organization, error) {
+	if len(organizationIds) == 0 {
+		return make([]shared.Organization, 0), nil
+	}
+
+	escaped := util.EscapeSqlLike(name)
+	query := fmt.Sprintf("select id, name, created_at, updated_at, deleted_at from organization where id in (%s) and name LIKE ? and deleted_at is null ORDER BY name = ? DESC, name LIKE ? DESC, name ASC", util.BuildSqlPlaceholderArray(organizationIds))
+	params := util.ConvertStringArrayToInterfaceArray(organizationIds)
+	params = append(params, "%"+escaped+"%", name, escaped+"%")
+
+	// add the pagination portion of the query
+	if pagination != nil {
+		query = fmt.Sprintf("%s LIMIT ?, ?", query)
+		params = append(params, pagination.Page*pagination.Count, pagination.Count)
+	}
+
+	rows, err := repo.Query(query, params...)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to search organizations")
+	}
+	defer rows.Close()
+
+	orgs := make([]shared.Organization, 0)
+	for rows.Next() {
+		var org shared.Organization
+		err := rows.Scan(&org.Id, &org.Name, &org.CreatedAt, &org.UpdatedAt, &org.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse organization")
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, nil
+}
@@ -41,7 +41,37 @@ func (service *OrganizationService) Create(name string) (*shared.Organization, e
 }
 
 func (service *OrganizationService) List(u *shared.User) ([]shared.Organization, error) {
+	organizationIds, err := service.findViewableIds(u)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs, err := service.organizationRepository.Find(organizationIds)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find organizations")
+	}
+
+	return orgs, nil
+}
+
+/**
+Finds the organizations the user can view with the name in theirs
+*/
+func (service *OrganizationService) Search(u *shared.User, name string, pagination *shared.Pagination) ([]shared.Organization, error) {
+	organizationIds, err := service.findViewableIds(u)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs, err := service.organizationRepository.Search(organizationIds, name, pagination)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to search organizations")
+	}
 
+	return orgs, nil
+}
+
+func (service *OrganizationService) findViewableIds(u *shared.User) ([]string, error) {
 	orgResourceData, err := service.aclService.GetResourceDataForModel(&shared.Organization{})
 	if err != nil {
 		return nil, shared.NewInternalServerError("failed to find organization information")
@@ -53,21 +83,30 @@ func (service *OrganizationService) List(u *shared.User) ([]shared.Organization,
 		return nil, shared.NewInternalServerError("failed to find accessible organizations")
 	}
 
+	// denies take precedence over the roles
+	deniedIds, err := service.aclService.DeniedResourceIds(u, orgResourceData.ResourcePath, "view")
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find accessible organizations")
+	}
+	denied := make(map[string]bool)
+	for _, id := range deniedIds["organization"] {
+		denied[id] = true
+	}
+
 	organizationIds := make([]string, 0)
 	for _, res := range resp {
-		if strings.HasPrefix(res.ResourcePath, "organization") {
+		if strings.HasPrefix(res.ResourcePath, "organization") && !denied[res.ResourceId] {
 			organizationIds = append(organizationIds, res.ResourceId)
 		}
 	}
 
-	orgs, err := service.organizationRepository.Find(organizationIds)
-	if err != nil {
-		return nil, shared.NewInternalServerError("failed to find organizations")
-	}
-
-	return orgs, nil
+	return organizationIds, nil
 }
 
 func (service *OrganizationService) FindById(id string) *shared.Organization {
 	return service.organizationRepository.FindById(id)
 }
+
+func (service *OrganizationService) FindByIds(ids []string) ([]shared.Organization, error) {
+	return service.organizationRepository.Find(ids)
+}
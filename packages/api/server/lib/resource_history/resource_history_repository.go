@@ -64,4 +64,45 @@ func (repo *ResourceHistoryRepository) FindOne(resourceId string, resourceName s
 	}
 
 	return &history;
-}
\ No newline at end of file
+}
+
+func (repo *ResourceHistoryRepository) FindByUserId(userId string) ([]shared.ResourceHistory, error) {
+	rows, err := repo.Query(
+		"select id, resource_id, resource_name, user_id, action, created_at, updated_at, deleted_at from resource_history where user_id = ? and deleted_at is null ORDER BY created_at ASC",
+		userId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find resource history")
+	}
+	defer rows.Close()
+
+	histories := make([]shared.ResourceHistory, 0)
+	for rows.Next() {
+		var history shared.ResourceHistory
+		err := rows.Scan(&history.Id, &history.ResourceId, &history.ResourceName, &history.UserId, &history.Action, &history.CreatedAt, &history.UpdatedAt, &history.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse resource history")
+		}
+		histories = append(histories, history)
+	}
+
+	return histories, nil
+}
+
+func (repo *ResourceHistoryRepository) ReassignUser(fromUserId string, toUserId string) error {
+	_, err := repo.Exec(
+		"update resource_history set user_id = ?, updated_at = ? where user_id = ?",
+		toUserId,
+		util.NowUnix(),
+		fromUserId,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to reassign resource history")
+	}
+
+	return nil
+}
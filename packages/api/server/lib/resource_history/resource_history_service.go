@@ -41,3 +41,15 @@ func (service *ResourceHistoryService) Create(resourceId string, resourceName st
 
 	return history, nil;
 }
+
+func (service *ResourceHistoryService) FindByUserId(userId string) ([]shared.ResourceHistory, error) {
+	histories, err := service.resourceHistoryRepository.FindByUserId(userId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("could not find resource history")
+	}
+	return histories, nil
+}
+
+func (service *ResourceHistoryService) ReassignUser(fromUserId string, toUserId string) error {
+	return service.resourceHistoryRepository.ReassignUser(fromUserId, toUserId)
+}
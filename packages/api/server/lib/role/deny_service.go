@@ -0,0 +1,195 @@
+package role
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/folder"
+	"github.com/honerlaw/mentordoc/server/lib/organization"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	uuid "github.com/satori/go.uuid"
+	"strings"
+)
+
+/*
+Lets the owners of an organization take actions away from a user or role on a resource in the organization, which the
+roles alone can not do since they only ever add actions. Managing denies requires the manage:role action.
+*/
+type DenyService struct {
+	organizationService *organization.OrganizationService
+	folderService       *folder.FolderService
+	documentService     *document.DocumentService
+	aclService          *acl.AclService
+}
+
+func NewDenyService(
+	organizationService *organization.OrganizationService,
+	folderService *folder.FolderService,
+	documentService *document.DocumentService,
+	aclService *acl.AclService,
+) *DenyService {
+	return &DenyService{
+		organizationService: organizationService,
+		folderService:       folderService,
+		documentService:     documentService,
+		aclService:          aclService,
+	}
+}
+
+func (service *DenyService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewDenyService(
+		service.organizationService.InjectTransaction(tx).(*organization.OrganizationService),
+		service.folderService.InjectTransaction(tx).(*folder.FolderService),
+		service.documentService.InjectTransaction(tx).(*document.DocumentService),
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+	)
+}
+
+func (service *DenyService) List(user *shared.User, organizationId string) ([]acl.AclDeny, error) {
+	err := service.canManageDenies(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	denies, err := service.aclService.FindOrganizationDenies(organizationId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find denies")
+	}
+
+	return denies, nil
+}
+
+/**
+Denies the action to either the user or every holder of the role, on the resource and everything below it
+*/
+func (service *DenyService) Create(user *shared.User, organizationId string, deniedUser *shared.User, roleId *string, resourcePath string, resourceId string, action string) (*acl.AclDeny, error) {
+	err := service.canManageDenies(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.validate(organizationId, deniedUser, roleId, resourcePath, resourceId, action)
+	if err != nil {
+		return nil, err
+	}
+
+	deny := &acl.AclDeny{
+		Id:             uuid.NewV4().String(),
+		OrganizationId: organizationId,
+		RoleId:         roleId,
+		ResourcePath:   resourcePath,
+		ResourceId:     resourceId,
+		Action:         action,
+	}
+	if deniedUser != nil {
+		deny.UserId = &deniedUser.Id
+	}
+
+	err = service.aclService.CreateDeny(deny)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create deny")
+	}
+
+	return deny, nil
+}
+
+func (service *DenyService) Delete(user *shared.User, organizationId string, denyId string) (*acl.AclDeny, error) {
+	err := service.canManageDenies(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	deny := service.aclService.FindDenyById(denyId)
+	if deny == nil || deny.OrganizationId != organizationId {
+		return nil, shared.NewNotFoundError("could not find deny")
+	}
+
+	err = service.aclService.DeleteDeny(deny)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete deny")
+	}
+
+	return deny, nil
+}
+
+func (service *DenyService) canManageDenies(user *shared.User, organizationId string) error {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return shared.NewNotFoundError("could not find organization")
+	}
+
+	if !service.aclService.UserCanAccessResourceByModel(user, org, "manage:role") {
+		return shared.NewForbiddenError("you do not have permission to manage denies in this organization")
+	}
+
+	return nil
+}
+
+func (service *DenyService) validate(organizationId string, deniedUser *shared.User, roleId *string, resourcePath string, resourceId string, action string) error {
+	messages := make([]string, 0)
+
+	if (deniedUser == nil) == (roleId == nil) {
+		messages = append(messages, "a deny must be for either a user or a role")
+	}
+
+	// the built in roles can be used along with the roles of the organization
+	if roleId != nil {
+		role, err := service.aclService.FindRoleById(*roleId)
+		if err != nil {
+			return shared.NewInternalServerError("failed to find role")
+		}
+		if role == nil || (role.OrganizationId != nil && *role.OrganizationId != organizationId) {
+			messages = append(messages, "could not find role")
+		}
+	}
+
+	if !service.aclService.IsResourcePath(resourcePath) {
+		messages = append(messages, fmt.Sprintf("%s is not a valid resource path", resourcePath))
+	} else if !service.resourceInOrganization(organizationId, resourcePath, resourceId) {
+		messages = append(messages, "could not find the resource in the organization")
+	}
+
+	if action != acl.DenyAllActions {
+		actions, err := service.aclService.FindActions()
+		if err != nil {
+			return shared.NewInternalServerError("failed to find actions")
+		}
+
+		known := false
+		for _, a := range actions {
+			known = known || a == action
+		}
+		if !known {
+			messages = append(messages, fmt.Sprintf("%s is not a valid action", action))
+		}
+	}
+
+	if len(messages) > 0 {
+		return shared.NewBadRequestError(messages...)
+	}
+
+	return nil
+}
+
+/**
+The id of a deny belongs to the first resource in its path, which has to be part of the organization
+*/
+func (service *DenyService) resourceInOrganization(organizationId string, resourcePath string, resourceId string) bool {
+	return resourceInOrganization(service.folderService, service.documentService, organizationId, strings.Split(resourcePath, ":")[0], resourceId)
+}
+
+func resourceInOrganization(folderService *folder.FolderService, documentService *document.DocumentService, organizationId string, resourceName string, resourceId string) bool {
+	switch resourceName {
+	case "organization":
+		return resourceId == organizationId
+	case "folder":
+		f := folderService.FindById(resourceId)
+		return f != nil && f.OrganizationId == organizationId
+	case "document":
+		d := documentService.FindById(resourceId)
+		return d != nil && d.OrganizationId == organizationId
+	}
+
+	return false
+}
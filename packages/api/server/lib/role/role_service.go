@@ -0,0 +1,341 @@
+package role
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/folder"
+	"github.com/honerlaw/mentordoc/server/lib/organization"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"strings"
+)
+
+/*
+Lets the owners of an organization define their own roles on top of the built in ones. A custom role is always linked
+to users on the organization itself, so its permissions apply to everything inside of the organization. Teams can also
+be given the built in roles on the resource they are for, the same way users get them.
+*/
+type RoleService struct {
+	organizationService *organization.OrganizationService
+	folderService       *folder.FolderService
+	documentService     *document.DocumentService
+	aclService          *acl.AclService
+}
+
+// the actions only the owners of an organization have, custom roles can not hand them out
+var ownerActions = []string{"view:acl", "manage:role"}
+
+func NewRoleService(
+	organizationService *organization.OrganizationService,
+	folderService *folder.FolderService,
+	documentService *document.DocumentService,
+	aclService *acl.AclService,
+) *RoleService {
+	return &RoleService{
+		organizationService: organizationService,
+		folderService:       folderService,
+		documentService:     documentService,
+		aclService:          aclService,
+	}
+}
+
+func (service *RoleService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewRoleService(
+		service.organizationService.InjectTransaction(tx).(*organization.OrganizationService),
+		service.folderService.InjectTransaction(tx).(*folder.FolderService),
+		service.documentService.InjectTransaction(tx).(*document.DocumentService),
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+	)
+}
+
+func (service *RoleService) Find(user *shared.User, organizationId string, roleId string) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.findRole(organizationId, roleId)
+}
+
+func (service *RoleService) List(user *shared.User, organizationId string) ([]acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := service.aclService.FindOrganizationRoles(organizationId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find roles")
+	}
+
+	return roles, nil
+}
+
+func (service *RoleService) Create(user *shared.User, organizationId string, name string, permissions map[string][]string) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.validate(organizationId, nil, name, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := service.aclService.CreateOrganizationRole(organizationId, name, permissions)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create role")
+	}
+
+	return role, nil
+}
+
+func (service *RoleService) Update(user *shared.User, organizationId string, roleId string, name string, permissions map[string][]string) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := service.findRole(organizationId, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.validate(organizationId, role, name, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err = service.aclService.UpdateOrganizationRole(role, name, permissions)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to update role")
+	}
+
+	return role, nil
+}
+
+func (service *RoleService) Delete(user *shared.User, organizationId string, roleId string) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := service.findRole(organizationId, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := service.aclService.CountUsersWithRoleId(role.Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete role")
+	}
+	if count > 0 {
+		return nil, shared.NewBadRequestError(fmt.Sprintf("role is still held by %d user(s)", count))
+	}
+
+	count, err = service.aclService.CountTeamsWithRoleId(role.Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete role")
+	}
+	if count > 0 {
+		return nil, shared.NewBadRequestError(fmt.Sprintf("role is still held by %d team(s)", count))
+	}
+
+	err = service.aclService.DeleteOrganizationRole(role)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete role")
+	}
+
+	return role, nil
+}
+
+/**
+Gives the user the role in the organization
+*/
+func (service *RoleService) Assign(user *shared.User, organizationId string, roleId string, assignee *shared.User, expiresAt *int64) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt != nil && *expiresAt <= util.NowUnix() {
+		return nil, shared.NewBadRequestError("expiration must be in the future")
+	}
+
+	role, err := service.findRole(organizationId, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.aclService.LinkUserToRoleByIdUntil(assignee, role.Id, organizationId, expiresAt)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to assign role")
+	}
+
+	return role, nil
+}
+
+func (service *RoleService) Unassign(user *shared.User, organizationId string, roleId string, assignee *shared.User) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := service.findRole(organizationId, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.aclService.UnlinkUserFromRoleById(assignee, role.Id, organizationId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to unassign role")
+	}
+
+	return role, nil
+}
+
+/**
+Gives every member of the team the role on the resource, which is the organization when no resource is given. A custom
+role can only be given on the organization, a built in role only on the kind of resource it is named after, e.g.
+folder:viewer on a folder of the organization.
+*/
+func (service *RoleService) AssignTeam(user *shared.User, organizationId string, roleId string, team *shared.Team, resourceId *string) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if team.OrganizationId != organizationId {
+		return nil, shared.NewNotFoundError("could not find team")
+	}
+
+	role, grantedResourceId, err := service.findTeamRole(organizationId, roleId, resourceId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.aclService.LinkTeamToRoleById(team.Id, role.Id, grantedResourceId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to assign role")
+	}
+
+	return role, nil
+}
+
+func (service *RoleService) UnassignTeam(user *shared.User, organizationId string, roleId string, team *shared.Team, resourceId *string) (*acl.Role, error) {
+	err := service.canManageRoles(user, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if team.OrganizationId != organizationId {
+		return nil, shared.NewNotFoundError("could not find team")
+	}
+
+	role, grantedResourceId, err := service.findTeamRole(organizationId, roleId, resourceId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.aclService.UnlinkTeamFromRoleById(team.Id, role.Id, grantedResourceId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to unassign role")
+	}
+
+	return role, nil
+}
+
+func (service *RoleService) canManageRoles(user *shared.User, organizationId string) error {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return shared.NewNotFoundError("could not find organization")
+	}
+
+	if !service.aclService.UserCanAccessResourceByModel(user, org, "manage:role") {
+		return shared.NewForbiddenError("you do not have permission to manage roles in this organization")
+	}
+
+	return nil
+}
+
+/**
+Finds the role, making sure it is a custom role of the organization, the built in roles can never be changed
+*/
+func (service *RoleService) findRole(organizationId string, roleId string) (*acl.Role, error) {
+	role, err := service.aclService.FindRoleById(roleId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find role")
+	}
+
+	if role == nil || role.OrganizationId == nil || *role.OrganizationId != organizationId {
+		return nil, shared.NewNotFoundError("could not find role")
+	}
+
+	return role, nil
+}
+
+/**
+Finds a role that can be given to a team on the resource, along with the id of the resource the grant is for
+*/
+func (service *RoleService) findTeamRole(organizationId string, roleId string, resourceId *string) (*acl.Role, string, error) {
+	role, err := service.aclService.FindRoleById(roleId)
+	if err != nil {
+		return nil, "", shared.NewInternalServerError("failed to find role")
+	}
+
+	if role == nil || (role.OrganizationId != nil && *role.OrganizationId != organizationId) {
+		return nil, "", shared.NewNotFoundError("could not find role")
+	}
+
+	grantedResourceId := organizationId
+	if resourceId != nil {
+		grantedResourceId = *resourceId
+	}
+
+	// the custom roles only have permissions relative to the organization
+	resourceName := "organization"
+	if role.OrganizationId == nil {
+		resourceName = strings.Split(role.Name, ":")[0]
+	}
+
+	if !resourceInOrganization(service.folderService, service.documentService, organizationId, resourceName, grantedResourceId) {
+		return nil, "", shared.NewBadRequestError(fmt.Sprintf("%s can only be given on a resource of type %s in the organization", role.Name, resourceName))
+	}
+
+	return role, grantedResourceId, nil
+}
+
+func (service *RoleService) validate(organizationId string, existing *acl.Role, name string, permissions map[string][]string) error {
+	messages := make([]string, 0)
+
+	if duplicate := service.aclService.FindOrganizationRoleByName(organizationId, name); duplicate != nil && (existing == nil || duplicate.Id != existing.Id) {
+		messages = append(messages, fmt.Sprintf("a role named %s already exists", name))
+	}
+
+	for path, actions := range permissions {
+		// custom roles are only ever given on the organization, so permissions on any other path would never apply
+		if path != "organization" && !strings.HasPrefix(path, "organization:") {
+			messages = append(messages, fmt.Sprintf("%s is not a path in the organization", path))
+			continue
+		}
+
+		for _, action := range actions {
+			if !service.aclService.PermissionExists(path, action) {
+				messages = append(messages, fmt.Sprintf("%s is not a valid action on %s", action, path))
+			}
+			for _, ownerAction := range ownerActions {
+				if action == ownerAction {
+					messages = append(messages, fmt.Sprintf("%s is only for the owners of the organization", action))
+				}
+			}
+		}
+	}
+
+	if len(messages) > 0 {
+		return shared.NewBadRequestError(messages...)
+	}
+
+	return nil
+}
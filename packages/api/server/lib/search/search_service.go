@@ -0,0 +1,247 @@
+package search
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/folder"
+	"github.com/honerlaw/mentordoc/server/lib/organization"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"strings"
+)
+
+const HitTypeOrganization = "organization"
+const HitTypeFolder = "folder"
+const HitTypeDocument = "document"
+
+/**
+How many hits of each type are found when the search is not paginated
+*/
+const defaultHitCount = 10
+
+var hitTypes = []string{HitTypeOrganization, HitTypeFolder, HitTypeDocument}
+
+/*
+A single search result, the acl wrapped organization, folder or document along with its path from the organization down
+to the result itself, e.g. Org / Eng / Runbooks / Deploy
+*/
+type Hit struct {
+	acl.AclWrappedModel
+	Type string     `json:"type"`
+	Path []PathItem `json:"path"`
+}
+
+type PathItem struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+/*
+Searches organizations and folders by name and documents by their latest draft, see DocumentService.Search. Each type is
+searched on its own and the hits are returned grouped by type, organizations first, so paginating pages through each type
+at the same time.
+*/
+type SearchService struct {
+	organizationService *organization.OrganizationService
+	folderService       *folder.FolderService
+	documentService     *document.DocumentService
+	aclService          *acl.AclService
+}
+
+func NewSearchService(
+	organizationService *organization.OrganizationService,
+	folderService *folder.FolderService,
+	documentService *document.DocumentService,
+	aclService *acl.AclService,
+) *SearchService {
+	return &SearchService{
+		organizationService: organizationService,
+		folderService:       folderService,
+		documentService:     documentService,
+		aclService:          aclService,
+	}
+}
+
+/**
+Checks the requested types of hits, every type is searched if none are given
+*/
+func ParseHitTypes(value string) ([]string, error) {
+	if len(value) == 0 {
+		return hitTypes, nil
+	}
+
+	types := make([]string, 0)
+	for _, hitType := range strings.Split(value, ",") {
+		hitType = strings.TrimSpace(hitType)
+		if hitType != HitTypeOrganization && hitType != HitTypeFolder && hitType != HitTypeDocument {
+			return nil, shared.NewBadRequestError(fmt.Sprintf("unknown type %s, must be one of %s", hitType, strings.Join(hitTypes, ", ")))
+		}
+		types = append(types, hitType)
+	}
+
+	return types, nil
+}
+
+func (service *SearchService) Search(user *shared.User, searchQuery string, types []string, pagination *shared.Pagination) ([]Hit, error) {
+	parsed, err := document.ParseSearchQuery(searchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	if pagination == nil {
+		pagination = &shared.Pagination{Page: 0, Count: defaultHitCount}
+	}
+
+	wanted := make(map[string]bool)
+	for _, hitType := range types {
+		wanted[hitType] = true
+	}
+
+	// organizations and folders are only found by name, and the qualifiers only apply to documents
+	searchNames := len(parsed.Text) > 0 && !parsed.HasQualifiers()
+
+	orgs := make([]shared.Organization, 0)
+	if wanted[HitTypeOrganization] && searchNames {
+		orgs, err = service.organizationService.Search(user, parsed.Text, pagination)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	folders := make([]shared.Folder, 0)
+	if wanted[HitTypeFolder] && searchNames {
+		folders, err = service.folderService.Search(user, parsed.Text, pagination)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	documents := make([]shared.Document, 0)
+	if wanted[HitTypeDocument] {
+		documents, err = service.documentService.Search(user, searchQuery, nil, pagination)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	paths := newPathBuilder(service.organizationService, service.folderService)
+	models := make([]interface{}, 0)
+	hits := make([]Hit, 0)
+	for _, org := range orgs {
+		models = append(models, org)
+		hits = append(hits, Hit{Type: HitTypeOrganization})
+		paths.add(org.Id, nil, PathItem{Type: HitTypeOrganization, Id: org.Id, Name: org.Name})
+	}
+	for _, f := range folders {
+		models = append(models, f)
+		hits = append(hits, Hit{Type: HitTypeFolder})
+		paths.add(f.OrganizationId, f.ParentFolderId, PathItem{Type: HitTypeFolder, Id: f.Id, Name: f.Name})
+	}
+	for _, doc := range documents {
+		name := ""
+		if len(doc.Drafts) > 0 {
+			name = doc.Drafts[0].Name
+		}
+		models = append(models, doc)
+		hits = append(hits, Hit{Type: HitTypeDocument})
+		paths.add(doc.OrganizationId, doc.FolderId, PathItem{Type: HitTypeDocument, Id: doc.Id, Name: name})
+	}
+
+	err = paths.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := service.aclService.Wrap(user, models)
+	if err != nil {
+		return nil, shared.NewInternalServerError("found results but failed to find user access")
+	}
+	for i := range hits {
+		hits[i].AclWrappedModel = wrapped[i]
+		hits[i].Path = paths.paths[i]
+	}
+
+	return hits, nil
+}
+
+/*
+Builds the paths of the hits, looking up all of the organizations and folders in them at once. The hits are added first,
+with the organization and the folder they are in, and resolve fills in the names of everything above them.
+*/
+type pathBuilder struct {
+	organizationService *organization.OrganizationService
+	folderService       *folder.FolderService
+	organizationIds     []string
+	parentFolderIds     []*string
+	paths               [][]PathItem
+}
+
+func newPathBuilder(organizationService *organization.OrganizationService, folderService *folder.FolderService) *pathBuilder {
+	return &pathBuilder{
+		organizationService: organizationService,
+		folderService:       folderService,
+	}
+}
+
+func (builder *pathBuilder) add(organizationId string, parentFolderId *string, item PathItem) {
+	builder.organizationIds = append(builder.organizationIds, organizationId)
+	builder.parentFolderIds = append(builder.parentFolderIds, parentFolderId)
+	builder.paths = append(builder.paths, []PathItem{item})
+}
+
+func (builder *pathBuilder) resolve() error {
+	// the folders above each hit, root folder first
+	ancestry := make([][]string, len(builder.paths))
+	folderIds := make([]string, 0)
+	for i, parentFolderId := range builder.parentFolderIds {
+		if parentFolderId == nil {
+			continue
+		}
+
+		ancestorIds, err := builder.folderService.FindAncestorIds(*parentFolderId)
+		if err != nil {
+			return shared.NewInternalServerError("failed to find the path of the results")
+		}
+
+		ids := []string{*parentFolderId}
+		ids = append(ids, ancestorIds...)
+		for left, right := 0, len(ids)-1; left < right; left, right = left+1, right-1 {
+			ids[left], ids[right] = ids[right], ids[left]
+		}
+		ancestry[i] = ids
+		folderIds = append(folderIds, ids...)
+	}
+
+	folders, err := builder.folderService.FindByIds(folderIds)
+	if err != nil {
+		return shared.NewInternalServerError("failed to find the path of the results")
+	}
+	folderNames := make(map[string]string)
+	for _, f := range folders {
+		folderNames[f.Id] = f.Name
+	}
+
+	orgs, err := builder.organizationService.FindByIds(builder.organizationIds)
+	if err != nil {
+		return shared.NewInternalServerError("failed to find the path of the results")
+	}
+	orgNames := make(map[string]string)
+	for _, org := range orgs {
+		orgNames[org.Id] = org.Name
+	}
+
+	for i, item := range builder.paths {
+		path := make([]PathItem, 0)
+		if item[0].Type != HitTypeOrganization {
+			organizationId := builder.organizationIds[i]
+			path = append(path, PathItem{Type: HitTypeOrganization, Id: organizationId, Name: orgNames[organizationId]})
+		}
+		for _, id := range ancestry[i] {
+			path = append(path, PathItem{Type: HitTypeFolder, Id: id, Name: folderNames[id]})
+		}
+		builder.paths[i] = append(path, item...)
+	}
+
+	return nil
+}
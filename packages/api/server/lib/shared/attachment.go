@@ -0,0 +1,12 @@
+package shared
+
+type Attachment struct {
+	Entity
+
+	DocumentId  string `json:"documentId"`
+	CreatorId   string `json:"creatorId"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	StorageKey  string `json:"-"`
+}
@@ -0,0 +1,36 @@
+package shared
+
+/*
+A discussion on a draft of a document. The anchor is nil for comments on the draft as a whole.
+*/
+type CommentThread struct {
+	Entity
+
+	DocumentId      string         `json:"documentId"`
+	DocumentDraftId string         `json:"documentDraftId"`
+	CreatorId       string         `json:"creatorId"`
+	Anchor          *CommentAnchor `json:"anchor"`
+	ResolvedAt      *int64         `json:"resolvedAt"`
+	ResolvedBy      *string        `json:"resolvedBy"`
+	Comments        []Comment      `json:"comments"`
+}
+
+/*
+Where a thread is in the content of its draft, either a range of characters (Start up to End) along with the text in it
+at the time, or a line starting at 1
+*/
+type CommentAnchor struct {
+	Start *int   `json:"start,omitempty"`
+	End   *int   `json:"end,omitempty"`
+	Line  *int   `json:"line,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+type Comment struct {
+	Entity
+
+	CommentThreadId string `json:"commentThreadId"`
+	CreatorId       string `json:"creatorId"`
+	Content         string `json:"content"`
+	EditedAt        *int64 `json:"editedAt"`
+}
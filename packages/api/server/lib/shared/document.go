@@ -1,9 +1,11 @@
 package shared
 
 type Document struct {
-	Entity
+	Entity `acl:"document"`
 
-	OrganizationId     string          `json:"organizationId"`
-	FolderId           *string         `json:"folderId"`
+	OrganizationId     string          `json:"organizationId" acl:"organization"`
+	FolderId           *string         `json:"folderId" acl:"folder"`
 	Drafts             []DocumentDraft `json:"drafts"`
+	Tags               []Tag           `json:"tags"`
+	Match              *SearchMatch    `json:"match,omitempty"` // only set on search results
 }
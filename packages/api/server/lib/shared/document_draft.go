@@ -9,4 +9,6 @@ type DocumentDraft struct {
 	CreatorId   string           `json:"creatorId"`
 	PublishedAt *int64           `json:"publishedAt"`
 	RetractedAt *int64           `json:"retractedAt"`
+	PublishAt   *int64           `json:"publishAt"` // when the scheduler publishes the draft
+	RetractAt   *int64           `json:"retractAt"` // when the scheduler retracts the draft
 }
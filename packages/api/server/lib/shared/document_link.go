@@ -0,0 +1,27 @@
+package shared
+
+const DocumentLinkStatusOk = "ok"
+const DocumentLinkStatusDeleted = "deleted"
+const DocumentLinkStatusInaccessible = "inaccessible"
+
+/*
+A link from a draft to another document
+*/
+type DocumentLink struct {
+	Entity
+
+	DocumentId       string `json:"documentId"`
+	DocumentDraftId  string `json:"documentDraftId"`
+	TargetDocumentId string `json:"targetDocumentId"`
+}
+
+/*
+Where a link goes for the user. The link is broken when the target is deleted, or when the user can not view it, in which
+case the target is left out.
+*/
+type DocumentLinkStatus struct {
+	TargetDocumentId string    `json:"targetDocumentId"`
+	Status           string    `json:"status"`
+	Broken           bool      `json:"broken"`
+	Target           *Document `json:"target,omitempty"`
+}
@@ -0,0 +1,46 @@
+package shared
+
+const ReviewDecisionApprove = "approve"
+const ReviewDecisionRequestChanges = "request_changes"
+
+/*
+The approvals the drafts of the documents in an organization, or in a folder, need before they can be published
+*/
+type ReviewPolicy struct {
+	Entity
+
+	OrganizationId    string  `json:"organizationId"`
+	FolderId          *string `json:"folderId"`
+	RequiredApprovals int     `json:"requiredApprovals"`
+}
+
+type DocumentReviewRequest struct {
+	Entity
+
+	DocumentDraftId string `json:"documentDraftId"`
+	RequesterId     string `json:"requesterId"`
+}
+
+type DocumentReview struct {
+	Entity
+
+	DocumentDraftId string  `json:"documentDraftId"`
+	ReviewerId      string  `json:"reviewerId"`
+	Decision        string  `json:"decision"`
+	Comment         *string `json:"comment"`
+}
+
+/*
+Where the review of a draft is at. The reviews are the latest review of each reviewer, and the draft can be published
+once it has the required approvals and none of the reviewers are waiting on changes.
+*/
+type ReviewStatus struct {
+	Draft             *DocumentDraft         `json:"draft"`
+	Policy            *ReviewPolicy          `json:"policy"`
+	Request           *DocumentReviewRequest `json:"request"`
+	Reviews           []DocumentReview       `json:"reviews"`
+	RequiredApprovals int                    `json:"requiredApprovals"`
+	Approvals         int                    `json:"approvals"`
+	ChangesRequested  int                    `json:"changesRequested"`
+	CanPublish        bool                   `json:"canPublish"`
+}
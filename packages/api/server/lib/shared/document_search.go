@@ -0,0 +1,97 @@
+package shared
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const DocumentSearchStatusPublished = "published"
+const DocumentSearchStatusDraft = "draft"
+
+/*
+Narrows down a document search, every field is optional. The status is either published, for published documents, or
+draft, for the drafts of the user that have not been published yet. The date range applies to when the draft was last
+updated, in unix nano. The title is matched against part of the draft name, and the author is the email of the creator.
+The tag is the name of a tag on the documents, which the search narrows down to the documents with the document ids.
+*/
+type DocumentSearchFilter struct {
+	FolderIds   []string
+	DocumentIds []string
+	Tag         *string
+	CreatorId   *string
+	Author      *string
+	Title       *string
+	Status      string
+	From        *int64
+	To          *int64
+}
+
+/**
+Reads the filter from the query parameters of the request
+*/
+func NewDocumentSearchFilter(req *http.Request) (*DocumentSearchFilter, error) {
+	query := req.URL.Query()
+	filter := &DocumentSearchFilter{
+		Status: query.Get("status"),
+	}
+	messages := make([]string, 0)
+
+	if folderId := query.Get("folderId"); len(folderId) > 0 {
+		filter.FolderIds = []string{folderId}
+	}
+	if creatorId := query.Get("creatorId"); len(creatorId) > 0 {
+		filter.CreatorId = &creatorId
+	}
+	if tag := query.Get("tag"); len(tag) > 0 {
+		filter.Tag = &tag
+	}
+
+	if filter.Status != "" && filter.Status != DocumentSearchStatusPublished && filter.Status != DocumentSearchStatusDraft {
+		messages = append(messages, "status must be published or draft")
+	}
+
+	var err error
+	if filter.From, err = parseUnixParam(query.Get("from")); err != nil {
+		messages = append(messages, "from must be a unix timestamp")
+	}
+	if filter.To, err = parseUnixParam(query.Get("to")); err != nil {
+		messages = append(messages, "to must be a unix timestamp")
+	}
+
+	if filter.From != nil && filter.To != nil && *filter.From > *filter.To {
+		messages = append(messages, "from must be before to")
+	}
+
+	if len(messages) > 0 {
+		return nil, NewBadRequestError(messages...)
+	}
+
+	return filter, nil
+}
+
+func parseUnixParam(value string) (*int64, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+/*
+How well a document matched a search, the highlights are html escaped snippets of the field with the matches wrapped in
+<mark> tags
+*/
+type SearchMatch struct {
+	Score      float64           `json:"score"`
+	Highlights []SearchHighlight `json:"highlights"`
+}
+
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
@@ -1,10 +1,10 @@
 package shared
 
 type Folder struct {
-	Entity
+	Entity `acl:"folder"`
 
 	Name           string  `json:"name"`
-	OrganizationId string  `json:"organizationId"`
+	OrganizationId string  `json:"organizationId" acl:"organization"`
 	ParentFolderId *string `json:"parentFolderId"`
 	ChildCount     int     `json:"childCount"`
 }
@@ -8,6 +8,17 @@ type HttpError struct {
 	error
 	Status  int `json:"-"`
 	Errors []string `json:"errors"`
+	Details []HttpErrorDetail `json:"details,omitempty"`
+}
+
+/*
+Points at the part of the input that caused an error, e.g. the token of a search query that could not be parsed. The
+position is the byte offset of the token in the input.
+*/
+type HttpErrorDetail struct {
+	Message  string `json:"message"`
+	Token    string `json:"token"`
+	Position int    `json:"position"`
 }
 
 func NewInternalServerError(message... string) *HttpError {
@@ -24,6 +35,19 @@ func NewBadRequestError(message... string) *HttpError {
 	}
 }
 
+func NewBadRequestErrorWithDetails(details... HttpErrorDetail) *HttpError {
+	messages := make([]string, len(details))
+	for i, detail := range details {
+		messages[i] = detail.Message
+	}
+
+	return &HttpError{
+		Status:  http.StatusBadRequest,
+		Errors:  messages,
+		Details: details,
+	}
+}
+
 func NewForbiddenError(message... string) *HttpError {
 	return &HttpError{
 		Status: http.StatusForbidden,
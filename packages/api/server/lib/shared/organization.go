@@ -1,7 +1,7 @@
 package shared
 
 type Organization struct {
-	Entity
+	Entity `acl:"organization"`
 
 	Name string `json:"name"`
 }
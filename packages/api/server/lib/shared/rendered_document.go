@@ -0,0 +1,23 @@
+package shared
+
+const RenderFormatHtml = "html"
+
+/*
+The latest draft of a document the user can view rendered to the format, along with the table of contents of its headings
+*/
+type RenderedDocument struct {
+	DocumentId string     `json:"documentId"`
+	DraftId    string     `json:"draftId"`
+	Format     string     `json:"format"`
+	Content    string     `json:"content"`
+	Toc        []TocEntry `json:"toc"`
+}
+
+/*
+A heading of the document, the anchor is the id of the heading in the rendered html
+*/
+type TocEntry struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+}
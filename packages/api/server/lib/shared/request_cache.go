@@ -0,0 +1,40 @@
+package shared
+
+import "sync"
+
+/*
+Holds values for the lifetime of a single request, e.g. the acl grants of the authenticated user so they are only
+loaded once no matter how many checks the request does
+*/
+type RequestCache struct {
+	lock   sync.Mutex
+	values map[string]interface{}
+}
+
+func NewRequestCache() *RequestCache {
+	return &RequestCache{
+		values: make(map[string]interface{}),
+	}
+}
+
+func (cache *RequestCache) Get(key string) (interface{}, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	value, ok := cache.values[key]
+	return value, ok
+}
+
+func (cache *RequestCache) Set(key string, value interface{}) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.values[key] = value
+}
+
+func (cache *RequestCache) Delete(key string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	delete(cache.values, key)
+}
@@ -0,0 +1,18 @@
+package shared
+
+type Tag struct {
+	Entity
+
+	OrganizationId string `json:"organizationId"`
+	Name           string `json:"name"`
+}
+
+/*
+Links a tag to a document
+*/
+type DocumentTag struct {
+	Entity
+
+	DocumentId string `json:"documentId"`
+	TagId      string `json:"tagId"`
+}
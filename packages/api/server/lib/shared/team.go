@@ -0,0 +1,8 @@
+package shared
+
+type Team struct {
+	Entity
+
+	OrganizationId string `json:"organizationId"`
+	Name           string `json:"name"`
+}
@@ -5,5 +5,9 @@ type User struct {
 
 	Email string `json:"email"`
 
-	Password string `json:"-"`
+	Password *string `json:"-"` // nil if the user only signs in through single sign on
+
+	Scopes []string `json:"-"` // nil unless authenticated with a personal access token, limits the allowed acl actions
+
+	Cache *RequestCache `json:"-"` // nil outside of a request
 }
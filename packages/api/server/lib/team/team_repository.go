@@ -0,0 +1,220 @@
+package team
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+type TeamRepository struct {
+	util.Repository
+}
+
+func NewTeamRepository(db *sql.DB, tx *sql.Tx) *TeamRepository {
+	repo := &TeamRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *TeamRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewTeamRepository(repo.Db, tx)
+}
+
+func (repo *TeamRepository) FindById(id string) *shared.Team {
+	row := repo.QueryRow(
+		"select id, organization_id, name, created_at, updated_at, deleted_at from team where id = ? and deleted_at is null",
+		id,
+	)
+
+	var team shared.Team
+	err := row.Scan(&team.Id, &team.OrganizationId, &team.Name, &team.CreatedAt, &team.UpdatedAt, &team.DeletedAt)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	return &team
+}
+
+func (repo *TeamRepository) FindByOrganizationIdAndName(organizationId string, name string) *shared.Team {
+	row := repo.QueryRow(
+		"select id, organization_id, name, created_at, updated_at, deleted_at from team where organization_id = ? and name = ? and deleted_at is null",
+		organizationId,
+		name,
+	)
+
+	var team shared.Team
+	err := row.Scan(&team.Id, &team.OrganizationId, &team.Name, &team.CreatedAt, &team.UpdatedAt, &team.DeletedAt)
+	if err != nil {
+		return nil
+	}
+
+	return &team
+}
+
+func (repo *TeamRepository) FindByOrganizationId(organizationId string) ([]shared.Team, error) {
+	rows, err := repo.Query(
+		"select id, organization_id, name, created_at, updated_at, deleted_at from team where organization_id = ? and deleted_at is null ORDER BY name ASC",
+		organizationId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find teams")
+	}
+	defer rows.Close()
+
+	teams := make([]shared.Team, 0)
+	for rows.Next() {
+		var team shared.Team
+		err := rows.Scan(&team.Id, &team.OrganizationId, &team.Name, &team.CreatedAt, &team.UpdatedAt, &team.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse team")
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+func (repo *TeamRepository) Insert(team *shared.Team) (*shared.Team, error) {
+	team.CreatedAt = util.NowUnix()
+	team.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into team (id, organization_id, name, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?)",
+		team.Id,
+		team.OrganizationId,
+		team.Name,
+		team.CreatedAt,
+		team.UpdatedAt,
+		team.DeletedAt,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to insert team")
+	}
+
+	return team, nil
+}
+
+func (repo *TeamRepository) Update(team *shared.Team) (*shared.Team, error) {
+	team.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update team set name = ?, updated_at = ?, deleted_at = ? where id = ?",
+		team.Name,
+		team.UpdatedAt,
+		team.DeletedAt,
+		team.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to update team")
+	}
+
+	return team, nil
+}
+
+func (repo *TeamRepository) AddMember(team *shared.Team, user *shared.User) error {
+	// check if they are already a member
+	row := repo.QueryRow(
+		"select count(*) from team_member where team_id = ? and user_id = ?",
+		team.Id,
+		user.Id,
+	)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to add team member")
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	_, err = repo.Exec(
+		"insert into team_member (team_id, user_id) values (?, ?)",
+		team.Id,
+		user.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to add team member")
+	}
+
+	return nil
+}
+
+func (repo *TeamRepository) RemoveMember(team *shared.Team, user *shared.User) error {
+	_, err := repo.Exec(
+		"delete from team_member where team_id = ? and user_id = ?",
+		team.Id,
+		user.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to remove team member")
+	}
+
+	return nil
+}
+
+func (repo *TeamRepository) RemoveAllMembers(team *shared.Team) error {
+	_, err := repo.Exec(
+		"delete from team_member where team_id = ?",
+		team.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to remove team members")
+	}
+
+	return nil
+}
+
+/**
+Takes the user out of every team they are in, in any organization
+*/
+func (repo *TeamRepository) RemoveMemberFromAllTeams(user *shared.User) error {
+	_, err := repo.Exec(
+		"delete from team_member where user_id = ?",
+		user.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to remove team member")
+	}
+
+	return nil
+}
+
+func (repo *TeamRepository) FindMembers(team *shared.Team) ([]shared.User, error) {
+	rows, err := repo.Query(
+		"select u.id, u.email, u.created_at, u.updated_at, u.deleted_at from team_member tm join user u on u.id = tm.user_id where tm.team_id = ? and u.deleted_at is null ORDER BY u.email ASC",
+		team.Id,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find team members")
+	}
+	defer rows.Close()
+
+	users := make([]shared.User, 0)
+	for rows.Next() {
+		var user shared.User
+		err := rows.Scan(&user.Id, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
+		if err != nil {
+			log.Print(err)
+			return nil, errors.New("failed to parse team member")
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,260 @@
+package team
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/organization"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	uuid "github.com/satori/go.uuid"
+)
+
+/*
+Teams group the users of an organization, roles given to a team are given to every member of it. Viewing the teams of an
+organization requires the view:team action on it and changing them requires the manage:team action.
+*/
+type TeamService struct {
+	teamRepository      *TeamRepository
+	organizationService *organization.OrganizationService
+	aclService          *acl.AclService
+	transactionManager  *util.TransactionManager
+}
+
+func NewTeamService(
+	teamRepository *TeamRepository,
+	organizationService *organization.OrganizationService,
+	aclService *acl.AclService,
+	transactionManager *util.TransactionManager,
+) *TeamService {
+	return &TeamService{
+		teamRepository:      teamRepository,
+		organizationService: organizationService,
+		aclService:          aclService,
+		transactionManager:  transactionManager,
+	}
+}
+
+func (service *TeamService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewTeamService(
+		service.teamRepository.InjectTransaction(tx).(*TeamRepository),
+		service.organizationService.InjectTransaction(tx).(*organization.OrganizationService),
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+		service.transactionManager.InjectTransaction(tx).(*util.TransactionManager),
+	)
+}
+
+/**
+Finds the team without checking access to it, e.g. to resolve a team given in a request
+*/
+func (service *TeamService) FindById(id string) *shared.Team {
+	return service.teamRepository.FindById(id)
+}
+
+func (service *TeamService) Find(user *shared.User, organizationId string, teamId string) (*shared.Team, error) {
+	err := service.checkAccess(user, organizationId, "view:team")
+	if err != nil {
+		return nil, err
+	}
+
+	return service.findTeam(organizationId, teamId)
+}
+
+func (service *TeamService) List(user *shared.User, organizationId string) ([]shared.Team, error) {
+	err := service.checkAccess(user, organizationId, "view:team")
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := service.teamRepository.FindByOrganizationId(organizationId)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find teams")
+	}
+
+	return teams, nil
+}
+
+func (service *TeamService) Create(user *shared.User, organizationId string, name string) (*shared.Team, error) {
+	err := service.checkAccess(user, organizationId, "manage:team")
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.validate(organizationId, nil, name)
+	if err != nil {
+		return nil, err
+	}
+
+	team := &shared.Team{
+		OrganizationId: organizationId,
+		Name:           name,
+	}
+	team.Id = uuid.NewV4().String()
+
+	team, err = service.teamRepository.Insert(team)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create team")
+	}
+
+	return team, nil
+}
+
+func (service *TeamService) Update(user *shared.User, organizationId string, teamId string, name string) (*shared.Team, error) {
+	err := service.checkAccess(user, organizationId, "manage:team")
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := service.findTeam(organizationId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.validate(organizationId, team, name)
+	if err != nil {
+		return nil, err
+	}
+
+	team.Name = name
+	team, err = service.teamRepository.Update(team)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to update team")
+	}
+
+	return team, nil
+}
+
+/**
+Deletes the team, its members lose every role that was given to the team
+*/
+func (service *TeamService) Delete(user *shared.User, organizationId string, teamId string) (*shared.Team, error) {
+	err := service.checkAccess(user, organizationId, "manage:team")
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := service.findTeam(organizationId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*TeamService)
+
+		err := injectedService.aclService.UnlinkTeamFromAllRoles(team.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.teamRepository.RemoveAllMembers(team)
+		if err != nil {
+			return nil, err
+		}
+
+		deletedAt := util.NowUnix()
+		team.DeletedAt = &deletedAt
+		return injectedService.teamRepository.Update(team)
+	})
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete team")
+	}
+
+	return team, nil
+}
+
+func (service *TeamService) ListMembers(user *shared.User, organizationId string, teamId string) ([]shared.User, error) {
+	err := service.checkAccess(user, organizationId, "view:team")
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := service.findTeam(organizationId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := service.teamRepository.FindMembers(team)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find team members")
+	}
+
+	return members, nil
+}
+
+func (service *TeamService) AddMember(user *shared.User, organizationId string, teamId string, member *shared.User) (*shared.Team, error) {
+	err := service.checkAccess(user, organizationId, "manage:team")
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := service.findTeam(organizationId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.teamRepository.AddMember(team, member)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to add team member")
+	}
+
+	return team, nil
+}
+
+func (service *TeamService) RemoveMember(user *shared.User, organizationId string, teamId string, member *shared.User) (*shared.Team, error) {
+	err := service.checkAccess(user, organizationId, "manage:team")
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := service.findTeam(organizationId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.teamRepository.RemoveMember(team, member)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to remove team member")
+	}
+
+	return team, nil
+}
+
+/**
+Takes the user out of all of their teams without checking access, e.g. when their account is deleted
+*/
+func (service *TeamService) RemoveUserFromAllTeams(user *shared.User) error {
+	return service.teamRepository.RemoveMemberFromAllTeams(user)
+}
+
+func (service *TeamService) checkAccess(user *shared.User, organizationId string, action string) error {
+	org := service.organizationService.FindById(organizationId)
+	if org == nil {
+		return shared.NewNotFoundError("could not find organization")
+	}
+
+	if !service.aclService.UserCanAccessResourceByModel(user, org, action) {
+		return shared.NewForbiddenError("you do not have permission to do this to the teams of this organization")
+	}
+
+	return nil
+}
+
+/**
+Finds the team, making sure it belongs to the organization
+*/
+func (service *TeamService) findTeam(organizationId string, teamId string) (*shared.Team, error) {
+	team := service.teamRepository.FindById(teamId)
+	if team == nil || team.OrganizationId != organizationId {
+		return nil, shared.NewNotFoundError("could not find team")
+	}
+
+	return team, nil
+}
+
+func (service *TeamService) validate(organizationId string, existing *shared.Team, name string) error {
+	if duplicate := service.teamRepository.FindByOrganizationIdAndName(organizationId, name); duplicate != nil && (existing == nil || duplicate.Id != existing.Id) {
+		return shared.NewBadRequestError(fmt.Sprintf("a team named %s already exists", name))
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/attachment"
+	"github.com/honerlaw/mentordoc/server/lib/comment"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/resource_history"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/team"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+)
+
+// the user that the drafts, comments, attachments and history of deleted users are handed over to, created by the migrations
+const TombstoneUserId = "00000000-0000-0000-0000-000000000000"
+
+/*
+Handles everything around a user leaving, e.g. exporting all of their data and deleting their account
+*/
+type AccountService struct {
+	userRepository                *UserRepository
+	personalAccessTokenRepository *PersonalAccessTokenRepository
+	documentService               *document.DocumentService
+	commentService                *comment.CommentService
+	attachmentService             *attachment.AttachmentService
+	teamService                   *team.TeamService
+	resourceHistoryService        *resource_history.ResourceHistoryService
+	aclService                    *acl.AclService
+	transactionManager            *util.TransactionManager
+}
+
+func NewAccountService(
+	userRepository *UserRepository,
+	personalAccessTokenRepository *PersonalAccessTokenRepository,
+	documentService *document.DocumentService,
+	commentService *comment.CommentService,
+	attachmentService *attachment.AttachmentService,
+	teamService *team.TeamService,
+	resourceHistoryService *resource_history.ResourceHistoryService,
+	aclService *acl.AclService,
+	transactionManager *util.TransactionManager,
+) *AccountService {
+	return &AccountService{
+		userRepository:                userRepository,
+		personalAccessTokenRepository: personalAccessTokenRepository,
+		documentService:               documentService,
+		commentService:                commentService,
+		attachmentService:             attachmentService,
+		teamService:                   teamService,
+		resourceHistoryService:        resourceHistoryService,
+		aclService:                    aclService,
+		transactionManager:            transactionManager,
+	}
+}
+
+func (service *AccountService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewAccountService(
+		service.userRepository.InjectTransaction(tx).(*UserRepository),
+		service.personalAccessTokenRepository.InjectTransaction(tx).(*PersonalAccessTokenRepository),
+		service.documentService.InjectTransaction(tx).(*document.DocumentService),
+		service.commentService.InjectTransaction(tx).(*comment.CommentService),
+		service.attachmentService.InjectTransaction(tx).(*attachment.AttachmentService),
+		service.teamService.InjectTransaction(tx).(*team.TeamService),
+		service.resourceHistoryService.InjectTransaction(tx).(*resource_history.ResourceHistoryService),
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+		service.transactionManager.InjectTransaction(tx).(*util.TransactionManager),
+	)
+}
+
+/**
+Gathers all of the data that belongs to the user
+*/
+func (service *AccountService) Export(user *shared.User) (*UserExport, error) {
+	memberships, err := service.aclService.FindUserRoles(user)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to export memberships")
+	}
+
+	drafts, err := service.documentService.FindDraftsByCreator(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	threads, comments, err := service.commentService.FindByCreator(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := service.attachmentService.FindByCreator(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := service.resourceHistoryService.FindByUserId(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := service.personalAccessTokenRepository.FindByUserId(user.Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to export personal access tokens")
+	}
+
+	return &UserExport{
+		ExportedAt:           util.NowUnix(),
+		User:                 user,
+		Memberships:          memberships,
+		Drafts:               drafts,
+		CommentThreads:       threads,
+		Comments:             comments,
+		Attachments:          attachments,
+		History:              history,
+		PersonalAccessTokens: tokens,
+	}, nil
+}
+
+/*
+Deletes the account of the user. The user itself is anonymized instead of removed, their drafts, comments, attachments and
+history are handed over to the tombstone user, and they lose access to everything along with their teams and denies. A
+user can not leave while they are the only owner of an organization, someone else has to be made owner first.
+*/
+func (service *AccountService) Delete(user *shared.User) (*shared.User, error) {
+	if user.Scopes != nil {
+		return nil, shared.NewForbiddenError("personal access tokens can not delete accounts")
+	}
+
+	memberships, err := service.aclService.FindUserRoles(user)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete account")
+	}
+
+	resp, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injectedService := injected.(*AccountService)
+
+		// the owners are counted in the transaction with their grants locked, so co-owners leaving at the same time
+		// can not leave the organization without an owner
+		soleOwnerOf := make([]string, 0)
+		for _, membership := range memberships {
+			if membership.RoleName != "organization:owner" {
+				continue
+			}
+			count, err := injectedService.aclService.CountUsersWithRoleForUpdate(membership.RoleName, membership.ResourceId)
+			if err != nil {
+				return nil, err
+			}
+			if count <= 1 {
+				soleOwnerOf = append(soleOwnerOf, membership.ResourceId)
+			}
+		}
+
+		if len(soleOwnerOf) > 0 {
+			messages := make([]string, len(soleOwnerOf))
+			for i, organizationId := range soleOwnerOf {
+				messages[i] = fmt.Sprintf("you are the only owner of organization %s", organizationId)
+			}
+			return nil, shared.NewBadRequestError(messages...)
+		}
+
+		err := injectedService.documentService.ReassignCreator(user.Id, TombstoneUserId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.commentService.ReassignCreator(user.Id, TombstoneUserId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.attachmentService.ReassignCreator(user.Id, TombstoneUserId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.resourceHistoryService.ReassignUser(user.Id, TombstoneUserId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.aclService.UnlinkUserFromAllRoles(user)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.teamService.RemoveUserFromAllTeams(user)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.aclService.DeleteUserDenies(user)
+		if err != nil {
+			return nil, err
+		}
+
+		err = injectedService.personalAccessTokenRepository.DeleteByUserId(user.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		deletedAt := util.NowUnix()
+		user.Email = fmt.Sprintf("deleted-%s@mentordoc.invalid", user.Id)
+		user.Password = nil
+		user.DeletedAt = &deletedAt
+
+		return injectedService.userRepository.Update(user)
+	})
+
+	if httpErr, ok := err.(*shared.HttpError); ok {
+		return nil, httpErr
+	}
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to delete account")
+	}
+
+	return resp.(*shared.User), nil
+}
@@ -0,0 +1,11 @@
+package user
+
+import "github.com/honerlaw/mentordoc/server/lib/shared"
+
+type OidcAuthRequest struct {
+	shared.Entity
+
+	State        string
+	CodeVerifier string
+	Nonce        string
+}
@@ -0,0 +1,84 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+)
+
+type OidcAuthRequestRepository struct {
+	util.Repository
+}
+
+func NewOidcAuthRequestRepository(db *sql.DB, tx *sql.Tx) *OidcAuthRequestRepository {
+	repo := &OidcAuthRequestRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *OidcAuthRequestRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewOidcAuthRequestRepository(repo.Db, tx)
+}
+
+func (repo *OidcAuthRequestRepository) Insert(request *OidcAuthRequest) error {
+	request.CreatedAt = util.NowUnix()
+	request.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into oidc_auth_request (id, state, code_verifier, nonce, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?)",
+		request.Id,
+		request.State,
+		request.CodeVerifier,
+		request.Nonce,
+		request.CreatedAt,
+		request.UpdatedAt,
+		request.DeletedAt,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert oidc auth request")
+	}
+
+	return nil
+}
+
+/**
+Finds the pending auth request for the given state that was created after the given time
+*/
+func (repo *OidcAuthRequestRepository) FindByState(state string, createdAfter int64) *OidcAuthRequest {
+	row := repo.QueryRow(
+		"select id, state, code_verifier, nonce, created_at, updated_at, deleted_at from oidc_auth_request where state = ? and created_at > ? and deleted_at is null",
+		state,
+		createdAfter,
+	)
+
+	var request OidcAuthRequest
+	err := row.Scan(&request.Id, &request.State, &request.CodeVerifier, &request.Nonce, &request.CreatedAt, &request.UpdatedAt, &request.DeletedAt)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	return &request
+}
+
+func (repo *OidcAuthRequestRepository) Update(request *OidcAuthRequest) error {
+	request.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update oidc_auth_request set updated_at = ?, deleted_at = ? where id = ?",
+		request.UpdatedAt,
+		request.DeletedAt,
+		request.Id,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update oidc auth request")
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package user
+
+import "github.com/honerlaw/mentordoc/server/lib/shared"
+
+type PersonalAccessToken struct {
+	shared.Entity
+
+	UserId     string   `json:"userId"`
+	Name       string   `json:"name"`
+	TokenHash  string   `json:"-"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  int64    `json:"expiresAt"`
+	LastUsedAt *int64   `json:"lastUsedAt"`
+
+	// the raw token is only ever available when the token is first created
+	Token *string `json:"token,omitempty"`
+}
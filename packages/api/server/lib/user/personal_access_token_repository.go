@@ -0,0 +1,140 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"log"
+	"strings"
+)
+
+const personalAccessTokenColumns = "id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at, updated_at, deleted_at"
+
+type PersonalAccessTokenRepository struct {
+	util.Repository
+}
+
+func NewPersonalAccessTokenRepository(db *sql.DB, tx *sql.Tx) *PersonalAccessTokenRepository {
+	repo := &PersonalAccessTokenRepository{}
+	repo.Db = db
+	repo.Tx = tx
+	return repo
+}
+
+func (repo *PersonalAccessTokenRepository) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewPersonalAccessTokenRepository(repo.Db, tx)
+}
+
+func (repo *PersonalAccessTokenRepository) Insert(token *PersonalAccessToken) error {
+	token.CreatedAt = util.NowUnix()
+	token.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"insert into personal_access_token (id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at, updated_at, deleted_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		token.Id,
+		token.UserId,
+		token.Name,
+		token.TokenHash,
+		strings.Join(token.Scopes, ","),
+		token.ExpiresAt,
+		token.LastUsedAt,
+		token.CreatedAt,
+		token.UpdatedAt,
+		token.DeletedAt,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to insert personal access token")
+	}
+
+	return nil
+}
+
+func (repo *PersonalAccessTokenRepository) Update(token *PersonalAccessToken) error {
+	token.UpdatedAt = util.NowUnix()
+
+	_, err := repo.Exec(
+		"update personal_access_token set last_used_at = ?, updated_at = ?, deleted_at = ? where id = ?",
+		token.LastUsedAt,
+		token.UpdatedAt,
+		token.DeletedAt,
+		token.Id,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to update personal access token")
+	}
+
+	return nil
+}
+
+func (repo *PersonalAccessTokenRepository) DeleteByUserId(userId string) error {
+	deletedAt := util.NowUnix()
+
+	_, err := repo.Exec(
+		"update personal_access_token set updated_at = ?, deleted_at = ? where user_id = ? and deleted_at is null",
+		deletedAt,
+		deletedAt,
+		userId,
+	)
+
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to delete personal access tokens")
+	}
+
+	return nil
+}
+
+func (repo *PersonalAccessTokenRepository) FindById(id string) *PersonalAccessToken {
+	row := repo.QueryRow(
+		"select "+personalAccessTokenColumns+" from personal_access_token where id = ? and deleted_at is null",
+		id,
+	)
+	return repo.scan(row)
+}
+
+func (repo *PersonalAccessTokenRepository) FindByHash(tokenHash string) *PersonalAccessToken {
+	row := repo.QueryRow(
+		"select "+personalAccessTokenColumns+" from personal_access_token where token_hash = ? and deleted_at is null",
+		tokenHash,
+	)
+	return repo.scan(row)
+}
+
+func (repo *PersonalAccessTokenRepository) FindByUserId(userId string) ([]PersonalAccessToken, error) {
+	rows, err := repo.Query(
+		"select "+personalAccessTokenColumns+" from personal_access_token where user_id = ? and deleted_at is null ORDER BY created_at DESC",
+		userId,
+	)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to find personal access tokens")
+	}
+	defer rows.Close()
+
+	tokens := make([]PersonalAccessToken, 0)
+	for rows.Next() {
+		token := repo.scan(rows)
+		if token == nil {
+			return nil, errors.New("failed to parse personal access token")
+		}
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, nil
+}
+
+func (repo *PersonalAccessTokenRepository) scan(row interface{ Scan(dest ...interface{}) error }) *PersonalAccessToken {
+	var token PersonalAccessToken
+	var scopes string
+	err := row.Scan(&token.Id, &token.UserId, &token.Name, &token.TokenHash, &scopes, &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt, &token.UpdatedAt, &token.DeletedAt)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	token.Scopes = strings.Split(scopes, ",")
+	return &token
+}
@@ -0,0 +1,165 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"strings"
+)
+
+// prefix for all personal access tokens, makes them easy to tell apart from jwts and to find in leaked secrets
+const PersonalAccessTokenPrefix = "mdp_"
+
+type PersonalAccessTokenService struct {
+	personalAccessTokenRepository *PersonalAccessTokenRepository
+	userRepository                *UserRepository
+	aclService                    *acl.AclService
+}
+
+func NewPersonalAccessTokenService(
+	personalAccessTokenRepository *PersonalAccessTokenRepository,
+	userRepository *UserRepository,
+	aclService *acl.AclService,
+) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		personalAccessTokenRepository: personalAccessTokenRepository,
+		userRepository:                userRepository,
+		aclService:                    aclService,
+	}
+}
+
+func (service *PersonalAccessTokenService) InjectTransaction(tx *sql.Tx) interface{} {
+	return NewPersonalAccessTokenService(
+		service.personalAccessTokenRepository.InjectTransaction(tx).(*PersonalAccessTokenRepository),
+		service.userRepository.InjectTransaction(tx).(*UserRepository),
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+	)
+}
+
+/*
+Creates a new token for the user, the raw token is only returned here, only the hash of it is ever stored. Tokens can
+only be created from a regular session so that a token can never be used to escalate its own scope.
+*/
+func (service *PersonalAccessTokenService) Create(user *shared.User, name string, expiresAt int64, scopes []string) (*PersonalAccessToken, error) {
+	if user.Scopes != nil {
+		return nil, shared.NewForbiddenError("personal access tokens can not create other tokens")
+	}
+
+	if expiresAt <= util.NowUnix() {
+		return nil, shared.NewBadRequestError("expiration must be in the future")
+	}
+
+	actions, err := service.aclService.FindActions()
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create personal access token")
+	}
+
+	for _, scope := range scopes {
+		found := false
+		for _, action := range actions {
+			if scope == action {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, shared.NewBadRequestError("invalid scope " + scope)
+		}
+	}
+
+	data := make([]byte, 32)
+	_, err = rand.Read(data)
+	if err != nil {
+		log.Print(err)
+		return nil, shared.NewInternalServerError("failed to create personal access token")
+	}
+	rawToken := PersonalAccessTokenPrefix + hex.EncodeToString(data)
+
+	token := &PersonalAccessToken{
+		UserId:    user.Id,
+		Name:      name,
+		TokenHash: service.hash(rawToken),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	token.Id = uuid.NewV4().String()
+
+	err = service.personalAccessTokenRepository.Insert(token)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to create personal access token")
+	}
+
+	token.Token = &rawToken
+
+	return token, nil
+}
+
+func (service *PersonalAccessTokenService) List(user *shared.User) ([]PersonalAccessToken, error) {
+	tokens, err := service.personalAccessTokenRepository.FindByUserId(user.Id)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to find personal access tokens")
+	}
+	return tokens, nil
+}
+
+func (service *PersonalAccessTokenService) Revoke(user *shared.User, id string) (*PersonalAccessToken, error) {
+	token := service.personalAccessTokenRepository.FindById(id)
+	if token == nil || token.UserId != user.Id {
+		return nil, shared.NewNotFoundError("could not find personal access token")
+	}
+
+	deletedAt := util.NowUnix()
+	token.DeletedAt = &deletedAt
+
+	err := service.personalAccessTokenRepository.Update(token)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to revoke personal access token")
+	}
+
+	return token, nil
+}
+
+func (service *PersonalAccessTokenService) IsPersonalAccessToken(rawToken string) bool {
+	return strings.HasPrefix(rawToken, PersonalAccessTokenPrefix)
+}
+
+/**
+Finds the user for the given raw token, the user is restricted to the scopes of the token
+*/
+func (service *PersonalAccessTokenService) Authenticate(rawToken string) (*shared.User, error) {
+	token := service.personalAccessTokenRepository.FindByHash(service.hash(rawToken))
+	if token == nil {
+		return nil, shared.NewUnauthorizedError("invalid token")
+	}
+
+	now := util.NowUnix()
+	if token.ExpiresAt <= now {
+		return nil, shared.NewUnauthorizedError("invalid token")
+	}
+
+	user := service.userRepository.FindById(token.UserId)
+	if user == nil {
+		return nil, shared.NewUnauthorizedError("invalid token")
+	}
+
+	token.LastUsedAt = &now
+	err := service.personalAccessTokenRepository.Update(token)
+	if err != nil {
+		log.Print(err)
+	}
+
+	user.Scopes = token.Scopes
+
+	return user, nil
+}
+
+func (service *PersonalAccessTokenService) hash(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
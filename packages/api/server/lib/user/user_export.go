@@ -0,0 +1,18 @@
+package user
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+)
+
+type UserExport struct {
+	ExportedAt           int64                    `json:"exportedAt"`
+	User                 *shared.User             `json:"user"`
+	Memberships          []acl.UserRole           `json:"memberships"`
+	Drafts               []shared.DocumentDraft   `json:"drafts"`
+	CommentThreads       []shared.CommentThread   `json:"commentThreads"`
+	Comments             []shared.Comment         `json:"comments"`
+	Attachments          []shared.Attachment      `json:"attachments"`
+	History              []shared.ResourceHistory `json:"history"`
+	PersonalAccessTokens []PersonalAccessToken    `json:"personalAccessTokens"`
+}
@@ -68,7 +68,7 @@ func (repo *UserRepository) Update(user *shared.User) (*shared.User, error) {
 
 func (repo *UserRepository) FindByEmail(email string) *shared.User {
 	row := repo.QueryRow(
-		"select id, email, password, created_at, updated_at, deleted_at from user where email = ?",
+		"select id, email, password, created_at, updated_at, deleted_at from user where email = ? and deleted_at is null",
 		strings.TrimSpace(strings.ToLower(email)),
 	)
 	user := &shared.User{}
@@ -82,7 +82,7 @@ func (repo *UserRepository) FindByEmail(email string) *shared.User {
 
 func (repo *UserRepository) FindById(id string) *shared.User {
 	row := repo.QueryRow(
-		"select id, email, password, created_at, updated_at, deleted_at from user where id = ?",
+		"select id, email, password, created_at, updated_at, deleted_at from user where id = ? and deleted_at is null",
 		id,
 	)
 	user := &shared.User{}
@@ -10,27 +10,37 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"log"
 	"strings"
+	"time"
 )
 
+// how long a user has to complete the sign in with the identity provider
+const oidcAuthRequestExpireTime = 10 * time.Minute
+
 type UserService struct {
-	userRepository      *UserRepository
-	organizationService *organization.OrganizationService
-	transactionManager  *util.TransactionManager
-	aclService          *acl.AclService
+	userRepository            *UserRepository
+	oidcAuthRequestRepository *OidcAuthRequestRepository
+	organizationService       *organization.OrganizationService
+	transactionManager        *util.TransactionManager
+	aclService                *acl.AclService
+	oidcService               *util.OidcService
 }
 
 func NewUserService(
 	userRepository *UserRepository,
+	oidcAuthRequestRepository *OidcAuthRequestRepository,
 	organizationService *organization.OrganizationService,
 	transactionManager *util.TransactionManager,
 	aclService *acl.AclService,
+	oidcService *util.OidcService,
 ) *UserService {
 
 	service := &UserService{
-		userRepository:      userRepository,
-		organizationService: organizationService,
-		transactionManager:  transactionManager,
-		aclService:          aclService,
+		userRepository:            userRepository,
+		oidcAuthRequestRepository: oidcAuthRequestRepository,
+		organizationService:       organizationService,
+		transactionManager:        transactionManager,
+		aclService:                aclService,
+		oidcService:               oidcService,
 	};
 	return service
 }
@@ -38,9 +48,11 @@ func NewUserService(
 func (service *UserService) InjectTransaction(tx *sql.Tx) interface{} {
 	return NewUserService(
 		service.userRepository.InjectTransaction(tx).(*UserRepository),
+		service.oidcAuthRequestRepository.InjectTransaction(tx).(*OidcAuthRequestRepository),
 		service.organizationService.InjectTransaction(tx).(*organization.OrganizationService),
 		service.transactionManager.InjectTransaction(tx).(*util.TransactionManager),
-		service.aclService.InjectTransaction(tx).(*acl.AclService))
+		service.aclService.InjectTransaction(tx).(*acl.AclService),
+		service.oidcService)
 }
 
 func (service *UserService) Create(email string, password string) (*shared.User, error) {
@@ -55,16 +67,25 @@ func (service *UserService) Create(email string, password string) (*shared.User,
 		return nil, shared.NewInternalServerError("failed to create user")
 	}
 
+	hashValue := string(hash)
+	return service.createWithOrganization(email, &hashValue)
+}
+
+/*
+Creates the user along with their own organization, a nil password creates a user that can only sign in through single
+sign on
+*/
+func (service *UserService) createWithOrganization(email string, password *string) (*shared.User, error) {
 	resp, err := service.transactionManager.Transact(service, func(injected interface{}) (interface{}, error) {
 		injectedService := injected.(*UserService)
 
-		user = &shared.User{
+		user := &shared.User{
 			Email:    email,
-			Password: string(hash),
+			Password: password,
 		}
 		user.Id = uuid.NewV4().String()
 
-		user, err = injectedService.userRepository.Insert(user)
+		user, err := injectedService.userRepository.Insert(user)
 		if err != nil {
 			return nil, shared.NewInternalServerError("failed to create user")
 		}
@@ -95,7 +116,12 @@ func (service *UserService) Authenticate(email string, password string) (*shared
 		return nil, shared.NewBadRequestError("invalid email or password")
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	// single sign on only users can not sign in with a password
+	if user.Password == nil {
+		return nil, shared.NewBadRequestError("invalid email or password")
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password))
 	if err != nil {
 		return nil, shared.NewBadRequestError("invalid email or password")
 	}
@@ -103,6 +129,78 @@ func (service *UserService) Authenticate(email string, password string) (*shared
 	return user, nil
 }
 
+/**
+Starts the single sign on flow, the returned url is where the user should be sent to sign in with the identity provider
+*/
+func (service *UserService) BeginOidcAuthentication() (*string, error) {
+	if !service.oidcService.Enabled() {
+		return nil, shared.NewNotFoundError("single sign on is not configured")
+	}
+
+	request := &OidcAuthRequest{}
+	request.Id = uuid.NewV4().String()
+
+	var err error
+	for _, value := range []*string{&request.State, &request.Nonce, &request.CodeVerifier} {
+		*value, err = service.oidcService.GenerateRandomString()
+		if err != nil {
+			return nil, shared.NewInternalServerError("failed to start single sign on")
+		}
+	}
+
+	authorizationUrl, err := service.oidcService.AuthorizationUrl(request.State, request.Nonce, request.CodeVerifier)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to start single sign on")
+	}
+
+	err = service.oidcAuthRequestRepository.Insert(request)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to start single sign on")
+	}
+
+	return &authorizationUrl, nil
+}
+
+/*
+Completes the single sign on flow. The user is linked by their verified email address, if no user exists with that email
+a new user without a password is created for them.
+*/
+func (service *UserService) CompleteOidcAuthentication(code string, state string) (*shared.User, error) {
+	if !service.oidcService.Enabled() {
+		return nil, shared.NewNotFoundError("single sign on is not configured")
+	}
+
+	createdAfter := util.NowUnix() - oidcAuthRequestExpireTime.Nanoseconds()
+	request := service.oidcAuthRequestRepository.FindByState(state, createdAfter)
+	if request == nil {
+		return nil, shared.NewUnauthorizedError("invalid or expired sign in request")
+	}
+
+	// the request can only ever be used once
+	deletedAt := util.NowUnix()
+	request.DeletedAt = &deletedAt
+	err := service.oidcAuthRequestRepository.Update(request)
+	if err != nil {
+		return nil, shared.NewInternalServerError("failed to complete single sign on")
+	}
+
+	identity, err := service.oidcService.Exchange(code, request.CodeVerifier, request.Nonce)
+	if err != nil {
+		return nil, shared.NewUnauthorizedError("failed to verify identity")
+	}
+
+	if len(identity.Email) == 0 || !identity.EmailVerified {
+		return nil, shared.NewForbiddenError("a verified email address is required")
+	}
+
+	user := service.userRepository.FindByEmail(identity.Email)
+	if user != nil {
+		return user, nil
+	}
+
+	return service.createWithOrganization(identity.Email, nil)
+}
+
 func (service *UserService) FindByEmail(email string) *shared.User {
 	return service.userRepository.FindByEmail(email)
 }
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+const BlobStoreLocal = "local"
+const BlobStoreS3 = "s3"
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+/*
+Stores files, e.g. attachments, outside of the database. Keys are paths such as attachments/<document>/<attachment>, the
+store decides where they end up.
+*/
+type BlobStore interface {
+	// stores the body under the key, replacing anything already there
+	Put(key string, body io.Reader, size int64, contentType string) error
+
+	// the body stored under the key, ErrBlobNotFound if there is none. The caller closes it.
+	Get(key string) (io.ReadCloser, error)
+
+	// removes the body stored under the key, a key with nothing stored under it is not an error
+	Delete(key string) error
+}
+
+/**
+Creates the blob store named by BLOB_STORE, local by default. The local store keeps files under BLOB_STORE_PATH, the s3
+store keeps them in S3_BUCKET at S3_ENDPOINT, which can be any S3 compatible service, e.g. MinIO.
+*/
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch os.Getenv("BLOB_STORE") {
+	case "", BlobStoreLocal:
+		path := os.Getenv("BLOB_STORE_PATH")
+		if len(path) == 0 {
+			return nil, errors.New("BLOB_STORE_PATH is required for the local blob store")
+		}
+		return NewLocalBlobStore(path)
+	case BlobStoreS3:
+		return NewS3BlobStore(NewS3ConfigFromEnv())
+	}
+
+	return nil, errors.New("BLOB_STORE must be local or s3")
+}
@@ -0,0 +1,181 @@
+package util
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const highlightStart = "<mark>"
+const highlightEnd = "</mark>"
+const snippetEllipsis = "…"
+
+type highlightMatch struct {
+	start int
+	end   int
+}
+
+/**
+Splits a search query into the terms to highlight, e.g. "+travel -policy*" becomes travel and policy
+*/
+func SearchTerms(query string) []string {
+	seen := make(map[string]bool)
+	terms := make([]string, 0)
+
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+/**
+Finds up to maxSnippets snippets of the text around the terms, with radius characters on either side of the matches. The
+text is html escaped and the matches are wrapped in <mark> tags. Returns nothing if none of the terms are in the text.
+*/
+func HighlightSnippets(text string, terms []string, maxSnippets int, radius int) []string {
+	runes := []rune(text)
+	matches := findHighlightMatches(runes, terms)
+
+	snippets := make([]string, 0)
+	for index := 0; index < len(matches) && len(snippets) < maxSnippets; {
+		start := matches[index].start - radius
+		if start < 0 {
+			start = 0
+		}
+		end := matches[index].end + radius
+		if end > len(runes) {
+			end = len(runes)
+		}
+
+		// take every match that fits in the snippet
+		included := make([]highlightMatch, 0)
+		for ; index < len(matches) && matches[index].end <= end; index++ {
+			included = append(included, matches[index])
+		}
+
+		// do not cut words in half at either end of the snippet
+		for start > 0 && start < included[0].start && !unicode.IsSpace(runes[start-1]) {
+			start++
+		}
+		for end < len(runes) && end > included[len(included)-1].end && !unicode.IsSpace(runes[end]) {
+			end--
+		}
+		for start < included[0].start && unicode.IsSpace(runes[start]) {
+			start++
+		}
+		for end > included[len(included)-1].end && unicode.IsSpace(runes[end-1]) {
+			end--
+		}
+
+		snippets = append(snippets, renderSnippet(runes, start, end, included))
+	}
+
+	return snippets
+}
+
+/**
+Highlights every match of the terms in the text, the text is kept whole
+*/
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return renderSnippet(runes, 0, len(runes), findHighlightMatches(runes, terms))
+}
+
+/**
+Finds where the terms start a word in the text, overlapping matches are merged
+*/
+func findHighlightMatches(runes []rune, terms []string) []highlightMatch {
+	lower := make([]rune, len(runes))
+	for index, r := range runes {
+		lower[index] = unicode.ToLower(r)
+	}
+
+	matches := make([]highlightMatch, 0)
+	for _, term := range terms {
+		termRunes := []rune(strings.ToLower(term))
+		if len(termRunes) == 0 {
+			continue
+		}
+
+		for index := 0; index+len(termRunes) <= len(lower); index++ {
+			if index > 0 && (unicode.IsLetter(lower[index-1]) || unicode.IsDigit(lower[index-1])) {
+				continue
+			}
+			if string(lower[index:index+len(termRunes)]) != string(termRunes) {
+				continue
+			}
+
+			// highlight the rest of the word as well, e.g. travelling for travel
+			end := index + len(termRunes)
+			for end < len(lower) && (unicode.IsLetter(lower[end]) || unicode.IsDigit(lower[end])) {
+				end++
+			}
+			matches = append(matches, highlightMatch{start: index, end: end})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+
+	merged := make([]highlightMatch, 0)
+	for _, match := range matches {
+		last := len(merged) - 1
+		if last >= 0 && match.start <= merged[last].end {
+			if match.end > merged[last].end {
+				merged[last].end = match.end
+			}
+			continue
+		}
+		merged = append(merged, match)
+	}
+
+	return merged
+}
+
+func renderSnippet(runes []rune, start int, end int, matches []highlightMatch) string {
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString(snippetEllipsis)
+	}
+
+	position := start
+	for _, match := range matches {
+		builder.WriteString(escapeSnippetText(runes[position:match.start]))
+		builder.WriteString(highlightStart)
+		builder.WriteString(escapeSnippetText(runes[match.start:match.end]))
+		builder.WriteString(highlightEnd)
+		position = match.end
+	}
+	builder.WriteString(escapeSnippetText(runes[position:end]))
+
+	if end < len(runes) {
+		builder.WriteString(snippetEllipsis)
+	}
+
+	return builder.String()
+}
+
+/**
+Snippets are shown on a single line, so every run of whitespace becomes a single space before escaping
+*/
+func escapeSnippetText(runes []rune) string {
+	var builder strings.Builder
+	for index, r := range runes {
+		if !unicode.IsSpace(r) {
+			builder.WriteRune(r)
+		} else if index == 0 || !unicode.IsSpace(runes[index-1]) {
+			builder.WriteRune(' ')
+		}
+	}
+
+	return html.EscapeString(builder.String())
+}
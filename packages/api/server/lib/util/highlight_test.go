@@ -0,0 +1,43 @@
+package util_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"travel", "policy"}, util.SearchTerms(`+Travel -policy* "travel"`))
+	assert.Equal(t, []string{}, util.SearchTerms("+-*"))
+}
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "<mark>Travel</mark> policy", util.Highlight("Travel policy", []string{"travel"}))
+	assert.Equal(t, "<mark>travelling</mark> &amp; unravel", util.Highlight("travelling & unravel", []string{"travel"}))
+	assert.Equal(t, "no match", util.Highlight("no match", []string{"travel"}))
+}
+
+func TestHighlightSnippets(t *testing.T) {
+	text := "The travel policy covers\n\nflights and hotels. Mileage is <b>reimbursed</b> for travel by car."
+
+	assert.Equal(t, []string{
+		"The <mark>travel</mark> policy…",
+		"…for <mark>travel</mark> by car.",
+	}, util.HighlightSnippets(text, []string{"travel"}, 5, 8))
+
+	// snippets do not cut words in half and are escaped
+	assert.Equal(t, []string{
+		"…and hotels. <mark>Mileage</mark> is…",
+		"…is &lt;b&gt;<mark>reimbursed</mark>&lt;/b&gt; for…",
+	}, util.HighlightSnippets(text, []string{"mileage", "reimbursed"}, 2, 12))
+
+	// whitespace is collapsed and matches that fit share a snippet
+	assert.Equal(t, []string{
+		"The <mark>travel</mark> policy covers…",
+	}, util.HighlightSnippets(text, []string{"travel"}, 1, 20))
+	assert.Equal(t, []string{
+		"The <mark>travel</mark> <mark>policy</mark> covers…",
+	}, util.HighlightSnippets(text, []string{"travel", "policy"}, 1, 20))
+
+	assert.Len(t, util.HighlightSnippets(text, []string{"visa"}, 1, 20), 0)
+}
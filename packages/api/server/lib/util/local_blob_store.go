@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+Keeps blobs as files under a directory, the key is the path of the file in it
+*/
+type LocalBlobStore struct {
+	root string
+}
+
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	err := os.MkdirAll(root, 0750)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to create the blob store directory")
+	}
+
+	return &LocalBlobStore{
+		root: root,
+	}, nil
+}
+
+/**
+Writes to a temporary file first and moves it into place, so a failed write never leaves half of a file under the key
+*/
+func (store *LocalBlobStore) Put(key string, body io.Reader, size int64, contentType string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to store blob")
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(path), ".upload-")
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to store blob")
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, body)
+	closeErr := file.Close()
+	if err != nil || closeErr != nil {
+		log.Print(err, closeErr)
+		return errors.New("failed to store blob")
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		log.Print(err)
+		return errors.New("failed to store blob")
+	}
+
+	return nil
+}
+
+func (store *LocalBlobStore) Get(key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to read blob")
+	}
+
+	return file, nil
+}
+
+func (store *LocalBlobStore) Delete(key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Print(err)
+		return errors.New("failed to delete blob")
+	}
+
+	return nil
+}
+
+/**
+The path of the file for the key, keys can not point outside of the directory
+*/
+func (store *LocalBlobStore) path(key string) (string, error) {
+	for _, segment := range strings.Split(key, "/") {
+		if len(segment) == 0 || segment == "." || segment == ".." || strings.Contains(segment, "\\") {
+			return "", errors.New("invalid blob key")
+		}
+	}
+
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
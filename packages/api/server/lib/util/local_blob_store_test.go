@@ -0,0 +1,57 @@
+package util_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "blobs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	store, err := util.NewLocalBlobStore(filepath.Join(root, "store"))
+	assert.Nil(t, err)
+
+	err = store.Put("attachments/doc/file", strings.NewReader("content"), 7, "text/plain")
+	assert.Nil(t, err)
+
+	body, err := store.Get("attachments/doc/file")
+	assert.Nil(t, err)
+	read, err := ioutil.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Nil(t, body.Close())
+	assert.Equal(t, "content", string(read))
+
+	// the temporary file is moved into place, so only the blob is left
+	files, err := ioutil.ReadDir(filepath.Join(root, "store", "attachments", "doc"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	assert.Nil(t, store.Delete("attachments/doc/file"))
+	_, err = store.Get("attachments/doc/file")
+	assert.Equal(t, util.ErrBlobNotFound, err)
+
+	// deleting again is not an error
+	assert.Nil(t, store.Delete("attachments/doc/file"))
+}
+
+func TestLocalBlobStoreRejectsKeysOutsideOfTheRoot(t *testing.T) {
+	root, err := ioutil.TempDir("", "blobs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	store, err := util.NewLocalBlobStore(root)
+	assert.Nil(t, err)
+
+	for _, key := range []string{"../escape", "/etc/passwd", "a//b", "a/./b", "a\\..\\b"} {
+		assert.NotNil(t, store.Put(key, strings.NewReader("content"), 7, "text/plain"), key)
+		_, err = store.Get(key)
+		assert.NotNil(t, err, key)
+	}
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"container/list"
+	"sync"
+)
+
+/*
+Holds up to size values, once full the least recently used value is dropped to make room for the next one
+*/
+type LruCache struct {
+	lock    sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruCacheEntry struct {
+	key   string
+	value interface{}
+}
+
+func NewLruCache(size int) *LruCache {
+	return &LruCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (cache *LruCache) Get(key string) (interface{}, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+
+	return element.Value.(*lruCacheEntry).value, true
+}
+
+func (cache *LruCache) Set(key string, value interface{}) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value.(*lruCacheEntry).value = value
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&lruCacheEntry{key: key, value: value})
+	if cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruCacheEntry).key)
+	}
+}
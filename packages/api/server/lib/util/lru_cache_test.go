@@ -0,0 +1,29 @@
+package util_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLruCacheDropsLeastRecentlyUsed(t *testing.T) {
+	cache := util.NewLruCache(2)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	// reading a makes b the least recently used
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.Set("c", 3)
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	value, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+}
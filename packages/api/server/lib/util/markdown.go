@@ -0,0 +1,66 @@
+package util
+
+import (
+	"bytes"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"regexp"
+)
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	// raw html is kept and then sanitized along with everything else
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+var markdownPolicy = newMarkdownPolicy()
+
+func newMarkdownPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	return policy
+}
+
+/**
+Renders markdown (with the github extensions, e.g. tables) to sanitized html. Every heading gets an id to link to, and the
+headings are returned in order as the table of contents.
+*/
+func RenderMarkdown(source string) (string, []shared.TocEntry, error) {
+	sourceBytes := []byte(source)
+	document := markdown.Parser().Parse(text.NewReader(sourceBytes))
+
+	toc := make([]shared.TocEntry, 0)
+	err := ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		anchor, _ := heading.AttributeString("id")
+		anchorBytes, _ := anchor.([]byte)
+		toc = append(toc, shared.TocEntry{
+			Level:  heading.Level,
+			Text:   string(heading.Text(sourceBytes)),
+			Anchor: string(anchorBytes),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buffer bytes.Buffer
+	err = markdown.Renderer().Render(&buffer, sourceBytes, document)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return markdownPolicy.Sanitize(buffer.String()), toc, nil
+}
@@ -0,0 +1,36 @@
+package util_test
+
+import (
+	"bytes"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRenderMarkdownAddsHeadingAnchors(t *testing.T) {
+	html, toc, err := util.RenderMarkdown("# Getting *Started*\n\n## Setup\n\ntext\n\n## Setup\n")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "<h1 id=\"getting-started\">Getting <em>Started</em></h1>\n<h2 id=\"setup\">Setup</h2>\n<p>text</p>\n<h2 id=\"setup-1\">Setup</h2>\n", html)
+	assert.Equal(t, []shared.TocEntry{
+		{Level: 1, Text: "Getting Started", Anchor: "getting-started"},
+		{Level: 2, Text: "Setup", Anchor: "setup"},
+		{Level: 2, Text: "Setup", Anchor: "setup-1"},
+	}, toc)
+}
+
+func TestRenderMarkdownSanitizesHtml(t *testing.T) {
+	html, _, err := util.RenderMarkdown("hi <script>alert(1)</script><b onclick=\"steal()\">there</b> [link](javascript:alert(1))")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "<p>hi <b>there</b> link</p>\n", html)
+}
+
+func TestWriteMarkdownPdf(t *testing.T) {
+	var buffer bytes.Buffer
+	err := util.WriteMarkdownPdf(&buffer, "Guide", "Org / Eng", "# Steps\n\n1. **build** it\n2. ship `it`\n\n```\ncode\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n")
+
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(buffer.Bytes(), []byte("%PDF-")))
+}
@@ -0,0 +1,355 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+type OidcConfig struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+}
+
+/**
+Builds the oidc configuration from the environment, returns nil if single sign on is not configured
+*/
+func NewOidcConfigFromEnv() *OidcConfig {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if len(issuer) == 0 {
+		return nil
+	}
+
+	scopes := []string{"openid", "email", "profile"}
+	if envScopes := os.Getenv("OIDC_SCOPES"); len(envScopes) > 0 {
+		scopes = strings.Split(envScopes, " ")
+	}
+
+	return &OidcConfig{
+		Issuer:       issuer,
+		ClientId:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectUrl:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       scopes,
+	}
+}
+
+type OidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	IdToken string `json:"id_token"`
+	Error   string `json:"error"`
+}
+
+type oidcJwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+type oidcIdTokenClaims struct {
+	Issuer        string      `json:"iss"`
+	Subject       string      `json:"sub"`
+	Audience      interface{} `json:"aud"`
+	ExpiresAt     int64       `json:"exp"`
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+}
+
+func (claims *oidcIdTokenClaims) Valid() error {
+	if time.Now().Unix() > claims.ExpiresAt {
+		return errors.New("id token is expired")
+	}
+	return nil
+}
+
+func (claims *oidcIdTokenClaims) hasAudience(clientId string) bool {
+	switch aud := claims.Audience.(type) {
+	case string:
+		return aud == clientId
+	case []interface{}:
+		for _, value := range aud {
+			if value == clientId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// some providers send email_verified as a string instead of a boolean
+func (claims *oidcIdTokenClaims) isEmailVerified() bool {
+	switch verified := claims.EmailVerified.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+/*
+Handles the authorization code flow with PKCE against an OpenID Connect provider. The provider metadata and signing keys
+are discovered lazily from the issuer and kept around for the lifetime of the service.
+*/
+type OidcService struct {
+	config     *OidcConfig
+	httpClient *http.Client
+	lock       sync.Mutex
+	metadata   *oidcProviderMetadata
+	keys       map[string]*rsa.PublicKey
+}
+
+func NewOidcService(config *OidcConfig) *OidcService {
+	return &OidcService{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (service *OidcService) Enabled() bool {
+	return service.config != nil
+}
+
+/**
+Generates a random url safe string, used for the state, nonce, and code verifier
+*/
+func (service *OidcService) GenerateRandomString() (string, error) {
+	data := make([]byte, 32)
+	_, err := rand.Read(data)
+	if err != nil {
+		log.Print(err)
+		return "", errors.New("failed to generate random string")
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (service *OidcService) AuthorizationUrl(state string, nonce string, codeVerifier string) (string, error) {
+	metadata, err := service.getMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", service.config.ClientId)
+	query.Set("redirect_uri", service.config.RedirectUrl)
+	query.Set("scope", strings.Join(service.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+/**
+Exchanges the authorization code for an id token, and verifies the id token against the provider's signing keys
+*/
+func (service *OidcService) Exchange(code string, codeVerifier string, nonce string) (*OidcIdentity, error) {
+	metadata, err := service.getMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", service.config.RedirectUrl)
+	form.Set("client_id", service.config.ClientId)
+	form.Set("code_verifier", codeVerifier)
+	if len(service.config.ClientSecret) > 0 {
+		form.Set("client_secret", service.config.ClientSecret)
+	}
+
+	resp, err := service.httpClient.PostForm(metadata.TokenEndpoint, form)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to exchange authorization code")
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse oidcTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&tokenResponse)
+	if err != nil || resp.StatusCode != http.StatusOK || len(tokenResponse.IdToken) == 0 {
+		log.Print("token endpoint rejected authorization code", resp.StatusCode, tokenResponse.Error, err)
+		return nil, errors.New("failed to exchange authorization code")
+	}
+
+	claims, err := service.verifyIdToken(tokenResponse.IdToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Nonce != nonce {
+		log.Print("nonce on id token does not match the auth request")
+		return nil, errors.New("invalid id token")
+	}
+
+	return &OidcIdentity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.isEmailVerified(),
+	}, nil
+}
+
+func (service *OidcService) verifyIdToken(idToken string) (*oidcIdTokenClaims, error) {
+	claims := &oidcIdTokenClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return service.getKey(kid)
+	})
+	if err != nil || !token.Valid {
+		log.Print("failed to verify id token", err)
+		return nil, errors.New("invalid id token")
+	}
+
+	if claims.Issuer != service.config.Issuer {
+		log.Print("invalid issuer on id token", claims.Issuer)
+		return nil, errors.New("invalid id token")
+	}
+
+	if !claims.hasAudience(service.config.ClientId) {
+		log.Print("invalid audience on id token", claims.Audience)
+		return nil, errors.New("invalid id token")
+	}
+
+	return claims, nil
+}
+
+func (service *OidcService) getMetadata() (*oidcProviderMetadata, error) {
+	if !service.Enabled() {
+		return nil, errors.New("single sign on is not configured")
+	}
+
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if service.metadata != nil {
+		return service.metadata, nil
+	}
+
+	var metadata oidcProviderMetadata
+	err := service.getJson(strings.TrimSuffix(service.config.Issuer, "/")+oidcDiscoveryPath, &metadata)
+	if err != nil {
+		return nil, errors.New("failed to discover oidc provider")
+	}
+
+	if metadata.Issuer != service.config.Issuer {
+		log.Print("discovered issuer does not match configured issuer", metadata.Issuer)
+		return nil, errors.New("failed to discover oidc provider")
+	}
+
+	service.metadata = &metadata
+
+	return service.metadata, nil
+}
+
+/**
+Finds the signing key for the given key id, the keys are refetched once if the key is unknown to handle key rotation
+*/
+func (service *OidcService) getKey(kid string) (*rsa.PublicKey, error) {
+	metadata, err := service.getMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if key, ok := service.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks oidcJwks
+	err = service.getJson(metadata.JwksUri, &jwks)
+	if err != nil {
+		return nil, errors.New("failed to fetch oidc signing keys")
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	service.keys = keys
+
+	key, ok := service.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key, nil
+}
+
+func (service *OidcService) getJson(endpoint string, model interface{}) error {
+	resp, err := service.httpClient.Get(endpoint)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Print("unexpected status from oidc provider", endpoint, resp.StatusCode)
+		return errors.New("unexpected status from oidc provider")
+	}
+
+	return json.NewDecoder(resp.Body).Decode(model)
+}
@@ -0,0 +1,196 @@
+package util_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+/**
+A minimal OpenID Connect provider that issues a single authorization code, used to test the full code + PKCE exchange
+*/
+type mockOidcProvider struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	clientId      string
+	code          string
+	codeChallenge string
+	nonce         string
+	email         string
+	emailVerified bool
+}
+
+func newMockOidcProvider(t *testing.T) *mockOidcProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	provider := &mockOidcProvider{
+		key:           key,
+		clientId:      "mentordoc",
+		code:          "test-code",
+		email:         "sso@example.com",
+		emailVerified: true,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.server.URL,
+			"authorization_endpoint": provider.server.URL + "/authorize",
+			"token_endpoint":         provider.server.URL + "/token",
+			"jwks_uri":               provider.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test-key",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		_ = req.ParseForm()
+		verifierHash := sha256.Sum256([]byte(req.Form.Get("code_verifier")))
+		if req.Form.Get("code") != provider.code || base64.RawURLEncoding.EncodeToString(verifierHash[:]) != provider.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            provider.server.URL,
+			"sub":            "subject-1",
+			"aud":            provider.clientId,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"nonce":          provider.nonce,
+			"email":          provider.email,
+			"email_verified": provider.emailVerified,
+		})
+		token.Header["kid"] = "test-key"
+		idToken, err := token.SignedString(key)
+		assert.Nil(t, err)
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+
+	provider.server = httptest.NewServer(mux)
+
+	return provider
+}
+
+func (provider *mockOidcProvider) newService() *util.OidcService {
+	return util.NewOidcService(&util.OidcConfig{
+		Issuer:      provider.server.URL,
+		ClientId:    provider.clientId,
+		RedirectUrl: "http://localhost/v1/user/auth/oidc/callback",
+		Scopes:      []string{"openid", "email"},
+	})
+}
+
+/**
+Follows the authorization url the way the provider would, remembering the challenge and nonce for the token endpoint
+*/
+func (provider *mockOidcProvider) authorize(t *testing.T, authorizationUrl string) {
+	parsed, err := url.Parse(authorizationUrl)
+	assert.Nil(t, err)
+
+	query := parsed.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, provider.clientId, query.Get("client_id"))
+
+	provider.codeChallenge = query.Get("code_challenge")
+	provider.nonce = query.Get("nonce")
+}
+
+func TestOidcExchange(t *testing.T) {
+	provider := newMockOidcProvider(t)
+	defer provider.server.Close()
+	service := provider.newService()
+
+	authorizationUrl, err := service.AuthorizationUrl("state", "nonce", "verifier")
+	assert.Nil(t, err)
+	provider.authorize(t, authorizationUrl)
+
+	identity, err := service.Exchange(provider.code, "verifier", "nonce")
+	assert.Nil(t, err)
+	assert.Equal(t, "subject-1", identity.Subject)
+	assert.Equal(t, "sso@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+}
+
+func TestOidcExchangeFailsWithWrongCodeVerifier(t *testing.T) {
+	provider := newMockOidcProvider(t)
+	defer provider.server.Close()
+	service := provider.newService()
+
+	authorizationUrl, err := service.AuthorizationUrl("state", "nonce", "verifier")
+	assert.Nil(t, err)
+	provider.authorize(t, authorizationUrl)
+
+	_, err = service.Exchange(provider.code, "another-verifier", "nonce")
+	assert.NotNil(t, err)
+}
+
+func TestOidcExchangeFailsWithWrongNonce(t *testing.T) {
+	provider := newMockOidcProvider(t)
+	defer provider.server.Close()
+	service := provider.newService()
+
+	authorizationUrl, err := service.AuthorizationUrl("state", "nonce", "verifier")
+	assert.Nil(t, err)
+	provider.authorize(t, authorizationUrl)
+
+	_, err = service.Exchange(provider.code, "verifier", "another-nonce")
+	assert.NotNil(t, err)
+}
+
+func TestOidcExchangeFailsWithWrongAudience(t *testing.T) {
+	provider := newMockOidcProvider(t)
+	defer provider.server.Close()
+	service := provider.newService()
+
+	authorizationUrl, err := service.AuthorizationUrl("state", "nonce", "verifier")
+	assert.Nil(t, err)
+	provider.authorize(t, authorizationUrl)
+	provider.clientId = "another-client"
+
+	_, err = service.Exchange(provider.code, "verifier", "nonce")
+	assert.NotNil(t, err)
+}
+
+func TestOidcExchangeReportsUnverifiedEmail(t *testing.T) {
+	provider := newMockOidcProvider(t)
+	defer provider.server.Close()
+	service := provider.newService()
+	provider.emailVerified = false
+
+	authorizationUrl, err := service.AuthorizationUrl("state", "nonce", "verifier")
+	assert.Nil(t, err)
+	provider.authorize(t, authorizationUrl)
+
+	identity, err := service.Exchange(provider.code, "verifier", "nonce")
+	assert.Nil(t, err)
+	assert.False(t, identity.EmailVerified)
+}
+
+func TestOidcDisabledWithoutConfig(t *testing.T) {
+	service := util.NewOidcService(nil)
+
+	assert.False(t, service.Enabled())
+	_, err := service.AuthorizationUrl("state", "nonce", "verifier")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,277 @@
+package util
+
+import (
+	"fmt"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"io"
+	"strings"
+)
+
+const pdfFont = "Helvetica"
+const pdfCodeFont = "Courier"
+const pdfFontSize = 11
+const pdfLineHeight = 5.5
+const pdfIndent = 6
+
+var pdfHeadingSizes = []float64{20, 16, 14, 12, 11, 11}
+
+/*
+Writes markdown to a pdf, with the title and subtitle at the top of the first page. Only the core pdf fonts are used, so
+nothing has to be embedded, and characters they do not have (anything outside of windows-1252) are left out.
+*/
+func WriteMarkdownPdf(w io.Writer, title string, subtitle string, source string) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+
+	writer := &pdfWriter{
+		pdf:       pdf,
+		source:    []byte(source),
+		translate: pdf.UnicodeTranslatorFromDescriptor(""),
+	}
+
+	pdf.SetFont(pdfFont, "B", pdfHeadingSizes[0])
+	pdf.MultiCell(0, 9, writer.translate(title), "", "L", false)
+	if len(subtitle) > 0 {
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.MultiCell(0, 5, writer.translate(subtitle), "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(4)
+
+	writer.writeBlocks(markdown.Parser().Parse(text.NewReader(writer.source)))
+
+	if pdf.Err() {
+		return pdf.Error()
+	}
+
+	return pdf.Output(w)
+}
+
+type pdfWriter struct {
+	pdf       *gofpdf.Fpdf
+	source    []byte
+	translate func(string) string
+	style     string
+	code      bool
+}
+
+func (writer *pdfWriter) writeBlocks(parent ast.Node) {
+	for node := parent.FirstChild(); node != nil; node = node.NextSibling() {
+		writer.writeBlock(node)
+	}
+}
+
+func (writer *pdfWriter) writeBlock(node ast.Node) {
+	pdf := writer.pdf
+
+	switch block := node.(type) {
+	case *ast.Heading:
+		size := pdfHeadingSizes[block.Level-1]
+		pdf.Ln(2)
+		writer.writeParagraph(block, "B", size)
+		pdf.Ln(1)
+	case *ast.Paragraph, *ast.TextBlock:
+		writer.writeParagraph(block, "", pdfFontSize)
+		pdf.Ln(2)
+	case *ast.List:
+		writer.writeList(block)
+		pdf.Ln(2)
+	case *ast.Blockquote:
+		writer.indent(pdfIndent, func() {
+			pdf.SetTextColor(90, 90, 90)
+			writer.style = "I"
+			writer.writeBlocks(block)
+			writer.style = ""
+			pdf.SetTextColor(0, 0, 0)
+		})
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		writer.writeCode(block)
+		pdf.Ln(2)
+	case *ast.ThematicBreak:
+		left, _, right, _ := pdf.GetMargins()
+		width, _ := pdf.GetPageSize()
+		pdf.Line(left, pdf.GetY()+2, width-right, pdf.GetY()+2)
+		pdf.Ln(6)
+	case *east.Table:
+		writer.writeTable(block)
+		pdf.Ln(2)
+	default:
+		// raw html has no place in a pdf, anything else is written as the blocks in it
+		if _, ok := node.(*ast.HTMLBlock); !ok {
+			writer.writeBlocks(node)
+		}
+	}
+}
+
+/**
+Writes the inline content of the block, wrapping at the right margin
+*/
+func (writer *pdfWriter) writeParagraph(block ast.Node, style string, size float64) {
+	previousStyle := writer.style
+	writer.style = style + writer.style
+	writer.pdf.SetFontSize(size)
+	writer.writeInlines(block, size*0.5)
+	writer.pdf.Ln(size * 0.5)
+	writer.pdf.SetFontSize(pdfFontSize)
+	writer.style = previousStyle
+}
+
+func (writer *pdfWriter) writeInlines(parent ast.Node, lineHeight float64) {
+	pdf := writer.pdf
+
+	for node := parent.FirstChild(); node != nil; node = node.NextSibling() {
+		switch inline := node.(type) {
+		case *ast.Text:
+			writer.write(string(inline.Segment.Value(writer.source)), lineHeight)
+			if inline.HardLineBreak() {
+				pdf.Ln(lineHeight)
+			} else if inline.SoftLineBreak() {
+				writer.write(" ", lineHeight)
+			}
+		case *ast.String:
+			writer.write(string(inline.Value), lineHeight)
+		case *ast.Emphasis:
+			previousStyle := writer.style
+			if inline.Level == 2 {
+				writer.style += "B"
+			} else {
+				writer.style += "I"
+			}
+			writer.writeInlines(inline, lineHeight)
+			writer.style = previousStyle
+		case *ast.CodeSpan:
+			writer.code = true
+			writer.writeInlines(inline, lineHeight)
+			writer.code = false
+		case *ast.Link:
+			pdf.SetTextColor(30, 80, 170)
+			writer.writeInlines(inline, lineHeight)
+			pdf.SetTextColor(0, 0, 0)
+		case *ast.AutoLink:
+			pdf.SetTextColor(30, 80, 170)
+			writer.write(string(inline.Label(writer.source)), lineHeight)
+			pdf.SetTextColor(0, 0, 0)
+		case *ast.RawHTML:
+			// left out, see writeBlock
+		default:
+			writer.writeInlines(node, lineHeight)
+		}
+	}
+}
+
+func (writer *pdfWriter) write(value string, lineHeight float64) {
+	family := pdfFont
+	if writer.code {
+		family = pdfCodeFont
+	}
+	writer.pdf.SetFont(family, normalizePdfStyle(writer.style), 0)
+	writer.pdf.Write(lineHeight, writer.translate(value))
+}
+
+func (writer *pdfWriter) writeList(list *ast.List) {
+	number := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "•"
+		if list.IsOrdered() {
+			marker = fmt.Sprintf("%d.", number)
+			number++
+		}
+
+		writer.pdf.SetFont(pdfFont, "", pdfFontSize)
+		writer.pdf.CellFormat(pdfIndent, pdfLineHeight, writer.translate(marker), "", 0, "R", false, 0, "")
+		writer.indent(pdfIndent+1, func() {
+			writer.pdf.SetX(writer.leftMargin())
+			for child := item.FirstChild(); child != nil; child = child.NextSibling() {
+				if _, ok := child.(*ast.List); ok {
+					writer.writeList(child.(*ast.List))
+					continue
+				}
+				if _, ok := child.(*ast.TextBlock); ok {
+					writer.writeParagraph(child, "", pdfFontSize)
+					continue
+				}
+				writer.writeBlock(child)
+			}
+		})
+	}
+}
+
+func (writer *pdfWriter) writeCode(block ast.Node) {
+	pdf := writer.pdf
+
+	var builder strings.Builder
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		builder.Write(segment.Value(writer.source))
+	}
+
+	pdf.SetFont(pdfCodeFont, "", 9)
+	pdf.SetFillColor(240, 240, 240)
+	pdf.MultiCell(0, 4.5, writer.translate(strings.TrimRight(builder.String(), "\n")), "", "L", true)
+	pdf.SetFont(pdfFont, "", pdfFontSize)
+}
+
+/**
+Tables are written a row at a time with the cells split evenly across the page, the header row in bold
+*/
+func (writer *pdfWriter) writeTable(table *east.Table) {
+	pdf := writer.pdf
+	left, _, right, _ := pdf.GetMargins()
+	width, _ := pdf.GetPageSize()
+
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		style := ""
+		if _, ok := row.(*east.TableHeader); ok {
+			style = "B"
+		}
+
+		cells := make([]string, 0)
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, string(cell.Text(writer.source)))
+		}
+		if len(cells) == 0 {
+			continue
+		}
+
+		cellWidth := (width - left - right) / float64(len(cells))
+		pdf.SetFont(pdfFont, style, 10)
+		for _, cell := range cells {
+			pdf.CellFormat(cellWidth, 6, writer.translate(cell), "1", 0, "L", false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.SetFont(pdfFont, "", pdfFontSize)
+}
+
+func (writer *pdfWriter) indent(amount float64, write func()) {
+	left := writer.leftMargin()
+	writer.pdf.SetLeftMargin(left + amount)
+	write()
+	writer.pdf.SetLeftMargin(left)
+	writer.pdf.SetX(left)
+}
+
+func (writer *pdfWriter) leftMargin() float64 {
+	left, _, _, _ := writer.pdf.GetMargins()
+	return left
+}
+
+/**
+Nested emphasis can repeat a style, e.g. BB, which gofpdf does not accept
+*/
+func normalizePdfStyle(style string) string {
+	normalized := ""
+	for _, s := range []string{"B", "I"} {
+		if strings.Contains(style, s) {
+			normalized += s
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,279 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const s3DefaultRegion = "us-east-1"
+const s3Service = "s3"
+const s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+const s3DateFormat = "20060102T150405Z"
+
+// the body is streamed, so it is left out of the signature
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+type S3Config struct {
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyId     string
+	SecretAccessKey string
+}
+
+func NewS3ConfigFromEnv() *S3Config {
+	region := os.Getenv("S3_REGION")
+	if len(region) == 0 {
+		region = s3DefaultRegion
+	}
+
+	return &S3Config{
+		Endpoint:        os.Getenv("S3_ENDPOINT"),
+		Bucket:          os.Getenv("S3_BUCKET"),
+		Region:          region,
+		AccessKeyId:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+}
+
+/*
+Keeps blobs as objects in a bucket of an S3 compatible service. Requests are signed with signature version 4 and use path
+style urls, e.g. https://s3.example.com/bucket/key, which every S3 compatible service supports.
+*/
+type S3BlobStore struct {
+	config   *S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3BlobStore(config *S3Config) (*S3BlobStore, error) {
+	if len(config.Endpoint) == 0 || len(config.Bucket) == 0 {
+		return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required for the s3 blob store")
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || len(endpoint.Host) == 0 {
+		return nil, errors.New("S3_ENDPOINT must be a url, e.g. https://s3.us-east-1.amazonaws.com")
+	}
+
+	return &S3BlobStore{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+func (store *S3BlobStore) Put(key string, body io.Reader, size int64, contentType string) error {
+	req, err := store.newRequest(http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := store.do(req)
+	if err != nil {
+		return errors.New("failed to store blob")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		logS3Error(res)
+		return errors.New("failed to store blob")
+	}
+
+	return nil
+}
+
+func (store *S3BlobStore) Get(key string) (io.ReadCloser, error) {
+	req, err := store.newRequest(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := store.do(req)
+	if err != nil {
+		return nil, errors.New("failed to read blob")
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		logS3Error(res)
+		res.Body.Close()
+		return nil, errors.New("failed to read blob")
+	}
+
+	return res.Body, nil
+}
+
+func (store *S3BlobStore) Delete(key string) error {
+	req, err := store.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := store.do(req)
+	if err != nil {
+		return errors.New("failed to delete blob")
+	}
+	defer res.Body.Close()
+
+	// s3 answers no content whether or not the object existed
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		logS3Error(res)
+		return errors.New("failed to delete blob")
+	}
+
+	return nil
+}
+
+func (store *S3BlobStore) newRequest(method string, key string, body io.Reader) (*http.Request, error) {
+	if len(key) == 0 || strings.HasPrefix(key, "/") {
+		return nil, errors.New("invalid blob key")
+	}
+
+	objectUrl := *store.endpoint
+	objectUrl.Path = strings.TrimSuffix(objectUrl.Path, "/") + "/" + store.config.Bucket + "/" + key
+	objectUrl.RawPath = ""
+
+	req, err := http.NewRequest(method, objectUrl.String(), body)
+	if err != nil {
+		log.Print(err)
+		return nil, errors.New("failed to build blob request")
+	}
+
+	return req, nil
+}
+
+func (store *S3BlobStore) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	SignS3Request(req, s3UnsignedPayload, store.config, store.now())
+
+	res, err := store.client.Do(req)
+	if err != nil {
+		log.Print(err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+/**
+Signs the request with signature version 4, adding the X-Amz-Date and Authorization headers. The host and every header
+already on the request are signed.
+*/
+func SignS3Request(req *http.Request, payloadHash string, config *S3Config, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(s3DateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Del("Authorization")
+
+	headers := map[string]string{"host": req.URL.Host}
+	if len(req.Host) > 0 {
+		headers["host"] = req.Host
+	}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format("20060102"), config.Region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{s3SigningAlgorithm, amzDate, scope, s3Sha256Hex(canonicalRequest)}, "\n")
+
+	key := s3Hmac([]byte("AWS4"+config.SecretAccessKey), now.Format("20060102"))
+	key = s3Hmac(key, config.Region)
+	key = s3Hmac(key, s3Service)
+	key = s3Hmac(key, "aws4_request")
+	signature := hex.EncodeToString(s3Hmac(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, config.AccessKeyId, scope, signedHeaders, signature,
+	))
+}
+
+/**
+Escapes everything but the unreserved characters and slashes, as signature version 4 requires
+*/
+func s3EscapePath(path string) string {
+	if len(path) == 0 {
+		return "/"
+	}
+
+	var builder strings.Builder
+	for _, b := range []byte(path) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || strings.IndexByte("-_.~/", b) != -1 {
+			builder.WriteByte(b)
+		} else {
+			builder.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+
+	return builder.String()
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0)
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, strings.ReplaceAll(url.QueryEscape(name)+"="+url.QueryEscape(value), "+", "%20"))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+func s3Hmac(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+func s3Sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func logS3Error(res *http.Response) {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	log.Printf("s3 request failed with %d: %s", res.StatusCode, body)
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+/**
+Runs background jobs on a fixed interval until it is stopped. A job that fails is logged and run again on the next tick.
+*/
+type Scheduler struct {
+	stop      chan struct{}
+	waitGroup sync.WaitGroup
+	once      sync.Once
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+	}
+}
+
+func (scheduler *Scheduler) Schedule(name string, interval time.Duration, job func() error) {
+	scheduler.waitGroup.Add(1)
+
+	go func() {
+		defer scheduler.waitGroup.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-scheduler.stop:
+				return
+			case <-ticker.C:
+				err := job()
+				if err != nil {
+					log.Printf("job %s failed: %s", name, err)
+				}
+			}
+		}
+	}()
+}
+
+/**
+Stops every job and waits for the ones that are running to finish
+*/
+func (scheduler *Scheduler) Stop() {
+	scheduler.once.Do(func() {
+		close(scheduler.stop)
+	})
+	scheduler.waitGroup.Wait()
+}
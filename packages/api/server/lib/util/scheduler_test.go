@@ -0,0 +1,32 @@
+package util_test
+
+import (
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsJobUntilStopped(t *testing.T) {
+	scheduler := util.NewScheduler()
+
+	var runs int32
+	scheduler.Schedule("test", time.Millisecond, func() error {
+		atomic.AddInt32(&runs, 1)
+		return errors.New("failed jobs keep running")
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	scheduler.Stop()
+
+	stoppedAt := atomic.LoadInt32(&runs)
+	assert.True(t, stoppedAt > 1)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stoppedAt, atomic.LoadInt32(&runs))
+
+	// stopping again is a no-op
+	scheduler.Stop()
+}
@@ -2,12 +2,22 @@ package util
 
 import (
 	"database/sql"
+	"sync"
 )
 
 type Transactionable interface {
 	InjectTransaction(tx *sql.Tx) interface{}
 }
 
+/*
+The hooks to run once a transaction commits, keyed by the transaction since every service a transaction is injected into
+gets its own copy of the manager and only the *sql.Tx is passed along. Sharing the map is safe since hooks are only added
+while their transaction is open, the Transact call that began the transaction takes them back out whether it commits or
+rolls back, so they never reach another transaction, and the lock covers transactions running at the same time.
+*/
+var afterCommitHooks = make(map[*sql.Tx][]func())
+var afterCommitLock sync.Mutex
+
 type TransactionManager struct {
 	db *sql.DB
 	tx *sql.Tx
@@ -24,8 +34,12 @@ func (manager *TransactionManager) InjectTransaction(tx *sql.Tx) interface{} {
 	return NewTransactionManager(manager.db, tx)
 }
 
+/*
+Runs the handle in a transaction. If the manager already has a transaction injected the handle joins it, and committing
+or rolling back is left to whoever started the transaction.
+*/
 func (manager *TransactionManager) Transact(obj Transactionable, handle func(obj interface{}) (interface{}, error)) (interface{}, error) {
-	tx, err := manager.getTransaction()
+	tx, owned, err := manager.getTransaction()
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +47,13 @@ func (manager *TransactionManager) Transact(obj Transactionable, handle func(obj
 	injected := obj.InjectTransaction(tx)
 
 	resp, err := handle(injected)
-	
+
+	if !owned {
+		return resp, err
+	}
+
 	if err != nil {
+		takeAfterCommitHooks(tx)
 		rollbackErr := tx.Rollback()
 		if rollbackErr != nil {
 			panic(rollbackErr)
@@ -43,20 +62,51 @@ func (manager *TransactionManager) Transact(obj Transactionable, handle func(obj
 	}
 
 	err = tx.Commit()
+	hooks := takeAfterCommitHooks(tx)
 	if err != nil {
 		return nil, err
 	}
-	
+
+	for _, hook := range hooks {
+		hook()
+	}
+
 	return resp, nil
 }
 
-func (manager *TransactionManager) getTransaction() (*sql.Tx, error) {
+/*
+Runs the hook once the injected transaction commits, e.g. to update something outside of the database that should not see
+changes that are rolled back. Without a transaction the hook runs right away.
+*/
+func (manager *TransactionManager) AfterCommit(hook func()) {
+	if manager.tx == nil {
+		hook()
+		return
+	}
+
+	afterCommitLock.Lock()
+	defer afterCommitLock.Unlock()
+
+	afterCommitHooks[manager.tx] = append(afterCommitHooks[manager.tx], hook)
+}
+
+func takeAfterCommitHooks(tx *sql.Tx) []func() {
+	afterCommitLock.Lock()
+	defer afterCommitLock.Unlock()
+
+	hooks := afterCommitHooks[tx]
+	delete(afterCommitHooks, tx)
+
+	return hooks
+}
+
+func (manager *TransactionManager) getTransaction() (*sql.Tx, bool, error) {
 	if manager.tx != nil {
-		return manager.tx, nil
+		return manager.tx, false, nil
 	}
 	tx, err := manager.db.Begin();
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	return tx, nil
+	return tx, true, nil
 }
@@ -0,0 +1,179 @@
+package util_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+/*
+A driver that only keeps count of the transactions, enough to see what the manager begins, commits and rolls back
+*/
+type countingDriver struct {
+	lock      sync.Mutex
+	begins    int
+	commits   int
+	rollbacks int
+}
+
+type countingConn struct {
+	driver *countingDriver
+}
+
+type countingTx struct {
+	driver *countingDriver
+}
+
+var transactions = &countingDriver{}
+
+func init() {
+	sql.Register("counting", transactions)
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	return &countingConn{driver: d}, nil
+}
+
+func (d *countingDriver) reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.begins, d.commits, d.rollbacks = 0, 0, 0
+}
+
+func (conn *countingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("statements are not supported")
+}
+
+func (conn *countingConn) Close() error {
+	return nil
+}
+
+func (conn *countingConn) Begin() (driver.Tx, error) {
+	conn.driver.lock.Lock()
+	defer conn.driver.lock.Unlock()
+	conn.driver.begins++
+	return &countingTx{driver: conn.driver}, nil
+}
+
+func (tx *countingTx) Commit() error {
+	tx.driver.lock.Lock()
+	defer tx.driver.lock.Unlock()
+	tx.driver.commits++
+	return nil
+}
+
+func (tx *countingTx) Rollback() error {
+	tx.driver.lock.Lock()
+	defer tx.driver.lock.Unlock()
+	tx.driver.rollbacks++
+	return nil
+}
+
+/*
+Stands in for a service, it gets a manager with the transaction injected the same way the services do
+*/
+type transactingService struct {
+	db      *sql.DB
+	manager *util.TransactionManager
+}
+
+func (service *transactingService) InjectTransaction(tx *sql.Tx) interface{} {
+	return &transactingService{
+		db:      service.db,
+		manager: util.NewTransactionManager(service.db, tx),
+	}
+}
+
+func newTransactingService(t *testing.T) *transactingService {
+	db, err := sql.Open("counting", "")
+	assert.Nil(t, err)
+	transactions.reset()
+
+	return &transactingService{
+		db:      db,
+		manager: util.NewTransactionManager(db, nil),
+	}
+}
+
+func TestTransactNestedJoinsTheOuterTransaction(t *testing.T) {
+	service := newTransactingService(t)
+
+	resp, err := service.manager.Transact(service, func(injected interface{}) (interface{}, error) {
+		outer := injected.(*transactingService)
+		return outer.manager.Transact(outer, func(injected interface{}) (interface{}, error) {
+			return "done", nil
+		})
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "done", resp)
+	assert.Equal(t, 1, transactions.begins)
+	assert.Equal(t, 1, transactions.commits)
+	assert.Equal(t, 0, transactions.rollbacks)
+}
+
+func TestTransactNestedErrorRollsBackTheOuterTransaction(t *testing.T) {
+	service := newTransactingService(t)
+
+	_, err := service.manager.Transact(service, func(injected interface{}) (interface{}, error) {
+		outer := injected.(*transactingService)
+		return outer.manager.Transact(outer, func(injected interface{}) (interface{}, error) {
+			return nil, errors.New("failed")
+		})
+	})
+
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 1, transactions.begins)
+	assert.Equal(t, 0, transactions.commits)
+	assert.Equal(t, 1, transactions.rollbacks)
+}
+
+func TestAfterCommitRunsOnceTheOuterTransactionCommits(t *testing.T) {
+	service := newTransactingService(t)
+	ran := make([]string, 0)
+
+	_, err := service.manager.Transact(service, func(injected interface{}) (interface{}, error) {
+		outer := injected.(*transactingService)
+		outer.manager.AfterCommit(func() {
+			ran = append(ran, "outer")
+		})
+
+		_, err := outer.manager.Transact(outer, func(injected interface{}) (interface{}, error) {
+			injected.(*transactingService).manager.AfterCommit(func() {
+				ran = append(ran, "inner")
+			})
+			return nil, nil
+		})
+
+		// nothing runs until the transaction that began it commits
+		assert.Len(t, ran, 0)
+		return nil, err
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"outer", "inner"}, ran)
+}
+
+func TestAfterCommitIsDroppedOnRollback(t *testing.T) {
+	service := newTransactingService(t)
+	ran := false
+
+	_, err := service.manager.Transact(service, func(injected interface{}) (interface{}, error) {
+		injected.(*transactingService).manager.AfterCommit(func() {
+			ran = true
+		})
+		return nil, errors.New("failed")
+	})
+	assert.NotNil(t, err)
+
+	// the hooks of the rolled back transaction do not carry over to the next one
+	_, err = service.manager.Transact(service, func(injected interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	assert.False(t, ran)
+}
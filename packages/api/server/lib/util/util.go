@@ -2,10 +2,13 @@ package util
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"log"
+	"mime"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -64,4 +67,44 @@ func ConvertStringArrayToInterfaceArray(slice []string) []interface{} {
 		newSlice[i] = slice[i]
 	}
 	return newSlice
-}
\ No newline at end of file
+}
+
+/**
+Builds a where clause that leaves out the rows where a column holds one of the excluded ids, the excluded ids and the
+columns are keyed by the same resource name. Null columns are never excluded. Returns an empty clause if there is
+nothing to exclude.
+*/
+func BuildSqlExclusionClause(excluded map[string][]string, columns map[string]string) (string, []interface{}) {
+	names := make([]string, 0)
+	for name := range columns {
+		if len(excluded[name]) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	clauses := make([]string, 0)
+	params := make([]interface{}, 0)
+	for _, name := range names {
+		column := columns[name]
+		clauses = append(clauses, fmt.Sprintf("(%s IS NULL OR %s NOT IN (%s))", column, column, BuildSqlPlaceholderArray(excluded[name])))
+		params = append(params, ConvertStringArrayToInterfaceArray(excluded[name])...)
+	}
+
+	return strings.Join(clauses, " AND "), params
+}
+
+/**
+Escapes the wildcards of a LIKE pattern, so that the value is matched as it is
+*/
+func EscapeSqlLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
+
+/**
+Sets the headers of a file download, the body is written after
+*/
+func WriteDownloadHeaders(w http.ResponseWriter, contentType string, fileName string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+}
@@ -0,0 +1,40 @@
+package util_test
+
+import (
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildSqlExclusionClause(t *testing.T) {
+	clause, params := util.BuildSqlExclusionClause(map[string][]string{
+		"organization": {"1"},
+		"folder":       {"2", "3"},
+		"team":         {"4"},
+	}, map[string]string{
+		"organization": "d.organization_id",
+		"folder":       "d.folder_id",
+		"document":     "d.id",
+	})
+
+	assert.Equal(t, "(d.folder_id IS NULL OR d.folder_id NOT IN (?, ?)) AND (d.organization_id IS NULL OR d.organization_id NOT IN (?))", clause)
+	assert.Equal(t, []interface{}{"2", "3", "1"}, params)
+}
+
+func TestBuildSqlExclusionClauseWithNothingExcluded(t *testing.T) {
+	clause, params := util.BuildSqlExclusionClause(map[string][]string{}, map[string]string{
+		"document": "d.id",
+	})
+
+	assert.Equal(t, "", clause)
+	assert.Len(t, params, 0)
+}
+
+func TestAfterCommitRunsRightAwayWithoutTransaction(t *testing.T) {
+	ran := false
+	util.NewTransactionManager(nil, nil).AfterCommit(func() {
+		ran = true
+	})
+
+	assert.True(t, ran)
+}
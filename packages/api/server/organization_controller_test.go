@@ -1,8 +1,13 @@
 package server_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
+	"github.com/honerlaw/mentordoc/server/http/request"
 	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/document"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
 	"github.com/honerlaw/mentordoc/server/test"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -32,3 +37,119 @@ func TestIntegrationListOrganizations(t *testing.T) {
 	assert.Len(t, r, 1)
 	assert.Equal(t, r[0].Model.(map[string]interface{})["id"], authData.Organization.Id)
 }
+
+func TestIntegrationImportOrganization(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	archive := func(files map[string]string) []byte {
+		var buffer bytes.Buffer
+		writer := zip.NewWriter(&buffer)
+		for name, content := range files {
+			file, err := writer.Create(name)
+			assert.Nil(t, err)
+			_, err = file.Write([]byte(content))
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, writer.Close())
+		return buffer.Bytes()
+	}
+	importArchive := func(query string, body []byte, responseModel interface{}) (int, interface{}) {
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "POST",
+			Path:   fmt.Sprintf("/organization/%s/import%s", authData.Organization.Id, query),
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+				"Content-Type":  "application/zip",
+			},
+			Body:          body,
+			ResponseModel: responseModel,
+		})
+		assert.Nil(t, err)
+		return status, resp
+	}
+
+	body := archive(map[string]string{
+		"eng/runbooks/deploy.md": "---\ntitle: Deploy to prod\npublish: true\n---\n# Deploy",
+		"eng/overview.md":        "# Overview",
+		"eng/diagram.png":        "not markdown",
+		".DS_Store":              "",
+	})
+
+	status, resp := importArchive("?dryRun=true", body, &document.ImportReport{})
+	assert.Equal(t, http.StatusOK, status)
+	report := resp.(*document.ImportReport)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []document.ImportedFolder{
+		{Path: "eng", Name: "eng"},
+		{Path: "eng/runbooks", Name: "runbooks"},
+	}, report.Folders)
+	assert.Len(t, report.Documents, 2)
+	assert.ElementsMatch(t, []string{"eng/diagram.png", ".DS_Store"}, report.Skipped)
+
+	folders, err := testData.TestServer.FolderService.List(authData.User, authData.Organization.Id, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, folders, 0)
+
+	// a broken file fails the whole import
+	status, resp = importArchive("", archive(map[string]string{
+		"eng/overview.md": "# Overview",
+		"eng/broken.md":   "---\ntitle: [\n---\n",
+	}), &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "eng/broken.md", resp.(*shared.HttpError).Details[0].Token)
+
+	folders, err = testData.TestServer.FolderService.List(authData.User, authData.Organization.Id, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, folders, 0)
+
+	status, resp = importArchive("", body, &document.ImportReport{})
+	assert.Equal(t, http.StatusCreated, status)
+	report = resp.(*document.ImportReport)
+	assert.False(t, report.DryRun)
+
+	deploy := report.Documents[1]
+	assert.Equal(t, "eng/runbooks/deploy.md", deploy.Path)
+	assert.Equal(t, "Deploy to prod", deploy.Name)
+	assert.True(t, deploy.Published)
+
+	doc, err := testData.TestServer.DocumentService.FindDocument(authData.User, deploy.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, report.Folders[1].Id, *doc.FolderId)
+	assert.Equal(t, "Deploy to prod", doc.Drafts[0].Name)
+	assert.Equal(t, "# Deploy", doc.Drafts[0].Content.Content)
+	assert.NotNil(t, doc.Drafts[0].PublishedAt)
+
+	overview, err := testData.TestServer.DocumentService.FindDocument(authData.User, report.Documents[0].Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "overview", overview.Drafts[0].Name)
+	assert.Nil(t, overview.Drafts[0].PublishedAt)
+
+	// importing into a folder needs access to the folder, not only to the organization
+	contributorData := test.SetupAuthentication(t, testData)
+	err = testData.TestServer.AclService.LinkUserToRole(contributorData.User, "organization:contributor", authData.Organization.Id)
+	assert.Nil(t, err)
+	createDeny(t, map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}, authData.Organization.Id, &request.DenyCreateRequest{
+		UserId:       &contributorData.User.Id,
+		ResourcePath: "folder",
+		ResourceId:   report.Folders[0].Id,
+		Action:       "create:folder",
+	})
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/organization/%s/import?dryRun=true&folderId=%s", authData.Organization.Id, report.Folders[0].Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", contributorData.AccessToken),
+			"Content-Type":  "application/zip",
+		},
+		Body:          archive(map[string]string{"guides/setup.md": "# Setup"}),
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+}
@@ -0,0 +1,157 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIntegrationDocumentReview(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	authorData := test.SetupAuthentication(t, testData)
+	reviewerData := test.SetupAuthentication(t, testData)
+	viewerData := test.SetupAuthentication(t, testData)
+
+	folder, err := testData.TestServer.FolderService.Create(authData.User, "runbooks", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	subFolder, err := testData.TestServer.FolderService.Create(authData.User, "drafts", authData.Organization.Id, &folder.Id)
+	assert.Nil(t, err)
+
+	for _, userData := range []*test.AuthData{authorData, reviewerData} {
+		err = testData.TestServer.AclService.LinkUserToRole(userData.User, "organization:contributor", authData.Organization.Id)
+		assert.Nil(t, err)
+	}
+	err = testData.TestServer.AclService.LinkUserToRole(viewerData.User, "folder:viewer", folder.Id)
+	assert.Nil(t, err)
+
+	one := 1
+	two := 2
+	none := 0
+
+	// only users that can modify the organization manage its policies
+	policyPath := fmt.Sprintf("/organization/%s/review-policy", authData.Organization.Id)
+	status, _ := test.Send(t, "PUT", policyPath, authorData.AccessToken, &request.ReviewPolicySetRequest{RequiredApprovals: &one}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = test.Send(t, "PUT", policyPath, authData.AccessToken, &request.ReviewPolicySetRequest{RequiredApprovals: &two}, &shared.ReviewPolicy{})
+	assert.Equal(t, http.StatusOK, status)
+	status, resp := test.Send(t, "PUT", fmt.Sprintf("/folder/%s/review-policy", folder.Id), authData.AccessToken, &request.ReviewPolicySetRequest{RequiredApprovals: &one}, &shared.ReviewPolicy{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, folder.Id, *resp.(*shared.ReviewPolicy).FolderId)
+
+	// the policy of the nearest folder applies
+	doc, err := testData.TestServer.DocumentService.Create(authorData.User, authData.Organization.Id, &subFolder.Id, "restart", "# Restart\nrun it")
+	assert.Nil(t, err)
+	draftId := doc.Drafts[0].Id
+
+	_, err = testData.TestServer.DocumentService.Update(authorData.User, doc.Id, draftId, nil, nil, true, false, nil, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*shared.HttpError).Status)
+
+	// the draft is only visible to reviewers once it is in review
+	reviewPath := fmt.Sprintf("/document/%s/draft/%s/review", doc.Id, draftId)
+	status, _ = test.Send(t, "POST", reviewPath, reviewerData.AccessToken, &request.ReviewCreateRequest{Decision: "approve"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	requestPath := fmt.Sprintf("/document/%s/draft/%s/review-request", doc.Id, draftId)
+	status, _ = test.Send(t, "POST", requestPath, reviewerData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, resp = test.Send(t, "POST", requestPath, authorData.AccessToken, nil, &shared.ReviewStatus{})
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, 1, resp.(*shared.ReviewStatus).RequiredApprovals)
+	assert.NotNil(t, resp.(*shared.ReviewStatus).Request)
+
+	status, _ = test.Send(t, "POST", reviewPath, authorData.AccessToken, &request.ReviewCreateRequest{Decision: "approve"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = test.Send(t, "POST", reviewPath, viewerData.AccessToken, &request.ReviewCreateRequest{Decision: "approve"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = test.Send(t, "POST", reviewPath, reviewerData.AccessToken, &request.ReviewCreateRequest{Decision: "maybe"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	comment := "step two is missing"
+	status, _ = test.Send(t, "POST", reviewPath, reviewerData.AccessToken, &request.ReviewCreateRequest{Decision: "request_changes", Comment: &comment}, &shared.DocumentReview{})
+	assert.Equal(t, http.StatusCreated, status)
+	status, _ = test.Send(t, "POST", reviewPath, authData.AccessToken, &request.ReviewCreateRequest{Decision: "approve"}, &shared.DocumentReview{})
+	assert.Equal(t, http.StatusCreated, status)
+
+	status, resp = test.Send(t, "GET", reviewPath, authorData.AccessToken, nil, &shared.ReviewStatus{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, resp.(*shared.ReviewStatus).Approvals)
+	assert.Equal(t, 1, resp.(*shared.ReviewStatus).ChangesRequested)
+	assert.False(t, resp.(*shared.ReviewStatus).CanPublish)
+
+	// changing the draft dismisses the reviews
+	content := "# Restart\nstop it\nrun it"
+	_, err = testData.TestServer.DocumentService.Update(authorData.User, doc.Id, draftId, nil, &content, false, false, nil, nil)
+	assert.Nil(t, err)
+	status, resp = test.Send(t, "GET", reviewPath, authorData.AccessToken, nil, &shared.ReviewStatus{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, resp.(*shared.ReviewStatus).Reviews, 0)
+
+	status, _ = test.Send(t, "POST", reviewPath, reviewerData.AccessToken, &request.ReviewCreateRequest{Decision: "approve"}, &shared.DocumentReview{})
+	assert.Equal(t, http.StatusCreated, status)
+
+	// withdrawing and submitting the draft again starts the review over
+	status, _ = test.Send(t, "DELETE", requestPath, authorData.AccessToken, nil, &shared.ReviewStatus{})
+	assert.Equal(t, http.StatusOK, status)
+	status, resp = test.Send(t, "POST", requestPath, authorData.AccessToken, nil, &shared.ReviewStatus{})
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, 0, resp.(*shared.ReviewStatus).Approvals)
+	assert.False(t, resp.(*shared.ReviewStatus).CanPublish)
+
+	_, err = testData.TestServer.DocumentService.Update(authorData.User, doc.Id, draftId, nil, nil, true, false, nil, nil)
+	assert.NotNil(t, err)
+
+	// scheduling the draft does not revise it, so the reviews still count
+	status, _ = test.Send(t, "POST", reviewPath, reviewerData.AccessToken, &request.ReviewCreateRequest{Decision: "approve"}, &shared.DocumentReview{})
+	assert.Equal(t, http.StatusCreated, status)
+	publishAt := util.NowUnix() + int64(time.Hour)
+	_, err = testData.TestServer.DocumentService.Update(authorData.User, doc.Id, draftId, nil, nil, false, false, &publishAt, nil)
+	assert.Nil(t, err)
+	status, resp = test.Send(t, "GET", reviewPath, authorData.AccessToken, nil, &shared.ReviewStatus{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, resp.(*shared.ReviewStatus).Approvals)
+
+	// changing the content and publishing in one go would publish what nobody reviewed
+	unreviewed := "# Restart\nrun it twice"
+	status, _ = test.Send(t, "PUT", "/document", authorData.AccessToken, &request.DocumentUpdateRequest{
+		DocumentId:    doc.Id,
+		DraftId:       draftId,
+		Content:       &unreviewed,
+		ShouldPublish: true,
+	}, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, resp = test.Send(t, "GET", reviewPath, authorData.AccessToken, nil, &shared.ReviewStatus{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, resp.(*shared.ReviewStatus).Approvals)
+
+	published, err := testData.TestServer.DocumentService.Update(authorData.User, doc.Id, draftId, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, published.Drafts[0].PublishedAt)
+
+	// a folder policy that requires no approvals lifts the policy of the organization
+	status, _ = test.Send(t, "PUT", fmt.Sprintf("/folder/%s/review-policy", subFolder.Id), authData.AccessToken, &request.ReviewPolicySetRequest{RequiredApprovals: &none}, &shared.ReviewPolicy{})
+	assert.Equal(t, http.StatusOK, status)
+	otherDoc, err := testData.TestServer.DocumentService.Create(authorData.User, authData.Organization.Id, &subFolder.Id, "stop", "# Stop")
+	assert.Nil(t, err)
+	_, err = testData.TestServer.DocumentService.Update(authorData.User, otherDoc.Id, otherDoc.Drafts[0].Id, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+
+	// documents outside of the folders fall back to the organization policy
+	status, _ = test.Send(t, "DELETE", policyPath, authData.AccessToken, nil, &shared.ReviewPolicy{})
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = test.Send(t, "DELETE", policyPath, authData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusNotFound, status)
+	rootDoc, err := testData.TestServer.DocumentService.Create(authorData.User, authData.Organization.Id, nil, "root", "# Root")
+	assert.Nil(t, err)
+	_, err = testData.TestServer.DocumentService.Update(authorData.User, rootDoc.Id, rootDoc.Drafts[0].Id, nil, nil, true, false, nil, nil)
+	assert.Nil(t, err)
+}
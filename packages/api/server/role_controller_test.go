@@ -0,0 +1,277 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/util"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIntegrationCreateRoleFailsWithUnknownPermission(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/organization/%s/role", authData.Organization.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		Body: &request.RoleCreateRequest{
+			Name: "reviewer",
+			Permissions: map[string][]string{
+				"organization:folder:document": {"view", "publish"},
+			},
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestIntegrationCreateRoleFailsWithPathOutsideOfOrganization(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/organization/%s/role", authData.Organization.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		Body: &request.RoleCreateRequest{
+			Name: "folder-reader",
+			Permissions: map[string][]string{
+				"folder":          {"view"},
+				"folder:document": {"view"},
+			},
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestIntegrationCreateRoleFailsWithOwnerActions(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	for _, action := range []string{"view:acl", "manage:role"} {
+		status, _, err := test.Request(&test.RequestOptions{
+			Method: "POST",
+			Path:   fmt.Sprintf("/organization/%s/role", authData.Organization.Id),
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+			},
+			Body: &request.RoleCreateRequest{
+				Name: "auditor",
+				Permissions: map[string][]string{
+					"organization": {"view", action},
+				},
+			},
+			ResponseModel: &shared.HttpError{},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, status)
+	}
+}
+
+func TestIntegrationCreateRoleFailsWhenNotOwner(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/organization/%s/role", authData.Organization.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", otherAuthData.AccessToken),
+		},
+		Body: &request.RoleCreateRequest{
+			Name: "reviewer",
+			Permissions: map[string][]string{
+				"organization:folder:document": {"view"},
+			},
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestIntegrationCustomRoleLifecycle(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method:  "POST",
+		Path:    fmt.Sprintf("/organization/%s/role", authData.Organization.Id),
+		Headers: headers,
+		Body: &request.RoleCreateRequest{
+			Name: "reviewer",
+			Permissions: map[string][]string{
+				"organization":                 {"view", "view:document"},
+				"organization:folder:document": {"view"},
+			},
+		},
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	role := resp.(*acl.Role)
+	assert.Equal(t, "reviewer", role.Name)
+	assert.Equal(t, authData.Organization.Id, *role.OrganizationId)
+	assert.Len(t, role.Permissions, 3)
+
+	// only the document permission is left after the update
+	status, resp, err = test.Request(&test.RequestOptions{
+		Method:  "PUT",
+		Path:    fmt.Sprintf("/organization/%s/role/%s", authData.Organization.Id, role.Id),
+		Headers: headers,
+		Body: &request.RoleUpdateRequest{
+			Name: "document-reviewer",
+			Permissions: map[string][]string{
+				"organization:folder:document": {"view"},
+			},
+		},
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "document-reviewer", resp.(*acl.Role).Name)
+	assert.Len(t, resp.(*acl.Role).Permissions, 1)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "test-name", "test content")
+	assert.Nil(t, err)
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/user", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		Body:          &request.RoleAssignRequest{UserId: otherAuthData.User.Id},
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "modify"))
+
+	// can not delete the role while someone holds it
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/role/%s", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/user/%s", authData.Organization.Id, role.Id, otherAuthData.User.Id),
+		Headers:       headers,
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/role/%s", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	roles := make([]acl.Role, 0)
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "GET",
+		Path:          fmt.Sprintf("/organization/%s/role/list", authData.Organization.Id),
+		Headers:       headers,
+		ResponseModel: &roles,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, roles, 0)
+}
+
+func TestIntegrationExpiringRoleGrant(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+
+	role, err := testData.TestServer.AclService.CreateOrganizationRole(authData.Organization.Id, "temporary", map[string][]string{
+		"organization": {"view"},
+	})
+	assert.Nil(t, err)
+
+	// can not grant a role that has already expired
+	expiresAt := util.NowUnix() - int64(time.Hour)
+	status, _, err := test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/user", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		Body:          &request.RoleAssignRequest{UserId: otherAuthData.User.Id, ExpiresAt: &expiresAt},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	expiresAt = util.NowUnix() + int64(time.Hour)
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/user", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		Body:          &request.RoleAssignRequest{UserId: otherAuthData.User.Id, ExpiresAt: &expiresAt},
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, authData.Organization, "view"))
+
+	// once it expires the grant is ignored, even before the job removes it
+	_, err = testData.TestServer.Db.Exec("update user_role set expires_at = ? where user_id = ? and role_id = ?",
+		util.NowUnix()-1, otherAuthData.User.Id, role.Id)
+	assert.Nil(t, err)
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, authData.Organization, "view"))
+
+	err = testData.TestServer.GrantExpiryJob.Run()
+	assert.Nil(t, err)
+
+	var count int
+	err = testData.TestServer.Db.QueryRow("select count(*) from user_role where user_id = ? and role_id = ?",
+		otherAuthData.User.Id, role.Id).Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	history := testData.TestServer.ResourceHistoryRepository.FindOne(authData.Organization.Id, "user_role", otherAuthData.User.Id, "expired")
+	assert.NotNil(t, history)
+}
@@ -0,0 +1,107 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/lib/search"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestIntegrationSearchFindsEveryTypeWithItsPath(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	org, err := testData.TestServer.OrganizationService.Create("quokka org")
+	assert.Nil(t, err)
+	err = testData.TestServer.AclService.LinkUserToRole(authData.User, "organization:owner", org.Id)
+	assert.Nil(t, err)
+	eng, err := testData.TestServer.FolderService.Create(authData.User, "eng", org.Id, nil)
+	assert.Nil(t, err)
+	runbooks, err := testData.TestServer.FolderService.Create(authData.User, "quokka runbooks", org.Id, &eng.Id)
+	assert.Nil(t, err)
+	deploy, err := testData.TestServer.DocumentService.Create(authData.User, org.Id, &runbooks.Id, "deploy", "how to deploy the quokka service")
+	assert.Nil(t, err)
+
+	find := func(accessToken string, query string) (int, []search.Hit) {
+		hits := make([]search.Hit, 0)
+		status, resp, err := test.Request(&test.RequestOptions{
+			Method: "GET",
+			Path:   "/search?query=" + query,
+			Headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+			},
+			ResponseModel: &hits,
+		})
+		assert.Nil(t, err)
+		return status, *resp.(*[]search.Hit)
+	}
+
+	status, hits := find(authData.AccessToken, "quokka")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, hits, 3)
+
+	assert.Equal(t, search.HitTypeOrganization, hits[0].Type)
+	assert.Equal(t, []search.PathItem{
+		{Type: search.HitTypeOrganization, Id: org.Id, Name: "quokka org"},
+	}, hits[0].Path)
+
+	assert.Equal(t, search.HitTypeFolder, hits[1].Type)
+	assert.Equal(t, []search.PathItem{
+		{Type: search.HitTypeOrganization, Id: org.Id, Name: "quokka org"},
+		{Type: search.HitTypeFolder, Id: eng.Id, Name: "eng"},
+		{Type: search.HitTypeFolder, Id: runbooks.Id, Name: "quokka runbooks"},
+	}, hits[1].Path)
+	assert.Equal(t, []string{"create:document", "create:folder", "delete", "modify", "view", "view:document", "view:folder"}, hits[1].Actions)
+
+	assert.Equal(t, search.HitTypeDocument, hits[2].Type)
+	assert.Equal(t, []search.PathItem{
+		{Type: search.HitTypeOrganization, Id: org.Id, Name: "quokka org"},
+		{Type: search.HitTypeFolder, Id: eng.Id, Name: "eng"},
+		{Type: search.HitTypeFolder, Id: runbooks.Id, Name: "quokka runbooks"},
+		{Type: search.HitTypeDocument, Id: deploy.Id, Name: "deploy"},
+	}, hits[2].Path)
+	doc := test.ConvertModel(hits[2].Model, &shared.Document{}).(*shared.Document)
+	assert.Equal(t, deploy.Id, doc.Id)
+
+	status, hits = find(authData.AccessToken, "quokka&types=folder")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, search.HitTypeFolder, hits[0].Type)
+
+	// qualifiers only apply to documents
+	status, hits = find(authData.AccessToken, "quokka+is:draft")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, search.HitTypeDocument, hits[0].Type)
+
+	ops, err := testData.TestServer.DocumentService.CreateTag(authData.User, org.Id, "ops")
+	assert.Nil(t, err)
+	_, err = testData.TestServer.DocumentService.TagDocument(authData.User, deploy.Id, ops.Id)
+	assert.Nil(t, err)
+	status, hits = find(authData.AccessToken, "quokka+tag:ops")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, search.HitTypeDocument, hits[0].Type)
+
+	// nothing is found where the user has no access
+	status, hits = find(otherAuthData.AccessToken, "quokka")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, hits, 0)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   "/search?query=quokka&types=team",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -8,7 +8,7 @@ import (
 var testData *test.GlobalTestData
 
 func TestMain(m *testing.M) {
-	testData = test.InitTestData("../.env.test", "../migrations")
+	testData = test.InitTestData("../.env.test", "../migrations", "../policy/acl.yaml")
 
 	test.RunTests(m, testData)
 }
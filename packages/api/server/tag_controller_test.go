@@ -0,0 +1,104 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestIntegrationDocumentTags(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	contributorData := test.SetupAuthentication(t, testData)
+	otherData := test.SetupAuthentication(t, testData)
+
+	err := testData.TestServer.AclService.LinkUserToRole(contributorData.User, "organization:contributor", authData.Organization.Id)
+	assert.Nil(t, err)
+
+	folder, err := testData.TestServer.FolderService.Create(authData.User, "releases", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	notes, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, &folder.Id, "release notes", "# 2.0 zebrafish")
+	assert.Nil(t, err)
+	_, err = testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "roadmap", "# zebrafish")
+	assert.Nil(t, err)
+
+
+	tagPath := fmt.Sprintf("/organization/%s/tag", authData.Organization.Id)
+	status, _ := test.Send(t, "POST", tagPath, otherData.AccessToken, &request.TagCreateRequest{Name: "release"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, resp := test.Send(t, "POST", tagPath, contributorData.AccessToken, &request.TagCreateRequest{Name: "release"}, &shared.Tag{})
+	assert.Equal(t, http.StatusCreated, status)
+	release := resp.(*shared.Tag)
+	status, _ = test.Send(t, "POST", tagPath, contributorData.AccessToken, &request.TagCreateRequest{Name: "release"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, resp = test.Send(t, "POST", tagPath, authData.AccessToken, &request.TagCreateRequest{Name: "roadmap"}, &shared.Tag{})
+	assert.Equal(t, http.StatusCreated, status)
+	roadmap := resp.(*shared.Tag)
+
+	tags := make([]shared.Tag, 0)
+	status, resp = test.Send(t, "GET", fmt.Sprintf("/organization/%s/tag/autocomplete?q=rel", authData.Organization.Id), contributorData.AccessToken, nil, &tags)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []shared.Tag{*release}, *resp.(*[]shared.Tag))
+
+	// contributors can tag documents but only owners can rename and delete tags
+	status, resp = test.Send(t, "POST", fmt.Sprintf("/document/%s/tag/%s", notes.Id, release.Id), contributorData.AccessToken, nil, &tags)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, *resp.(*[]shared.Tag), 1)
+	status, _ = test.Send(t, "PUT", fmt.Sprintf("/tag/%s", release.Id), contributorData.AccessToken, &request.TagUpdateRequest{Name: "releases"}, &shared.HttpError{})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, resp = test.Send(t, "PUT", fmt.Sprintf("/tag/%s", release.Id), authData.AccessToken, &request.TagUpdateRequest{Name: "releases"}, &shared.Tag{})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "releases", resp.(*shared.Tag).Name)
+
+	// the document is still an unpublished draft, so only its creator can read it
+	status, resp = test.Send(t, "GET", fmt.Sprintf("/document/%s", notes.Id), authData.AccessToken, nil, &acl.AclWrappedModel{})
+	assert.Equal(t, http.StatusCreated, status)
+	found := test.ConvertModel(resp.(*acl.AclWrappedModel).Model, &shared.Document{}).(*shared.Document)
+	assert.Equal(t, []string{"releases"}, []string{found.Tags[0].Name})
+
+	// listing by tag finds documents in every folder
+	wrapped := make([]acl.AclWrappedModel, 0)
+	status, resp = test.Send(t, "GET", fmt.Sprintf("/document/list/%s?tag=releases", authData.Organization.Id), authData.AccessToken, nil, &wrapped)
+	assert.Equal(t, http.StatusOK, status)
+	listed := *resp.(*[]acl.AclWrappedModel)
+	assert.Len(t, listed, 1)
+	assert.Equal(t, notes.Id, test.ConvertModel(listed[0].Model, &shared.Document{}).(*shared.Document).Id)
+
+	wrapped = make([]acl.AclWrappedModel, 0)
+	status, resp = test.Send(t, "GET", "/document/search?query=zebrafish&tag=releases", authData.AccessToken, nil, &wrapped)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, *resp.(*[]acl.AclWrappedModel), 1)
+	wrapped = make([]acl.AclWrappedModel, 0)
+	status, resp = test.Send(t, "GET", "/document/search?query=zebrafish+tag:roadmap", authData.AccessToken, nil, &wrapped)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, *resp.(*[]acl.AclWrappedModel), 0)
+
+	status, _ = test.Send(t, "DELETE", fmt.Sprintf("/document/%s/tag/%s", notes.Id, roadmap.Id), authData.AccessToken, nil, &shared.HttpError{})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// deleting the tag takes it off of the documents
+	status, _ = test.Send(t, "DELETE", fmt.Sprintf("/tag/%s", release.Id), authData.AccessToken, nil, &shared.Tag{})
+	assert.Equal(t, http.StatusOK, status)
+	found, err = testData.TestServer.DocumentService.FindDocument(authData.User, notes.Id)
+	assert.Nil(t, err)
+	assert.Len(t, found.Tags, 0)
+
+	actions := make([]string, 0)
+	for _, userId := range []string{authData.User.Id, contributorData.User.Id} {
+		history, err := testData.TestServer.ResourceHistoryService.FindByUserId(userId)
+		assert.Nil(t, err)
+		for _, entry := range history {
+			if entry.ResourceName == "tag" || entry.ResourceName == "document_tag" {
+				actions = append(actions, entry.ResourceName+":"+entry.Action)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"tag:created", "tag:created", "tag:updated", "tag:deleted", "document_tag:created"}, actions)
+}
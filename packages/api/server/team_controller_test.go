@@ -0,0 +1,231 @@
+package server_test
+
+import (
+	"fmt"
+	"github.com/honerlaw/mentordoc/server/http/request"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
+	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestIntegrationCreateTeamFailsWhenNotOwner(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/organization/%s/team", authData.Organization.Id),
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", otherAuthData.AccessToken),
+		},
+		Body:          &request.TeamCreateRequest{Name: "writers"},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestIntegrationTeamGrantsRolesToMembers(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+	}
+
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/team", authData.Organization.Id),
+		Headers:       headers,
+		Body:          &request.TeamCreateRequest{Name: "writers"},
+		ResponseModel: &shared.Team{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	team := resp.(*shared.Team)
+	assert.Equal(t, authData.Organization.Id, team.OrganizationId)
+
+	// names are unique within the organization
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/team", authData.Organization.Id),
+		Headers:       headers,
+		Body:          &request.TeamCreateRequest{Name: "writers"},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/team/%s/member", authData.Organization.Id, team.Id),
+		Headers:       headers,
+		Body:          &request.TeamMemberAddRequest{UserId: otherAuthData.User.Id},
+		ResponseModel: &shared.Team{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	members := make([]shared.User, 0)
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "GET",
+		Path:          fmt.Sprintf("/organization/%s/team/%s/member/list", authData.Organization.Id, team.Id),
+		Headers:       headers,
+		ResponseModel: &members,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, members, 1)
+	assert.Equal(t, otherAuthData.User.Id, members[0].Id)
+
+	role, err := testData.TestServer.AclService.CreateOrganizationRole(authData.Organization.Id, "team-viewer", map[string][]string{
+		"organization":                 {"view", "view:document"},
+		"organization:folder:document": {"view"},
+	})
+	assert.Nil(t, err)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "test-name", "test content")
+	assert.Nil(t, err)
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/team", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		Body:          &request.RoleAssignTeamRequest{TeamId: team.Id},
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// the member gets the role through the team
+	assert.True(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "modify"))
+	resources, err := testData.TestServer.AclService.UserActionableResourcesByPath(otherAuthData.User, []string{"organization", "folder", "document"}, "view")
+	assert.Nil(t, err)
+	teamResources := make([]string, 0)
+	for _, res := range resources {
+		// the member also owns the organization they signed up with
+		if res.ResourceId == authData.Organization.Id {
+			teamResources = append(teamResources, res.ResourceId)
+		}
+	}
+	assert.Len(t, teamResources, 1)
+
+	explanation, err := testData.TestServer.AclService.Explain(otherAuthData.User, []string{"organization", "folder", "document"}, []string{authData.Organization.Id, "", doc.Id}, "view")
+	assert.Nil(t, err)
+	assert.True(t, explanation.Granted)
+	assert.Equal(t, team.Id, *explanation.Grants[0].TeamId)
+
+	// built in roles are given on the resource they are named after, like they are for users
+	legal, err := testData.TestServer.FolderService.Create(authData.User, "legal", authData.Organization.Id, nil)
+	assert.Nil(t, err)
+	legalDoc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, &legal.Id, "contract", "indemnification clauses")
+	assert.Nil(t, err)
+	viewerRoleId := findRoleId(t, "folder:viewer")
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/team", authData.Organization.Id, viewerRoleId),
+		Headers:       headers,
+		Body:          &request.RoleAssignTeamRequest{TeamId: team.Id},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/team", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		Body:          &request.RoleAssignTeamRequest{TeamId: team.Id, ResourceId: &legal.Id},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, legal, "view"))
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "POST",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/team", authData.Organization.Id, viewerRoleId),
+		Headers:       headers,
+		Body:          &request.RoleAssignTeamRequest{TeamId: team.Id, ResourceId: &legal.Id},
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, legal, "view"))
+	assert.True(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, legalDoc, "view"))
+
+	teamRoles, err := testData.TestServer.AclService.FindTeamRoles(team.Id)
+	assert.Nil(t, err)
+	assert.Len(t, teamRoles, 2)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/role/%s/team/%s?resourceId=%s", authData.Organization.Id, viewerRoleId, team.Id, legal.Id),
+		Headers:       headers,
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, legal, "view"))
+
+	// can not delete the role while a team holds it
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/role/%s", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/team/%s/member/%s", authData.Organization.Id, team.Id, otherAuthData.User.Id),
+		Headers:       headers,
+		ResponseModel: &shared.Team{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, testData.TestServer.AclService.UserCanAccessResourceByModel(otherAuthData.User, doc, "view"))
+
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/team/%s", authData.Organization.Id, team.Id),
+		Headers:       headers,
+		ResponseModel: &shared.Team{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	teams := make([]shared.Team, 0)
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "GET",
+		Path:          fmt.Sprintf("/organization/%s/team/list", authData.Organization.Id),
+		Headers:       headers,
+		ResponseModel: &teams,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, teams, 0)
+
+	// deleting the team released the role
+	status, _, err = test.Request(&test.RequestOptions{
+		Method:        "DELETE",
+		Path:          fmt.Sprintf("/organization/%s/role/%s", authData.Organization.Id, role.Id),
+		Headers:       headers,
+		ResponseModel: &acl.Role{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
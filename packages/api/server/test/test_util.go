@@ -13,10 +13,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"testing"
+	"time"
 )
 
 type GlobalTestData struct {
@@ -24,7 +26,7 @@ type GlobalTestData struct {
 	TestServer               *http2.Server
 }
 
-func InitTestData(envPath string, migrationDir string) *GlobalTestData {
+func InitTestData(envPath string, migrationDir string, policyPath string) *GlobalTestData {
 	data := &GlobalTestData{
 		Integration: flag.Bool("it", false, "run integration tests"),
 	}
@@ -44,8 +46,27 @@ func InitTestData(envPath string, migrationDir string) *GlobalTestData {
 		}
 
 		_ = os.Setenv("MIGRATION_DIR", migrationDir)
+		_ = os.Setenv("ACL_POLICY_PATH", policyPath)
+
+		blobStorePath, err := ioutil.TempDir("", "mentordoc-blobs")
+		if err != nil {
+			log.Fatal(err)
+		}
+		_ = os.Setenv("BLOB_STORE", "local")
+		_ = os.Setenv("BLOB_STORE_PATH", blobStorePath)
 
 		data.TestServer = http2.StartServer(nil)
+
+		// the server listens in the background, so wait until it accepts connections before running any tests
+		address := net.JoinHostPort(os.Getenv("API_HOST"), os.Getenv("API_PORT"))
+		for tick := 0; tick < 50; tick++ {
+			conn, err := net.Dial("tcp", address)
+			if err == nil {
+				_ = conn.Close()
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
 	}
 
 	return data
@@ -111,9 +132,11 @@ Utility method to make an http request and get the response body
 */
 func Request(options *RequestOptions) (int, interface{}, error) {
 
-	// attempt to convert the body to byte array
+	// attempt to convert the body to byte array, raw bytes (e.g. a zip) are sent as is
 	var data []byte
-	if options.Body != nil {
+	if raw, ok := options.Body.([]byte); ok {
+		data = raw
+	} else if options.Body != nil {
 		marshalled, err := json.Marshal(options.Body)
 		if err != nil {
 			return -1, nil, err
@@ -164,6 +187,23 @@ func Request(options *RequestOptions) (int, interface{}, error) {
 	return response.StatusCode, options.ResponseModel, nil
 }
 
+/**
+Makes an http request as the user of the access token, failing the test if the request could not be made
+*/
+func Send(t *testing.T, method string, path string, accessToken string, body interface{}, responseModel interface{}) (int, interface{}) {
+	status, resp, err := Request(&RequestOptions{
+		Method: method,
+		Path:   path,
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", accessToken),
+		},
+		Body:          body,
+		ResponseModel: responseModel,
+	})
+	assert.Nil(t, err)
+	return status, resp
+}
+
 func ConvertModel(source interface{}, target interface{}) interface{} {
 	data, _ := json.Marshal(source)
 	_ = json.Unmarshal(data, target)
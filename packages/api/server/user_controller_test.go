@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"github.com/honerlaw/mentordoc/server/http/request"
 	"github.com/honerlaw/mentordoc/server/http/response"
+	"github.com/honerlaw/mentordoc/server/lib/acl"
 	"github.com/honerlaw/mentordoc/server/lib/shared"
+	"github.com/honerlaw/mentordoc/server/lib/user"
+	"github.com/honerlaw/mentordoc/server/lib/util"
 	"github.com/honerlaw/mentordoc/server/test"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIntegrationSigninValidationFailure(t *testing.T) {
@@ -113,4 +118,235 @@ func TestIntegrationGetCurrentUser(t *testing.T) {
 
 	u := resp.(*shared.User)
 	assert.Equal(t, u.Id, authData.User.Id)
-}
\ No newline at end of file
+}
+func TestIntegrationPersonalAccessTokenIsLimitedToScope(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   "/user/token",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		Body: &request.PersonalAccessTokenCreateRequest{
+			Name:      "ci",
+			ExpiresAt: util.NowUnix() + int64(time.Hour),
+			Scopes:    []string{"view"},
+		},
+		ResponseModel: &user.PersonalAccessToken{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	token := resp.(*user.PersonalAccessToken)
+	assert.NotNil(t, token.Token)
+
+	// the token can view the organization
+	aclWrappedModels := make([]acl.AclWrappedModel, 0)
+	status, _, err = test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   "/organization/list",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", *token.Token),
+		},
+		ResponseModel: &aclWrappedModels,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, aclWrappedModels, 1)
+	assert.Equal(t, []string{"view"}, aclWrappedModels[0].Actions)
+
+	// but it can not create anything in it
+	status, _, err = test.Request(&test.RequestOptions{
+		Method: "POST",
+		Path:   "/folder",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", *token.Token),
+		},
+		Body: &request.FolderCreateRequest{
+			OrganizationId: authData.Organization.Id,
+			Name:           "test-name",
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestIntegrationRevokedPersonalAccessTokenIsRejected(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	token, err := testData.TestServer.PersonalAccessTokenService.Create(authData.User, "ci", util.NowUnix()+int64(time.Hour), []string{"view"})
+	assert.Nil(t, err)
+
+	_, err = testData.TestServer.PersonalAccessTokenService.Revoke(authData.User, token.Id)
+	assert.Nil(t, err)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   "/organization/list",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", *token.Token),
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestIntegrationExportCurrentUser(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "notes", "# notes")
+	assert.Nil(t, err)
+	thread, err := testData.TestServer.CommentService.CreateThread(authData.User, doc.Id, doc.Drafts[0].Id, nil, "looks good")
+	assert.Nil(t, err)
+	attachment, err := testData.TestServer.AttachmentService.Create(authData.User, doc.Id, "notes.txt", strings.NewReader("plain notes"))
+	assert.Nil(t, err)
+
+	status, resp, err := test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   "/user/export",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		ResponseModel: &user.UserExport{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	export := resp.(*user.UserExport)
+	assert.Equal(t, authData.User.Id, export.User.Id)
+	assert.Len(t, export.Memberships, 1)
+	assert.Equal(t, "organization:owner", export.Memberships[0].RoleName)
+	assert.Equal(t, authData.Organization.Id, export.Memberships[0].ResourceId)
+	assert.Len(t, export.CommentThreads, 1)
+	assert.Equal(t, thread.Id, export.CommentThreads[0].Id)
+	assert.Len(t, export.Comments, 1)
+	assert.Equal(t, "looks good", export.Comments[0].Content)
+	assert.Len(t, export.Attachments, 1)
+	assert.Equal(t, attachment.Id, export.Attachments[0].Id)
+}
+
+func TestIntegrationDeleteUserFailsWhenOnlyOwner(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "DELETE",
+		Path:   "/user",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestIntegrationDeleteUser(t *testing.T) {
+	if !*testData.Integration {
+		t.Skip("skipping integration test")
+	}
+	authData := test.SetupAuthentication(t, testData)
+	otherAuthData := test.SetupAuthentication(t, testData)
+
+	// make someone else an owner so the organization is not left without one
+	err := testData.TestServer.AclService.LinkUserToRole(otherAuthData.User, "organization:owner", authData.Organization.Id)
+	assert.Nil(t, err)
+
+	doc, err := testData.TestServer.DocumentService.Create(authData.User, authData.Organization.Id, nil, "notes", "# notes")
+	assert.Nil(t, err)
+	thread, err := testData.TestServer.CommentService.CreateThread(authData.User, doc.Id, doc.Drafts[0].Id, nil, "looks good")
+	assert.Nil(t, err)
+	attachment, err := testData.TestServer.AttachmentService.Create(authData.User, doc.Id, "notes.txt", strings.NewReader("plain notes"))
+	assert.Nil(t, err)
+	team, err := testData.TestServer.TeamService.Create(otherAuthData.User, authData.Organization.Id, "writers")
+	assert.Nil(t, err)
+	_, err = testData.TestServer.TeamService.AddMember(otherAuthData.User, authData.Organization.Id, team.Id, authData.User)
+	assert.Nil(t, err)
+	createDeny(t, map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", otherAuthData.AccessToken),
+	}, authData.Organization.Id, &request.DenyCreateRequest{
+		UserId:       &authData.User.Id,
+		ResourcePath: "document",
+		ResourceId:   doc.Id,
+		Action:       "modify",
+	})
+
+	status, _, err := test.Request(&test.RequestOptions{
+		Method: "DELETE",
+		Path:   "/user",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		ResponseModel: &shared.User{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	// the user is anonymized and can no longer authenticate
+	assert.Nil(t, testData.TestServer.UserRepository.FindById(authData.User.Id))
+	status, _, err = test.Request(&test.RequestOptions{
+		Method: "GET",
+		Path:   "/user",
+		Headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", authData.AccessToken),
+		},
+		ResponseModel: &shared.HttpError{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// what they wrote stays, handed over to the tombstone user
+	threads, comments, err := testData.TestServer.CommentService.FindByCreator(authData.User.Id)
+	assert.Nil(t, err)
+	assert.Len(t, threads, 0)
+	assert.Len(t, comments, 0)
+	threads, _, err = testData.TestServer.CommentService.FindByCreator(user.TombstoneUserId)
+	assert.Nil(t, err)
+	threadIds := make([]string, 0)
+	for _, found := range threads {
+		threadIds = append(threadIds, found.Id)
+	}
+	assert.Contains(t, threadIds, thread.Id)
+
+	// the draft was never published, it is kept so the document does not lose its only draft
+	drafts, err := testData.TestServer.DocumentService.FindDraftsByCreator(user.TombstoneUserId)
+	assert.Nil(t, err)
+	draftIds := make([]string, 0)
+	for _, found := range drafts {
+		draftIds = append(draftIds, found.Id)
+	}
+	assert.Contains(t, draftIds, doc.Drafts[0].Id)
+
+	attachments, err := testData.TestServer.AttachmentService.FindByCreator(authData.User.Id)
+	assert.Nil(t, err)
+	assert.Len(t, attachments, 0)
+	attachments, err = testData.TestServer.AttachmentService.FindByCreator(user.TombstoneUserId)
+	assert.Nil(t, err)
+	attachmentIds := make([]string, 0)
+	for _, found := range attachments {
+		attachmentIds = append(attachmentIds, found.Id)
+	}
+	assert.Contains(t, attachmentIds, attachment.Id)
+
+	// and they are taken out of their teams and denies
+	members, err := testData.TestServer.TeamService.ListMembers(otherAuthData.User, authData.Organization.Id, team.Id)
+	assert.Nil(t, err)
+	assert.Len(t, members, 0)
+	denies, err := testData.TestServer.AclService.FindOrganizationDenies(authData.Organization.Id)
+	assert.Nil(t, err)
+	assert.Len(t, denies, 0)
+}